@@ -0,0 +1,40 @@
+// Package cache содержит кеш значений с ограниченным временем жизни и его реализации:
+// LRU в памяти процесса и Redis, общий для нескольких экземпляров сервиса
+package cache
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cache хранит байтовые значения по строковым ключам не дольше заданного ttl.
+// Ошибки означают недоступность кеша, а не отсутствие ключа: вызывающий код
+// в этом случае должен идти в источник данных
+type Cache interface {
+	// Get возвращает значение и true, если ключ есть и не устарел
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete удаляет ключи, отсутствующие ключи пропускаются
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// New создаёт кеш по cfg.Backend. Для none возвращается nil
+func New(ctx context.Context, cfg config.Cache) (Cache, error) {
+	switch cfg.Backend {
+	case config.CacheNone, "":
+		return nil, nil
+	case config.CacheMemory:
+		return NewLRU(cfg.Size), nil
+	case config.CacheRedis:
+		r, err := NewRedis(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("неизвестный бэкенд кеша: %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCache проверяет общее для всех реализаций поведение
+func testCache(t *testing.T, c Cache) {
+	ctx := context.Background()
+
+	_, ok, err := c.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	buf := []byte("value1")
+	assert.NoError(t, c.Set(ctx, "key1", buf, time.Minute))
+	copy(buf, "broken")
+	value, ok, err := c.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value1"), value)
+
+	assert.NoError(t, c.Set(ctx, "key1", []byte("value2"), time.Minute))
+	assert.NoError(t, c.Set(ctx, "key2", []byte("value3"), time.Minute))
+	value, _, _ = c.Get(ctx, "key1")
+	assert.Equal(t, []byte("value2"), value)
+
+	assert.NoError(t, c.Delete(ctx, "key1", "key2", "missing"))
+	assert.NoError(t, c.Delete(ctx))
+	_, ok, _ = c.Get(ctx, "key1")
+	assert.False(t, ok)
+	_, ok, _ = c.Get(ctx, "key2")
+	assert.False(t, ok)
+}
+
+func TestLRU(t *testing.T) {
+	testCache(t, NewLRU(10))
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(3)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprint(i), []byte{byte(i)}, time.Minute))
+	}
+
+	// обращение к "0" делает вытесняемым "1"
+	_, ok, _ := c.Get(ctx, "0")
+	assert.True(t, ok)
+	require.NoError(t, c.Set(ctx, "3", []byte{3}, time.Minute))
+	assert.Equal(t, 3, c.Len())
+	_, ok, _ = c.Get(ctx, "1")
+	assert.False(t, ok)
+	for _, key := range []string{"0", "2", "3"} {
+		_, ok, _ = c.Get(ctx, key)
+		assert.True(t, ok, key)
+	}
+}
+
+func TestLRUExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "short", []byte("1"), time.Second))
+	require.NoError(t, c.Set(ctx, "long", []byte("2"), time.Hour))
+	now = now.Add(time.Minute)
+
+	_, ok, _ := c.Get(ctx, "short")
+	assert.False(t, ok)
+	_, ok, _ = c.Get(ctx, "long")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func newMiniredis(t *testing.T) (*miniredis.Miniredis, *Redis) {
+	server := miniredis.RunT(t)
+	c, err := NewRedis(context.Background(), config.Cache{RedisAddr: server.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return server, c
+}
+
+func TestRedis(t *testing.T) {
+	_, c := newMiniredis(t)
+	testCache(t, c)
+}
+
+func TestRedisExpiration(t *testing.T) {
+	ctx := context.Background()
+	server, c := newMiniredis(t)
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Second))
+	assert.Equal(t, time.Second, server.TTL("key"))
+	server.FastForward(2 * time.Second)
+	_, ok, err := c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	ctx := context.Background()
+	server, c := newMiniredis(t)
+	addr := server.Addr()
+
+	// недоступность сервера -- ошибка, а не промах
+	server.Close()
+	_, ok, err := c.Get(ctx, "key")
+	assert.Error(t, err)
+	assert.False(t, ok)
+	assert.Error(t, c.Set(ctx, "key", []byte("value"), time.Second))
+
+	_, err = NewRedis(ctx, config.Cache{RedisAddr: addr})
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(ctx, config.Cache{Backend: config.CacheNone})
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	c, err = New(ctx, config.Cache{Backend: config.CacheMemory, Size: 10})
+	assert.NoError(t, err)
+	assert.IsType(t, &LRU{}, c)
+
+	_, err = New(ctx, config.Cache{Backend: "memcached"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis -- кеш в Redis или совместимом сервере (KeyDB, Valkey, Dragonfly).
+// Один кеш на все экземпляры сервиса, поэтому сброс записи виден им всем сразу
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis подключается к серверу из cfg и проверяет его доступность
+func NewRedis(ctx context.Context, cfg config.Cache) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ошибка подключения к Redis %s: %w", cfg.RedisAddr, err)
+	}
+	slog.Info("cache connection established", "addr", cfg.RedisAddr, "db", cfg.RedisDB)
+	return &Redis{client: client}, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка чтения из кеша: %w", err)
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("ошибка записи в кеш: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("ошибка удаления из кеша: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
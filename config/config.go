@@ -0,0 +1,796 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// значение, которым заменяются секреты при выводе конфигурации
+const redacted = "******"
+
+// Config -- конфигурация сервиса. Значения загружаются в порядке приоритета:
+// значения по умолчанию, файл конфигурации (YAML/TOML), переменные окружения, флаги
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Database  Database  `yaml:"database" toml:"database"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	Shop      Shop      `yaml:"shop" toml:"shop"`
+	Transfers Transfers `yaml:"transfers" toml:"transfers"`
+	Cache     Cache     `yaml:"cache" toml:"cache"`
+	RateLimit RateLimit `yaml:"ratelimit" toml:"ratelimit"`
+	Admin     Admin     `yaml:"admin" toml:"admin"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+}
+
+type Server struct {
+	Port            int           `yaml:"port" toml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	HealthTimeout   time.Duration `yaml:"health_timeout" toml:"health_timeout"`
+}
+
+// драйверы хранилища
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+type Database struct {
+	Driver          string        `yaml:"driver" toml:"driver"`
+	Path            string        `yaml:"path" toml:"path"`
+	Host            string        `yaml:"host" toml:"host"`
+	Port            int           `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	Name            string        `yaml:"name" toml:"name"`
+	SSLMode         string        `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	MigrateOnStart  bool          `yaml:"migrate_on_start" toml:"migrate_on_start"`
+	// строки подключения к репликам Postgres, на которые уходят чтения инвентаря, истории и каталога
+	Replicas             []string      `yaml:"replicas" toml:"replicas"`
+	ReplicaMaxLag        time.Duration `yaml:"replica_max_lag" toml:"replica_max_lag"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" toml:"replica_check_interval"`
+}
+
+type Auth struct {
+	JwtKey   string        `yaml:"jwt_key" toml:"jwt_key"`
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+}
+
+type Shop struct {
+	StartingBalance float64 `yaml:"starting_balance" toml:"starting_balance"`
+	// сколько времени после покупки пользователь может сам вернуть товар, 0 -- только через администратора
+	RefundWindow time.Duration `yaml:"refund_window" toml:"refund_window"`
+	// период выполнения регулярных начислений и сжигания монет с истёкшим сроком, 0 -- выключено
+	GrantCheckInterval time.Duration `yaml:"grant_check_interval" toml:"grant_check_interval"`
+}
+
+// Transfers -- правила, которые проверяются перед переводом монет. Нулевое значение выключает правило
+type Transfers struct {
+	// максимальная сумма одного перевода
+	MaxAmount float64 `yaml:"max_amount" toml:"max_amount"`
+	// сколько монет пользователь может отправить за сутки
+	DailyLimit float64 `yaml:"daily_limit" toml:"daily_limit"`
+	// сколько монет пользователь может отправить за сутки одному получателю
+	RecipientDailyLimit float64 `yaml:"recipient_daily_limit" toml:"recipient_daily_limit"`
+	// не больше VelocityCount переводов за VelocityWindow
+	VelocityCount  int           `yaml:"velocity_count" toml:"velocity_count"`
+	VelocityWindow time.Duration `yaml:"velocity_window" toml:"velocity_window"`
+	// пользователи, которые не могут ни отправлять, ни получать монеты
+	Blocklist []string `yaml:"blocklist" toml:"blocklist"`
+}
+
+// бэкенды кеша каталога и авторизации
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+type Cache struct {
+	// время жизни закешированного ответа /api/info, 0 -- кеш выключен
+	InfoTTL time.Duration `yaml:"info_ttl" toml:"info_ttl"`
+	// кеш товаров каталога и проверенных паролей: none, memory (LRU в процессе) или redis
+	Backend       string        `yaml:"backend" toml:"backend"`
+	TTL           time.Duration `yaml:"ttl" toml:"ttl"`
+	Size          int           `yaml:"size" toml:"size"`
+	RedisAddr     string        `yaml:"redis_addr" toml:"redis_addr"`
+	RedisPassword string        `yaml:"redis_password" toml:"redis_password"`
+	RedisDB       int           `yaml:"redis_db" toml:"redis_db"`
+}
+
+// хранилища состояния ограничителя частоты запросов
+const (
+	RateLimitMemory = "memory"
+	RateLimitRedis  = "redis"
+)
+
+// Limit -- корзина токенов: Rate запросов в секунду в среднем и не больше Burst подряд.
+// Нулевой Rate снимает ограничение
+type Limit struct {
+	Rate  float64 `yaml:"rate" toml:"rate"`
+	Burst int     `yaml:"burst" toml:"burst"`
+}
+
+// RouteLimits -- ограничения маршрута по пользователю из JWT и по IP-адресу клиента
+type RouteLimits struct {
+	User Limit `yaml:"user" toml:"user"`
+	IP   Limit `yaml:"ip" toml:"ip"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// memory -- счётчики в памяти процесса, redis -- общие для всех экземпляров, подключение из cache.redis_*
+	Store string `yaml:"store" toml:"store"`
+	// брать адрес клиента из X-Forwarded-For, если сервис стоит за балансировщиком
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" toml:"trust_forwarded_for"`
+	// ограничения маршрутов, для которых нет записи в Routes
+	Default RouteLimits `yaml:"default" toml:"default"`
+	// ограничения по именам маршрутов (ApiSendCoinPost, ApiAuthPost, ...), заменяют Default целиком
+	Routes map[string]RouteLimits `yaml:"routes" toml:"routes"`
+}
+
+// Admin -- доступ к /api/admin/*. Администратор передаёт свой токен в заголовке Authorization: Bearer <token>,
+// имя администратора попадает в логи. Без токенов административные запросы отклоняются
+type Admin struct {
+	// токены по именам администраторов
+	Tokens map[string]string `yaml:"tokens" toml:"tokens"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			HealthTimeout:   2 * time.Second,
+		},
+		Database: Database{
+			Driver:               DriverPostgres,
+			Path:                 "shop.db",
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 "postgres",
+			Name:                 "shop",
+			SSLMode:              "disable",
+			MaxOpenConns:         20,
+			MaxIdleConns:         10,
+			ConnMaxLifetime:      30 * time.Minute,
+			MigrateOnStart:       true,
+			ReplicaMaxLag:        5 * time.Second,
+			ReplicaCheckInterval: time.Second,
+		},
+		Auth: Auth{
+			TokenTTL: 24 * time.Hour,
+		},
+		Shop: Shop{
+			StartingBalance:    1000,
+			RefundWindow:       24 * time.Hour,
+			GrantCheckInterval: time.Minute,
+		},
+		Transfers: Transfers{
+			VelocityWindow: time.Minute,
+		},
+		Cache: Cache{
+			Backend:   CacheNone,
+			TTL:       5 * time.Minute,
+			Size:      10000,
+			RedisAddr: "localhost:6379",
+		},
+		RateLimit: RateLimit{
+			Store: RateLimitMemory,
+			Default: RouteLimits{
+				User: Limit{Rate: 10, Burst: 20},
+				IP:   Limit{Rate: 50, Burst: 100},
+			},
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			ServiceName: "avito-shop-service",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// binding связывает флаг командной строки с переменной окружения
+type binding struct {
+	flag string
+	env  string
+}
+
+// bind регистрирует флаги поверх полей конфигурации и возвращает соответствие флагов переменным окружения
+func (c *Config) bind(fs *flag.FlagSet) []binding {
+	fs.IntVar(&c.Server.Port, "port", c.Server.Port, "порт HTTP-сервера")
+	fs.DurationVar(&c.Server.ReadTimeout, "read-timeout", c.Server.ReadTimeout, "таймаут чтения запроса")
+	fs.DurationVar(&c.Server.WriteTimeout, "write-timeout", c.Server.WriteTimeout, "таймаут записи ответа")
+	fs.DurationVar(&c.Server.ShutdownTimeout, "shutdown-timeout", c.Server.ShutdownTimeout, "таймаут корректного завершения")
+	fs.DurationVar(&c.Server.HealthTimeout, "health-timeout", c.Server.HealthTimeout, "таймаут проверок /readyz и /livez")
+
+	fs.StringVar(&c.Database.Driver, "db-driver", c.Database.Driver, "хранилище: postgres, sqlite или memory (данные в памяти процесса)")
+	fs.StringVar(&c.Database.Path, "db-path", c.Database.Path, "путь к файлу базы данных SQLite")
+	fs.StringVar(&c.Database.Host, "db-host", c.Database.Host, "хост базы данных")
+	fs.IntVar(&c.Database.Port, "db-port", c.Database.Port, "порт базы данных")
+	fs.StringVar(&c.Database.User, "db-user", c.Database.User, "пользователь базы данных")
+	fs.StringVar(&c.Database.Password, "db-password", c.Database.Password, "пароль базы данных")
+	fs.StringVar(&c.Database.Name, "db-name", c.Database.Name, "имя базы данных")
+	fs.StringVar(&c.Database.SSLMode, "db-sslmode", c.Database.SSLMode, "режим SSL подключения к базе данных")
+	fs.IntVar(&c.Database.MaxOpenConns, "db-max-open-conns", c.Database.MaxOpenConns, "максимум открытых соединений")
+	fs.IntVar(&c.Database.MaxIdleConns, "db-max-idle-conns", c.Database.MaxIdleConns, "максимум простаивающих соединений")
+	fs.DurationVar(&c.Database.ConnMaxLifetime, "db-conn-max-lifetime", c.Database.ConnMaxLifetime, "время жизни соединения")
+	fs.BoolVar(&c.Database.MigrateOnStart, "db-migrate-on-start", c.Database.MigrateOnStart, "применять миграции при старте сервиса")
+	fs.Var((*stringList)(&c.Database.Replicas), "db-replicas", "строки подключения к репликам через запятую")
+	fs.DurationVar(&c.Database.ReplicaMaxLag, "db-replica-max-lag", c.Database.ReplicaMaxLag, "максимальное отставание реплики, при котором на неё идут чтения")
+	fs.DurationVar(&c.Database.ReplicaCheckInterval, "db-replica-check-interval", c.Database.ReplicaCheckInterval, "период проверки отставания реплик")
+
+	fs.StringVar(&c.Auth.JwtKey, "jwt-key", c.Auth.JwtKey, "ключ подписи JWT-токенов")
+	fs.DurationVar(&c.Auth.TokenTTL, "token-ttl", c.Auth.TokenTTL, "время жизни JWT-токена")
+
+	fs.Float64Var(&c.Shop.StartingBalance, "starting-balance", c.Shop.StartingBalance, "стартовый баланс нового пользователя")
+	fs.DurationVar(&c.Shop.RefundWindow, "refund-window", c.Shop.RefundWindow, "сколько времени после покупки пользователь может сам вернуть товар, 0 -- только через администратора")
+	fs.DurationVar(&c.Shop.GrantCheckInterval, "grant-check-interval", c.Shop.GrantCheckInterval, "период выполнения регулярных начислений и сжигания монет с истёкшим сроком, 0 -- выключено")
+
+	fs.Float64Var(&c.Transfers.MaxAmount, "transfer-max-amount", c.Transfers.MaxAmount, "максимальная сумма перевода, 0 -- без ограничения")
+	fs.Float64Var(&c.Transfers.DailyLimit, "transfer-daily-limit", c.Transfers.DailyLimit, "сумма переводов пользователя за сутки, 0 -- без ограничения")
+	fs.Float64Var(&c.Transfers.RecipientDailyLimit, "transfer-recipient-daily-limit", c.Transfers.RecipientDailyLimit, "сумма переводов одному получателю за сутки, 0 -- без ограничения")
+	fs.IntVar(&c.Transfers.VelocityCount, "transfer-velocity-count", c.Transfers.VelocityCount, "число переводов за transfer-velocity-window, 0 -- без ограничения")
+	fs.DurationVar(&c.Transfers.VelocityWindow, "transfer-velocity-window", c.Transfers.VelocityWindow, "окно, за которое считается число переводов")
+	fs.Var((*stringList)(&c.Transfers.Blocklist), "transfer-blocklist", "пользователи через запятую, которым запрещены переводы")
+
+	fs.DurationVar(&c.Cache.InfoTTL, "cache-info-ttl", c.Cache.InfoTTL, "время жизни кеша /api/info, 0 -- без кеша")
+	fs.StringVar(&c.Cache.Backend, "cache-backend", c.Cache.Backend, "кеш каталога и авторизации: none, memory или redis")
+	fs.DurationVar(&c.Cache.TTL, "cache-ttl", c.Cache.TTL, "время жизни записей кеша каталога и авторизации")
+	fs.IntVar(&c.Cache.Size, "cache-size", c.Cache.Size, "максимум записей в кеше memory")
+	fs.StringVar(&c.Cache.RedisAddr, "cache-redis-addr", c.Cache.RedisAddr, "адрес Redis для кеша redis")
+	fs.StringVar(&c.Cache.RedisPassword, "cache-redis-password", c.Cache.RedisPassword, "пароль Redis")
+	fs.IntVar(&c.Cache.RedisDB, "cache-redis-db", c.Cache.RedisDB, "номер базы Redis")
+
+	fs.BoolVar(&c.RateLimit.Enabled, "ratelimit-enabled", c.RateLimit.Enabled, "ограничивать частоту запросов")
+	fs.StringVar(&c.RateLimit.Store, "ratelimit-store", c.RateLimit.Store, "хранилище счётчиков: memory или redis")
+	fs.BoolVar(&c.RateLimit.TrustForwardedFor, "ratelimit-trust-forwarded-for", c.RateLimit.TrustForwardedFor, "брать адрес клиента из X-Forwarded-For")
+	fs.Float64Var(&c.RateLimit.Default.User.Rate, "ratelimit-user-rate", c.RateLimit.Default.User.Rate, "запросов в секунду от пользователя, 0 -- без ограничения")
+	fs.IntVar(&c.RateLimit.Default.User.Burst, "ratelimit-user-burst", c.RateLimit.Default.User.Burst, "запросов подряд от пользователя")
+	fs.Float64Var(&c.RateLimit.Default.IP.Rate, "ratelimit-ip-rate", c.RateLimit.Default.IP.Rate, "запросов в секунду с IP-адреса, 0 -- без ограничения")
+	fs.IntVar(&c.RateLimit.Default.IP.Burst, "ratelimit-ip-burst", c.RateLimit.Default.IP.Burst, "запросов подряд с IP-адреса")
+	fs.Var((*routeLimits)(&c.RateLimit.Routes), "ratelimit-routes", "ограничения маршрутов: Route=user:RATE/BURST,ip:RATE/BURST;...")
+
+	fs.Var((*tokenMap)(&c.Admin.Tokens), "admin-tokens", "токены администраторов: name=token,...")
+
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, "уровень логирования: debug, info, warn, error")
+	fs.StringVar(&c.Log.Format, "log-format", c.Log.Format, "формат логов: json, text")
+
+	fs.StringVar(&c.Tracing.Exporter, "tracing-exporter", c.Tracing.Exporter, "экспортёр трейсов: none, stdout, otlp")
+	fs.StringVar(&c.Tracing.Endpoint, "tracing-endpoint", c.Tracing.Endpoint, "адрес OTLP/HTTP коллектора")
+	fs.BoolVar(&c.Tracing.Insecure, "tracing-insecure", c.Tracing.Insecure, "подключаться к коллектору без TLS")
+	fs.StringVar(&c.Tracing.ServiceName, "tracing-service-name", c.Tracing.ServiceName, "имя сервиса в трейсах")
+	fs.Float64Var(&c.Tracing.SampleRatio, "tracing-sample-ratio", c.Tracing.SampleRatio, "доля сэмплируемых трейсов от 0 до 1")
+
+	return []binding{
+		{"port", "SERVER_PORT"},
+		{"read-timeout", "SERVER_READ_TIMEOUT"},
+		{"write-timeout", "SERVER_WRITE_TIMEOUT"},
+		{"shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT"},
+		{"health-timeout", "SERVER_HEALTH_TIMEOUT"},
+		{"db-driver", "DATABASE_DRIVER"},
+		{"db-path", "DATABASE_PATH"},
+		{"db-host", "DATABASE_HOST"},
+		{"db-port", "DATABASE_PORT"},
+		{"db-user", "DATABASE_USER"},
+		{"db-password", "DATABASE_PASSWORD"},
+		{"db-name", "DATABASE_NAME"},
+		{"db-sslmode", "DATABASE_SSLMODE"},
+		{"db-max-open-conns", "DATABASE_MAX_OPEN_CONNS"},
+		{"db-max-idle-conns", "DATABASE_MAX_IDLE_CONNS"},
+		{"db-conn-max-lifetime", "DATABASE_CONN_MAX_LIFETIME"},
+		{"db-migrate-on-start", "DATABASE_MIGRATE_ON_START"},
+		{"db-replicas", "DATABASE_REPLICAS"},
+		{"db-replica-max-lag", "DATABASE_REPLICA_MAX_LAG"},
+		{"db-replica-check-interval", "DATABASE_REPLICA_CHECK_INTERVAL"},
+		{"jwt-key", "JWT_KEY"},
+		{"token-ttl", "TOKEN_TTL"},
+		{"starting-balance", "SHOP_STARTING_BALANCE"},
+		{"refund-window", "SHOP_REFUND_WINDOW"},
+		{"grant-check-interval", "SHOP_GRANT_CHECK_INTERVAL"},
+		{"transfer-max-amount", "TRANSFERS_MAX_AMOUNT"},
+		{"transfer-daily-limit", "TRANSFERS_DAILY_LIMIT"},
+		{"transfer-recipient-daily-limit", "TRANSFERS_RECIPIENT_DAILY_LIMIT"},
+		{"transfer-velocity-count", "TRANSFERS_VELOCITY_COUNT"},
+		{"transfer-velocity-window", "TRANSFERS_VELOCITY_WINDOW"},
+		{"transfer-blocklist", "TRANSFERS_BLOCKLIST"},
+		{"cache-info-ttl", "CACHE_INFO_TTL"},
+		{"cache-backend", "CACHE_BACKEND"},
+		{"cache-ttl", "CACHE_TTL"},
+		{"cache-size", "CACHE_SIZE"},
+		{"cache-redis-addr", "CACHE_REDIS_ADDR"},
+		{"cache-redis-password", "CACHE_REDIS_PASSWORD"},
+		{"cache-redis-db", "CACHE_REDIS_DB"},
+		{"ratelimit-enabled", "RATELIMIT_ENABLED"},
+		{"ratelimit-store", "RATELIMIT_STORE"},
+		{"ratelimit-trust-forwarded-for", "RATELIMIT_TRUST_FORWARDED_FOR"},
+		{"ratelimit-user-rate", "RATELIMIT_USER_RATE"},
+		{"ratelimit-user-burst", "RATELIMIT_USER_BURST"},
+		{"ratelimit-ip-rate", "RATELIMIT_IP_RATE"},
+		{"ratelimit-ip-burst", "RATELIMIT_IP_BURST"},
+		{"ratelimit-routes", "RATELIMIT_ROUTES"},
+		{"admin-tokens", "ADMIN_TOKENS"},
+		{"log-level", "LOG_LEVEL"},
+		{"log-format", "LOG_FORMAT"},
+		{"tracing-exporter", "TRACING_EXPORTER"},
+		{"tracing-endpoint", "TRACING_ENDPOINT"},
+		{"tracing-insecure", "TRACING_INSECURE"},
+		{"tracing-service-name", "TRACING_SERVICE_NAME"},
+		{"tracing-sample-ratio", "TRACING_SAMPLE_RATIO"},
+	}
+}
+
+// Load собирает конфигурацию из значений по умолчанию, файла, окружения и аргументов командной строки.
+// Путь к файлу задаётся флагом -config или переменной CONFIG_FILE.
+// Вторым значением возвращаются аргументы, оставшиеся после флагов (например, подкоманда)
+func Load(args []string) (*Config, []string, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("api-avito-shop", flag.ContinueOnError)
+	path, _ := lookupEnv("CONFIG_FILE")
+	fs.StringVar(&path, "config", path, "путь к файлу конфигурации (.yaml, .yml, .toml)")
+	bindings := cfg.bind(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// запомним явно заданные флаги, чтобы применить их поверх файла и окружения
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, b := range bindings {
+		value, ok := lookupEnv(b.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := fs.Set(b.flag, value); err != nil {
+			return nil, nil, fmt.Errorf("некорректное значение переменной %s: %w", b.env, err)
+		}
+	}
+
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return nil, nil, fmt.Errorf("некорректное значение флага -%s: %w", name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("неподдерживаемый формат файла конфигурации: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка разбора файла конфигурации %s: %w", path, err)
+	}
+	return nil
+}
+
+// validatePostgres проверяет параметры подключения, которые нужны только драйверу postgres
+func (d Database) validatePostgres() []error {
+	var errs []error
+	if d.Host == "" {
+		errs = append(errs, errors.New("database.host не задан"))
+	}
+	if d.Port <= 0 || d.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port вне диапазона: %d", d.Port))
+	}
+	if d.User == "" {
+		errs = append(errs, errors.New("database.user не задан"))
+	}
+	if d.Name == "" {
+		errs = append(errs, errors.New("database.name не задан"))
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("размеры пула соединений не могут быть отрицательными"))
+	}
+	if len(d.Replicas) > 0 && (d.ReplicaMaxLag <= 0 || d.ReplicaCheckInterval <= 0) {
+		errs = append(errs, errors.New("database.replica_max_lag и database.replica_check_interval должны быть положительными"))
+	}
+	return errs
+}
+
+// Validate проверяет корректность значений конфигурации
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port вне диапазона: %d", c.Server.Port))
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.ShutdownTimeout <= 0 || c.Server.HealthTimeout <= 0 {
+		errs = append(errs, errors.New("таймауты сервера должны быть положительными"))
+	}
+	switch c.Database.Driver {
+	case DriverPostgres:
+		errs = append(errs, c.Database.validatePostgres()...)
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path не задан"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный database.driver: %q", c.Database.Driver))
+	}
+	if c.Auth.JwtKey == "" {
+		errs = append(errs, errors.New("auth.jwt_key не задан"))
+	}
+	if c.Auth.TokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("auth.token_ttl должен быть положительным: %s", c.Auth.TokenTTL))
+	}
+	if c.Shop.StartingBalance < 0 {
+		errs = append(errs, fmt.Errorf("shop.starting_balance не может быть отрицательным: %v", c.Shop.StartingBalance))
+	}
+	if c.Shop.RefundWindow < 0 {
+		errs = append(errs, fmt.Errorf("shop.refund_window не может быть отрицательным: %s", c.Shop.RefundWindow))
+	}
+	if c.Shop.GrantCheckInterval < 0 {
+		errs = append(errs, fmt.Errorf("shop.grant_check_interval не может быть отрицательным: %s", c.Shop.GrantCheckInterval))
+	}
+	if c.Cache.InfoTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache.info_ttl не может быть отрицательным: %s", c.Cache.InfoTTL))
+	}
+	errs = append(errs, c.Transfers.validate()...)
+	errs = append(errs, c.Cache.validate()...)
+	errs = append(errs, c.RateLimit.validate(c.Cache)...)
+	errs = append(errs, c.Admin.validate()...)
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный log.level: %q", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный log.format: %q", c.Log.Format))
+	}
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint обязателен для экспортёра otlp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный tracing.exporter: %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio должен быть в диапазоне [0, 1]: %v", c.Tracing.SampleRatio))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("некорректная конфигурация: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (t Transfers) validate() []error {
+	var errs []error
+	if t.MaxAmount < 0 || t.DailyLimit < 0 || t.RecipientDailyLimit < 0 {
+		errs = append(errs, errors.New("transfers.max_amount, transfers.daily_limit и transfers.recipient_daily_limit не могут быть отрицательными"))
+	}
+	if t.VelocityCount < 0 {
+		errs = append(errs, fmt.Errorf("transfers.velocity_count не может быть отрицательным: %d", t.VelocityCount))
+	}
+	if t.VelocityCount > 0 && t.VelocityWindow <= 0 {
+		errs = append(errs, fmt.Errorf("transfers.velocity_window должен быть положительным: %s", t.VelocityWindow))
+	}
+	return errs
+}
+
+func (c Cache) validate() []error {
+	var errs []error
+	switch c.Backend {
+	case CacheNone:
+		return nil
+	case CacheMemory:
+		if c.Size <= 0 {
+			errs = append(errs, fmt.Errorf("cache.size должен быть положительным: %d", c.Size))
+		}
+	case CacheRedis:
+		if c.RedisAddr == "" {
+			errs = append(errs, errors.New("cache.redis_addr обязателен для кеша redis"))
+		}
+	default:
+		return []error{fmt.Errorf("неизвестный cache.backend: %q", c.Backend)}
+	}
+	if c.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("cache.ttl должен быть положительным: %s", c.TTL))
+	}
+	return errs
+}
+
+func (a Admin) validate() []error {
+	var errs []error
+	names := make(map[string]string, len(a.Tokens))
+	for name, token := range a.Tokens {
+		if name == "" || token == "" {
+			errs = append(errs, errors.New("admin.tokens: имя администратора и токен не могут быть пустыми"))
+			continue
+		}
+		if other, ok := names[token]; ok {
+			errs = append(errs, fmt.Errorf("admin.tokens: у администраторов %s и %s одинаковый токен", min(name, other), max(name, other)))
+		}
+		names[token] = name
+	}
+	return errs
+}
+
+func (r RateLimit) validate(cache Cache) []error {
+	if !r.Enabled {
+		return nil
+	}
+	var errs []error
+	switch r.Store {
+	case RateLimitMemory:
+	case RateLimitRedis:
+		if cache.RedisAddr == "" {
+			errs = append(errs, errors.New("cache.redis_addr обязателен для ratelimit.store redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный ratelimit.store: %q", r.Store))
+	}
+	check := func(name string, l Limit) {
+		if l.Rate < 0 || (l.Rate > 0 && l.Burst < 1) {
+			errs = append(errs, fmt.Errorf("ratelimit.%s: rate не может быть отрицательным, burst должен быть не меньше 1", name))
+		}
+	}
+	check("default.user", r.Default.User)
+	check("default.ip", r.Default.IP)
+	routes := make([]string, 0, len(r.Routes))
+	for route := range r.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		check("routes."+route+".user", r.Routes[route].User)
+		check("routes."+route+".ip", r.Routes[route].IP)
+	}
+	return errs
+}
+
+// Redacted возвращает копию конфигурации со скрытыми секретами
+func (c Config) Redacted() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = redacted
+	}
+	if c.Auth.JwtKey != "" {
+		c.Auth.JwtKey = redacted
+	}
+	if c.Cache.RedisPassword != "" {
+		c.Cache.RedisPassword = redacted
+	}
+	if len(c.Admin.Tokens) > 0 {
+		tokens := make(map[string]string, len(c.Admin.Tokens))
+		for name := range c.Admin.Tokens {
+			tokens[name] = redacted
+		}
+		c.Admin.Tokens = tokens
+	}
+	if len(c.Database.Replicas) > 0 {
+		replicas := make([]string, len(c.Database.Replicas))
+		for i, dsn := range c.Database.Replicas {
+			replicas[i] = redactDSN(dsn)
+		}
+		c.Database.Replicas = replicas
+	}
+	return c
+}
+
+var dsnPasswordRe = regexp.MustCompile(`password=\S*`)
+
+// redactDSN скрывает пароль в строке подключения вида URL или key=value
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+	}
+	return dsnPasswordRe.ReplaceAllString(dsn, "password="+redacted)
+}
+
+// routeLimits -- флаг с ограничениями маршрутов в виде Route=user:RATE/BURST,ip:RATE/BURST;...
+// Не указанный вид ограничения у маршрута не ограничен. Новое значение заменяет прежний набор
+type routeLimits map[string]RouteLimits
+
+func (l *routeLimits) String() string {
+	if l == nil || len(*l) == 0 {
+		return ""
+	}
+	routes := make([]string, 0, len(*l))
+	for route := range *l {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var b strings.Builder
+	for i, route := range routes {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		limits := (*l)[route]
+		fmt.Fprintf(&b, "%s=user:%v/%d,ip:%v/%d", route, limits.User.Rate, limits.User.Burst, limits.IP.Rate, limits.IP.Burst)
+	}
+	return b.String()
+}
+
+func (l *routeLimits) Set(value string) error {
+	routes := make(routeLimits)
+	for _, spec := range strings.Split(value, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		route, kinds, ok := strings.Cut(spec, "=")
+		if !ok || route == "" {
+			return fmt.Errorf("ожидается Route=user:RATE/BURST,ip:RATE/BURST: %q", spec)
+		}
+		var limits RouteLimits
+		for _, kind := range strings.Split(kinds, ",") {
+			name, limit, ok := strings.Cut(strings.TrimSpace(kind), ":")
+			if !ok {
+				return fmt.Errorf("ожидается user:RATE/BURST или ip:RATE/BURST: %q", kind)
+			}
+			parsed, err := parseLimit(limit)
+			if err != nil {
+				return fmt.Errorf("некорректное ограничение %q: %w", kind, err)
+			}
+			switch name {
+			case "user":
+				limits.User = parsed
+			case "ip":
+				limits.IP = parsed
+			default:
+				return fmt.Errorf("неизвестный вид ограничения %q", name)
+			}
+		}
+		routes[strings.TrimSpace(route)] = limits
+	}
+	*l = routes
+	return nil
+}
+
+func parseLimit(value string) (Limit, error) {
+	rate, burst, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, errors.New("ожидается RATE/BURST")
+	}
+	var l Limit
+	var err error
+	if l.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+		return Limit{}, err
+	}
+	if l.Burst, err = strconv.Atoi(burst); err != nil {
+		return Limit{}, err
+	}
+	return l, nil
+}
+
+// stringList -- флаг со списком значений через запятую, новое значение заменяет прежний список
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// tokenMap -- флаг с токенами в виде name=token,name=token. Новое значение заменяет прежний набор
+type tokenMap map[string]string
+
+func (m *tokenMap) String() string {
+	if m == nil || len(*m) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(*m))
+	for name, token := range *m {
+		items = append(items, name+"="+token)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (m *tokenMap) Set(value string) error {
+	tokens := make(tokenMap)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(item, "=")
+		if !ok {
+			// значение не выводится, в нём может быть токен
+			return errors.New("ожидается name=token")
+		}
+		tokens[strings.TrimSpace(name)] = strings.TrimSpace(token)
+	}
+	*m = tokens
+	return nil
+}
+
+// String возвращает конфигурацию без секретов, пригодную для вывода в лог
+func (c Config) String() string {
+	// отдельный тип нужен, чтобы fmt не зациклился на методе String
+	type plain Config
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+// DSN возвращает строку подключения к Postgres
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
@@ -0,0 +1,95 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/database/dbtest"
+	"api-avito-shop/migrations"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) database.Database {
+		return database.NewMemory()
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) database.Database {
+		db, err := database.NewSQLite(config.Database{Path: filepath.Join(t.TempDir(), "shop.db"), MaxOpenConns: 4})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		migrator, err := migrations.NewSQLite(db.DB())
+		require.NoError(t, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(t, err)
+		return db
+	})
+}
+
+// TestPostgresConformance по умолчанию пропускается, базу выбирает переменная TEST_POSTGRES:
+//   - external -- уже запущенный Postgres с параметрами из DATABASE_* (как у сервиса);
+//   - embedded -- временный Postgres, который скачивается и запускается без контейнера.
+//
+// Перед каждым сценарием все таблицы, кроме каталога товаров, очищаются, а остатки и лимиты товаров сбрасываются
+func TestPostgresConformance(t *testing.T) {
+	var cfg config.Database
+	switch os.Getenv("TEST_POSTGRES") {
+	case "external":
+		loaded, _, err := config.Load(nil)
+		require.NoError(t, err)
+		cfg = loaded.Database
+	case "embedded":
+		cfg = config.Default().Database
+		cfg.Port = 55432
+		cfg.Password = "postgres"
+		pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+			Port(uint32(cfg.Port)).
+			Database(cfg.Name).
+			RuntimePath(t.TempDir()).
+			Logger(nil))
+		require.NoError(t, pg.Start())
+		t.Cleanup(func() { pg.Stop() })
+	default:
+		t.Skip("TEST_POSTGRES не задан")
+	}
+
+	db, err := database.NewPostgres(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.NewPostgres(db.DB())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	// список таблиц берётся из схемы, чтобы таблицы новых миграций тоже очищались
+	rows, err := db.DB().Query(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema()
+		AND tablename NOT IN ('products', 'schema_migrations')`)
+	require.NoError(t, err)
+	var tables []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	rows.Close()
+	truncate := "TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"
+
+	dbtest.Run(t, func(t *testing.T) database.Database {
+		_, err := db.DB().Exec(truncate)
+		require.NoError(t, err)
+		_, err = db.DB().Exec("UPDATE products SET stock = NULL, max_per_user = NULL")
+		require.NoError(t, err)
+		return db
+	})
+}
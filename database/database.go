@@ -1,14 +1,447 @@
 package database
 
-import "api-avito-shop/models"
+import (
+	"api-avito-shop/models"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
 
 type Database interface {
-	AddNewUser(username, password string) (bool, error)
-	AuthorizeUser(username, password string) (bool, int64, error)
-	GetUserCoinsAndItemPrice(userId int64, item string) (float64, float64, int64, error)
-	UpdateUserBalanceAndInventory(userId int64, price float64, itemId int64) error
-	GetUserCoins(username string) (float64, error)
-	SendCoins(userFrom, userTo string, amount float64) error
-	GetUserInventory(userId int64) (*[]models.InfoResponseInventoryInner, error)
-	GetUserReceivedAndSentCoins(userId int64) (*models.InfoResponseCoinHistory, error)
+	AddNewUser(ctx context.Context, username, password string, balance float64) (bool, error)
+	AuthorizeUser(ctx context.Context, username, password string) (bool, int64, error)
+	GetUserCoinsAndItemPrice(ctx context.Context, userId int64, item string) (float64, float64, int64, error)
+	// UpdateUserBalanceAndInventory покупает товар: списывает монеты, уменьшает остаток, добавляет товар
+	// в инвентарь и сохраняет покупку. Возвращает id покупки
+	UpdateUserBalanceAndInventory(ctx context.Context, userId int64, price float64, itemId int64) (int64, error)
+	// GiftItem покупает товар в подарок: монеты списываются у userId, а предмет попадает в инвентарь toUser,
+	// лимит покупок считается по инвентарю получателя. Возвращает id покупки
+	GiftItem(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message string) (int64, error)
+	// BuyWithPromoCode покупает товар, как UpdateUserBalanceAndInventory, со скидкой по промокоду code.
+	// Код проверяется и применяется в той же транзакции. Возвращает id покупки и скидку
+	BuyWithPromoCode(ctx context.Context, userId int64, price float64, itemId int64, code string) (int64, float64, error)
+	GetUserCoins(ctx context.Context, username string) (float64, error)
+	SendCoins(ctx context.Context, userFrom, userTo string, amount float64) error
+	// SendItem передаёт quantity единиц предмета item из инвентаря userFrom в инвентарь userTo.
+	// Лимит покупок товара действует и на полученные предметы, иначе его можно было бы обойти через других пользователей
+	SendItem(ctx context.Context, userFrom, userTo, item string, quantity int64) error
+	// AddTrade сохраняет предложение обмена и возвращает его id. Предметы и монеты не резервируются,
+	// их наличие проверяется при принятии
+	AddTrade(ctx context.Context, trade Trade) (int64, error)
+	// GetTrades возвращает предложения обмена, отправленные и полученные пользователем, в порядке создания
+	GetTrades(ctx context.Context, username string) ([]Trade, error)
+	// AcceptTrade принимает открытое предложение от имени получателя username и выполняет обмен одной транзакцией:
+	// если одной из сторон не хватает предметов или монет, не меняется ничего. Чужое предложение -- ErrTradeNotFound
+	AcceptTrade(ctx context.Context, id int64, username string) (*Trade, error)
+	// CloseTrade закрывает открытое предложение без обмена: автор его отменяет, получатель отклоняет
+	CloseTrade(ctx context.Context, id int64, username string) (*Trade, error)
+	GetUserInventory(ctx context.Context, userId int64) (*[]models.InfoResponseInventoryInner, error)
+	GetUserReceivedAndSentCoins(ctx context.Context, userId int64) (*models.InfoResponseCoinHistory, error)
+	// GetUserInfo возвращает баланс, инвентарь и историю переводов пользователя за одно обращение к хранилищу
+	GetUserInfo(ctx context.Context, userId int64) (*models.InfoResponse, error)
+	// GetProducts возвращает каталог с остатками, упорядоченный по имени товара
+	GetProducts(ctx context.Context) ([]Product, error)
+	// SetProductStock задаёт остаток товара, nil снимает ограничение
+	SetProductStock(ctx context.Context, name string, stock *int64) error
+	// RestockProduct добавляет quantity к остатку товара и возвращает новый остаток
+	RestockProduct(ctx context.Context, name string, quantity int64) (int64, error)
+	// SetProductLimit задаёт, сколько единиц товара может купить один пользователь, nil снимает ограничение
+	SetProductLimit(ctx context.Context, name string, maxPerUser *int64) error
+	// GetPurchase возвращает покупку по id
+	GetPurchase(ctx context.Context, id int64) (*Purchase, error)
+	// RefundPurchase возвращает покупку: забирает предмет из инвентаря, начисляет цену покупки обратно,
+	// восстанавливает остаток и отмечает покупку возвращённой администратором by (пустая строка -- самим пользователем).
+	// Повторный возврат ничего не меняет, второе значение сообщает, была ли покупка возвращена этим вызовом
+	RefundPurchase(ctx context.Context, id int64, by string) (*Purchase, bool, error)
+	// AddToCart добавляет quantity единиц товара item в корзину пользователя
+	AddToCart(ctx context.Context, userId int64, item string, quantity int64) error
+	// RemoveFromCart убирает товар item из корзины целиком, false -- товара в корзине не было
+	RemoveFromCart(ctx context.Context, userId int64, item string) (bool, error)
+	// GetCart возвращает корзину пользователя с текущими ценами, упорядоченную по имени товара
+	GetCart(ctx context.Context, userId int64) ([]CartItem, error)
+	// Checkout покупает всё содержимое корзины одной транзакцией: если не хватает монет, товар закончился
+	// или превышен лимит, не покупается ничего. Корзина очищается, покупки сохраняются с общим чеком.
+	// prices -- цены товаров по акциям, товары без цены в prices покупаются по цене каталога
+	Checkout(ctx context.Context, userId int64, prices map[string]float64) (*Receipt, error)
+	// AddPromoCode создаёт промокод, занятый код -- ErrPromoCodeExists
+	AddPromoCode(ctx context.Context, promo PromoCode) error
+	// GetPromoCodes возвращает промокоды, упорядоченные по коду
+	GetPromoCodes(ctx context.Context) ([]PromoCode, error)
+	// DisablePromoCode отключает промокод, повторное отключение ничего не меняет
+	DisablePromoCode(ctx context.Context, code string) (*PromoCode, error)
+	// AddPriceCampaign создаёт акцию и возвращает её id
+	AddPriceCampaign(ctx context.Context, campaign PriceCampaign) (int64, error)
+	// GetPriceCampaigns возвращает акции товара item (пустая строка -- всех товаров), не закончившиеся к since,
+	// упорядоченные по товару и началу акции
+	GetPriceCampaigns(ctx context.Context, item string, since time.Time) ([]PriceCampaign, error)
+	// DeletePriceCampaign удаляет акцию и возвращает её, покупки по акции сохраняют уплаченную цену
+	DeletePriceCampaign(ctx context.Context, id int64) (*PriceCampaign, error)
+	// GrantCoins начисляет монеты от имени системы всем получателям grants одной транзакцией:
+	// если хотя бы один получатель не найден, не начисляется ничего
+	GrantCoins(ctx context.Context, grants []Grant) error
+	// ExpireGrants сжигает непотраченные монеты начислений, срок которых истёк к now, и возвращает,
+	// сколько монет сгорело
+	ExpireGrants(ctx context.Context, now time.Time) (float64, error)
+	// AddGrantSchedule создаёт регулярное начисление и возвращает его id. Неизвестный получатель
+	// возвращается как GrantError с номером в schedule.Usernames
+	AddGrantSchedule(ctx context.Context, schedule GrantSchedule) (int64, error)
+	// GetGrantSchedules возвращает регулярные начисления, упорядоченные по id
+	GetGrantSchedules(ctx context.Context) ([]GrantSchedule, error)
+	// StopGrantSchedule останавливает регулярное начисление, повторная остановка ничего не меняет
+	StopGrantSchedule(ctx context.Context, id int64) (*GrantSchedule, error)
+	// RunGrantSchedule выполняет один наступивший к now период одного регулярного начисления: начисляет монеты
+	// получателям и переносит NextRunAt на Interval. Период выполняется ровно один раз, даже если его
+	// одновременно пытаются выполнить несколько экземпляров сервиса. Возвращает расписание со временем
+	// выполненного периода в NextRunAt и число получателей, nil -- наступивших периодов нет
+	RunGrantSchedule(ctx context.Context, now time.Time) (*GrantSchedule, int64, error)
+	// AdjustBalance исправляет баланс пользователя по решению администратора: положительная сумма начисляет монеты,
+	// отрицательная списывает. Списание больше баланса -- ErrInsufficientFunds. Возвращает сохранённое исправление
+	// с балансом после него
+	AdjustBalance(ctx context.Context, adjustment Adjustment) (*Adjustment, error)
+	// GetUserStatus возвращает баланс пользователя и сведения о блокировке
+	GetUserStatus(ctx context.Context, username string) (*UserStatus, error)
+	// FreezeUser блокирует пользователя от имени администратора by: заблокированный пользователь не проходит
+	// AuthorizeUser, не может тратить монеты и отдавать предметы. Повторная блокировка ничего не меняет
+	FreezeUser(ctx context.Context, username, by, reason string) (*UserStatus, error)
+	// UnfreezeUser снимает блокировку, снятие с незаблокированного пользователя ничего не меняет
+	UnfreezeUser(ctx context.Context, username string) (*UserStatus, error)
+	// AddAdminAction сохраняет запись журнала действий администраторов
+	AddAdminAction(ctx context.Context, action AdminAction) error
+	// GetAdminActions возвращает записи журнала от новых к старым
+	GetAdminActions(ctx context.Context, filter AdminActionFilter) ([]AdminAction, error)
+	// GetSentTransfers возвращает переводы пользователя, отправленные не раньше since, в порядке отправки
+	GetSentTransfers(ctx context.Context, userId int64, since time.Time) ([]Transfer, error)
+	// AddTransferReview сохраняет перевод, отклонённый правилами, для разбора
+	AddTransferReview(ctx context.Context, review TransferReview) error
+	// GetTransferReviews возвращает отклонённые переводы пользователя в порядке отклонения
+	GetTransferReviews(ctx context.Context, userId int64) ([]TransferReview, error)
+}
+
+// Purchase -- покупка товара, возвращённая покупка остаётся с отметкой о возврате
+type Purchase struct {
+	Id        int64
+	UserId    int64
+	Username  string
+	Item      string
+	Price     float64
+	CreatedAt time.Time
+	// время возврата, nil -- покупка не возвращена
+	RefundedAt *time.Time
+	// кто оформил возврат: пустая строка -- сам пользователь, иначе имя администратора
+	RefundedBy string
+	// получатель подарка, пустая строка -- покупатель купил товар себе
+	Recipient string
+	Message   string
+	// применённый промокод, пустая строка -- покупка без скидки. Price -- цена уже со скидкой
+	PromoCode string
+	Discount  float64
+}
+
+// CartItem -- позиция корзины, Price -- текущая цена одной единицы
+type CartItem struct {
+	Item     string
+	Quantity int64
+	Price    float64
+}
+
+// Receipt -- чек оформленной корзины
+type Receipt struct {
+	Id        int64
+	Items     []ReceiptItem
+	Total     float64
+	Balance   float64
+	CreatedAt time.Time
+}
+
+// ReceiptItem -- позиция чека, каждая купленная единица сохраняется отдельной покупкой,
+// чтобы её можно было вернуть
+type ReceiptItem struct {
+	Item        string
+	Quantity    int64
+	Price       float64
+	PurchaseIds []int64
+}
+
+// PromoCode -- промокод на скидку: процент от цены или фиксированная сумма
+type PromoCode struct {
+	Code string
+	// скидка в процентах, 0 -- скидка фиксированная
+	Percent int64
+	// фиксированная скидка в монетах
+	Amount float64
+	// товар, на который действует код, пустая строка -- любой товар
+	Item string
+	// окно действия, nil -- без ограничения с этой стороны
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	// сколько раз код можно применить, nil -- без ограничения
+	MaxUses *int64
+	Uses    int64
+	// время отключения администратором, nil -- код не отключён
+	DisabledAt *time.Time
+}
+
+// validate проверяет параметры нового промокода так же, как ограничения таблицы promo_codes
+func (p *PromoCode) validate() error {
+	switch {
+	case p.Code == "":
+		return fmt.Errorf("%w: пустой промокод", ErrInvalidArgument)
+	case (p.Percent > 0) == (p.Amount > 0), p.Percent < 0, p.Percent > 100, p.Amount < 0:
+		return fmt.Errorf("%w: нужна скидка в процентах от 1 до 100 или положительная сумма", ErrInvalidAmount)
+	case p.StartsAt != nil && p.EndsAt != nil && !p.StartsAt.Before(*p.EndsAt):
+		return fmt.Errorf("%w: окно действия промокода пустое", ErrInvalidArgument)
+	case p.MaxUses != nil && *p.MaxUses <= 0:
+		return fmt.Errorf("%w: число применений должно быть положительным", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// check проверяет, что код можно применить к товару item в момент now
+func (p *PromoCode) check(item string, now time.Time) error {
+	switch {
+	case p.DisabledAt != nil, p.StartsAt != nil && now.Before(*p.StartsAt), p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return fmt.Errorf("%w: %s", ErrPromoCodeInactive, p.Code)
+	case p.MaxUses != nil && p.Uses >= *p.MaxUses:
+		return fmt.Errorf("%w: %s", ErrPromoCodeExhausted, p.Code)
+	case p.Item != "" && p.Item != item:
+		return fmt.Errorf("%w: %s, товар %s", ErrPromoCodeNotApplicable, p.Code, item)
+	}
+	return nil
+}
+
+// Discount возвращает скидку для цены price в целых монетах, скидка не больше цены
+func (p *PromoCode) Discount(price float64) float64 {
+	discount := p.Amount
+	if p.Percent > 0 {
+		discount = math.Floor(price * float64(p.Percent) / 100)
+	}
+	return min(discount, price)
+}
+
+// PriceCampaign -- акция: цена Price товара Item с StartsAt до EndsAt
+type PriceCampaign struct {
+	Id       int64
+	Name     string
+	Item     string
+	Price    float64
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// validate проверяет параметры новой акции так же, как ограничения таблицы price_campaigns
+func (c *PriceCampaign) validate() error {
+	switch {
+	case c.Price <= 0:
+		return fmt.Errorf("%w: цена по акции должна быть положительной", ErrInvalidAmount)
+	case !c.StartsAt.Before(c.EndsAt):
+		return fmt.Errorf("%w: окно действия акции пустое", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Active сообщает, действует ли акция в момент now
+func (c *PriceCampaign) Active(now time.Time) bool {
+	return !now.Before(c.StartsAt) && now.Before(c.EndsAt)
+}
+
+// SystemUser -- источник начислений и получатель сгоревших монет в истории переводов
+const SystemUser = "system"
+
+// Grant -- начисление Amount монет пользователю Username от имени системы
+type Grant struct {
+	Username string
+	Amount   float64
+	Reason   string
+	// когда непотраченные монеты начисления сгорают, nil -- монеты бессрочные
+	ExpiresAt *time.Time
+}
+
+// GrantError -- ошибка начисления с номером Index в пакете GrantCoins или в списке получателей расписания
+type GrantError struct {
+	Index    int
+	Username string
+	Err      error
+}
+
+func (e *GrantError) Error() string {
+	return fmt.Sprintf("начисление %d пользователю %s: %v", e.Index, e.Username, e.Err)
+}
+
+func (e *GrantError) Unwrap() error {
+	return e.Err
+}
+
+// GrantSchedule -- регулярное начисление Amount монет каждому получателю каждые Interval
+type GrantSchedule struct {
+	Id     int64
+	Name   string
+	Amount float64
+	Reason string
+	// Interval и ExpiresAfter округляются вниз до секунд
+	Interval time.Duration
+	// через сколько после периода начисленные монеты сгорают, 0 -- монеты бессрочные
+	ExpiresAfter time.Duration
+	// получатели в порядке имён, пустой список -- все пользователи
+	Usernames []string
+	// время ближайшего невыполненного периода
+	NextRunAt time.Time
+	// время остановки администратором, nil -- расписание действует
+	StoppedAt *time.Time
+}
+
+// validate проверяет параметры нового расписания так же, как ограничения таблицы grant_schedules
+func (s *GrantSchedule) validate() error {
+	switch {
+	case s.Amount <= 0:
+		return fmt.Errorf("%w: начисление должно быть положительным", ErrInvalidAmount)
+	case s.Interval < time.Second:
+		return fmt.Errorf("%w: период начисления меньше секунды", ErrInvalidArgument)
+	case s.ExpiresAfter < 0 || s.ExpiresAfter > 0 && s.ExpiresAfter < time.Second:
+		return fmt.Errorf("%w: срок монет меньше секунды", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// grant возвращает начисление получателю username за период NextRunAt
+func (s *GrantSchedule) grant(username string) Grant {
+	g := Grant{Username: username, Amount: s.Amount, Reason: s.Reason}
+	if s.ExpiresAfter > 0 {
+		expiresAt := s.NextRunAt.Add(s.ExpiresAfter)
+		g.ExpiresAt = &expiresAt
+	}
+	return g
+}
+
+// Adjustment -- исправление баланса пользователя Username администратором Admin на Amount монет
+type Adjustment struct {
+	Id       int64
+	Username string
+	// положительная сумма начисляет монеты, отрицательная списывает
+	Amount float64
+	Reason string
+	Admin  string
+	// баланс после исправления
+	Balance   float64
+	CreatedAt time.Time
+}
+
+// validate проверяет параметры исправления так же, как ограничения таблицы balance_adjustments
+func (a *Adjustment) validate() error {
+	switch {
+	case a.Amount == 0:
+		return fmt.Errorf("%w: сумма исправления не может быть нулевой", ErrInvalidAmount)
+	case a.Reason == "":
+		return fmt.Errorf("%w: не указана причина исправления", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// UserStatus -- баланс пользователя и сведения о блокировке
+type UserStatus struct {
+	Id       int64
+	Username string
+	Balance  float64
+	// время блокировки, nil -- пользователь не заблокирован
+	FrozenAt *time.Time
+	// кто и почему заблокировал пользователя
+	FrozenBy     string
+	FrozenReason string
+}
+
+// AdminAction -- запись журнала: запрос администратора Admin к эндпоинту Action и код ответа
+type AdminAction struct {
+	Id     int64
+	Admin  string
+	Action string
+	Method string
+	Path   string
+	Status int
+	// идентификатор запроса, по нему запись связывается с логами и трассой
+	RequestId string
+	// начало тела запроса
+	Body      string
+	CreatedAt time.Time
+}
+
+// AdminActionFilter -- выборка из журнала действий администраторов
+type AdminActionFilter struct {
+	// администратор, пустая строка -- все администраторы
+	Admin string
+	// записи с id меньше Before, 0 -- с самой новой записи
+	Before int64
+	Limit  int
+}
+
+// Transfer -- исходящий перевод пользователя
+type Transfer struct {
+	ToUser    string
+	Amount    float64
+	CreatedAt time.Time
+}
+
+// TransferReview -- перевод, отклонённый правилом Rule
+type TransferReview struct {
+	UserId    int64
+	ToUser    string
+	Amount    float64
+	Rule      string
+	CreatedAt time.Time
+}
+
+// Статусы предложения обмена
+const (
+	TradeOpen      = "open"
+	TradeAccepted  = "accepted"
+	TradeDeclined  = "declined"
+	TradeCancelled = "cancelled"
+)
+
+// Trade -- предложение обмена: From отдаёт Quantity единиц предмета Item пользователю To
+// в обмен на WantQuantity единиц предмета WantItem и Coins монет
+type Trade struct {
+	Id       int64
+	From     string
+	To       string
+	Item     string
+	Quantity int64
+	// пустая строка -- в обмен нужны только монеты
+	WantItem     string
+	WantQuantity int64
+	Coins        float64
+	Status       string
+	CreatedAt    time.Time
+	// время принятия, отклонения или отмены, nil -- предложение открыто
+	ClosedAt *time.Time
+}
+
+// validate проверяет параметры нового предложения так же, как ограничения таблицы trades
+func (t *Trade) validate() error {
+	switch {
+	case t.From == t.To:
+		return fmt.Errorf("%w: обмен с самим собой", ErrInvalidArgument)
+	case t.Quantity <= 0 || t.Quantity > math.MaxInt32 || t.WantQuantity < 0 || t.WantQuantity > math.MaxInt32:
+		return fmt.Errorf("%w: количество %d и %d", ErrInvalidAmount, t.Quantity, t.WantQuantity)
+	case t.Coins < 0:
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, t.Coins)
+	case (t.WantItem == "") != (t.WantQuantity == 0), t.WantQuantity == 0 && t.Coins == 0:
+		return fmt.Errorf("%w: в обмен нужен предмет с количеством или монеты", ErrInvalidArgument)
+	case t.WantItem == t.Item:
+		return fmt.Errorf("%w: обмен предмета на тот же предмет", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// completeLimits упорядочивает лимиты покупок по товару и считает по инвентарю из info, сколько единиц
+// пользователь ещё может купить. Лимит могли уменьшить после покупок, поэтому остаток не бывает отрицательным
+func completeLimits(info *models.InfoResponse) {
+	sort.Slice(info.Limits, func(i, j int) bool { return info.Limits[i].Type < info.Limits[j].Type })
+	bought := make(map[string]int32, len(info.Inventory))
+	for _, item := range info.Inventory {
+		bought[item.Type] += item.Quantity
+	}
+	for i := range info.Limits {
+		info.Limits[i].Remaining = max(info.Limits[i].MaxPerUser-bought[info.Limits[i].Type], 0)
+	}
 }
@@ -0,0 +1,1684 @@
+// Package dbtest содержит общий набор сценариев, которым должна удовлетворять любая реализация database.Database.
+// Движок рассчитывает именно на это поведение, поэтому новое хранилище достаточно прогнать через Run
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory возвращает пустое хранилище с каталогом database.DefaultProducts.
+// Освобождать ресурсы хранилища нужно через t.Cleanup
+type Factory func(t *testing.T) database.Database
+
+// Run прогоняет все сценарии, каждый -- на новом хранилище от newDB
+func Run(t *testing.T, newDB Factory) {
+	scenarios := []struct {
+		name string
+		run  func(t *testing.T, db database.Database)
+	}{
+		{"Users", testUsers},
+		{"ConcurrentRegistration", testConcurrentRegistration},
+		{"Buy", testBuy},
+		{"ConcurrentBuy", testConcurrentBuy},
+		{"Stock", testStock},
+		{"ConcurrentStock", testConcurrentStock},
+		{"PurchaseLimits", testPurchaseLimits},
+		{"ConcurrentPurchaseLimits", testConcurrentPurchaseLimits},
+		{"Refunds", testRefunds},
+		{"Gifts", testGifts},
+		{"ConcurrentRefunds", testConcurrentRefunds},
+		{"SendCoins", testSendCoins},
+		{"History", testHistory},
+		{"SentTransfers", testSentTransfers},
+		{"TransferReviews", testTransferReviews},
+		{"Info", testInfo},
+		{"ConcurrentTransfers", testConcurrentTransfers},
+		{"OppositeTransfers", testOppositeTransfers},
+		{"ItemTransfers", testItemTransfers},
+		{"ConcurrentItemTransfers", testConcurrentItemTransfers},
+		{"Trades", testTrades},
+		{"ConcurrentTrades", testConcurrentTrades},
+		{"Cart", testCart},
+		{"ConcurrentCheckout", testConcurrentCheckout},
+		{"PromoCodes", testPromoCodes},
+		{"ConcurrentPromoCodes", testConcurrentPromoCodes},
+		{"PriceCampaigns", testPriceCampaigns},
+		{"Grants", testGrants},
+		{"GrantRefunds", testGrantRefunds},
+		{"GrantTransfers", testGrantTransfers},
+		{"GrantSchedules", testGrantSchedules},
+		{"ConcurrentGrantSchedules", testConcurrentGrantSchedules},
+		{"Adjustments", testAdjustments},
+		{"FreezeUser", testFreezeUser},
+		{"AdminActions", testAdminActions},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			scenario.run(t, newDB(t))
+		})
+	}
+}
+
+// addUser регистрирует пользователя и возвращает его id
+func addUser(t *testing.T, db database.Database, username string, balance float64) int64 {
+	ctx := context.Background()
+	added, err := db.AddNewUser(ctx, username, "pass", balance)
+	require.NoError(t, err)
+	require.True(t, added)
+	_, id, err := db.AuthorizeUser(ctx, username, "pass")
+	require.NoError(t, err)
+	return id
+}
+
+// parallel запускает fn n раз параллельно и возвращает число вызовов без ошибки
+func parallel(n int, fn func(i int) error) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if fn(i) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return succeeded
+}
+
+// buy покупает товар, когда id покупки не нужен
+func buy(ctx context.Context, db database.Database, userId int64, price float64, itemId int64) error {
+	_, err := db.UpdateUserBalanceAndInventory(ctx, userId, price, itemId)
+	return err
+}
+
+func coins(t *testing.T, db database.Database, username string) float64 {
+	coins, err := db.GetUserCoins(context.Background(), username)
+	require.NoError(t, err)
+	return coins
+}
+
+func testUsers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+
+	added, err := db.AddNewUser(ctx, "user1", "pass1", 1000)
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	// повторная регистрация не создаёт пользователя и не меняет пароль
+	added, err = db.AddNewUser(ctx, "user1", "other", 1000)
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	ok, id, err := db.AuthorizeUser(ctx, "user1", "pass1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotZero(t, id)
+
+	ok, _, err = db.AuthorizeUser(ctx, "user1", "other")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = db.AuthorizeUser(ctx, "unknown", "pass1")
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+
+	// движок проверяет существование получателя перевода через GetUserCoins,
+	// для неизвестного пользователя это ноль монет, а не ошибка
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+	assert.Zero(t, coins(t, db, "unknown"))
+
+	// у нового пользователя пустые инвентарь и история, а не nil
+	inventory, err := db.GetUserInventory(ctx, id)
+	assert.NoError(t, err)
+	assert.NotNil(t, inventory)
+	assert.Empty(t, *inventory)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, id)
+	assert.NoError(t, err)
+	assert.NotNil(t, history.Sent)
+	assert.NotNil(t, history.Received)
+	assert.Empty(t, history.Sent)
+	assert.Empty(t, history.Received)
+}
+
+func testConcurrentRegistration(t *testing.T, db database.Database) {
+	ctx := context.Background()
+
+	// из параллельных регистраций одного имени создать пользователя должна ровно одна
+	added := parallel(20, func(i int) error {
+		ok, err := db.AddNewUser(ctx, "user1", fmt.Sprintf("pass%d", i), 1000)
+		if err != nil || !ok {
+			return fmt.Errorf("не добавлен: %v", err)
+		}
+		return nil
+	})
+	assert.Equal(t, 1, added)
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+}
+
+func testBuy(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 330)
+
+	balance, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(330), balance)
+	assert.Equal(t, float64(20), price)
+	assert.NoError(t, buy(ctx, db, userId, price, cupId))
+	assert.NoError(t, buy(ctx, db, userId, price, cupId))
+
+	_, price, penId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pen")
+	assert.NoError(t, err)
+	assert.NoError(t, buy(ctx, db, userId, price, penId))
+
+	// на худи уже не хватает, состояние не должно измениться
+	_, price, hoodyId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "hoody")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, buy(ctx, db, userId, price, hoodyId), database.ErrInsufficientFunds)
+	assert.ErrorIs(t, buy(ctx, db, userId, 1, 1_000_000), database.ErrProductNotFound)
+	assert.ErrorIs(t, buy(ctx, db, userId+1000, 1, cupId), database.ErrUserNotFound)
+
+	_, _, _, err = db.GetUserCoinsAndItemPrice(ctx, userId, "unknown")
+	assert.ErrorIs(t, err, database.ErrProductNotFound)
+	_, _, _, err = db.GetUserCoinsAndItemPrice(ctx, userId+1000, "cup")
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+
+	assert.Equal(t, float64(280), coins(t, db, "user1"))
+	inventory, err := db.GetUserInventory(ctx, userId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 2}, {Type: "pen", Quantity: 1}}, *inventory)
+}
+
+func testConcurrentBuy(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 100)
+	_, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+
+	// денег хватает ровно на 5 кружек из 20 параллельных покупок
+	bought := parallel(20, func(int) error {
+		return buy(ctx, db, userId, price, cupId)
+	})
+	assert.Equal(t, 5, bought)
+	assert.Zero(t, coins(t, db, "user1"))
+
+	inventory, err := db.GetUserInventory(ctx, userId)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 5}}, *inventory)
+}
+
+// product возвращает товар каталога по имени
+func product(t *testing.T, db database.Database, name string) database.Product {
+	products, err := db.GetProducts(context.Background())
+	require.NoError(t, err)
+	for _, p := range products {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("товар %s не найден в каталоге", name)
+	return database.Product{}
+}
+
+func testStock(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+
+	// по умолчанию остатки не ограничены, каталог упорядочен по имени
+	products, err := db.GetProducts(ctx)
+	assert.NoError(t, err)
+	require.Len(t, products, len(database.DefaultProducts))
+	for i, p := range products {
+		assert.Nil(t, p.Stock, p.Name)
+		if i > 0 {
+			assert.Less(t, products[i-1].Name, p.Name)
+		}
+	}
+	assert.Equal(t, float64(500), product(t, db, "pink-hoody").Price)
+
+	two := int64(2)
+	assert.NoError(t, db.SetProductStock(ctx, "pink-hoody", &two))
+	assert.Equal(t, &two, product(t, db, "pink-hoody").Stock)
+
+	_, _, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pink-hoody")
+	require.NoError(t, err)
+	price := float64(100)
+	assert.NoError(t, buy(ctx, db, userId, price, itemId))
+	assert.NoError(t, buy(ctx, db, userId, price, itemId))
+
+	// распроданный товар не списывает монеты и не попадает в инвентарь
+	assert.ErrorIs(t, buy(ctx, db, userId, price, itemId), database.ErrSoldOut)
+	assert.Equal(t, float64(800), coins(t, db, "user1"))
+	inventory, err := db.GetUserInventory(ctx, userId)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pink-hoody", Quantity: 2}}, *inventory)
+	zero := int64(0)
+	assert.Equal(t, &zero, product(t, db, "pink-hoody").Stock)
+
+	stock, err := db.RestockProduct(ctx, "pink-hoody", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stock)
+	assert.NoError(t, buy(ctx, db, userId, price, itemId))
+
+	_, err = db.RestockProduct(ctx, "cup", 3)
+	assert.ErrorIs(t, err, database.ErrUnlimitedStock)
+	_, err = db.RestockProduct(ctx, "unknown", 3)
+	assert.ErrorIs(t, err, database.ErrProductNotFound)
+	_, err = db.RestockProduct(ctx, "pink-hoody", 0)
+	assert.ErrorIs(t, err, database.ErrInvalidAmount)
+
+	negative := int64(-1)
+	assert.ErrorIs(t, db.SetProductStock(ctx, "pink-hoody", &negative), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SetProductStock(ctx, "unknown", &two), database.ErrProductNotFound)
+
+	// снятие ограничения
+	assert.NoError(t, db.SetProductStock(ctx, "pink-hoody", nil))
+	assert.Nil(t, product(t, db, "pink-hoody").Stock)
+	assert.NoError(t, buy(ctx, db, userId, price, itemId))
+}
+
+func testConcurrentStock(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userIds := make([]int64, 10)
+	for i := range userIds {
+		userIds[i] = addUser(t, db, fmt.Sprintf("user%d", i), 1000)
+	}
+	stock := int64(3)
+	require.NoError(t, db.SetProductStock(ctx, "pink-hoody", &stock))
+	_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userIds[0], "pink-hoody")
+	require.NoError(t, err)
+
+	// остатка хватает ровно на 3 покупки из 10 параллельных
+	bought := parallel(len(userIds), func(i int) error {
+		return buy(ctx, db, userIds[i], price, itemId)
+	})
+	assert.Equal(t, 3, bought)
+	zero := int64(0)
+	assert.Equal(t, &zero, product(t, db, "pink-hoody").Stock)
+
+	spent := float64(0)
+	for i := range userIds {
+		spent += 1000 - coins(t, db, fmt.Sprintf("user%d", i))
+	}
+	assert.Equal(t, 3*price, spent)
+}
+
+func testPurchaseLimits(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+	otherId := addUser(t, db, "user2", 1000)
+	_, _, hoodyId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pink-hoody")
+	require.NoError(t, err)
+	_, _, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+
+	// без лимитов в сводке нет раздела лимитов
+	info, err := db.GetUserInfo(ctx, userId)
+	require.NoError(t, err)
+	assert.Empty(t, info.Limits)
+
+	one, two := int64(1), int64(2)
+	assert.NoError(t, db.SetProductLimit(ctx, "pink-hoody", &one))
+	assert.NoError(t, db.SetProductLimit(ctx, "cup", &two))
+	assert.Equal(t, &one, product(t, db, "pink-hoody").MaxPerUser)
+	assert.Nil(t, product(t, db, "pen").MaxPerUser)
+
+	assert.NoError(t, buy(ctx, db, userId, 100, hoodyId))
+	assert.NoError(t, buy(ctx, db, userId, 10, cupId))
+
+	// сверх лимита покупка не списывает монеты и не меняет инвентарь
+	assert.ErrorIs(t, buy(ctx, db, userId, 100, hoodyId), database.ErrPurchaseLimit)
+	assert.Equal(t, float64(890), coins(t, db, "user1"))
+	info, err = db.GetUserInfo(ctx, userId)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseInventoryInner{{Type: "pink-hoody", Quantity: 1}, {Type: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []models.InfoResponseLimitsInner{
+		{Type: "cup", MaxPerUser: 2, Remaining: 1},
+		{Type: "pink-hoody", MaxPerUser: 1, Remaining: 0},
+	}, info.Limits)
+
+	// лимит считается для каждого пользователя отдельно
+	assert.NoError(t, buy(ctx, db, otherId, 100, hoodyId))
+
+	// после уменьшения лимита остаток не уходит в минус
+	assert.NoError(t, buy(ctx, db, userId, 10, cupId))
+	assert.NoError(t, db.SetProductLimit(ctx, "cup", &one))
+	info, err = db.GetUserInfo(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, models.InfoResponseLimitsInner{Type: "cup", MaxPerUser: 1, Remaining: 0}, info.Limits[0])
+
+	zero := int64(0)
+	assert.ErrorIs(t, db.SetProductLimit(ctx, "cup", &zero), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SetProductLimit(ctx, "unknown", &one), database.ErrProductNotFound)
+
+	// снятие ограничения
+	assert.NoError(t, db.SetProductLimit(ctx, "pink-hoody", nil))
+	assert.NoError(t, buy(ctx, db, userId, 100, hoodyId))
+}
+
+func testConcurrentPurchaseLimits(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 10000)
+	limit := int64(3)
+	require.NoError(t, db.SetProductLimit(ctx, "pink-hoody", &limit))
+	_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pink-hoody")
+	require.NoError(t, err)
+
+	// из 10 параллельных покупок одного пользователя проходят только 3
+	bought := parallel(10, func(int) error {
+		return buy(ctx, db, userId, price, itemId)
+	})
+	assert.Equal(t, 3, bought)
+	assert.Equal(t, 10000-3*price, coins(t, db, "user1"))
+	inventory, err := db.GetUserInventory(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pink-hoody", Quantity: 3}}, *inventory)
+}
+
+func testRefunds(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+	one := int64(1)
+	require.NoError(t, db.SetProductStock(ctx, "pink-hoody", &one))
+	require.NoError(t, db.SetProductLimit(ctx, "pink-hoody", &one))
+	_, _, hoodyId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pink-hoody")
+	require.NoError(t, err)
+	_, _, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+
+	before := time.Now().Add(-time.Minute)
+	hoodyPurchase, err := db.UpdateUserBalanceAndInventory(ctx, userId, 100, hoodyId)
+	require.NoError(t, err)
+	cupPurchase, err := db.UpdateUserBalanceAndInventory(ctx, userId, 10, cupId)
+	require.NoError(t, err)
+	assert.NotEqual(t, hoodyPurchase, cupPurchase)
+
+	purchase, err := db.GetPurchase(ctx, hoodyPurchase)
+	require.NoError(t, err)
+	assert.Equal(t, hoodyPurchase, purchase.Id)
+	assert.Equal(t, userId, purchase.UserId)
+	assert.Equal(t, "user1", purchase.Username)
+	assert.Equal(t, "pink-hoody", purchase.Item)
+	assert.Equal(t, float64(100), purchase.Price)
+	assert.True(t, purchase.CreatedAt.After(before), purchase.CreatedAt)
+	assert.Nil(t, purchase.RefundedAt)
+
+	// возврат начисляет цену покупки, забирает предмет и возвращает единицу в остаток и лимит
+	purchase, refunded, err := db.RefundPurchase(ctx, hoodyPurchase, "")
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	require.NotNil(t, purchase.RefundedAt)
+	assert.Empty(t, purchase.RefundedBy)
+	assert.Equal(t, float64(990), coins(t, db, "user1"))
+	inventory, err := db.GetUserInventory(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 1}}, *inventory)
+	assert.Equal(t, &one, product(t, db, "pink-hoody").Stock)
+	assert.NoError(t, buy(ctx, db, userId, 100, hoodyId))
+
+	// повторный возврат ничего не меняет
+	again, refunded, err := db.RefundPurchase(ctx, hoodyPurchase, "admin")
+	require.NoError(t, err)
+	assert.False(t, refunded)
+	assert.Empty(t, again.RefundedBy)
+	assert.Equal(t, purchase.RefundedAt, again.RefundedAt)
+	assert.Equal(t, float64(890), coins(t, db, "user1"))
+
+	purchase, refunded, err = db.RefundPurchase(ctx, cupPurchase, "admin")
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	assert.Equal(t, "admin", purchase.RefundedBy)
+	purchase, err = db.GetPurchase(ctx, cupPurchase)
+	require.NoError(t, err)
+	assert.NotNil(t, purchase.RefundedAt)
+	assert.Equal(t, float64(900), coins(t, db, "user1"))
+
+	// возвраты видны в истории как переводы от системы
+	received := []models.InfoResponseCoinHistoryReceivedInner{{FromUser: database.SystemUser, Amount: 100}, {FromUser: database.SystemUser, Amount: 10}}
+	info, err := db.GetUserInfo(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, int32(900), info.Coins)
+	assert.ElementsMatch(t, received, info.CoinHistory.Received)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, received, history.Received)
+
+	_, err = db.GetPurchase(ctx, 1000)
+	assert.ErrorIs(t, err, database.ErrPurchaseNotFound)
+	_, _, err = db.RefundPurchase(ctx, 1000, "")
+	assert.ErrorIs(t, err, database.ErrPurchaseNotFound)
+}
+
+func testGifts(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 1000)
+	userId2 := addUser(t, db, "user2", 1000)
+	one := int64(1)
+	require.NoError(t, db.SetProductLimit(ctx, "pink-hoody", &one))
+	_, _, hoodyId, err := db.GetUserCoinsAndItemPrice(ctx, userId1, "pink-hoody")
+	require.NoError(t, err)
+	_, _, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId1, "cup")
+	require.NoError(t, err)
+
+	// монеты списываются у покупателя, а предмет попадает к получателю
+	hoodyGift, err := db.GiftItem(ctx, userId1, "user2", 100, hoodyId, "с днём рождения")
+	require.NoError(t, err)
+	_, err = db.GiftItem(ctx, userId2, "user1", 10, cupId, "")
+	require.NoError(t, err)
+	assert.Equal(t, float64(900), coins(t, db, "user1"))
+	assert.Equal(t, float64(990), coins(t, db, "user2"))
+
+	info, err := db.GetUserInfo(ctx, userId1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []models.InfoResponseGiftsSentInner{{ToUser: "user2", Item: "pink-hoody", Amount: 100, Message: "с днём рождения"}}, info.Gifts.Sent)
+	assert.Equal(t, []models.InfoResponseGiftsReceivedInner{{FromUser: "user2", Item: "cup"}}, info.Gifts.Received)
+	info, err = db.GetUserInfo(ctx, userId2)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pink-hoody", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []models.InfoResponseGiftsReceivedInner{{FromUser: "user1", Item: "pink-hoody", Message: "с днём рождения"}}, info.Gifts.Received)
+
+	purchase, err := db.GetPurchase(ctx, hoodyGift)
+	require.NoError(t, err)
+	assert.Equal(t, userId1, purchase.UserId)
+	assert.Equal(t, "user2", purchase.Recipient)
+	assert.Equal(t, "с днём рождения", purchase.Message)
+
+	// лимит покупок считается по инвентарю получателя
+	assert.ErrorIs(t, buy(ctx, db, userId2, 100, hoodyId), database.ErrPurchaseLimit)
+	_, err = db.GiftItem(ctx, userId1, "user2", 100, hoodyId, "")
+	assert.ErrorIs(t, err, database.ErrPurchaseLimit)
+	assert.NoError(t, buy(ctx, db, userId1, 100, hoodyId))
+
+	_, err = db.GiftItem(ctx, userId1, "unknown", 10, cupId, "")
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+	_, err = db.GiftItem(ctx, userId1, "user1", 10, cupId, "")
+	assert.ErrorIs(t, err, database.ErrInvalidAmount)
+	_, err = db.GiftItem(ctx, userId1, "user2", 10000, cupId, "")
+	assert.ErrorIs(t, err, database.ErrInsufficientFunds)
+	assert.Equal(t, float64(800), coins(t, db, "user1"))
+
+	// возврат подарка забирает предмет у получателя, возвращает монеты покупателю и убирает подарок из истории
+	_, refunded, err := db.RefundPurchase(ctx, hoodyGift, "")
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	assert.Equal(t, float64(900), coins(t, db, "user1"))
+	info, err = db.GetUserInfo(ctx, userId2)
+	require.NoError(t, err)
+	assert.Empty(t, info.Inventory)
+	assert.Empty(t, info.Gifts.Received)
+	assert.Len(t, info.Gifts.Sent, 1)
+}
+
+func testConcurrentRefunds(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+	_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+	purchaseId, err := db.UpdateUserBalanceAndInventory(ctx, userId, price, itemId)
+	require.NoError(t, err)
+
+	// из параллельных возвратов одной покупки монеты начисляет только один
+	var mu sync.Mutex
+	refunds := 0
+	succeeded := parallel(10, func(int) error {
+		_, refunded, err := db.RefundPurchase(ctx, purchaseId, "")
+		if refunded {
+			mu.Lock()
+			refunds++
+			mu.Unlock()
+		}
+		return err
+	})
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, 1, refunds)
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+}
+
+func testSendCoins(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	addUser(t, db, "user1", 100)
+	addUser(t, db, "user2", 0)
+
+	assert.NoError(t, db.SendCoins(ctx, "user1", "user2", 70))
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "user2", 40), database.ErrInsufficientFunds)
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "user2", -10), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "user2", 0), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "user1", 10), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "unknown", 10), database.ErrUserNotFound)
+	assert.ErrorIs(t, db.SendCoins(ctx, "unknown", "user1", 10), database.ErrUserNotFound)
+
+	// неудачные переводы не меняют балансы
+	assert.Equal(t, float64(30), coins(t, db, "user1"))
+	assert.Equal(t, float64(70), coins(t, db, "user2"))
+}
+
+func testSentTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 100)
+	addUser(t, db, "user2", 100)
+	addUser(t, db, "user3", 100)
+
+	// время хранилища может немного расходиться с часами теста, поэтому границы берутся с запасом
+	before := time.Now().Add(-time.Minute)
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 10))
+	require.NoError(t, db.SendCoins(ctx, "user1", "user3", 20))
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 5))
+
+	// в выборку попадают только исходящие переводы
+	transfers, err := db.GetSentTransfers(ctx, userId1, before)
+	assert.NoError(t, err)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, "user2", transfers[0].ToUser)
+	assert.Equal(t, float64(10), transfers[0].Amount)
+	assert.Equal(t, "user3", transfers[1].ToUser)
+	assert.Equal(t, float64(20), transfers[1].Amount)
+	assert.WithinDuration(t, time.Now(), transfers[0].CreatedAt, time.Minute)
+
+	transfers, err = db.GetSentTransfers(ctx, userId1, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.NotNil(t, transfers)
+	assert.Empty(t, transfers)
+}
+
+func testTransferReviews(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 100)
+	userId2 := addUser(t, db, "user2", 100)
+
+	reviews, err := db.GetTransferReviews(ctx, userId1)
+	assert.NoError(t, err)
+	assert.NotNil(t, reviews)
+	assert.Empty(t, reviews)
+
+	// получатель отклонённого перевода может не существовать
+	require.NoError(t, db.AddTransferReview(ctx, database.TransferReview{UserId: userId1, ToUser: "user2", Amount: 50, Rule: "max_amount"}))
+	require.NoError(t, db.AddTransferReview(ctx, database.TransferReview{UserId: userId1, ToUser: "unknown", Amount: 5, Rule: "blocklist"}))
+	require.NoError(t, db.AddTransferReview(ctx, database.TransferReview{UserId: userId2, ToUser: "user1", Amount: 1, Rule: "velocity"}))
+
+	reviews, err = db.GetTransferReviews(ctx, userId1)
+	assert.NoError(t, err)
+	require.Len(t, reviews, 2)
+	assert.Equal(t, userId1, reviews[0].UserId)
+	assert.Equal(t, "user2", reviews[0].ToUser)
+	assert.Equal(t, float64(50), reviews[0].Amount)
+	assert.Equal(t, "max_amount", reviews[0].Rule)
+	assert.WithinDuration(t, time.Now(), reviews[0].CreatedAt, time.Minute)
+	assert.Equal(t, "blocklist", reviews[1].Rule)
+
+	// баланс отклонённый перевод не меняет
+	assert.Equal(t, float64(100), coins(t, db, "user1"))
+}
+
+func testHistory(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 100)
+	userId2 := addUser(t, db, "user2", 100)
+	addUser(t, db, "user3", 100)
+
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 10))
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 20))
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 5))
+	require.NoError(t, db.SendCoins(ctx, "user3", "user1", 1))
+
+	// каждый перевод -- отдельная запись, суммы не схлопываются
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{{ToUser: "user2", Amount: 10}, {ToUser: "user2", Amount: 20}}, history.Sent)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{{FromUser: "user2", Amount: 5}, {FromUser: "user3", Amount: 1}}, history.Received)
+
+	history, err = db.GetUserReceivedAndSentCoins(ctx, userId2)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseCoinHistorySentInner{{ToUser: "user1", Amount: 5}}, history.Sent)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{{FromUser: "user1", Amount: 10}, {FromUser: "user1", Amount: 20}}, history.Received)
+
+	assert.Equal(t, float64(76), coins(t, db, "user1"))
+	assert.Equal(t, float64(125), coins(t, db, "user2"))
+	assert.Equal(t, float64(99), coins(t, db, "user3"))
+}
+
+func testInfo(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 100)
+	addUser(t, db, "user2", 100)
+
+	// у нового пользователя пустые списки, а не nil
+	info, err := db.GetUserInfo(ctx, userId1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(100), info.Coins)
+	assert.NotNil(t, info.Inventory)
+	assert.NotNil(t, info.CoinHistory.Sent)
+	assert.NotNil(t, info.CoinHistory.Received)
+	assert.Empty(t, info.Inventory)
+
+	_, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId1, "cup")
+	require.NoError(t, err)
+	require.NoError(t, buy(ctx, db, userId1, price, cupId))
+	require.NoError(t, buy(ctx, db, userId1, price, cupId))
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 7))
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 3))
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 3))
+
+	// сводка совпадает с тем, что возвращают отдельные методы
+	info, err = db.GetUserInfo(ctx, userId1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(59), info.Coins)
+	assert.Equal(t, float64(info.Coins), coins(t, db, "user1"))
+	inventory, err := db.GetUserInventory(ctx, userId1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, *inventory, info.Inventory)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 2}}, info.Inventory)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, history.Sent, info.CoinHistory.Sent)
+	assert.ElementsMatch(t, history.Received, info.CoinHistory.Received)
+	assert.Equal(t, []models.InfoResponseCoinHistorySentInner{{ToUser: "user2", Amount: 7}}, info.CoinHistory.Sent)
+	assert.Len(t, info.CoinHistory.Received, 2)
+
+	_, err = db.GetUserInfo(ctx, userId1+1000)
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+}
+
+func testConcurrentTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 100)
+	addUser(t, db, "user2", 0)
+
+	// из 200 параллельных переводов по 1 монете пройти должны ровно 100
+	succeeded := parallel(200, func(int) error {
+		return db.SendCoins(ctx, "user1", "user2", 1)
+	})
+
+	assert.Equal(t, 100, succeeded)
+	assert.Zero(t, coins(t, db, "user1"))
+	assert.Equal(t, float64(100), coins(t, db, "user2"))
+
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId1)
+	assert.NoError(t, err)
+	assert.Len(t, history.Sent, 100)
+}
+
+func testOppositeTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	addUser(t, db, "user1", 1000)
+	addUser(t, db, "user2", 1000)
+
+	// встречные переводы не должны блокировать друг друга, все проходят, сумма монет сохраняется
+	succeeded := parallel(100, func(i int) error {
+		if i%2 == 0 {
+			return db.SendCoins(ctx, "user1", "user2", 1)
+		}
+		return db.SendCoins(ctx, "user2", "user1", 2)
+	})
+
+	assert.Equal(t, 100, succeeded)
+	assert.Equal(t, float64(1050), coins(t, db, "user1"))
+	assert.Equal(t, float64(950), coins(t, db, "user2"))
+}
+
+// inventoryOf возвращает инвентарь пользователя в виде товар -> количество
+func inventoryOf(t *testing.T, db database.Database, userId int64) map[string]int32 {
+	inventory, err := db.GetUserInventory(context.Background(), userId)
+	require.NoError(t, err)
+	items := make(map[string]int32, len(*inventory))
+	for _, item := range *inventory {
+		items[item.Type] = item.Quantity
+	}
+	return items
+}
+
+func testItemTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 1000)
+	userId2 := addUser(t, db, "user2", 1000)
+	_, _, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId1, "cup")
+	require.NoError(t, err)
+	cupPurchase, err := db.UpdateUserBalanceAndInventory(ctx, userId1, 10, cupId)
+	require.NoError(t, err)
+	require.NoError(t, buy(ctx, db, userId1, 10, cupId))
+	require.NoError(t, buy(ctx, db, userId1, 10, cupId))
+
+	// предметы переходят без изменения балансов, пустая позиция удаляется
+	assert.NoError(t, db.SendItem(ctx, "user1", "user2", "cup", 2))
+	assert.Equal(t, map[string]int32{"cup": 1}, inventoryOf(t, db, userId1))
+	assert.Equal(t, map[string]int32{"cup": 2}, inventoryOf(t, db, userId2))
+	assert.NoError(t, db.SendItem(ctx, "user1", "user2", "cup", 1))
+	assert.Empty(t, inventoryOf(t, db, userId1))
+	assert.Equal(t, map[string]int32{"cup": 3}, inventoryOf(t, db, userId2))
+	assert.NoError(t, db.SendItem(ctx, "user2", "user1", "cup", 1))
+	assert.Equal(t, float64(970), coins(t, db, "user1"))
+	assert.Equal(t, float64(1000), coins(t, db, "user2"))
+
+	info, err := db.GetUserInfo(ctx, userId1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseItemHistorySentInner{{ToUser: "user2", Type: "cup", Quantity: 2}, {ToUser: "user2", Type: "cup", Quantity: 1}}, info.ItemHistory.Sent)
+	assert.Equal(t, []models.InfoResponseItemHistoryReceivedInner{{FromUser: "user2", Type: "cup", Quantity: 1}}, info.ItemHistory.Received)
+	info, err = db.GetUserInfo(ctx, userId2)
+	require.NoError(t, err)
+	assert.Len(t, info.ItemHistory.Received, 2)
+	assert.Equal(t, []models.InfoResponseItemHistorySentInner{{ToUser: "user1", Type: "cup", Quantity: 1}}, info.ItemHistory.Sent)
+
+	// отдать можно только то, что есть в инвентаре, неудачная передача ничего не меняет
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user2", "cup", 2), database.ErrItemNotOwned)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user2", "pen", 1), database.ErrItemNotOwned)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "unknown", "cup", 1), database.ErrUserNotFound)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user2", "unknown", 1), database.ErrProductNotFound)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user2", "cup", 0), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user1", "cup", 1), database.ErrInvalidAmount)
+	assert.Equal(t, map[string]int32{"cup": 1}, inventoryOf(t, db, userId1))
+
+	// лимит покупок действует и на полученные предметы
+	limit := int64(2)
+	require.NoError(t, db.SetProductLimit(ctx, "cup", &limit))
+	assert.ErrorIs(t, db.SendItem(ctx, "user2", "user1", "cup", 2), database.ErrPurchaseLimit)
+	assert.Equal(t, map[string]int32{"cup": 2}, inventoryOf(t, db, userId2))
+	assert.NoError(t, db.SendItem(ctx, "user2", "user1", "cup", 1))
+
+	// отданную покупку вернуть нельзя, пока предмета нет в инвентаре
+	require.NoError(t, db.SetProductLimit(ctx, "cup", nil))
+	require.NoError(t, db.SendItem(ctx, "user1", "user2", "cup", 2))
+	_, _, err = db.RefundPurchase(ctx, cupPurchase, "")
+	assert.ErrorIs(t, err, database.ErrItemNotOwned)
+	purchase, err := db.GetPurchase(ctx, cupPurchase)
+	require.NoError(t, err)
+	assert.Nil(t, purchase.RefundedAt)
+}
+
+func testConcurrentItemTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userIds := []int64{addUser(t, db, "user1", 1000), addUser(t, db, "user2", 1000)}
+	for _, userId := range userIds {
+		_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pen")
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			require.NoError(t, buy(ctx, db, userId, price, itemId))
+		}
+	}
+
+	// встречные передачи одного товара не блокируют друг друга и не теряют предметы,
+	// предметов хватает на все передачи в любом порядке
+	succeeded := parallel(40, func(i int) error {
+		if i%2 == 0 {
+			return db.SendItem(ctx, "user1", "user2", "pen", 1)
+		}
+		return db.SendItem(ctx, "user2", "user1", "pen", 1)
+	})
+	assert.Equal(t, 40, succeeded)
+	assert.Equal(t, map[string]int32{"pen": 20}, inventoryOf(t, db, userIds[0]))
+	assert.Equal(t, map[string]int32{"pen": 20}, inventoryOf(t, db, userIds[1]))
+}
+
+// buyItems покупает quantity единиц товара item по цене каталога
+func buyItems(t *testing.T, db database.Database, userId int64, item string, quantity int) {
+	ctx := context.Background()
+	_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, item)
+	require.NoError(t, err)
+	for i := 0; i < quantity; i++ {
+		require.NoError(t, buy(ctx, db, userId, price, itemId))
+	}
+}
+
+// trade возвращает предложение обмена из списка предложений пользователя username
+func trade(t *testing.T, db database.Database, username string, id int64) database.Trade {
+	trades, err := db.GetTrades(context.Background(), username)
+	require.NoError(t, err)
+	for _, trade := range trades {
+		if trade.Id == id {
+			return trade
+		}
+	}
+	require.Failf(t, "предложение не найдено", "%d", id)
+	return database.Trade{}
+}
+
+func testTrades(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 1000)
+	userId2 := addUser(t, db, "user2", 1000)
+	addUser(t, db, "user3", 1000)
+	buyItems(t, db, userId1, "cup", 2)
+	buyItems(t, db, userId2, "book", 1)
+
+	// некорректное предложение не сохраняется
+	for _, tc := range []struct {
+		trade database.Trade
+		err   error
+	}{
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 0, Coins: 10}, database.ErrInvalidAmount},
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1, Coins: -10}, database.ErrInvalidAmount},
+		{database.Trade{From: "user1", To: "user1", Item: "cup", Quantity: 1, Coins: 10}, database.ErrInvalidArgument},
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1}, database.ErrInvalidArgument},
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1, WantItem: "book"}, database.ErrInvalidArgument},
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1, WantItem: "cup", WantQuantity: 1}, database.ErrInvalidArgument},
+		{database.Trade{From: "user1", To: "unknown", Item: "cup", Quantity: 1, Coins: 10}, database.ErrUserNotFound},
+		{database.Trade{From: "user1", To: "user2", Item: "unknown", Quantity: 1, Coins: 10}, database.ErrProductNotFound},
+		{database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1, WantItem: "unknown", WantQuantity: 1}, database.ErrProductNotFound},
+	} {
+		_, err := db.AddTrade(ctx, tc.trade)
+		assert.ErrorIs(t, err, tc.err, "%+v", tc.trade)
+	}
+	trades, err := db.GetTrades(ctx, "user2")
+	require.NoError(t, err)
+	assert.Empty(t, trades)
+
+	// предложение видят оба участника, принять его может только получатель
+	itemTrade, err := db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 2, WantItem: "book", WantQuantity: 1})
+	require.NoError(t, err)
+	coinTrade, err := db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "cup", Quantity: 1, Coins: 30})
+	require.NoError(t, err)
+	opened := trade(t, db, "user1", itemTrade)
+	assert.Equal(t, database.TradeOpen, opened.Status)
+	assert.Nil(t, opened.ClosedAt)
+	assert.Equal(t, opened, trade(t, db, "user2", itemTrade))
+	assert.Equal(t, database.Trade{Id: coinTrade, From: "user1", To: "user2", Item: "cup", Quantity: 1, Coins: 30, Status: database.TradeOpen,
+		CreatedAt: trade(t, db, "user2", coinTrade).CreatedAt}, trade(t, db, "user2", coinTrade))
+	trades, err = db.GetTrades(ctx, "user3")
+	require.NoError(t, err)
+	assert.Empty(t, trades)
+	for _, username := range []string{"user1", "user3"} {
+		_, err = db.AcceptTrade(ctx, itemTrade, username)
+		assert.ErrorIs(t, err, database.ErrTradeNotFound)
+	}
+	_, err = db.AcceptTrade(ctx, 1000, "user2")
+	assert.ErrorIs(t, err, database.ErrTradeNotFound)
+
+	// обе стороны отдают предметы одной транзакцией, балансы не меняются
+	accepted, err := db.AcceptTrade(ctx, itemTrade, "user2")
+	require.NoError(t, err)
+	assert.Equal(t, database.TradeAccepted, accepted.Status)
+	assert.NotNil(t, accepted.ClosedAt)
+	assert.Equal(t, database.TradeAccepted, trade(t, db, "user1", itemTrade).Status)
+	assert.Equal(t, map[string]int32{"book": 1}, inventoryOf(t, db, userId1))
+	assert.Equal(t, map[string]int32{"cup": 2}, inventoryOf(t, db, userId2))
+	assert.Equal(t, float64(960), coins(t, db, "user1"))
+	assert.Equal(t, float64(950), coins(t, db, "user2"))
+	_, err = db.AcceptTrade(ctx, itemTrade, "user2")
+	assert.ErrorIs(t, err, database.ErrTradeClosed)
+	_, err = db.CloseTrade(ctx, itemTrade, "user1")
+	assert.ErrorIs(t, err, database.ErrTradeClosed)
+
+	// если у автора нет предметов, обмен не выполняется и предложение остаётся открытым
+	_, err = db.AcceptTrade(ctx, coinTrade, "user2")
+	assert.ErrorIs(t, err, database.ErrItemNotOwned)
+	assert.Equal(t, float64(950), coins(t, db, "user2"))
+	assert.Equal(t, database.TradeOpen, trade(t, db, "user2", coinTrade).Status)
+
+	// предмет за монеты: перевод виден в истории монет, передачи -- в истории предметов
+	require.NoError(t, db.SendItem(ctx, "user2", "user1", "cup", 1))
+	_, err = db.AcceptTrade(ctx, coinTrade, "user2")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int32{"book": 1}, inventoryOf(t, db, userId1))
+	assert.Equal(t, map[string]int32{"cup": 2}, inventoryOf(t, db, userId2))
+	assert.Equal(t, float64(990), coins(t, db, "user1"))
+	assert.Equal(t, float64(920), coins(t, db, "user2"))
+	info, err := db.GetUserInfo(ctx, userId1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseCoinHistoryReceivedInner{{FromUser: "user2", Amount: 30}}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseItemHistoryReceivedInner{{FromUser: "user2", Type: "book", Quantity: 1}, {FromUser: "user2", Type: "cup", Quantity: 1}}, info.ItemHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseItemHistorySentInner{{ToUser: "user2", Type: "cup", Quantity: 2}, {ToUser: "user2", Type: "cup", Quantity: 1}}, info.ItemHistory.Sent)
+
+	// монет получателя должно хватить на обмен
+	expensive, err := db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "book", Quantity: 1, Coins: 5000})
+	require.NoError(t, err)
+	_, err = db.AcceptTrade(ctx, expensive, "user2")
+	assert.ErrorIs(t, err, database.ErrInsufficientFunds)
+	assert.Equal(t, map[string]int32{"book": 1}, inventoryOf(t, db, userId1))
+	assert.Equal(t, float64(920), coins(t, db, "user2"))
+
+	// получатель отклоняет предложение, автор отменяет, посторонний не может ни того, ни другого
+	_, err = db.CloseTrade(ctx, expensive, "user3")
+	assert.ErrorIs(t, err, database.ErrTradeNotFound)
+	declined, err := db.CloseTrade(ctx, expensive, "user2")
+	require.NoError(t, err)
+	assert.Equal(t, database.TradeDeclined, declined.Status)
+	assert.NotNil(t, declined.ClosedAt)
+	_, err = db.AcceptTrade(ctx, expensive, "user2")
+	assert.ErrorIs(t, err, database.ErrTradeClosed)
+	cancelled, err := db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "book", Quantity: 1, Coins: 10})
+	require.NoError(t, err)
+	closed, err := db.CloseTrade(ctx, cancelled, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, database.TradeCancelled, closed.Status)
+
+	// заблокированный пользователь не может ни предложить обмен, ни принять его, ни отдать по нему предметы
+	frozenTrade, err := db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "book", Quantity: 1, Coins: 10})
+	require.NoError(t, err)
+	_, err = db.FreezeUser(ctx, "user1", "alice", "compromised")
+	require.NoError(t, err)
+	_, err = db.AddTrade(ctx, database.Trade{From: "user1", To: "user2", Item: "book", Quantity: 1, Coins: 10})
+	assert.ErrorIs(t, err, database.ErrUserFrozen)
+	_, err = db.AcceptTrade(ctx, frozenTrade, "user2")
+	assert.ErrorIs(t, err, database.ErrUserFrozen)
+	assert.Equal(t, map[string]int32{"book": 1}, inventoryOf(t, db, userId1))
+	assert.Equal(t, database.TradeOpen, trade(t, db, "user2", frozenTrade).Status)
+}
+
+func testConcurrentTrades(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userIds := []int64{addUser(t, db, "user1", 1000), addUser(t, db, "user2", 1000)}
+	for _, userId := range userIds {
+		buyItems(t, db, userId, "pen", 20)
+		buyItems(t, db, userId, "cup", 20)
+	}
+	// встречные обмены: каждый отдаёт ручку за кружку, предметов хватает на все обмены в любом порядке
+	ids := make([]int64, 40)
+	for i := range ids {
+		from, to := "user1", "user2"
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		var err error
+		ids[i], err = db.AddTrade(ctx, database.Trade{From: from, To: to, Item: "pen", Quantity: 1, WantItem: "cup", WantQuantity: 1})
+		require.NoError(t, err)
+	}
+
+	// обмены не блокируют друг друга и не теряют предметы, каждое предложение принимается ровно один раз
+	succeeded := parallel(80, func(i int) error {
+		to := "user2"
+		if i%2 == 1 {
+			to = "user1"
+		}
+		_, err := db.AcceptTrade(ctx, ids[i%40], to)
+		return err
+	})
+	assert.Equal(t, 40, succeeded)
+	assert.Equal(t, map[string]int32{"pen": 20, "cup": 20}, inventoryOf(t, db, userIds[0]))
+	assert.Equal(t, map[string]int32{"pen": 20, "cup": 20}, inventoryOf(t, db, userIds[1]))
+}
+
+func testCart(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+
+	cart, err := db.GetCart(ctx, userId)
+	require.NoError(t, err)
+	assert.Empty(t, cart)
+	_, err = db.Checkout(ctx, userId, nil)
+	assert.ErrorIs(t, err, database.ErrCartEmpty)
+
+	// повторное добавление увеличивает количество, корзина упорядочена по имени товара
+	require.NoError(t, db.AddToCart(ctx, userId, "pen", 2))
+	require.NoError(t, db.AddToCart(ctx, userId, "cup", 1))
+	require.NoError(t, db.AddToCart(ctx, userId, "pen", 1))
+	require.NoError(t, db.AddToCart(ctx, userId, "book", 1))
+	assert.ErrorIs(t, db.AddToCart(ctx, userId, "unknown", 1), database.ErrProductNotFound)
+	assert.ErrorIs(t, db.AddToCart(ctx, userId, "pen", 0), database.ErrInvalidAmount)
+	cart, err = db.GetCart(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, []database.CartItem{{Item: "book", Quantity: 1, Price: 50}, {Item: "cup", Quantity: 1, Price: 20}, {Item: "pen", Quantity: 3, Price: 10}}, cart)
+
+	removed, err := db.RemoveFromCart(ctx, userId, "book")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = db.RemoveFromCart(ctx, userId, "book")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	// корзина покупается целиком, каждая единица -- отдельная покупка, которую можно вернуть
+	receipt, err := db.Checkout(ctx, userId, nil)
+	require.NoError(t, err)
+	assert.Equal(t, float64(50), receipt.Total)
+	assert.Equal(t, float64(950), receipt.Balance)
+	require.Len(t, receipt.Items, 2)
+	assert.Equal(t, "cup", receipt.Items[0].Item)
+	assert.Equal(t, "pen", receipt.Items[1].Item)
+	assert.Equal(t, int64(3), receipt.Items[1].Quantity)
+	assert.Len(t, receipt.Items[1].PurchaseIds, 3)
+	assert.Equal(t, float64(950), coins(t, db, "user1"))
+	assert.Equal(t, map[string]int32{"cup": 1, "pen": 3}, inventoryOf(t, db, userId))
+	cart, err = db.GetCart(ctx, userId)
+	require.NoError(t, err)
+	assert.Empty(t, cart)
+	purchase, _, err := db.RefundPurchase(ctx, receipt.Items[1].PurchaseIds[0], "")
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), purchase.Price)
+	assert.Equal(t, float64(960), coins(t, db, "user1"))
+
+	// если хоть одна позиция не проходит, не покупается ничего и корзина остаётся
+	one := int64(1)
+	require.NoError(t, db.SetProductStock(ctx, "wallet", &one))
+	require.NoError(t, db.AddToCart(ctx, userId, "cup", 1))
+	require.NoError(t, db.AddToCart(ctx, userId, "wallet", 2))
+	_, err = db.Checkout(ctx, userId, nil)
+	assert.ErrorIs(t, err, database.ErrSoldOut)
+	var itemErr *database.ItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, "wallet", itemErr.Item)
+	assert.Equal(t, &one, product(t, db, "wallet").Stock)
+
+	_, err = db.RemoveFromCart(ctx, userId, "wallet")
+	require.NoError(t, err)
+	require.NoError(t, db.SetProductLimit(ctx, "cup", &one))
+	_, err = db.Checkout(ctx, userId, nil)
+	assert.ErrorIs(t, err, database.ErrPurchaseLimit)
+
+	require.NoError(t, db.SetProductLimit(ctx, "cup", nil))
+	require.NoError(t, db.AddToCart(ctx, userId, "hoody", 4))
+	_, err = db.Checkout(ctx, userId, nil)
+	assert.ErrorIs(t, err, database.ErrInsufficientFunds)
+	assert.Equal(t, float64(960), coins(t, db, "user1"))
+	assert.Equal(t, map[string]int32{"cup": 1, "pen": 2}, inventoryOf(t, db, userId))
+	cart, err = db.GetCart(ctx, userId)
+	require.NoError(t, err)
+	assert.Len(t, cart, 2)
+}
+
+func testConcurrentCheckout(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+	require.NoError(t, db.AddToCart(ctx, userId, "pen", 2))
+	require.NoError(t, db.AddToCart(ctx, userId, "cup", 1))
+
+	// из параллельных оформлений одной корзины проходит одно, товары не покупаются дважды
+	succeeded := parallel(10, func(int) error {
+		_, err := db.Checkout(ctx, userId, nil)
+		return err
+	})
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, float64(960), coins(t, db, "user1"))
+	assert.Equal(t, map[string]int32{"cup": 1, "pen": 2}, inventoryOf(t, db, userId))
+}
+
+func testPromoCodes(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+	_, price, hoodyId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "pink-hoody")
+	require.NoError(t, err)
+	_, cupPrice, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+
+	two := int64(2)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	require.NoError(t, db.AddPromoCode(ctx, database.PromoCode{Code: "HALF", Percent: 50, MaxUses: &two}))
+	require.NoError(t, db.AddPromoCode(ctx, database.PromoCode{Code: "CUP", Amount: 100, Item: "cup"}))
+	require.NoError(t, db.AddPromoCode(ctx, database.PromoCode{Code: "LATER", Percent: 10, StartsAt: &future}))
+	older := past.Add(-time.Hour)
+	require.NoError(t, db.AddPromoCode(ctx, database.PromoCode{Code: "OVER", Percent: 10, StartsAt: &older, EndsAt: &past}))
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "HALF", Percent: 10}), database.ErrPromoCodeExists)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "BAD", Percent: 10, Amount: 10}), database.ErrInvalidAmount)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "BAD", Percent: 101}), database.ErrInvalidAmount)
+	zero := int64(0)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Percent: 10}), database.ErrInvalidArgument)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "BAD", Percent: 10, StartsAt: &future, EndsAt: &past}), database.ErrInvalidArgument)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "BAD", Percent: 10, MaxUses: &zero}), database.ErrInvalidArgument)
+	assert.ErrorIs(t, db.AddPromoCode(ctx, database.PromoCode{Code: "BAD", Amount: 10, Item: "unknown"}), database.ErrProductNotFound)
+
+	// скидка сохраняется в покупке, возврат начисляет фактически уплаченную цену
+	purchaseId, discount, err := db.BuyWithPromoCode(ctx, userId, price, hoodyId, "HALF")
+	require.NoError(t, err)
+	assert.Equal(t, price/2, discount)
+	assert.Equal(t, 1000-price/2, coins(t, db, "user1"))
+	purchase, err := db.GetPurchase(ctx, purchaseId)
+	require.NoError(t, err)
+	assert.Equal(t, "HALF", purchase.PromoCode)
+	assert.Equal(t, price/2, purchase.Discount)
+	assert.Equal(t, price/2, purchase.Price)
+	_, _, err = db.RefundPurchase(ctx, purchaseId, "")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+
+	// фиксированная скидка не больше цены
+	_, discount, err = db.BuyWithPromoCode(ctx, userId, cupPrice, cupId, "CUP")
+	require.NoError(t, err)
+	assert.Equal(t, cupPrice, discount)
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+
+	// неподходящий код не меняет ни баланс, ни инвентарь, ни счётчик применений
+	for code, expected := range map[string]error{
+		"UNKNOWN": database.ErrPromoCodeNotFound,
+		"CUP":     database.ErrPromoCodeNotApplicable,
+		"LATER":   database.ErrPromoCodeInactive,
+		"OVER":    database.ErrPromoCodeInactive,
+	} {
+		_, _, err = db.BuyWithPromoCode(ctx, userId, price, hoodyId, code)
+		assert.ErrorIs(t, err, expected, code)
+	}
+	assert.Equal(t, float64(1000), coins(t, db, "user1"))
+	assert.Equal(t, map[string]int32{"cup": 1}, inventoryOf(t, db, userId))
+
+	_, _, err = db.BuyWithPromoCode(ctx, userId, price, hoodyId, "HALF")
+	require.NoError(t, err)
+	_, _, err = db.BuyWithPromoCode(ctx, userId, price, hoodyId, "HALF")
+	assert.ErrorIs(t, err, database.ErrPromoCodeExhausted)
+
+	promos, err := db.GetPromoCodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, promos, 4)
+	assert.Equal(t, "CUP", promos[0].Code)
+	assert.Equal(t, "cup", promos[0].Item)
+	assert.Equal(t, "HALF", promos[1].Code)
+	assert.Equal(t, int64(2), promos[1].Uses)
+	assert.Equal(t, &two, promos[1].MaxUses)
+	require.NotNil(t, promos[2].StartsAt)
+	assert.WithinDuration(t, future, *promos[2].StartsAt, time.Second)
+
+	promo, err := db.DisablePromoCode(ctx, "CUP")
+	require.NoError(t, err)
+	require.NotNil(t, promo.DisabledAt)
+	_, _, err = db.BuyWithPromoCode(ctx, userId, cupPrice, cupId, "CUP")
+	assert.ErrorIs(t, err, database.ErrPromoCodeInactive)
+	_, err = db.DisablePromoCode(ctx, "UNKNOWN")
+	assert.ErrorIs(t, err, database.ErrPromoCodeNotFound)
+}
+
+func testConcurrentPromoCodes(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userIds := make([]int64, 10)
+	for i := range userIds {
+		userIds[i] = addUser(t, db, fmt.Sprintf("user%d", i), 1000)
+	}
+	limit := int64(3)
+	require.NoError(t, db.AddPromoCode(ctx, database.PromoCode{Code: "FREE", Percent: 100, MaxUses: &limit}))
+	_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userIds[0], "cup")
+	require.NoError(t, err)
+
+	// код с лимитом в 3 применения проходит ровно в 3 покупках из 10 параллельных
+	bought := parallel(len(userIds), func(i int) error {
+		_, _, err := db.BuyWithPromoCode(ctx, userIds[i], price, itemId, "FREE")
+		return err
+	})
+	assert.Equal(t, 3, bought)
+	promos, err := db.GetPromoCodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), promos[0].Uses)
+}
+
+func testPriceCampaigns(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 1000)
+
+	now := time.Now()
+	week := now.Add(7 * 24 * time.Hour)
+	saleId, err := db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "sale", Item: "pink-hoody", Price: 250, StartsAt: now.Add(-time.Hour), EndsAt: week})
+	require.NoError(t, err)
+	_, err = db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "old", Item: "cup", Price: 10, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	_, err = db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "later", Item: "cup", Price: 15, StartsAt: now.Add(time.Hour), EndsAt: week})
+	require.NoError(t, err)
+	_, err = db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "bad", Item: "cup", Price: 0, StartsAt: now, EndsAt: week})
+	assert.ErrorIs(t, err, database.ErrInvalidAmount)
+	_, err = db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "bad", Item: "cup", Price: 10, StartsAt: week, EndsAt: now})
+	assert.ErrorIs(t, err, database.ErrInvalidArgument)
+	_, err = db.AddPriceCampaign(ctx, database.PriceCampaign{Name: "bad", Item: "unknown", Price: 10, StartsAt: now, EndsAt: week})
+	assert.ErrorIs(t, err, database.ErrProductNotFound)
+
+	// закончившиеся к since акции не возвращаются, остальные упорядочены по товару и началу
+	campaigns, err := db.GetPriceCampaigns(ctx, "", now)
+	require.NoError(t, err)
+	require.Len(t, campaigns, 2)
+	assert.Equal(t, "later", campaigns[0].Name)
+	assert.Equal(t, "cup", campaigns[0].Item)
+	assert.Equal(t, saleId, campaigns[1].Id)
+	assert.Equal(t, float64(250), campaigns[1].Price)
+	assert.WithinDuration(t, week, campaigns[1].EndsAt, time.Second)
+	assert.True(t, campaigns[1].Active(now))
+	assert.False(t, campaigns[0].Active(now))
+
+	campaigns, err = db.GetPriceCampaigns(ctx, "cup", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, campaigns, 2)
+	assert.Equal(t, "old", campaigns[0].Name)
+
+	// корзина покупается по переданным ценам, остальные товары -- по цене каталога
+	require.NoError(t, db.AddToCart(ctx, userId, "pink-hoody", 1))
+	require.NoError(t, db.AddToCart(ctx, userId, "cup", 1))
+	receipt, err := db.Checkout(ctx, userId, map[string]float64{"pink-hoody": 250})
+	require.NoError(t, err)
+	assert.Equal(t, float64(270), receipt.Total)
+	assert.Equal(t, float64(250), receipt.Items[1].Price)
+	assert.Equal(t, float64(730), coins(t, db, "user1"))
+	purchase, err := db.GetPurchase(ctx, receipt.Items[1].PurchaseIds[0])
+	require.NoError(t, err)
+	assert.Equal(t, float64(250), purchase.Price)
+
+	campaign, err := db.DeletePriceCampaign(ctx, saleId)
+	require.NoError(t, err)
+	assert.Equal(t, "pink-hoody", campaign.Item)
+	_, err = db.DeletePriceCampaign(ctx, saleId)
+	assert.ErrorIs(t, err, database.ErrPriceCampaignNotFound)
+	campaigns, err = db.GetPriceCampaigns(ctx, "pink-hoody", now)
+	require.NoError(t, err)
+	assert.Empty(t, campaigns)
+}
+
+func testGrants(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 0)
+	userId2 := addUser(t, db, "user2", 1000)
+
+	// пакет начисляется целиком или не начисляется вовсе, ошибка указывает строку пакета
+	err := db.GrantCoins(ctx, []database.Grant{{Username: "user1", Amount: 10}, {Username: "unknown", Amount: 10}})
+	var grantErr *database.GrantError
+	require.ErrorAs(t, err, &grantErr)
+	assert.Equal(t, 1, grantErr.Index)
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+	err = db.GrantCoins(ctx, []database.Grant{{Username: "user2", Amount: 10}, {Username: "user1", Amount: 0}})
+	assert.ErrorIs(t, err, database.ErrInvalidAmount)
+	assert.Equal(t, float64(0), coins(t, db, "user1"))
+	assert.Equal(t, float64(1000), coins(t, db, "user2"))
+
+	now := time.Now()
+	hour, twoHours := now.Add(time.Hour), now.Add(2*time.Hour)
+	require.NoError(t, db.GrantCoins(ctx, []database.Grant{
+		{Username: "user1", Amount: 100, Reason: "salary", ExpiresAt: &hour},
+		{Username: "user2", Amount: 10, ExpiresAt: &hour},
+		{Username: "user1", Amount: 50, ExpiresAt: &twoHours},
+		{Username: "user1", Amount: 30},
+	}))
+	assert.Equal(t, float64(180), coins(t, db, "user1"))
+
+	// монеты со сроком тратятся раньше бессрочных в порядке начисления: перевод тратит первое начисление
+	// и часть второго и передаёт их второму пользователю с тем же сроком, его покупка тратит
+	// сначала его собственное начисление, затем переданные монеты
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 120))
+	_, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId2, "cup")
+	require.NoError(t, err)
+	require.NoError(t, buy(ctx, db, userId2, price, cupId))
+	expired, err := db.ExpireGrants(ctx, now.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, float64(90), expired)
+	assert.Equal(t, float64(1020), coins(t, db, "user2"))
+
+	// от второго начисления сгорает непотраченный остаток у обоих, бессрочные монеты остаются
+	expired, err = db.ExpireGrants(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(50), expired)
+	assert.Equal(t, float64(30), coins(t, db, "user1"))
+	assert.Equal(t, float64(1000), coins(t, db, "user2"))
+	expired, err = db.ExpireGrants(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), expired)
+
+	// начисления видны в истории как переводы от системы, сгоревшие монеты -- как переводы системе
+	info, err := db.GetUserInfo(ctx, userId1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(30), info.Coins)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{
+		{FromUser: database.SystemUser, Amount: 100}, {FromUser: database.SystemUser, Amount: 50}, {FromUser: database.SystemUser, Amount: 30},
+	}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{
+		{ToUser: "user2", Amount: 120}, {ToUser: database.SystemUser, Amount: 30},
+	}, info.CoinHistory.Sent)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, history.Sent, info.CoinHistory.Sent)
+	assert.ElementsMatch(t, history.Received, info.CoinHistory.Received)
+
+	// переданные монеты со сроком видны как перевод, а не как начисление системы
+	info, err = db.GetUserInfo(ctx, userId2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{
+		{FromUser: database.SystemUser, Amount: 10}, {FromUser: "user1", Amount: 120},
+	}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{
+		{ToUser: database.SystemUser, Amount: 90}, {ToUser: database.SystemUser, Amount: 20},
+	}, info.CoinHistory.Sent)
+	history, err = db.GetUserReceivedAndSentCoins(ctx, userId2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, history.Sent, info.CoinHistory.Sent)
+	assert.ElementsMatch(t, history.Received, info.CoinHistory.Received)
+}
+
+func testGrantTransfers(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 0)
+	userId2 := addUser(t, db, "user2", 20)
+	now := time.Now()
+	hour := now.Add(time.Hour)
+	require.NoError(t, db.GrantCoins(ctx, []database.Grant{{Username: "user1", Amount: 100, ExpiresAt: &hour}}))
+
+	// перевод туда и обратно не делает сгорающие монеты бессрочными
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 100))
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 100))
+	buyItems(t, db, userId2, "cup", 1)
+	assert.Equal(t, float64(100), coins(t, db, "user1"))
+	assert.Equal(t, float64(0), coins(t, db, "user2"))
+
+	// оплата обмена тоже передаёт монеты вместе со сроком
+	id, err := db.AddTrade(ctx, database.Trade{From: "user2", To: "user1", Item: "cup", Quantity: 1, Coins: 60})
+	require.NoError(t, err)
+	_, err = db.AcceptTrade(ctx, id, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), coins(t, db, "user1"))
+	assert.Equal(t, float64(60), coins(t, db, "user2"))
+
+	expired, err := db.ExpireGrants(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), expired)
+	assert.Equal(t, float64(0), coins(t, db, "user1"))
+	assert.Equal(t, float64(0), coins(t, db, "user2"))
+
+	info, err := db.GetUserInfo(ctx, userId1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{
+		{FromUser: database.SystemUser, Amount: 100}, {FromUser: "user2", Amount: 100},
+	}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{
+		{ToUser: "user2", Amount: 100}, {ToUser: "user2", Amount: 60}, {ToUser: database.SystemUser, Amount: 40},
+	}, info.CoinHistory.Sent)
+	info, err = db.GetUserInfo(ctx, userId2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{
+		{FromUser: "user1", Amount: 100}, {FromUser: "user1", Amount: 60},
+	}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{
+		{ToUser: "user1", Amount: 100}, {ToUser: database.SystemUser, Amount: 60},
+	}, info.CoinHistory.Sent)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, history.Sent, info.CoinHistory.Sent)
+	assert.ElementsMatch(t, history.Received, info.CoinHistory.Received)
+}
+
+func testGrantRefunds(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 0)
+	now := time.Now()
+	hour := now.Add(time.Hour)
+	require.NoError(t, db.GrantCoins(ctx, []database.Grant{
+		{Username: "user1", Amount: 100, ExpiresAt: &hour},
+		{Username: "user1", Amount: 50},
+	}))
+
+	// покупка и оформление корзины тратят монеты со сроком, возврат отдельной покупки
+	// возвращает их в то же начисление, а не в бессрочные
+	_, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId, "cup")
+	require.NoError(t, err)
+	cupPurchase, err := db.UpdateUserBalanceAndInventory(ctx, userId, price, cupId)
+	require.NoError(t, err)
+	require.NoError(t, db.AddToCart(ctx, userId, "pen", 3))
+	receipt, err := db.Checkout(ctx, userId, nil)
+	require.NoError(t, err)
+	require.Len(t, receipt.Items, 1)
+	require.Len(t, receipt.Items[0].PurchaseIds, 3)
+	assert.Equal(t, float64(100), coins(t, db, "user1"))
+	for _, id := range []int64{cupPurchase, receipt.Items[0].PurchaseIds[0]} {
+		_, refunded, err := db.RefundPurchase(ctx, id, "")
+		require.NoError(t, err)
+		require.True(t, refunded)
+	}
+	assert.Equal(t, float64(130), coins(t, db, "user1"))
+
+	expired, err := db.ExpireGrants(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(80), expired)
+	assert.Equal(t, float64(50), coins(t, db, "user1"))
+
+	// монеты, возвращённые в уже сгоревшее начисление, сгорают при следующей проверке
+	_, refunded, err := db.RefundPurchase(ctx, receipt.Items[0].PurchaseIds[1], "")
+	require.NoError(t, err)
+	require.True(t, refunded)
+	assert.Equal(t, float64(60), coins(t, db, "user1"))
+	expired, err = db.ExpireGrants(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), expired)
+	assert.Equal(t, float64(50), coins(t, db, "user1"))
+}
+
+func testGrantSchedules(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	for _, name := range []string{"user1", "user2", "user3"} {
+		addUser(t, db, name, 0)
+	}
+
+	now := time.Now()
+	start := now.Add(-36 * time.Hour)
+	for _, tc := range []struct {
+		schedule database.GrantSchedule
+		err      error
+	}{
+		{database.GrantSchedule{Name: "bad", Amount: 0, Interval: time.Hour, NextRunAt: now}, database.ErrInvalidAmount},
+		{database.GrantSchedule{Name: "bad", Amount: 10, Interval: time.Millisecond, NextRunAt: now}, database.ErrInvalidArgument},
+		{database.GrantSchedule{Name: "bad", Amount: 10, Interval: time.Hour, ExpiresAfter: -time.Hour, NextRunAt: now}, database.ErrInvalidArgument},
+	} {
+		_, err := db.AddGrantSchedule(ctx, tc.schedule)
+		assert.ErrorIs(t, err, tc.err)
+	}
+	_, err := db.AddGrantSchedule(ctx, database.GrantSchedule{Name: "bad", Amount: 10, Interval: time.Hour, Usernames: []string{"unknown"}, NextRunAt: now})
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+
+	salaryId, err := db.AddGrantSchedule(ctx, database.GrantSchedule{
+		Name: "salary", Amount: 100, Reason: "зарплата", Interval: 24 * time.Hour, ExpiresAfter: 48 * time.Hour,
+		Usernames: []string{"user2", "user1", "user1"}, NextRunAt: start,
+	})
+	require.NoError(t, err)
+	bonusId, err := db.AddGrantSchedule(ctx, database.GrantSchedule{Name: "bonus", Amount: 10, Interval: time.Hour, NextRunAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+
+	schedules, err := db.GetGrantSchedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	salary := schedules[0]
+	assert.Equal(t, salaryId, salary.Id)
+	assert.Equal(t, []string{"user1", "user2"}, salary.Usernames)
+	assert.Equal(t, 24*time.Hour, salary.Interval)
+	assert.Equal(t, 48*time.Hour, salary.ExpiresAfter)
+	assert.WithinDuration(t, start, salary.NextRunAt, time.Second)
+	assert.Nil(t, salary.StoppedAt)
+	assert.Empty(t, schedules[1].Usernames)
+
+	// пропущенные периоды выполняются по одному за вызов, пока не наступит следующий
+	for _, period := range []time.Time{start, start.Add(24 * time.Hour)} {
+		run, recipients, err := db.RunGrantSchedule(ctx, now)
+		require.NoError(t, err)
+		require.NotNil(t, run)
+		assert.Equal(t, salaryId, run.Id)
+		assert.WithinDuration(t, period, run.NextRunAt, time.Second)
+		assert.Equal(t, int64(2), recipients)
+	}
+	run, _, err := db.RunGrantSchedule(ctx, now)
+	require.NoError(t, err)
+	assert.Nil(t, run)
+	assert.Equal(t, float64(200), coins(t, db, "user1"))
+	assert.Equal(t, float64(0), coins(t, db, "user3"))
+
+	// монеты сгорают через ExpiresAfter после своего периода, а не после выполнения
+	expired, err := db.ExpireGrants(ctx, start.Add(49*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(200), expired)
+	assert.Equal(t, float64(100), coins(t, db, "user1"))
+
+	// расписание без получателей начисляет монеты всем пользователям
+	run, recipients, err := db.RunGrantSchedule(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, bonusId, run.Id)
+	assert.Equal(t, int64(3), recipients)
+	assert.Equal(t, float64(10), coins(t, db, "user3"))
+
+	// остановленное расписание больше не выполняется, повторная остановка ничего не меняет
+	stopped, err := db.StopGrantSchedule(ctx, salaryId)
+	require.NoError(t, err)
+	require.NotNil(t, stopped.StoppedAt)
+	assert.Equal(t, []string{"user1", "user2"}, stopped.Usernames)
+	again, err := db.StopGrantSchedule(ctx, salaryId)
+	require.NoError(t, err)
+	assert.WithinDuration(t, *stopped.StoppedAt, *again.StoppedAt, time.Second)
+	run, _, err = db.RunGrantSchedule(ctx, now.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, bonusId, run.Id)
+	_, err = db.StopGrantSchedule(ctx, bonusId+100)
+	assert.ErrorIs(t, err, database.ErrGrantScheduleNotFound)
+}
+
+func testConcurrentGrantSchedules(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	addUser(t, db, "user1", 0)
+	now := time.Now()
+	_, err := db.AddGrantSchedule(ctx, database.GrantSchedule{Name: "salary", Amount: 100, Interval: time.Hour, NextRunAt: now.Add(-150 * time.Minute)})
+	require.NoError(t, err)
+
+	// экземпляры сервиса выполняют расписание одновременно, но каждый из трёх наступивших периодов
+	// выполняется ровно один раз
+	for {
+		ran := parallel(10, func(int) error {
+			run, _, err := db.RunGrantSchedule(ctx, now)
+			if err == nil && run == nil {
+				return fmt.Errorf("нет периодов")
+			}
+			return err
+		})
+		if ran == 0 {
+			break
+		}
+	}
+	assert.Equal(t, float64(300), coins(t, db, "user1"))
+}
+
+func testAdjustments(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId := addUser(t, db, "user1", 100)
+
+	_, err := db.AdjustBalance(ctx, database.Adjustment{Username: "user1", Amount: 0, Reason: "zero", Admin: "alice"})
+	assert.ErrorIs(t, err, database.ErrInvalidAmount)
+	_, err = db.AdjustBalance(ctx, database.Adjustment{Username: "user1", Amount: 10, Admin: "alice"})
+	assert.ErrorIs(t, err, database.ErrInvalidArgument)
+	_, err = db.AdjustBalance(ctx, database.Adjustment{Username: "unknown", Amount: 10, Reason: "typo", Admin: "alice"})
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+	_, err = db.AdjustBalance(ctx, database.Adjustment{Username: "user1", Amount: -101, Reason: "too much", Admin: "alice"})
+	assert.ErrorIs(t, err, database.ErrInsufficientFunds)
+	assert.Equal(t, float64(100), coins(t, db, "user1"))
+
+	credit, err := db.AdjustBalance(ctx, database.Adjustment{Username: "user1", Amount: 50, Reason: "lost purchase", Admin: "alice"})
+	require.NoError(t, err)
+	assert.NotZero(t, credit.Id)
+	assert.Equal(t, float64(150), credit.Balance)
+	assert.Equal(t, "alice", credit.Admin)
+	assert.False(t, credit.CreatedAt.IsZero())
+
+	// списание тратит монеты со сроком так же, как покупка, поэтому после сгорания баланс не уходит в минус
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, db.GrantCoins(ctx, []database.Grant{{Username: "user1", Amount: 30, ExpiresAt: &expiresAt}}))
+	debit, err := db.AdjustBalance(ctx, database.Adjustment{Username: "user1", Amount: -160, Reason: "double grant", Admin: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), debit.Balance)
+	expired, err := db.ExpireGrants(ctx, expiresAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), expired)
+	assert.Equal(t, float64(20), coins(t, db, "user1"))
+
+	// исправления видны в истории как переводы от системы и системе
+	info, err := db.GetUserInfo(ctx, userId)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistoryReceivedInner{
+		{FromUser: database.SystemUser, Amount: 50}, {FromUser: database.SystemUser, Amount: 30},
+	}, info.CoinHistory.Received)
+	assert.ElementsMatch(t, []models.InfoResponseCoinHistorySentInner{
+		{ToUser: database.SystemUser, Amount: 160},
+	}, info.CoinHistory.Sent)
+	history, err := db.GetUserReceivedAndSentCoins(ctx, userId)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, history.Sent, info.CoinHistory.Sent)
+	assert.ElementsMatch(t, history.Received, info.CoinHistory.Received)
+}
+
+func testFreezeUser(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	userId1 := addUser(t, db, "user1", 1000)
+	addUser(t, db, "user2", 1000)
+	_, price, cupId, err := db.GetUserCoinsAndItemPrice(ctx, userId1, "cup")
+	require.NoError(t, err)
+	require.NoError(t, buy(ctx, db, userId1, price, cupId))
+	require.NoError(t, db.AddToCart(ctx, userId1, "pen", 1))
+
+	_, err = db.FreezeUser(ctx, "unknown", "alice", "compromised")
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+	status, err := db.FreezeUser(ctx, "user1", "alice", "compromised")
+	require.NoError(t, err)
+	require.NotNil(t, status.FrozenAt)
+	assert.Equal(t, "alice", status.FrozenBy)
+	assert.Equal(t, "compromised", status.FrozenReason)
+	assert.Equal(t, float64(980), status.Balance)
+
+	// повторная блокировка сохраняет первую
+	again, err := db.FreezeUser(ctx, "user1", "bob", "again")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", again.FrozenBy)
+	assert.True(t, status.FrozenAt.Equal(*again.FrozenAt))
+
+	// с неверным паролем блокировка не раскрывается
+	ok, _, err := db.AuthorizeUser(ctx, "user1", "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, _, err = db.AuthorizeUser(ctx, "user1", "pass")
+	assert.ErrorIs(t, err, database.ErrUserFrozen)
+	assert.False(t, ok)
+
+	// заблокированный пользователь не тратит монеты и не отдаёт предметы, но может их получать
+	assert.ErrorIs(t, buy(ctx, db, userId1, price, cupId), database.ErrUserFrozen)
+	_, err = db.GiftItem(ctx, userId1, "user2", price, cupId, "")
+	assert.ErrorIs(t, err, database.ErrUserFrozen)
+	_, err = db.Checkout(ctx, userId1, nil)
+	assert.ErrorIs(t, err, database.ErrUserFrozen)
+	assert.ErrorIs(t, db.SendCoins(ctx, "user1", "user2", 10), database.ErrUserFrozen)
+	assert.ErrorIs(t, db.SendItem(ctx, "user1", "user2", "cup", 1), database.ErrUserFrozen)
+	require.NoError(t, db.SendCoins(ctx, "user2", "user1", 10))
+	assert.Equal(t, float64(990), coins(t, db, "user1"))
+	assert.Equal(t, map[string]int32{"cup": 1}, inventoryOf(t, db, userId1))
+
+	status, err = db.UnfreezeUser(ctx, "user1")
+	require.NoError(t, err)
+	assert.Nil(t, status.FrozenAt)
+	assert.Empty(t, status.FrozenBy)
+	status, err = db.GetUserStatus(ctx, "user1")
+	require.NoError(t, err)
+	assert.Nil(t, status.FrozenAt)
+	_, err = db.GetUserStatus(ctx, "unknown")
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+
+	ok, _, err = db.AuthorizeUser(ctx, "user1", "pass")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, db.SendCoins(ctx, "user1", "user2", 10))
+	_, err = db.Checkout(ctx, userId1, nil)
+	require.NoError(t, err)
+}
+
+func testAdminActions(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	for i, admin := range []string{"alice", "bob", "alice"} {
+		require.NoError(t, db.AddAdminAction(ctx, database.AdminAction{
+			Admin: admin, Action: "ApiAdminGrantsPost", Method: "POST", Path: "/api/admin/grants",
+			Status: 200 + i, RequestId: fmt.Sprint("req", i), Body: `{"username": "user1"}`, CreatedAt: time.Now(),
+		}))
+	}
+
+	// записи возвращаются от новых к старым
+	actions, err := db.GetAdminActions(ctx, database.AdminActionFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+	assert.Equal(t, 202, actions[0].Status)
+	assert.Equal(t, "req2", actions[0].RequestId)
+	assert.Equal(t, `{"username": "user1"}`, actions[0].Body)
+	assert.Equal(t, "/api/admin/grants", actions[0].Path)
+	assert.False(t, actions[0].CreatedAt.IsZero())
+	assert.Greater(t, actions[0].Id, actions[1].Id)
+
+	actions, err = db.GetAdminActions(ctx, database.AdminActionFilter{Admin: "alice", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, 202, actions[0].Status)
+	assert.Equal(t, 200, actions[1].Status)
+
+	page, err := db.GetAdminActions(ctx, database.AdminActionFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	page, err = db.GetAdminActions(ctx, database.AdminActionFilter{Before: page[0].Id, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "bob", page[0].Admin)
+}
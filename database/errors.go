@@ -0,0 +1,130 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Ошибки хранилища, на которые движок реагирует кодом 4xx, а не 500.
+// Реализации оборачивают их, поэтому проверять нужно через errors.Is
+var (
+	ErrUserNotFound      = errors.New("пользователь не найден")
+	ErrProductNotFound   = errors.New("товар не найден")
+	ErrInsufficientFunds = errors.New("недостаточно средств")
+	ErrInvalidAmount     = errors.New("некорректная сумма")
+	ErrInvalidArgument   = errors.New("некорректный параметр")
+	ErrSoldOut           = errors.New("товар закончился")
+	// ErrPurchaseLimit -- пользователь уже купил столько единиц товара, сколько разрешено
+	ErrPurchaseLimit = errors.New("превышен лимит покупок товара")
+	// ErrUnlimitedStock -- пополнить можно только товар с ограниченным остатком
+	ErrUnlimitedStock   = errors.New("остаток товара не ограничен")
+	ErrPurchaseNotFound = errors.New("покупка не найдена")
+	// ErrItemNotOwned -- купленного предмета уже нет в инвентаре, вернуть его нельзя
+	ErrItemNotOwned = errors.New("предмета нет в инвентаре")
+	ErrCartEmpty    = errors.New("корзина пуста")
+
+	ErrPromoCodeNotFound = errors.New("промокод не найден")
+	ErrPromoCodeExists   = errors.New("промокод уже существует")
+	// ErrPromoCodeInactive -- промокод отключён, ещё не начал действовать или уже истёк
+	ErrPromoCodeInactive = errors.New("промокод не действует")
+	// ErrPromoCodeExhausted -- промокод применён максимальное число раз
+	ErrPromoCodeExhausted = errors.New("промокод исчерпан")
+	// ErrPromoCodeNotApplicable -- промокод действует только на другой товар
+	ErrPromoCodeNotApplicable = errors.New("промокод не действует на этот товар")
+
+	ErrPriceCampaignNotFound = errors.New("акция не найдена")
+	ErrGrantScheduleNotFound = errors.New("регулярное начисление не найдено")
+	// ErrUserFrozen -- пользователь заблокирован администратором
+	ErrUserFrozen = errors.New("пользователь заблокирован")
+
+	ErrTradeNotFound = errors.New("предложение обмена не найдено")
+	// ErrTradeClosed -- предложение уже принято, отклонено или отменено
+	ErrTradeClosed = errors.New("предложение обмена закрыто")
+)
+
+// ItemError -- ошибка оформления корзины или обмена, относящаяся к товару Item
+type ItemError struct {
+	Item string
+	Err  error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Item, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// коды ошибок Postgres, см. https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgForeignKeyViolation pq.ErrorCode = "23503"
+	pgCheckViolation      pq.ErrorCode = "23514"
+)
+
+// ограничения схемы, нарушение которых соответствует ошибкам выше
+var constraintErrors = map[string]error{
+	"users_balance_check":                 ErrInsufficientFunds,
+	"products_stock_check":                ErrSoldOut,
+	"products_max_per_user_check":         ErrInvalidAmount,
+	"transactions_amount_check":           ErrInvalidAmount,
+	"transactions_src_dst_check":          ErrInvalidAmount,
+	"inventory_user_id_fkey":              ErrUserNotFound,
+	"inventory_product_id_fkey":           ErrProductNotFound,
+	"transactions_src_fkey":               ErrUserNotFound,
+	"transactions_dst_fkey":               ErrUserNotFound,
+	"purchases_user_id_fkey":              ErrUserNotFound,
+	"purchases_recipient_id_fkey":         ErrUserNotFound,
+	"purchases_product_id_fkey":           ErrProductNotFound,
+	"item_transfers_quantity_check":       ErrInvalidAmount,
+	"item_transfers_src_dst_check":        ErrInvalidAmount,
+	"item_transfers_src_fkey":             ErrUserNotFound,
+	"item_transfers_dst_fkey":             ErrUserNotFound,
+	"item_transfers_product_id_fkey":      ErrProductNotFound,
+	"cart_items_quantity_check":           ErrInvalidAmount,
+	"cart_items_user_id_fkey":             ErrUserNotFound,
+	"cart_items_product_id_fkey":          ErrProductNotFound,
+	"receipts_user_id_fkey":               ErrUserNotFound,
+	"promo_codes_product_id_fkey":         ErrProductNotFound,
+	"promo_codes_discount_check":          ErrInvalidAmount,
+	"promo_codes_window_check":            ErrInvalidArgument,
+	"promo_codes_max_uses_check":          ErrInvalidArgument,
+	"promo_codes_uses_check":              ErrPromoCodeExhausted,
+	"price_campaigns_product_id_fkey":     ErrProductNotFound,
+	"price_campaigns_price_check":         ErrInvalidAmount,
+	"price_campaigns_window_check":        ErrInvalidArgument,
+	"grant_schedules_amount_check":        ErrInvalidAmount,
+	"grant_schedules_interval_check":      ErrInvalidArgument,
+	"grant_schedules_expires_after_check": ErrInvalidArgument,
+	"grant_schedule_users_user_id_fkey":   ErrUserNotFound,
+	"coin_grants_user_id_fkey":            ErrUserNotFound,
+	"coin_grants_amount_check":            ErrInvalidAmount,
+	"balance_adjustments_user_id_fkey":    ErrUserNotFound,
+	"balance_adjustments_amount_check":    ErrInvalidAmount,
+	"balance_adjustments_reason_check":    ErrInvalidArgument,
+	"trades_from_id_fkey":                 ErrUserNotFound,
+	"trades_to_id_fkey":                   ErrUserNotFound,
+	"trades_product_id_fkey":              ErrProductNotFound,
+	"trades_want_product_id_fkey":         ErrProductNotFound,
+	"trades_from_to_check":                ErrInvalidArgument,
+	"trades_quantity_check":               ErrInvalidAmount,
+	"trades_want_check":                   ErrInvalidArgument,
+	"trades_status_check":                 ErrInvalidArgument,
+}
+
+// mapPgError оборачивает нарушение известного ограничения в соответствующую ошибку хранилища
+func mapPgError(err error) error {
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if pgErr.Code != pgCheckViolation && pgErr.Code != pgForeignKeyViolation {
+		return err
+	}
+	if mapped, ok := constraintErrors[pgErr.Constraint]; ok {
+		return fmt.Errorf("%w: %v", mapped, err)
+	}
+	return err
+}
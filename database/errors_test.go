@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapPgError(t *testing.T) {
+	check := &pq.Error{Code: pgCheckViolation, Constraint: "users_balance_check"}
+	err := mapPgError(fmt.Errorf("обновление: %w", check))
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	fk := &pq.Error{Code: pgForeignKeyViolation, Constraint: "transactions_dst_fkey"}
+	assert.ErrorIs(t, mapPgError(fk), ErrUserNotFound)
+
+	// неизвестные ограничения и прочие ошибки возвращаются как есть
+	unknown := &pq.Error{Code: pgCheckViolation, Constraint: "other_check"}
+	assert.Equal(t, error(unknown), mapPgError(unknown))
+	plain := errors.New("error")
+	assert.Equal(t, plain, mapPgError(plain))
+}
@@ -0,0 +1,103 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/migrations"
+
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkUserInfo сравнивает сборку /api/info из трёх методов хранилища с одним GetUserInfo.
+// По умолчанию меряется SQLite, с TEST_POSTGRES=external -- ещё и Postgres из DATABASE_*:
+//
+//	go test ./database -run '^$' -bench UserInfo
+func BenchmarkUserInfo(b *testing.B) {
+	b.Run("SQLite", func(b *testing.B) {
+		cfg := config.Default().Database
+		cfg.Path = filepath.Join(b.TempDir(), "shop.db")
+		db, err := database.NewSQLite(cfg)
+		require.NoError(b, err)
+		defer db.Close()
+		migrator, err := migrations.NewSQLite(db.DB())
+		require.NoError(b, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(b, err)
+		benchmarkUserInfo(b, db)
+	})
+
+	b.Run("Postgres", func(b *testing.B) {
+		if os.Getenv("TEST_POSTGRES") != "external" {
+			b.Skip("TEST_POSTGRES=external не задан")
+		}
+		cfg, _, err := config.Load(nil)
+		require.NoError(b, err)
+		db, err := database.NewPostgres(cfg.Database)
+		require.NoError(b, err)
+		defer db.Close()
+		migrator, err := migrations.NewPostgres(db.DB())
+		require.NoError(b, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(b, err)
+		_, err = db.DB().Exec("TRUNCATE transactions, inventory, users RESTART IDENTITY CASCADE")
+		require.NoError(b, err)
+		benchmarkUserInfo(b, db)
+	})
+}
+
+func benchmarkUserInfo(b *testing.B, db database.Database) {
+	ctx := context.Background()
+	userId := seedUserInfo(b, db)
+
+	b.Run("Separate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := db.GetUserCoins(ctx, "bench0"); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := db.GetUserInventory(ctx, userId); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := db.GetUserReceivedAndSentCoins(ctx, userId); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("SingleQuery", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := db.GetUserInfo(ctx, userId); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// seedUserInfo создаёт пользователя с несколькими товарами и парой десятков переводов в обе стороны
+func seedUserInfo(b *testing.B, db database.Database) int64 {
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := db.AddNewUser(ctx, fmt.Sprintf("bench%d", i), "pass", 10000)
+		require.NoError(b, err)
+	}
+	_, userId, err := db.AuthorizeUser(ctx, "bench0", "pass")
+	require.NoError(b, err)
+
+	for _, product := range database.DefaultProducts[:5] {
+		_, price, itemId, err := db.GetUserCoinsAndItemPrice(ctx, userId, product.Name)
+		require.NoError(b, err)
+		_, err = db.UpdateUserBalanceAndInventory(ctx, userId, price, itemId)
+		require.NoError(b, err)
+	}
+	for i := 0; i < 20; i++ {
+		other := fmt.Sprintf("bench%d", i%4+1)
+		require.NoError(b, db.SendCoins(ctx, "bench0", other, 10))
+		require.NoError(b, db.SendCoins(ctx, other, "bench0", 5))
+	}
+	return userId
+}
@@ -0,0 +1,1619 @@
+package database
+
+import (
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Product -- товар каталога
+type Product struct {
+	Name  string
+	Price float64
+	// остаток на складе, nil -- товар не ограничен
+	Stock *int64
+	// сколько единиц может купить один пользователь, nil -- без ограничения
+	MaxPerUser *int64
+}
+
+// DefaultProducts -- каталог, которым базовая миграция заполняет таблицу products
+var DefaultProducts = []Product{
+	{Name: "t-shirt", Price: 80},
+	{Name: "cup", Price: 20},
+	{Name: "book", Price: 50},
+	{Name: "pen", Price: 10},
+	{Name: "powerbank", Price: 200},
+	{Name: "hoody", Price: 300},
+	{Name: "umbrella", Price: 200},
+	{Name: "socks", Price: 10},
+	{Name: "wallet", Price: 50},
+	{Name: "pink-hoody", Price: 500},
+}
+
+var memorySystem = semconv.DBSystemKey.String("memory")
+
+type memoryProduct struct {
+	id         int64
+	name       string
+	price      float64
+	stock      *int64
+	maxPerUser *int64
+}
+
+type memoryItem struct {
+	product  *memoryProduct
+	quantity int32
+}
+
+type memoryTransfer struct {
+	from, to  *memoryUser
+	amount    float64
+	createdAt time.Time
+}
+
+type memoryItemTransfer struct {
+	from, to  *memoryUser
+	product   *memoryProduct
+	quantity  int32
+	createdAt time.Time
+}
+
+// memoryGrant -- начисление монет от имени системы, remaining и expired ведутся только для монет со сроком.
+// У монет со сроком, полученных переводом, transferred = true: в истории их показывает сам перевод
+type memoryGrant struct {
+	amount      float64
+	expiresAt   *time.Time
+	remaining   float64
+	expired     float64
+	transferred bool
+}
+
+type memoryPurchase struct {
+	id   int64
+	user *memoryUser
+	// в чьём инвентаре предмет: покупатель или получатель подарка
+	owner      *memoryUser
+	product    *memoryProduct
+	price      float64
+	message    string
+	createdAt  time.Time
+	refundedAt *time.Time
+	refundedBy string
+	promoCode  string
+	discount   float64
+	// монеты со сроком, которыми оплачена покупка; при возврате они снова могут сгореть
+	grants []memorySpentGrant
+}
+
+// memorySpentGrant -- сколько монет начисления потрачено на покупку или перевод
+type memorySpentGrant struct {
+	grant  *memoryGrant
+	amount float64
+}
+
+func (p *memoryPurchase) purchase() *Purchase {
+	refundedAt := p.refundedAt
+	if refundedAt != nil {
+		t := *refundedAt
+		refundedAt = &t
+	}
+	var recipient string
+	if p.owner != p.user {
+		recipient = p.owner.name
+	}
+	return &Purchase{
+		Id:         p.id,
+		UserId:     p.user.id,
+		Username:   p.user.name,
+		Item:       p.product.name,
+		Price:      p.price,
+		CreatedAt:  p.createdAt,
+		RefundedAt: refundedAt,
+		RefundedBy: p.refundedBy,
+		Recipient:  recipient,
+		Message:    p.message,
+		PromoCode:  p.promoCode,
+		Discount:   p.discount,
+	}
+}
+
+type memoryUser struct {
+	id        int64
+	name      string
+	md5       string
+	balance   float64
+	inventory []*memoryItem
+	sent      []memoryTransfer
+	received  []memoryTransfer
+	reviews   []TransferReview
+	// отправленные и полученные подарки в порядке покупки
+	gifts         []*memoryPurchase
+	itemsSent     []memoryItemTransfer
+	itemsReceived []memoryItemTransfer
+	cart          []*memoryItem
+	// начисления системы в порядке начисления
+	grants []*memoryGrant
+	// исправления баланса администраторами в порядке исправления
+	adjustments []*Adjustment
+	// возвращённые покупки в порядке возврата
+	refunds []*memoryPurchase
+	// время блокировки, nil -- пользователь не заблокирован
+	frozenAt     *time.Time
+	frozenBy     string
+	frozenReason string
+}
+
+// Memory -- хранилище в памяти процесса для локального запуска и тестов.
+// Все изменения выполняются под одной блокировкой и либо применяются целиком, либо не применяются вовсе,
+// ограничения схемы (уникальность имени, неотрицательный баланс, положительная сумма перевода)
+// проверяются так же, как в Postgres
+type Memory struct {
+	mu           sync.RWMutex
+	users        map[string]*memoryUser
+	usersById    map[int64]*memoryUser
+	products     map[string]*memoryProduct
+	productsById map[int64]*memoryProduct
+	purchases    map[int64]*memoryPurchase
+	promoCodes   map[string]*PromoCode
+	campaigns    map[int64]*PriceCampaign
+	schedules    map[int64]*GrantSchedule
+	trades       map[int64]*Trade
+	// журнал действий администраторов в порядке записи
+	actions      []AdminAction
+	nextUserId   int64
+	nextPurchase int64
+	nextReceipt  int64
+	nextCampaign int64
+	nextSchedule int64
+	nextAdjust   int64
+	nextTrade    int64
+}
+
+// NewMemory создаёт пустое хранилище с каталогом DefaultProducts
+func NewMemory() *Memory {
+	return NewMemoryWithProducts(DefaultProducts)
+}
+
+// NewMemoryWithProducts создаёт пустое хранилище с заданным каталогом
+func NewMemoryWithProducts(products []Product) *Memory {
+	m := &Memory{
+		users:        make(map[string]*memoryUser),
+		usersById:    make(map[int64]*memoryUser),
+		products:     make(map[string]*memoryProduct, len(products)),
+		productsById: make(map[int64]*memoryProduct, len(products)),
+		purchases:    make(map[int64]*memoryPurchase),
+		promoCodes:   make(map[string]*PromoCode),
+		campaigns:    make(map[int64]*PriceCampaign),
+		schedules:    make(map[int64]*GrantSchedule),
+		trades:       make(map[int64]*Trade),
+		nextUserId:   1,
+		nextPurchase: 1,
+		nextReceipt:  1,
+		nextCampaign: 1,
+		nextSchedule: 1,
+		nextAdjust:   1,
+		nextTrade:    1,
+	}
+	for i, p := range products {
+		product := &memoryProduct{id: int64(i + 1), name: p.Name, price: p.Price, stock: cloneInt64(p.Stock), maxPerUser: cloneInt64(p.MaxPerUser)}
+		m.products[p.Name] = product
+		m.productsById[product.id] = product
+	}
+	return m
+}
+
+// cloneInt64 копирует необязательное значение, чтобы вызывающий не мог изменить состояние хранилища
+func cloneInt64(v *int64) *int64 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func (m *Memory) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return startMethodSpan(ctx, memorySystem, method)
+}
+
+func (m *Memory) AddNewUser(ctx context.Context, username, password string, balance float64) (_ bool, err error) {
+	ctx, span := m.startSpan(ctx, "AddNewUser")
+	defer func() { tracing.End(span, err) }()
+
+	if balance < 0 {
+		return false, fmt.Errorf("%w: начальный баланс %v", ErrInsufficientFunds, balance)
+	}
+	hashStr, err := convertPassToMd5(password)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[username]; ok {
+		return false, nil
+	}
+	user := &memoryUser{id: m.nextUserId, name: username, md5: hashStr, balance: balance}
+	m.nextUserId++
+	m.users[username] = user
+	m.usersById[user.id] = user
+	slog.InfoContext(ctx, "user created", "new_user_id", user.id)
+
+	return true, nil
+}
+
+func (m *Memory) AuthorizeUser(ctx context.Context, username, password string) (_ bool, _ int64, err error) {
+	_, span := m.startSpan(ctx, "AuthorizeUser")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	user, ok := m.users[username]
+	var frozen bool
+	if ok {
+		frozen = user.frozenAt != nil
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return false, 0, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+
+	hashStr, err := convertPassToMd5(password)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// имя и хеш пароля после создания не меняются, поэтому читаем их без блокировки
+	if user.md5 != hashStr {
+		return false, user.id, nil
+	}
+	if frozen {
+		return false, user.id, fmt.Errorf("%w: %s", ErrUserFrozen, username)
+	}
+	return true, user.id, nil
+}
+
+func (m *Memory) GetUserCoinsAndItemPrice(ctx context.Context, userId int64, item string) (_, _ float64, _ int64, err error) {
+	_, span := m.startSpan(ctx, "GetUserCoinsAndItemPrice")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.usersById[userId]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	product, ok := m.products[item]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%w: %s", ErrProductNotFound, item)
+	}
+
+	return user.balance, product.price, product.id, nil
+}
+
+func (m *Memory) UpdateUserBalanceAndInventory(ctx context.Context, userId int64, price float64, itemId int64) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "UpdateUserBalanceAndInventory")
+	defer func() { tracing.End(span, err) }()
+
+	purchaseId, _, err := m.purchase(ctx, userId, "", price, itemId, "", "")
+	return purchaseId, err
+}
+
+func (m *Memory) GiftItem(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message string) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "GiftItem")
+	defer func() { tracing.End(span, err) }()
+
+	purchaseId, _, err := m.purchase(ctx, userId, toUser, price, itemId, message, "")
+	return purchaseId, err
+}
+
+func (m *Memory) BuyWithPromoCode(ctx context.Context, userId int64, price float64, itemId int64, code string) (_ int64, _ float64, err error) {
+	ctx, span := m.startSpan(ctx, "BuyWithPromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	return m.purchase(ctx, userId, "", price, itemId, "", code)
+}
+
+// purchase покупает товар за счёт userId в инвентарь toUser, пустое имя -- в инвентарь самого покупателя.
+// Непустой code применяет промокод, вторым значением возвращается скидка
+func (m *Memory) purchase(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message, code string) (int64, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// все проверки до изменений, чтобы при ошибке состояние не менялось
+	user, ok := m.usersById[userId]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	owner := user
+	if toUser != "" {
+		if owner, ok = m.users[toUser]; !ok {
+			return 0, 0, fmt.Errorf("%w: %s", ErrUserNotFound, toUser)
+		}
+		if owner == user {
+			return 0, 0, fmt.Errorf("%w: подарок самому себе", ErrInvalidAmount)
+		}
+	}
+	product, ok := m.productsById[itemId]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %d", ErrProductNotFound, itemId)
+	}
+	var promo *PromoCode
+	var discount float64
+	if code != "" {
+		if promo, ok = m.promoCodes[code]; !ok {
+			return 0, 0, fmt.Errorf("%w: %s", ErrPromoCodeNotFound, code)
+		}
+		if err := promo.check(product.name, time.Now()); err != nil {
+			return 0, 0, err
+		}
+		discount = promo.Discount(price)
+		price -= discount
+	}
+	if user.balance-price < 0 {
+		return 0, 0, fmt.Errorf("%w: баланс %v, цена %v", ErrInsufficientFunds, user.balance, price)
+	}
+	if user.frozenAt != nil {
+		return 0, 0, fmt.Errorf("%w: %d", ErrUserFrozen, userId)
+	}
+	if product.stock != nil && *product.stock == 0 {
+		return 0, 0, fmt.Errorf("%w: %s", ErrSoldOut, product.name)
+	}
+	if product.maxPerUser != nil && int64(owner.quantity(product)) >= *product.maxPerUser {
+		return 0, 0, fmt.Errorf("%w: %s, не больше %d", ErrPurchaseLimit, product.name, *product.maxPerUser)
+	}
+
+	user.balance -= price
+	spent := user.spendGrants(price)
+	if promo != nil {
+		promo.Uses++
+	}
+	if product.stock != nil {
+		*product.stock--
+	}
+	item := owner.item(product)
+	item.quantity++
+	purchase := &memoryPurchase{id: m.nextPurchase, user: user, owner: owner, product: product, price: price, message: message, createdAt: time.Now(), promoCode: code, discount: discount, grants: spent}
+	m.purchases[purchase.id] = purchase
+	m.nextPurchase++
+	if owner != user {
+		user.gifts = append(user.gifts, purchase)
+		owner.gifts = append(owner.gifts, purchase)
+	}
+	slog.DebugContext(ctx, "inventory updated", "product_id", itemId, "owner_id", owner.id, "quantity", item.quantity, "balance", user.balance)
+
+	return purchase.id, discount, nil
+}
+
+// quantity возвращает, сколько единиц товара в инвентаре пользователя
+func (u *memoryUser) quantity(product *memoryProduct) int32 {
+	for _, item := range u.inventory {
+		if item.product == product {
+			return item.quantity
+		}
+	}
+	return 0
+}
+
+// removeItems забирает из инвентаря quantity единиц товара и возвращает false, если их не хватает.
+// Пустая позиция удаляется
+func (u *memoryUser) removeItems(product *memoryProduct, quantity int32) bool {
+	for i, item := range u.inventory {
+		if item.product != product || item.quantity < quantity {
+			continue
+		}
+		item.quantity -= quantity
+		if item.quantity == 0 {
+			u.inventory = append(u.inventory[:i], u.inventory[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// item возвращает позицию инвентаря с товаром, добавляя пустую, если её ещё нет
+func (u *memoryUser) item(product *memoryProduct) *memoryItem {
+	for _, item := range u.inventory {
+		if item.product == product {
+			return item
+		}
+	}
+	item := &memoryItem{product: product}
+	u.inventory = append(u.inventory, item)
+	return item
+}
+
+// spendGrants списывает amount с монет со сроком в порядке начисления, они тратятся раньше бессрочных,
+// и возвращает, сколько потрачено с каждого начисления
+func (u *memoryUser) spendGrants(amount float64) []memorySpentGrant {
+	var spent []memorySpentGrant
+	for _, g := range u.grants {
+		if amount <= 0 {
+			break
+		}
+		if g.remaining <= 0 {
+			continue
+		}
+		sp := memorySpentGrant{grant: g, amount: min(g.remaining, amount)}
+		g.remaining -= sp.amount
+		amount -= sp.amount
+		spent = append(spent, sp)
+	}
+	return spent
+}
+
+// systemHistory добавляет к истории переводов начисления системы, исправления баланса и возвраты покупок
+// как полученные переводы, сгоревшие монеты и списания -- как отправленные
+func (u *memoryUser) systemHistory(sent []models.InfoResponseCoinHistorySentInner, received []models.InfoResponseCoinHistoryReceivedInner) ([]models.InfoResponseCoinHistorySentInner, []models.InfoResponseCoinHistoryReceivedInner) {
+	for _, g := range u.grants {
+		if !g.transferred {
+			received = append(received, models.InfoResponseCoinHistoryReceivedInner{FromUser: SystemUser, Amount: int32(g.amount)})
+		}
+		if g.expired > 0 {
+			sent = append(sent, models.InfoResponseCoinHistorySentInner{ToUser: SystemUser, Amount: int32(g.expired)})
+		}
+	}
+	for _, a := range u.adjustments {
+		if a.Amount > 0 {
+			received = append(received, models.InfoResponseCoinHistoryReceivedInner{FromUser: SystemUser, Amount: int32(a.Amount)})
+		} else {
+			sent = append(sent, models.InfoResponseCoinHistorySentInner{ToUser: SystemUser, Amount: int32(-a.Amount)})
+		}
+	}
+	for _, p := range u.refunds {
+		if p.price > 0 {
+			received = append(received, models.InfoResponseCoinHistoryReceivedInner{FromUser: SystemUser, Amount: int32(p.price)})
+		}
+	}
+	return sent, received
+}
+
+// carryGrants передаёт пользователю монеты со сроком, которыми оплачен перевод ему: каждая часть становится
+// его начислением с тем же сроком, иначе переводы туда и обратно делали бы сгорающие монеты бессрочными.
+// Баланс меняет вызывающий
+func (u *memoryUser) carryGrants(spent []memorySpentGrant) {
+	for _, sp := range spent {
+		u.grants = append(u.grants, &memoryGrant{amount: sp.amount, expiresAt: sp.grant.expiresAt, remaining: sp.amount, transferred: true})
+	}
+}
+
+// grant начисляет пользователю монеты, проверки выполняет вызывающий
+func (u *memoryUser) grant(grant Grant) {
+	g := &memoryGrant{amount: grant.Amount}
+	if grant.ExpiresAt != nil {
+		expiresAt := *grant.ExpiresAt
+		g.expiresAt = &expiresAt
+		g.remaining = grant.Amount
+	}
+	u.balance += grant.Amount
+	u.grants = append(u.grants, g)
+}
+
+func (m *Memory) GetUserCoins(ctx context.Context, username string) (_ float64, err error) {
+	_, span := m.startSpan(ctx, "GetUserCoins")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return 0, nil
+	}
+	return user.balance, nil
+}
+
+func (m *Memory) SendCoins(ctx context.Context, userFrom, userTo string, amount float64) (err error) {
+	ctx, span := m.startSpan(ctx, "SendCoins")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, ok := m.users[userFrom]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userFrom)
+	}
+	to, ok := m.users[userTo]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userTo)
+	}
+	if amount <= 0 || from == to {
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, amount)
+	}
+	if from.balance-amount < 0 {
+		return fmt.Errorf("%w: баланс %v, перевод %v", ErrInsufficientFunds, from.balance, amount)
+	}
+	// заблокированный пользователь может получать монеты, но не отправлять их
+	if from.frozenAt != nil {
+		return fmt.Errorf("%w: %s", ErrUserFrozen, userFrom)
+	}
+
+	from.balance -= amount
+	to.carryGrants(from.spendGrants(amount))
+	to.balance += amount
+	transfer := memoryTransfer{from: from, to: to, amount: amount, createdAt: time.Now()}
+	from.sent = append(from.sent, transfer)
+	to.received = append(to.received, transfer)
+	slog.DebugContext(ctx, "coins transferred", "from_user_id", from.id, "to_user_id", to.id, "amount", amount)
+
+	return nil
+}
+
+func (m *Memory) SendItem(ctx context.Context, userFrom, userTo, item string, quantity int64) (err error) {
+	ctx, span := m.startSpan(ctx, "SendItem")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, ok := m.users[userFrom]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userFrom)
+	}
+	to, ok := m.users[userTo]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userTo)
+	}
+	product, ok := m.products[item]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, item)
+	}
+	if quantity <= 0 || quantity > math.MaxInt32 || from == to {
+		return fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+	if from.frozenAt != nil {
+		return fmt.Errorf("%w: %s", ErrUserFrozen, userFrom)
+	}
+	if int64(from.quantity(product)) < quantity {
+		return fmt.Errorf("%w: %s, нужно %d", ErrItemNotOwned, item, quantity)
+	}
+	if product.maxPerUser != nil && int64(to.quantity(product))+quantity > *product.maxPerUser {
+		return fmt.Errorf("%w: %s, не больше %d", ErrPurchaseLimit, item, *product.maxPerUser)
+	}
+
+	from.removeItems(product, int32(quantity))
+	to.item(product).quantity += int32(quantity)
+	transfer := memoryItemTransfer{from: from, to: to, product: product, quantity: int32(quantity), createdAt: time.Now()}
+	from.itemsSent = append(from.itemsSent, transfer)
+	to.itemsReceived = append(to.itemsReceived, transfer)
+	slog.DebugContext(ctx, "items transferred", "from_user_id", from.id, "to_user_id", to.id, "product_id", product.id, "quantity", quantity)
+
+	return nil
+}
+
+// cloneTrade копирует предложение обмена вместе со временем закрытия
+func cloneTrade(t *Trade) *Trade {
+	c := *t
+	if t.ClosedAt != nil {
+		closedAt := *t.ClosedAt
+		c.ClosedAt = &closedAt
+	}
+	return &c
+}
+
+func (m *Memory) AddTrade(ctx context.Context, trade Trade) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "AddTrade")
+	defer func() { tracing.End(span, err) }()
+
+	if err := trade.validate(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, ok := m.users[trade.From]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUserNotFound, trade.From)
+	}
+	if _, ok := m.users[trade.To]; !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUserNotFound, trade.To)
+	}
+	if from.frozenAt != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUserFrozen, trade.From)
+	}
+	for _, item := range []string{trade.Item, trade.WantItem} {
+		if _, ok := m.products[item]; !ok && item != "" {
+			return 0, &ItemError{Item: item, Err: ErrProductNotFound}
+		}
+	}
+
+	trade.Id = m.nextTrade
+	m.nextTrade++
+	trade.Status = TradeOpen
+	trade.CreatedAt = time.Now()
+	trade.ClosedAt = nil
+	m.trades[trade.Id] = cloneTrade(&trade)
+	slog.DebugContext(ctx, "trade added", "trade_id", trade.Id, "from_user_id", from.id)
+	return trade.Id, nil
+}
+
+func (m *Memory) GetTrades(ctx context.Context, username string) (_ []Trade, err error) {
+	_, span := m.startSpan(ctx, "GetTrades")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trades := []Trade{}
+	for _, trade := range m.trades {
+		if trade.From == username || trade.To == username {
+			trades = append(trades, *cloneTrade(trade))
+		}
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Id < trades[j].Id })
+	return trades, nil
+}
+
+// closeTrade находит открытое предложение id участника username и возвращает его со статусом, в который его
+// переводит закрытие: accept -- принятие, иначе автор отменяет, а получатель отклоняет. Изменяет предложение вызывающий
+func (m *Memory) closeTrade(id int64, username string, accept bool) (*Trade, string, error) {
+	trade, ok := m.trades[id]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %d", ErrTradeNotFound, id)
+	}
+	var status string
+	switch {
+	case accept && username == trade.To:
+		status = TradeAccepted
+	case !accept && username == trade.To:
+		status = TradeDeclined
+	case !accept && username == trade.From:
+		status = TradeCancelled
+	default:
+		return nil, "", fmt.Errorf("%w: %d", ErrTradeNotFound, id)
+	}
+	if trade.Status != TradeOpen {
+		return nil, "", fmt.Errorf("%w: %d", ErrTradeClosed, id)
+	}
+	return trade, status, nil
+}
+
+func (m *Memory) AcceptTrade(ctx context.Context, id int64, username string) (_ *Trade, err error) {
+	ctx, span := m.startSpan(ctx, "AcceptTrade")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trade, status, err := m.closeTrade(id, username, true)
+	if err != nil {
+		return nil, err
+	}
+	from, to := m.users[trade.From], m.users[trade.To]
+	for _, user := range []*memoryUser{from, to} {
+		if user.frozenAt != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUserFrozen, user.name)
+		}
+	}
+	if to.balance-trade.Coins < 0 {
+		return nil, fmt.Errorf("%w: баланс %v, обмен %v", ErrInsufficientFunds, to.balance, trade.Coins)
+	}
+	type give struct {
+		from, to *memoryUser
+		product  *memoryProduct
+		quantity int64
+	}
+	gives := []give{{from, to, m.products[trade.Item], trade.Quantity}}
+	if trade.WantItem != "" {
+		gives = append(gives, give{to, from, m.products[trade.WantItem], trade.WantQuantity})
+	}
+	for _, g := range gives {
+		if int64(g.from.quantity(g.product)) < g.quantity {
+			return nil, &ItemError{Item: g.product.name, Err: fmt.Errorf("%w: у %s, нужно %d", ErrItemNotOwned, g.from.name, g.quantity)}
+		}
+		if g.product.maxPerUser != nil && int64(g.to.quantity(g.product))+g.quantity > *g.product.maxPerUser {
+			return nil, &ItemError{Item: g.product.name, Err: fmt.Errorf("%w: не больше %d", ErrPurchaseLimit, *g.product.maxPerUser)}
+		}
+	}
+
+	now := time.Now()
+	if trade.Coins > 0 {
+		to.balance -= trade.Coins
+		from.carryGrants(to.spendGrants(trade.Coins))
+		from.balance += trade.Coins
+		transfer := memoryTransfer{from: to, to: from, amount: trade.Coins, createdAt: now}
+		to.sent = append(to.sent, transfer)
+		from.received = append(from.received, transfer)
+	}
+	for _, g := range gives {
+		g.from.removeItems(g.product, int32(g.quantity))
+		g.to.item(g.product).quantity += int32(g.quantity)
+		transfer := memoryItemTransfer{from: g.from, to: g.to, product: g.product, quantity: int32(g.quantity), createdAt: now}
+		g.from.itemsSent = append(g.from.itemsSent, transfer)
+		g.to.itemsReceived = append(g.to.itemsReceived, transfer)
+	}
+	trade.Status = status
+	trade.ClosedAt = &now
+	slog.DebugContext(ctx, "trade accepted", "trade_id", id, "from_user_id", from.id, "to_user_id", to.id)
+	return cloneTrade(trade), nil
+}
+
+func (m *Memory) CloseTrade(ctx context.Context, id int64, username string) (_ *Trade, err error) {
+	ctx, span := m.startSpan(ctx, "CloseTrade")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trade, status, err := m.closeTrade(id, username, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	trade.Status = status
+	trade.ClosedAt = &now
+	slog.DebugContext(ctx, "trade closed", "trade_id", id, "status", status)
+	return cloneTrade(trade), nil
+}
+
+func (m *Memory) GetUserInventory(ctx context.Context, userId int64) (_ *[]models.InfoResponseInventoryInner, err error) {
+	_, span := m.startSpan(ctx, "GetUserInventory")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	goods := make([]models.InfoResponseInventoryInner, 0)
+	if user, ok := m.usersById[userId]; ok {
+		for _, item := range user.inventory {
+			goods = append(goods, models.InfoResponseInventoryInner{Type: item.product.name, Quantity: item.quantity})
+		}
+	}
+	return &goods, nil
+}
+
+func (m *Memory) GetUserReceivedAndSentCoins(ctx context.Context, userId int64) (_ *models.InfoResponseCoinHistory, err error) {
+	_, span := m.startSpan(ctx, "GetUserReceivedAndSentCoins")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sent := make([]models.InfoResponseCoinHistorySentInner, 0)
+	received := make([]models.InfoResponseCoinHistoryReceivedInner, 0)
+	if user, ok := m.usersById[userId]; ok {
+		for _, t := range user.sent {
+			sent = append(sent, models.InfoResponseCoinHistorySentInner{ToUser: t.to.name, Amount: int32(t.amount)})
+		}
+		for _, t := range user.received {
+			received = append(received, models.InfoResponseCoinHistoryReceivedInner{FromUser: t.from.name, Amount: int32(t.amount)})
+		}
+		sent, received = user.systemHistory(sent, received)
+	}
+
+	history := new(models.InfoResponseCoinHistory)
+	history.Received = received
+	history.Sent = sent
+	return history, nil
+}
+
+func (m *Memory) GetUserInfo(ctx context.Context, userId int64) (_ *models.InfoResponse, err error) {
+	_, span := m.startSpan(ctx, "GetUserInfo")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.usersById[userId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+
+	info := &models.InfoResponse{
+		Coins:     int32(user.balance),
+		Inventory: make([]models.InfoResponseInventoryInner, 0, len(user.inventory)),
+		CoinHistory: models.InfoResponseCoinHistory{
+			Received: make([]models.InfoResponseCoinHistoryReceivedInner, 0, len(user.received)),
+			Sent:     make([]models.InfoResponseCoinHistorySentInner, 0, len(user.sent)),
+		},
+		ItemHistory: models.InfoResponseItemHistory{
+			Received: make([]models.InfoResponseItemHistoryReceivedInner, 0, len(user.itemsReceived)),
+			Sent:     make([]models.InfoResponseItemHistorySentInner, 0, len(user.itemsSent)),
+		},
+		Gifts: models.InfoResponseGifts{
+			Received: make([]models.InfoResponseGiftsReceivedInner, 0),
+			Sent:     make([]models.InfoResponseGiftsSentInner, 0),
+		},
+	}
+	for _, item := range user.inventory {
+		info.Inventory = append(info.Inventory, models.InfoResponseInventoryInner{Type: item.product.name, Quantity: item.quantity})
+	}
+	for _, t := range user.sent {
+		info.CoinHistory.Sent = append(info.CoinHistory.Sent, models.InfoResponseCoinHistorySentInner{ToUser: t.to.name, Amount: int32(t.amount)})
+	}
+	for _, t := range user.received {
+		info.CoinHistory.Received = append(info.CoinHistory.Received, models.InfoResponseCoinHistoryReceivedInner{FromUser: t.from.name, Amount: int32(t.amount)})
+	}
+	info.CoinHistory.Sent, info.CoinHistory.Received = user.systemHistory(info.CoinHistory.Sent, info.CoinHistory.Received)
+	for _, t := range user.itemsSent {
+		info.ItemHistory.Sent = append(info.ItemHistory.Sent, models.InfoResponseItemHistorySentInner{ToUser: t.to.name, Type: t.product.name, Quantity: t.quantity})
+	}
+	for _, t := range user.itemsReceived {
+		info.ItemHistory.Received = append(info.ItemHistory.Received, models.InfoResponseItemHistoryReceivedInner{FromUser: t.from.name, Type: t.product.name, Quantity: t.quantity})
+	}
+	for _, g := range user.gifts {
+		switch {
+		case g.refundedAt != nil:
+		case g.user == user:
+			info.Gifts.Sent = append(info.Gifts.Sent, models.InfoResponseGiftsSentInner{ToUser: g.owner.name, Item: g.product.name, Amount: int32(g.price), Message: g.message})
+		default:
+			info.Gifts.Received = append(info.Gifts.Received, models.InfoResponseGiftsReceivedInner{FromUser: g.user.name, Item: g.product.name, Message: g.message})
+		}
+	}
+	for _, p := range m.products {
+		if p.maxPerUser != nil {
+			info.Limits = append(info.Limits, models.InfoResponseLimitsInner{Type: p.name, MaxPerUser: int32(*p.maxPerUser)})
+		}
+	}
+	completeLimits(info)
+	return info, nil
+}
+
+func (m *Memory) GetProducts(ctx context.Context) (_ []Product, err error) {
+	_, span := m.startSpan(ctx, "GetProducts")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	products := make([]Product, 0, len(m.products))
+	for _, p := range m.products {
+		products = append(products, Product{Name: p.name, Price: p.price, Stock: cloneInt64(p.stock), MaxPerUser: cloneInt64(p.maxPerUser)})
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
+	return products, nil
+}
+
+func (m *Memory) SetProductStock(ctx context.Context, name string, stock *int64) (err error) {
+	ctx, span := m.startSpan(ctx, "SetProductStock")
+	defer func() { tracing.End(span, err) }()
+
+	if stock != nil && *stock < 0 {
+		return fmt.Errorf("%w: остаток %d", ErrInvalidAmount, *stock)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, name)
+	}
+	product.stock = cloneInt64(stock)
+	slog.DebugContext(ctx, "product stock set", "product_id", product.id, "unlimited", stock == nil)
+	return nil
+}
+
+func (m *Memory) RestockProduct(ctx context.Context, name string, quantity int64) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "RestockProduct")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrProductNotFound, name)
+	}
+	if product.stock == nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnlimitedStock, name)
+	}
+	*product.stock += quantity
+	slog.DebugContext(ctx, "product restocked", "product_id", product.id, "stock", *product.stock)
+	return *product.stock, nil
+}
+
+func (m *Memory) SetProductLimit(ctx context.Context, name string, maxPerUser *int64) (err error) {
+	ctx, span := m.startSpan(ctx, "SetProductLimit")
+	defer func() { tracing.End(span, err) }()
+
+	if maxPerUser != nil && *maxPerUser <= 0 {
+		return fmt.Errorf("%w: лимит %d", ErrInvalidAmount, *maxPerUser)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, name)
+	}
+	product.maxPerUser = cloneInt64(maxPerUser)
+	slog.DebugContext(ctx, "product limit set", "product_id", product.id, "unlimited", maxPerUser == nil)
+	return nil
+}
+
+func (m *Memory) GetPurchase(ctx context.Context, id int64) (_ *Purchase, err error) {
+	_, span := m.startSpan(ctx, "GetPurchase")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	purchase, ok := m.purchases[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPurchaseNotFound, id)
+	}
+	return purchase.purchase(), nil
+}
+
+func (m *Memory) RefundPurchase(ctx context.Context, id int64, by string) (_ *Purchase, _ bool, err error) {
+	ctx, span := m.startSpan(ctx, "RefundPurchase")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purchase, ok := m.purchases[id]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %d", ErrPurchaseNotFound, id)
+	}
+	if purchase.refundedAt != nil {
+		return purchase.purchase(), false, nil
+	}
+	if !purchase.owner.removeItems(purchase.product, 1) {
+		return nil, false, fmt.Errorf("%w: %s", ErrItemNotOwned, purchase.product.name)
+	}
+
+	purchase.user.balance += purchase.price
+	purchase.user.refunds = append(purchase.user.refunds, purchase)
+	// монеты со сроком остаются со сроком, иначе покупка с возвратом продлевала бы их навсегда
+	for _, sp := range purchase.grants {
+		sp.grant.remaining += sp.amount
+	}
+	if purchase.product.stock != nil {
+		*purchase.product.stock++
+	}
+	now := time.Now()
+	purchase.refundedAt = &now
+	purchase.refundedBy = by
+	slog.DebugContext(ctx, "purchase refunded", "purchase_id", id, "balance", purchase.user.balance)
+	return purchase.purchase(), true, nil
+}
+
+func (m *Memory) AddToCart(ctx context.Context, userId int64, item string, quantity int64) (err error) {
+	ctx, span := m.startSpan(ctx, "AddToCart")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 || quantity > math.MaxInt32 {
+		return fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.usersById[userId]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	product, ok := m.products[item]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, item)
+	}
+	line := cartLine(&user.cart, product)
+	if int64(line.quantity)+quantity > math.MaxInt32 {
+		return fmt.Errorf("%w: %s, слишком много в корзине", ErrInvalidAmount, item)
+	}
+	line.quantity += int32(quantity)
+	slog.DebugContext(ctx, "cart updated", "product_id", product.id, "quantity", line.quantity)
+	return nil
+}
+
+// cartLine возвращает позицию корзины с товаром, добавляя пустую, если её ещё нет
+func cartLine(cart *[]*memoryItem, product *memoryProduct) *memoryItem {
+	for _, line := range *cart {
+		if line.product == product {
+			return line
+		}
+	}
+	line := &memoryItem{product: product}
+	*cart = append(*cart, line)
+	return line
+}
+
+func (m *Memory) RemoveFromCart(ctx context.Context, userId int64, item string) (_ bool, err error) {
+	_, span := m.startSpan(ctx, "RemoveFromCart")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.usersById[userId]
+	if !ok {
+		return false, nil
+	}
+	for i, line := range user.cart {
+		if line.product.name == item {
+			user.cart = append(user.cart[:i], user.cart[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) GetCart(ctx context.Context, userId int64) (_ []CartItem, err error) {
+	_, span := m.startSpan(ctx, "GetCart")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cart := []CartItem{}
+	if user, ok := m.usersById[userId]; ok {
+		for _, line := range user.cart {
+			cart = append(cart, CartItem{Item: line.product.name, Quantity: int64(line.quantity), Price: line.product.price})
+		}
+	}
+	sort.Slice(cart, func(i, j int) bool { return cart[i].Item < cart[j].Item })
+	return cart, nil
+}
+
+func (m *Memory) Checkout(ctx context.Context, userId int64, prices map[string]float64) (_ *Receipt, err error) {
+	ctx, span := m.startSpan(ctx, "Checkout")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.usersById[userId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	if len(user.cart) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrCartEmpty, userId)
+	}
+	lines := append([]*memoryItem(nil), user.cart...)
+	sort.Slice(lines, func(i, j int) bool { return lines[i].product.name < lines[j].product.name })
+
+	// все проверки до изменений и в том же порядке, что в Postgres: сначала баланс, затем позиции по имени товара
+	price := func(product *memoryProduct) float64 {
+		if p, ok := prices[product.name]; ok {
+			return p
+		}
+		return product.price
+	}
+	var total float64
+	for _, line := range lines {
+		total += price(line.product) * float64(line.quantity)
+	}
+	if user.balance-total < 0 {
+		return nil, fmt.Errorf("%w: баланс %v, сумма %v", ErrInsufficientFunds, user.balance, total)
+	}
+	if user.frozenAt != nil {
+		return nil, fmt.Errorf("%w: %d", ErrUserFrozen, userId)
+	}
+	for _, line := range lines {
+		product := line.product
+		if product.stock != nil && *product.stock < int64(line.quantity) {
+			return nil, &ItemError{Item: product.name, Err: ErrSoldOut}
+		}
+		if product.maxPerUser != nil && int64(user.quantity(product))+int64(line.quantity) > *product.maxPerUser {
+			return nil, &ItemError{Item: product.name, Err: fmt.Errorf("%w: не больше %d", ErrPurchaseLimit, *product.maxPerUser)}
+		}
+	}
+
+	now := time.Now()
+	user.balance -= total
+	receipt := &Receipt{Id: m.nextReceipt, Total: total, Balance: user.balance, CreatedAt: now}
+	m.nextReceipt++
+	for _, line := range lines {
+		product := line.product
+		if product.stock != nil {
+			*product.stock -= int64(line.quantity)
+		}
+		user.item(product).quantity += line.quantity
+		item := ReceiptItem{Item: product.name, Quantity: int64(line.quantity), Price: price(product), PurchaseIds: make([]int64, 0, line.quantity)}
+		for range line.quantity {
+			// монеты со сроком списываются по покупкам, чтобы возврат одной покупки вернул именно её монеты
+			purchase := &memoryPurchase{id: m.nextPurchase, user: user, owner: user, product: product, price: item.Price, createdAt: now, grants: user.spendGrants(item.Price)}
+			m.purchases[purchase.id] = purchase
+			m.nextPurchase++
+			item.PurchaseIds = append(item.PurchaseIds, purchase.id)
+		}
+		receipt.Items = append(receipt.Items, item)
+	}
+	user.cart = nil
+	slog.DebugContext(ctx, "cart checked out", "receipt_id", receipt.Id, "total", total, "balance", user.balance)
+
+	return receipt, nil
+}
+
+func (m *Memory) AddPromoCode(ctx context.Context, promo PromoCode) (err error) {
+	ctx, span := m.startSpan(ctx, "AddPromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	if err := promo.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.products[promo.Item]; promo.Item != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, promo.Item)
+	}
+	if _, ok := m.promoCodes[promo.Code]; ok {
+		return fmt.Errorf("%w: %s", ErrPromoCodeExists, promo.Code)
+	}
+	promo.Uses = 0
+	promo.DisabledAt = nil
+	m.promoCodes[promo.Code] = clonePromoCode(&promo)
+	slog.DebugContext(ctx, "promo code added", "code", promo.Code)
+	return nil
+}
+
+// clonePromoCode копирует промокод вместе с необязательными полями
+func clonePromoCode(p *PromoCode) *PromoCode {
+	c := *p
+	c.MaxUses = cloneInt64(p.MaxUses)
+	for _, t := range []**time.Time{&c.StartsAt, &c.EndsAt, &c.DisabledAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &c
+}
+
+func (m *Memory) GetPromoCodes(ctx context.Context) (_ []PromoCode, err error) {
+	_, span := m.startSpan(ctx, "GetPromoCodes")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	promos := make([]PromoCode, 0, len(m.promoCodes))
+	for _, promo := range m.promoCodes {
+		promos = append(promos, *clonePromoCode(promo))
+	}
+	sort.Slice(promos, func(i, j int) bool { return promos[i].Code < promos[j].Code })
+	return promos, nil
+}
+
+func (m *Memory) DisablePromoCode(ctx context.Context, code string) (_ *PromoCode, err error) {
+	_, span := m.startSpan(ctx, "DisablePromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	promo, ok := m.promoCodes[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPromoCodeNotFound, code)
+	}
+	if promo.DisabledAt == nil {
+		now := time.Now()
+		promo.DisabledAt = &now
+	}
+	return clonePromoCode(promo), nil
+}
+
+func (m *Memory) AddPriceCampaign(ctx context.Context, campaign PriceCampaign) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "AddPriceCampaign")
+	defer func() { tracing.End(span, err) }()
+
+	if err := campaign.validate(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.products[campaign.Item]; !ok {
+		return 0, fmt.Errorf("%w: %s", ErrProductNotFound, campaign.Item)
+	}
+	campaign.Id = m.nextCampaign
+	m.nextCampaign++
+	m.campaigns[campaign.Id] = &campaign
+	slog.DebugContext(ctx, "price campaign added", "price_campaign_id", campaign.Id)
+	return campaign.Id, nil
+}
+
+func (m *Memory) GetPriceCampaigns(ctx context.Context, item string, since time.Time) (_ []PriceCampaign, err error) {
+	_, span := m.startSpan(ctx, "GetPriceCampaigns")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	campaigns := []PriceCampaign{}
+	for _, c := range m.campaigns {
+		if (item == "" || c.Item == item) && c.EndsAt.After(since) {
+			campaigns = append(campaigns, *c)
+		}
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		a, b := campaigns[i], campaigns[j]
+		switch {
+		case a.Item != b.Item:
+			return a.Item < b.Item
+		case !a.StartsAt.Equal(b.StartsAt):
+			return a.StartsAt.Before(b.StartsAt)
+		}
+		return a.Id < b.Id
+	})
+	return campaigns, nil
+}
+
+func (m *Memory) DeletePriceCampaign(ctx context.Context, id int64) (_ *PriceCampaign, err error) {
+	ctx, span := m.startSpan(ctx, "DeletePriceCampaign")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaign, ok := m.campaigns[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPriceCampaignNotFound, id)
+	}
+	delete(m.campaigns, id)
+	slog.DebugContext(ctx, "price campaign deleted", "price_campaign_id", id)
+	return campaign, nil
+}
+
+func (m *Memory) GrantCoins(ctx context.Context, grants []Grant) (err error) {
+	ctx, span := m.startSpan(ctx, "GrantCoins")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// все проверки до изменений и в том же порядке, что в Postgres: по имени получателя
+	order := make([]int, len(grants))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return grants[order[i]].Username < grants[order[j]].Username })
+	users := make([]*memoryUser, len(grants))
+	for _, i := range order {
+		g := grants[i]
+		if g.Amount <= 0 {
+			return &GrantError{Index: i, Username: g.Username, Err: fmt.Errorf("%w: %v", ErrInvalidAmount, g.Amount)}
+		}
+		user, ok := m.users[g.Username]
+		if !ok {
+			return &GrantError{Index: i, Username: g.Username, Err: fmt.Errorf("%w: %s", ErrUserNotFound, g.Username)}
+		}
+		users[i] = user
+	}
+
+	for i, g := range grants {
+		users[i].grant(g)
+	}
+	slog.DebugContext(ctx, "coins granted", "grants", len(grants))
+	return nil
+}
+
+func (m *Memory) ExpireGrants(ctx context.Context, now time.Time) (_ float64, err error) {
+	ctx, span := m.startSpan(ctx, "ExpireGrants")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total float64
+	for _, user := range m.users {
+		var expired float64
+		for _, g := range user.grants {
+			if g.remaining > 0 && !g.expiresAt.After(now) {
+				// после возврата покупки монеты того же начисления могут сгореть повторно
+				expired += g.remaining
+				g.expired, g.remaining = g.expired+g.remaining, 0
+			}
+		}
+		if expired > 0 {
+			user.balance -= expired
+			total += expired
+			slog.DebugContext(ctx, "coins expired", "user_id", user.id, "amount", expired)
+		}
+	}
+	return total, nil
+}
+
+// cloneGrantSchedule копирует расписание вместе со списком получателей и временем остановки
+func cloneGrantSchedule(s *GrantSchedule) *GrantSchedule {
+	c := *s
+	c.Usernames = append([]string(nil), s.Usernames...)
+	if s.StoppedAt != nil {
+		stoppedAt := *s.StoppedAt
+		c.StoppedAt = &stoppedAt
+	}
+	return &c
+}
+
+func (m *Memory) AddGrantSchedule(ctx context.Context, schedule GrantSchedule) (_ int64, err error) {
+	ctx, span := m.startSpan(ctx, "AddGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	if err := schedule.validate(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, name := range schedule.Usernames {
+		if _, ok := m.users[name]; !ok {
+			return 0, &GrantError{Index: i, Username: name, Err: fmt.Errorf("%w: %s", ErrUserNotFound, name)}
+		}
+	}
+	// как в базе: секунды без дробной части, получатели без повторов в порядке имён
+	schedule.Interval = schedule.Interval.Truncate(time.Second)
+	schedule.ExpiresAfter = schedule.ExpiresAfter.Truncate(time.Second)
+	usernames := append([]string(nil), schedule.Usernames...)
+	sort.Strings(usernames)
+	schedule.Usernames = nil
+	for i, name := range usernames {
+		if i == 0 || name != usernames[i-1] {
+			schedule.Usernames = append(schedule.Usernames, name)
+		}
+	}
+	schedule.StoppedAt = nil
+	schedule.Id = m.nextSchedule
+	m.nextSchedule++
+	m.schedules[schedule.Id] = cloneGrantSchedule(&schedule)
+	slog.DebugContext(ctx, "grant schedule added", "grant_schedule_id", schedule.Id)
+	return schedule.Id, nil
+}
+
+func (m *Memory) GetGrantSchedules(ctx context.Context) (_ []GrantSchedule, err error) {
+	_, span := m.startSpan(ctx, "GetGrantSchedules")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedules := make([]GrantSchedule, 0, len(m.schedules))
+	for _, schedule := range m.schedules {
+		schedules = append(schedules, *cloneGrantSchedule(schedule))
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Id < schedules[j].Id })
+	return schedules, nil
+}
+
+func (m *Memory) StopGrantSchedule(ctx context.Context, id int64) (_ *GrantSchedule, err error) {
+	ctx, span := m.startSpan(ctx, "StopGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrGrantScheduleNotFound, id)
+	}
+	if schedule.StoppedAt == nil {
+		now := time.Now()
+		schedule.StoppedAt = &now
+	}
+	slog.DebugContext(ctx, "grant schedule stopped", "grant_schedule_id", id)
+	return cloneGrantSchedule(schedule), nil
+}
+
+func (m *Memory) RunGrantSchedule(ctx context.Context, now time.Time) (_ *GrantSchedule, _ int64, err error) {
+	ctx, span := m.startSpan(ctx, "RunGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var schedule *GrantSchedule
+	for _, s := range m.schedules {
+		if s.StoppedAt != nil || s.NextRunAt.After(now) {
+			continue
+		}
+		if schedule == nil || s.NextRunAt.Before(schedule.NextRunAt) || s.NextRunAt.Equal(schedule.NextRunAt) && s.Id < schedule.Id {
+			schedule = s
+		}
+	}
+	if schedule == nil {
+		return nil, 0, nil
+	}
+
+	var recipients []*memoryUser
+	for _, name := range schedule.Usernames {
+		recipients = append(recipients, m.users[name])
+	}
+	if len(recipients) == 0 {
+		for _, user := range m.users {
+			recipients = append(recipients, user)
+		}
+		sort.Slice(recipients, func(i, j int) bool { return recipients[i].name < recipients[j].name })
+	}
+	for _, user := range recipients {
+		user.grant(schedule.grant(user.name))
+	}
+	run := cloneGrantSchedule(schedule)
+	schedule.NextRunAt = schedule.NextRunAt.Add(schedule.Interval)
+	slog.DebugContext(ctx, "grant schedule run", "grant_schedule_id", run.Id, "period", run.NextRunAt, "recipients", len(recipients))
+	return run, int64(len(recipients)), nil
+}
+
+func (m *Memory) AdjustBalance(ctx context.Context, adjustment Adjustment) (_ *Adjustment, err error) {
+	ctx, span := m.startSpan(ctx, "AdjustBalance")
+	defer func() { tracing.End(span, err) }()
+
+	if err := adjustment.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[adjustment.Username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, adjustment.Username)
+	}
+	if user.balance+adjustment.Amount < 0 {
+		return nil, fmt.Errorf("%w: баланс %v, списание %v", ErrInsufficientFunds, user.balance, -adjustment.Amount)
+	}
+
+	user.balance += adjustment.Amount
+	if adjustment.Amount < 0 {
+		user.spendGrants(-adjustment.Amount)
+	}
+	adjustment.Id = m.nextAdjust
+	m.nextAdjust++
+	adjustment.Balance = user.balance
+	adjustment.CreatedAt = time.Now()
+	saved := adjustment
+	user.adjustments = append(user.adjustments, &saved)
+	slog.DebugContext(ctx, "balance adjusted", "user_id", user.id, "amount", adjustment.Amount, "balance", user.balance)
+	return &adjustment, nil
+}
+
+// status возвращает копию баланса и блокировки пользователя, вызывающий держит блокировку хранилища
+func (u *memoryUser) status() *UserStatus {
+	status := &UserStatus{Id: u.id, Username: u.name, Balance: u.balance, FrozenBy: u.frozenBy, FrozenReason: u.frozenReason}
+	if u.frozenAt != nil {
+		frozenAt := *u.frozenAt
+		status.FrozenAt = &frozenAt
+	}
+	return status
+}
+
+func (m *Memory) GetUserStatus(ctx context.Context, username string) (_ *UserStatus, err error) {
+	_, span := m.startSpan(ctx, "GetUserStatus")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	return user.status(), nil
+}
+
+func (m *Memory) FreezeUser(ctx context.Context, username, by, reason string) (_ *UserStatus, err error) {
+	ctx, span := m.startSpan(ctx, "FreezeUser")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	if user.frozenAt == nil {
+		now := time.Now()
+		user.frozenAt, user.frozenBy, user.frozenReason = &now, by, reason
+	}
+	slog.DebugContext(ctx, "user frozen", "frozen_user_id", user.id)
+	return user.status(), nil
+}
+
+func (m *Memory) UnfreezeUser(ctx context.Context, username string) (_ *UserStatus, err error) {
+	ctx, span := m.startSpan(ctx, "UnfreezeUser")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	user.frozenAt, user.frozenBy, user.frozenReason = nil, "", ""
+	slog.DebugContext(ctx, "user unfrozen", "frozen_user_id", user.id)
+	return user.status(), nil
+}
+
+func (m *Memory) AddAdminAction(ctx context.Context, action AdminAction) (err error) {
+	_, span := m.startSpan(ctx, "AddAdminAction")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	action.Id = int64(len(m.actions) + 1)
+	m.actions = append(m.actions, action)
+	return nil
+}
+
+func (m *Memory) GetAdminActions(ctx context.Context, filter AdminActionFilter) (_ []AdminAction, err error) {
+	_, span := m.startSpan(ctx, "GetAdminActions")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	actions := make([]AdminAction, 0)
+	for i := len(m.actions) - 1; i >= 0 && len(actions) < filter.Limit; i-- {
+		a := m.actions[i]
+		if (filter.Admin == "" || a.Admin == filter.Admin) && (filter.Before == 0 || a.Id < filter.Before) {
+			actions = append(actions, a)
+		}
+	}
+	return actions, nil
+}
+
+func (m *Memory) GetSentTransfers(ctx context.Context, userId int64, since time.Time) (_ []Transfer, err error) {
+	_, span := m.startSpan(ctx, "GetSentTransfers")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	transfers := make([]Transfer, 0)
+	if user, ok := m.usersById[userId]; ok {
+		for _, t := range user.sent {
+			if !t.createdAt.Before(since) {
+				transfers = append(transfers, Transfer{ToUser: t.to.name, Amount: t.amount, CreatedAt: t.createdAt})
+			}
+		}
+	}
+	return transfers, nil
+}
+
+func (m *Memory) AddTransferReview(ctx context.Context, review TransferReview) (err error) {
+	_, span := m.startSpan(ctx, "AddTransferReview")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.usersById[review.UserId]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUserNotFound, review.UserId)
+	}
+	review.CreatedAt = time.Now()
+	user.reviews = append(user.reviews, review)
+	return nil
+}
+
+func (m *Memory) GetTransferReviews(ctx context.Context, userId int64) (_ []TransferReview, err error) {
+	_, span := m.startSpan(ctx, "GetTransferReviews")
+	defer func() { tracing.End(span, err) }()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reviews := make([]TransferReview, 0)
+	if user, ok := m.usersById[userId]; ok {
+		reviews = append(reviews, user.reviews...)
+	}
+	return reviews, nil
+}
+
+// Ping всегда успешен, хранилище в памяти доступно, пока жив процесс
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
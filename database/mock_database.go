@@ -2,25 +2,17 @@ package database
 
 import (
 	"api-avito-shop/models"
-	"fmt"
+	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
 
-type User struct {
-	id        int64
-	username  string
-	password  string
-	balance   float64
-	inventory []models.InfoResponseInventoryInner
-	history   models.InfoResponseCoinHistory
-}
-
+// MockDatabase -- хранилище в памяти, в котором перед каждым методом можно подменить результат на ошибку
+// через mockDb.On("ErrorWithDb", <ключ метода>).Return(err)
 type MockDatabase struct {
 	mock.Mock
-	users    []User
-	usersMap map[string]string
-	nextId   int64
+	memory *Memory
 }
 
 var ProductsMap map[string]struct {
@@ -51,8 +43,12 @@ func init() {
 }
 
 func NewMockDb() *MockDatabase {
+	products := make([]Product, len(ProductsMap))
+	for name, product := range ProductsMap {
+		products[product.itemId-1] = Product{Name: name, Price: float64(product.Price)}
+	}
 	return &MockDatabase{
-		usersMap: make(map[string]string),
+		memory: NewMemoryWithProducts(products),
 	}
 }
 
@@ -64,190 +60,362 @@ const UserCoinsAndItemPriceKey = "user_coins_and_item_price"
 const UserInventoryKey = "user_inventory"
 const UserTransactionsKey = "user_transactions"
 const SendCoinsKey = "send_coins"
+const SendItemKey = "send_item"
+const TradesKey = "trades"
+const ProductsKey = "products"
+const ProductStockKey = "product_stock"
+const ProductLimitKey = "product_limit"
+const PurchaseKey = "purchase"
+const RefundPurchaseKey = "refund_purchase"
+const GiftItemKey = "gift_item"
+const CartKey = "cart"
+const PromoCodesKey = "promo_codes"
+const CheckoutKey = "checkout"
+const PriceCampaignsKey = "price_campaigns"
+const GrantsKey = "grants"
+const GrantSchedulesKey = "grant_schedules"
+const AdjustBalanceKey = "adjust_balance"
+const UserStatusKey = "user_status"
+const AdminActionsKey = "admin_actions"
+const SentTransfersKey = "sent_transfers"
+const AddTransferReviewKey = "add_transfer_review"
+const TransferReviewsKey = "transfer_reviews"
 
 func (m *MockDatabase) ErrorWithDb(s string) error {
 	args := m.Called(s)
 	return args.Error(0)
 }
 
-func (m *MockDatabase) checkUserById(userId int64) bool {
-	for _, user := range m.users {
-		if user.id == userId {
-			return true
-		}
+func (m *MockDatabase) AddNewUser(ctx context.Context, username, password string, balance float64) (bool, error) {
+	if err := m.ErrorWithDb(AddUserKey); err != nil {
+		return false, err
 	}
-	return false
+	return m.memory.AddNewUser(ctx, username, password, balance)
 }
 
-func (m *MockDatabase) getUserIdByUserName(username string) int64 {
-	for _, user := range m.users {
-		if user.username == username {
-			return user.id
-		}
+func (m *MockDatabase) AuthorizeUser(ctx context.Context, username, password string) (bool, int64, error) {
+	if err := m.ErrorWithDb(AuthorizeUserKey); err != nil {
+		return false, 0, err
 	}
-	return 0
+	return m.memory.AuthorizeUser(ctx, username, password)
 }
 
-func (m *MockDatabase) checkUserByUsername(username string) bool {
-	_, ok := m.usersMap[username]
-	return ok
+func (m *MockDatabase) GetUserCoinsAndItemPrice(ctx context.Context, userId int64, item string) (float64, float64, int64, error) {
+	if err := m.ErrorWithDb(UserCoinsAndItemPriceKey); err != nil {
+		return 0, 0, 0, err
+	}
+	return m.memory.GetUserCoinsAndItemPrice(ctx, userId, item)
 }
 
-func (m *MockDatabase) AddNewUser(username, password string) (bool, error) {
-	err := m.ErrorWithDb(AddUserKey)
-	if err != nil {
-		return false, err
+func (m *MockDatabase) UpdateUserBalanceAndInventory(ctx context.Context, userId int64, price float64, itemId int64) (int64, error) {
+	if err := m.ErrorWithDb(UpdateUserBalanceAndInventoryKey); err != nil {
+		return 0, err
 	}
+	return m.memory.UpdateUserBalanceAndInventory(ctx, userId, price, itemId)
+}
 
-	if _, ok := m.usersMap[username]; ok {
-		return false, nil
+func (m *MockDatabase) GetUserCoins(ctx context.Context, username string) (float64, error) {
+	if err := m.ErrorWithDb(GetUserCoinsKey); err != nil {
+		return 0, err
 	}
-	m.usersMap[username] = password
-	m.users = append(m.users, User{id: m.nextId, username: username, password: password, balance: 1000})
-	m.nextId += 1
-	return true, nil
+	return m.memory.GetUserCoins(ctx, username)
 }
 
-func (m *MockDatabase) AuthorizeUser(username, password string) (bool, int64, error) {
-	err := m.ErrorWithDb(AuthorizeUserKey)
-	if err != nil {
-		return false, 0, err
+func (m *MockDatabase) SendCoins(ctx context.Context, userFrom, userTo string, amount float64) error {
+	if err := m.ErrorWithDb(SendCoinsKey); err != nil {
+		return err
 	}
+	return m.memory.SendCoins(ctx, userFrom, userTo, amount)
+}
 
-	if _, ok := m.usersMap[username]; !ok {
-		return false, 0, nil
+func (m *MockDatabase) SendItem(ctx context.Context, userFrom, userTo, item string, quantity int64) error {
+	if err := m.ErrorWithDb(SendItemKey); err != nil {
+		return err
 	}
+	return m.memory.SendItem(ctx, userFrom, userTo, item, quantity)
+}
 
-	for _, user := range m.users {
-		if user.username == username && user.password == password {
-			return true, user.id, nil
-		}
+func (m *MockDatabase) AddTrade(ctx context.Context, trade Trade) (int64, error) {
+	if err := m.ErrorWithDb(TradesKey); err != nil {
+		return 0, err
 	}
-	return false, 0, nil
+	return m.memory.AddTrade(ctx, trade)
 }
 
-func (m *MockDatabase) GetUserCoinsAndItemPrice(userId int64, item string) (float64, float64, int64, error) {
-	err := m.ErrorWithDb(UserCoinsAndItemPriceKey)
-	if err != nil {
-		return 0, 0, 0, err
+func (m *MockDatabase) GetTrades(ctx context.Context, username string) ([]Trade, error) {
+	if err := m.ErrorWithDb(TradesKey); err != nil {
+		return nil, err
 	}
+	return m.memory.GetTrades(ctx, username)
+}
 
-	if _, ok := ProductsMap[item]; !ok {
-		return 0, 0, 0, fmt.Errorf("no product in product map")
+func (m *MockDatabase) AcceptTrade(ctx context.Context, id int64, username string) (*Trade, error) {
+	if err := m.ErrorWithDb(TradesKey); err != nil {
+		return nil, err
 	}
+	return m.memory.AcceptTrade(ctx, id, username)
+}
 
-	ok := false
-	for _, user := range m.users {
-		if user.id == userId {
-			ok = true
-		}
+func (m *MockDatabase) CloseTrade(ctx context.Context, id int64, username string) (*Trade, error) {
+	if err := m.ErrorWithDb(TradesKey); err != nil {
+		return nil, err
+	}
+	return m.memory.CloseTrade(ctx, id, username)
+}
+
+func (m *MockDatabase) BuyWithPromoCode(ctx context.Context, userId int64, price float64, itemId int64, code string) (int64, float64, error) {
+	if err := m.ErrorWithDb(UpdateUserBalanceAndInventoryKey); err != nil {
+		return 0, 0, err
+	}
+	return m.memory.BuyWithPromoCode(ctx, userId, price, itemId, code)
+}
+
+func (m *MockDatabase) AddPromoCode(ctx context.Context, promo PromoCode) error {
+	if err := m.ErrorWithDb(PromoCodesKey); err != nil {
+		return err
 	}
+	return m.memory.AddPromoCode(ctx, promo)
+}
 
-	if !ok {
-		return 0, 0, 0, fmt.Errorf("user not found")
+func (m *MockDatabase) GetPromoCodes(ctx context.Context) ([]PromoCode, error) {
+	if err := m.ErrorWithDb(PromoCodesKey); err != nil {
+		return nil, err
 	}
+	return m.memory.GetPromoCodes(ctx)
+}
 
-	return m.users[userId].balance, float64(ProductsMap[item].Price), ProductsMap[item].itemId, nil
+func (m *MockDatabase) DisablePromoCode(ctx context.Context, code string) (*PromoCode, error) {
+	if err := m.ErrorWithDb(PromoCodesKey); err != nil {
+		return nil, err
+	}
+	return m.memory.DisablePromoCode(ctx, code)
 }
 
-func (m *MockDatabase) UpdateUserBalanceAndInventory(userId int64, price float64, itemId int64) error {
-	err := m.ErrorWithDb(UpdateUserBalanceAndInventoryKey)
-	if err != nil {
+func (m *MockDatabase) AddToCart(ctx context.Context, userId int64, item string, quantity int64) error {
+	if err := m.ErrorWithDb(CartKey); err != nil {
 		return err
 	}
+	return m.memory.AddToCart(ctx, userId, item, quantity)
+}
 
-	ok := false
-	var typeKey string
-	for key, item := range ProductsMap {
-		if item.itemId == itemId {
-			ok = true
-			typeKey = key
-		}
+func (m *MockDatabase) RemoveFromCart(ctx context.Context, userId int64, item string) (bool, error) {
+	if err := m.ErrorWithDb(CartKey); err != nil {
+		return false, err
 	}
+	return m.memory.RemoveFromCart(ctx, userId, item)
+}
 
-	if !ok {
-		return fmt.Errorf("no product in product map")
+func (m *MockDatabase) GetCart(ctx context.Context, userId int64) ([]CartItem, error) {
+	if err := m.ErrorWithDb(CartKey); err != nil {
+		return nil, err
 	}
+	return m.memory.GetCart(ctx, userId)
+}
 
-	m.users[userId].balance -= price
-	for i, good := range m.users[userId].inventory {
-		if _, ok := ProductsMap[good.Type]; ok {
-			m.users[userId].inventory[i].Quantity += 1
-			return nil
-		}
+func (m *MockDatabase) Checkout(ctx context.Context, userId int64, prices map[string]float64) (*Receipt, error) {
+	if err := m.ErrorWithDb(CheckoutKey); err != nil {
+		return nil, err
 	}
-	m.users[userId].inventory = append(m.users[userId].inventory, models.InfoResponseInventoryInner{Type: typeKey, Quantity: 1})
-	return nil
+	return m.memory.Checkout(ctx, userId, prices)
 }
 
-func (m *MockDatabase) GetUserCoins(username string) (float64, error) {
-	err := m.ErrorWithDb(GetUserCoinsKey)
-	if err != nil {
+func (m *MockDatabase) AddPriceCampaign(ctx context.Context, campaign PriceCampaign) (int64, error) {
+	if err := m.ErrorWithDb(PriceCampaignsKey); err != nil {
 		return 0, err
 	}
+	return m.memory.AddPriceCampaign(ctx, campaign)
+}
 
-	if _, ok := m.usersMap[username]; !ok {
-		// не должно стрелять, т.к. в движке проверяется изначально, что юзер есть
-		return 0, fmt.Errorf("not enough user in db")
+func (m *MockDatabase) GetPriceCampaigns(ctx context.Context, item string, since time.Time) ([]PriceCampaign, error) {
+	if err := m.ErrorWithDb(PriceCampaignsKey); err != nil {
+		return nil, err
 	}
+	return m.memory.GetPriceCampaigns(ctx, item, since)
+}
 
-	for _, user := range m.users {
-		if user.username == username {
-			return user.balance, nil
-		}
+func (m *MockDatabase) DeletePriceCampaign(ctx context.Context, id int64) (*PriceCampaign, error) {
+	if err := m.ErrorWithDb(PriceCampaignsKey); err != nil {
+		return nil, err
+	}
+	return m.memory.DeletePriceCampaign(ctx, id)
+}
+
+func (m *MockDatabase) GrantCoins(ctx context.Context, grants []Grant) error {
+	if err := m.ErrorWithDb(GrantsKey); err != nil {
+		return err
+	}
+	return m.memory.GrantCoins(ctx, grants)
+}
+
+func (m *MockDatabase) ExpireGrants(ctx context.Context, now time.Time) (float64, error) {
+	if err := m.ErrorWithDb(GrantsKey); err != nil {
+		return 0, err
+	}
+	return m.memory.ExpireGrants(ctx, now)
+}
+
+func (m *MockDatabase) AddGrantSchedule(ctx context.Context, schedule GrantSchedule) (int64, error) {
+	if err := m.ErrorWithDb(GrantSchedulesKey); err != nil {
+		return 0, err
+	}
+	return m.memory.AddGrantSchedule(ctx, schedule)
+}
+
+func (m *MockDatabase) GetGrantSchedules(ctx context.Context) ([]GrantSchedule, error) {
+	if err := m.ErrorWithDb(GrantSchedulesKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetGrantSchedules(ctx)
+}
+
+func (m *MockDatabase) StopGrantSchedule(ctx context.Context, id int64) (*GrantSchedule, error) {
+	if err := m.ErrorWithDb(GrantSchedulesKey); err != nil {
+		return nil, err
+	}
+	return m.memory.StopGrantSchedule(ctx, id)
+}
+
+func (m *MockDatabase) RunGrantSchedule(ctx context.Context, now time.Time) (*GrantSchedule, int64, error) {
+	if err := m.ErrorWithDb(GrantSchedulesKey); err != nil {
+		return nil, 0, err
 	}
+	return m.memory.RunGrantSchedule(ctx, now)
+}
 
-	// не должно стрелять, т.к. в движке проверяется изначально, что юзер есть
-	return 0, fmt.Errorf("not enough user in db")
+func (m *MockDatabase) AdjustBalance(ctx context.Context, adjustment Adjustment) (*Adjustment, error) {
+	if err := m.ErrorWithDb(AdjustBalanceKey); err != nil {
+		return nil, err
+	}
+	return m.memory.AdjustBalance(ctx, adjustment)
 }
 
-func (m *MockDatabase) SendCoins(userFrom, userTo string, amount float64) error {
-	err := m.ErrorWithDb(SendCoinsKey)
-	if err != nil {
+func (m *MockDatabase) GetUserStatus(ctx context.Context, username string) (*UserStatus, error) {
+	if err := m.ErrorWithDb(UserStatusKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetUserStatus(ctx, username)
+}
+
+func (m *MockDatabase) FreezeUser(ctx context.Context, username, by, reason string) (*UserStatus, error) {
+	if err := m.ErrorWithDb(UserStatusKey); err != nil {
+		return nil, err
+	}
+	return m.memory.FreezeUser(ctx, username, by, reason)
+}
+
+func (m *MockDatabase) UnfreezeUser(ctx context.Context, username string) (*UserStatus, error) {
+	if err := m.ErrorWithDb(UserStatusKey); err != nil {
+		return nil, err
+	}
+	return m.memory.UnfreezeUser(ctx, username)
+}
+
+func (m *MockDatabase) AddAdminAction(ctx context.Context, action AdminAction) error {
+	if err := m.ErrorWithDb(AdminActionsKey); err != nil {
 		return err
 	}
+	return m.memory.AddAdminAction(ctx, action)
+}
+
+func (m *MockDatabase) GetAdminActions(ctx context.Context, filter AdminActionFilter) ([]AdminAction, error) {
+	if err := m.ErrorWithDb(AdminActionsKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetAdminActions(ctx, filter)
+}
 
-	if !m.checkUserByUsername(userFrom) || !m.checkUserByUsername(userTo) {
-		return fmt.Errorf("not enough user in db")
+func (m *MockDatabase) GetUserInventory(ctx context.Context, userId int64) (*[]models.InfoResponseInventoryInner, error) {
+	if err := m.ErrorWithDb(UserInventoryKey); err != nil {
+		return nil, err
 	}
+	return m.memory.GetUserInventory(ctx, userId)
+}
 
-	userFromId := m.getUserIdByUserName(userFrom)
-	userToId := m.getUserIdByUserName(userTo)
+func (m *MockDatabase) GetUserReceivedAndSentCoins(ctx context.Context, userId int64) (*models.InfoResponseCoinHistory, error) {
+	if err := m.ErrorWithDb(UserTransactionsKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetUserReceivedAndSentCoins(ctx, userId)
+}
 
-	m.users[userFromId].balance -= amount
-	m.users[userToId].balance += amount
+// GetUserInfo отдаёт те же данные, что GetUserCoins, GetUserInventory и GetUserReceivedAndSentCoins,
+// поэтому ошибка подменяется любым из их ключей
+func (m *MockDatabase) GetUserInfo(ctx context.Context, userId int64) (*models.InfoResponse, error) {
+	for _, key := range []string{GetUserCoinsKey, UserInventoryKey, UserTransactionsKey} {
+		if err := m.ErrorWithDb(key); err != nil {
+			return nil, err
+		}
+	}
+	return m.memory.GetUserInfo(ctx, userId)
+}
 
-	m.users[userFromId].history.Sent = append(m.users[userFromId].history.Sent,
-		models.InfoResponseCoinHistorySentInner{ToUser: userTo, Amount: int32(amount)})
+func (m *MockDatabase) GetSentTransfers(ctx context.Context, userId int64, since time.Time) ([]Transfer, error) {
+	if err := m.ErrorWithDb(SentTransfersKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetSentTransfers(ctx, userId, since)
+}
 
-	m.users[userToId].history.Received = append(m.users[userToId].history.Received,
-		models.InfoResponseCoinHistoryReceivedInner{FromUser: userFrom, Amount: int32(amount)})
+func (m *MockDatabase) AddTransferReview(ctx context.Context, review TransferReview) error {
+	if err := m.ErrorWithDb(AddTransferReviewKey); err != nil {
+		return err
+	}
+	return m.memory.AddTransferReview(ctx, review)
+}
 
-	return nil
+func (m *MockDatabase) GetTransferReviews(ctx context.Context, userId int64) ([]TransferReview, error) {
+	if err := m.ErrorWithDb(TransferReviewsKey); err != nil {
+		return nil, err
+	}
+	return m.memory.GetTransferReviews(ctx, userId)
 }
 
-func (m *MockDatabase) GetUserInventory(userId int64) (*[]models.InfoResponseInventoryInner, error) {
-	err := m.ErrorWithDb(UserInventoryKey)
-	if err != nil {
+func (m *MockDatabase) GetProducts(ctx context.Context) ([]Product, error) {
+	if err := m.ErrorWithDb(ProductsKey); err != nil {
 		return nil, err
 	}
+	return m.memory.GetProducts(ctx)
+}
 
-	if !m.checkUserById(userId) {
-		return nil, fmt.Errorf("user not found error")
+// SetProductStock и RestockProduct меняют остаток, поэтому ошибка подменяется одним ключом
+func (m *MockDatabase) SetProductStock(ctx context.Context, name string, stock *int64) error {
+	if err := m.ErrorWithDb(ProductStockKey); err != nil {
+		return err
 	}
+	return m.memory.SetProductStock(ctx, name, stock)
+}
 
-	return &m.users[userId].inventory, nil
+func (m *MockDatabase) RestockProduct(ctx context.Context, name string, quantity int64) (int64, error) {
+	if err := m.ErrorWithDb(ProductStockKey); err != nil {
+		return 0, err
+	}
+	return m.memory.RestockProduct(ctx, name, quantity)
 }
 
-func (m *MockDatabase) GetUserReceivedAndSentCoins(userId int64) (*models.InfoResponseCoinHistory, error) {
-	err := m.ErrorWithDb(UserTransactionsKey)
-	if err != nil {
+func (m *MockDatabase) SetProductLimit(ctx context.Context, name string, maxPerUser *int64) error {
+	if err := m.ErrorWithDb(ProductLimitKey); err != nil {
+		return err
+	}
+	return m.memory.SetProductLimit(ctx, name, maxPerUser)
+}
+
+func (m *MockDatabase) GetPurchase(ctx context.Context, id int64) (*Purchase, error) {
+	if err := m.ErrorWithDb(PurchaseKey); err != nil {
 		return nil, err
 	}
+	return m.memory.GetPurchase(ctx, id)
+}
 
-	if !m.checkUserById(userId) {
-		return nil, fmt.Errorf("user not found")
+func (m *MockDatabase) GiftItem(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message string) (int64, error) {
+	if err := m.ErrorWithDb(GiftItemKey); err != nil {
+		return 0, err
 	}
+	return m.memory.GiftItem(ctx, userId, toUser, price, itemId, message)
+}
 
-	return &m.users[userId].history, nil
+func (m *MockDatabase) RefundPurchase(ctx context.Context, id int64, by string) (*Purchase, bool, error) {
+	if err := m.ErrorWithDb(RefundPurchaseKey); err != nil {
+		return nil, false, err
+	}
+	return m.memory.RefundPurchase(ctx, id, by)
 }
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// запрос состояния реплики Postgres: идёт ли приём WAL с основной базы, сколько секунд от неё не было сообщений
+// и отставание применения. Если всё полученное уже применено, отставание применения нулевое, иначе это время
+// с последней применённой транзакции. Без pg_read_all_stats состояние приёмника скрыто, и реплика считается отставшей
+const pgReplicaLagQuery = `SELECT
+	COALESCE((SELECT status = 'streaming' FROM pg_stat_wal_receiver), false),
+	COALESCE((SELECT EXTRACT(EPOCH FROM now() - last_msg_receipt_time) FROM pg_stat_wal_receiver), 0),
+	CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// pgReceiverSilence -- сколько приёмник WAL может не получать сообщений от основной базы. Основная база
+// шлёт их не реже wal_sender_timeout/2 (30s по умолчанию), дольше молчит только остановившийся поток
+const pgReceiverSilence = time.Minute
+
+var errReplicaNotStreaming = errors.New("реплика не получает WAL с основной базы")
+
+func pgReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var streaming bool
+	var silence, lag float64
+	if err := db.QueryRowContext(ctx, pgReplicaLagQuery).Scan(&streaming, &silence, &lag); err != nil {
+		return 0, err
+	}
+	return receiverLag(streaming, fromSeconds(silence), fromSeconds(lag))
+}
+
+// receiverLag возвращает отставание реплики. Пока приём WAL остановлен, всё полученное давно применено
+// и отставание применения нулевое, поэтому такая реплика считается отставшей независимо от него
+func receiverLag(streaming bool, silence, lag time.Duration) (time.Duration, error) {
+	if !streaming || silence > pgReceiverSilence {
+		return 0, fmt.Errorf("%w: последнее сообщение %s назад", errReplicaNotStreaming, silence.Round(time.Second))
+	}
+	return lag, nil
+}
+
+func fromSeconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type replica struct {
+	db    *sql.DB
+	name  string
+	fresh atomic.Bool
+}
+
+// replicaSet распределяет чтения по репликам, отставание которых не больше maxLag.
+// Пользователь, который недавно что-то менял, читает с основной базы, пока реплики не могут
+// гарантированно содержать его изменения (read-your-writes)
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	maxLag   time.Duration
+	interval time.Duration
+	lag      func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+	// время последней записи по id пользователя
+	writes sync.Map
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func newReplicaSet(maxLag, interval time.Duration, lag func(ctx context.Context, db *sql.DB) (time.Duration, error)) *replicaSet {
+	return &replicaSet{maxLag: maxLag, interval: interval, lag: lag}
+}
+
+// window -- сколько после записи пользователь читает с основной базы: отставание свежей реплики
+// не больше maxLag на момент проверки и может вырасти до следующей проверки
+func (r *replicaSet) window() time.Duration {
+	return r.maxLag + r.interval
+}
+
+func (r *replicaSet) add(name string, db *sql.DB) {
+	r.replicas = append(r.replicas, &replica{db: db, name: name})
+}
+
+// start проверяет отставание реплик сразу и затем каждые interval до вызова Close
+func (r *replicaSet) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stop = cancel
+	r.done = make(chan struct{})
+	r.check(ctx)
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.check(ctx)
+			}
+		}
+	}()
+}
+
+func (r *replicaSet) check(ctx context.Context) {
+	for _, rep := range r.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, r.maxLag)
+		lag, err := r.lag(checkCtx, rep.db)
+		cancel()
+
+		fresh := err == nil && lag <= r.maxLag
+		if fresh != rep.fresh.Swap(fresh) {
+			slog.Warn("replica state changed", "replica", rep.name, "fresh", fresh, "lag", lag, "error", err)
+		}
+	}
+
+	// записи старше окна уже видны на всех свежих репликах
+	now := time.Now()
+	r.writes.Range(func(key, value any) bool {
+		if now.Sub(value.(time.Time)) > r.window() {
+			r.writes.Delete(key)
+		}
+		return true
+	})
+}
+
+// wrote отмечает изменения данных пользователей
+func (r *replicaSet) wrote(userIds ...int64) {
+	now := time.Now()
+	for _, id := range userIds {
+		r.writes.Store(id, now)
+	}
+}
+
+// pick возвращает реплику для чтения данных пользователя userId (0 -- данные не привязаны к пользователю)
+// или nil, если читать нужно с основной базы
+func (r *replicaSet) pick(userId int64) *replica {
+	if userId != 0 {
+		if at, ok := r.writes.Load(userId); ok && time.Since(at.(time.Time)) <= r.window() {
+			return nil
+		}
+	}
+
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if rep.fresh.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// Close останавливает проверки и закрывает пулы соединений к репликам
+func (r *replicaSet) Close() error {
+	if r.stop != nil {
+		r.stop()
+		<-r.done
+	}
+	var errs []error
+	for _, rep := range r.replicas {
+		errs = append(errs, rep.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api-avito-shop/config"
+	"api-avito-shop/migrations"
+	"api-avito-shop/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func openMigratedSQLite(t *testing.T, name string) *SQLite {
+	db, err := NewSQLite(config.Database{Path: filepath.Join(t.TempDir(), name)})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.NewSQLite(db.DB())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	primary := openMigratedSQLite(t, "primary.db")
+	replica := openMigratedSQLite(t, "replica.db")
+
+	var lag time.Duration
+	var lagErr error
+	s := newSQLDatabase(primary.DB(), semconv.DBSystemSqlite, mapSQLiteError)
+	s.replicas = newReplicaSet(time.Second, time.Hour, func(context.Context, *sql.DB) (time.Duration, error) {
+		return lag, lagErr
+	})
+	s.replicas.add("replica-1", replica.DB())
+	s.replicas.check(ctx)
+
+	_, err := s.AddNewUser(ctx, "user1", "pass", 1000)
+	require.NoError(t, err)
+	_, userId, err := s.AuthorizeUser(ctx, "user1", "pass")
+	require.NoError(t, err)
+
+	// на реплике у пользователя другой инвентарь, по нему видно, куда ушло чтение
+	_, err = replica.DB().Exec("INSERT INTO users (id, name, md5, balance) VALUES ($1, 'user1', '', 1000)", userId)
+	require.NoError(t, err)
+	_, err = replica.DB().Exec("INSERT INTO inventory (user_id, product_id, quantity) SELECT $1, id, 7 FROM products WHERE name = 'cup'", userId)
+	require.NoError(t, err)
+	fromReplica := []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 7}}
+
+	inventory := func() []models.InfoResponseInventoryInner {
+		goods, err := s.GetUserInventory(ctx, userId)
+		require.NoError(t, err)
+		return *goods
+	}
+
+	// сразу после регистрации пользователь читает свои данные с основной базы
+	assert.Empty(t, inventory())
+
+	// другие пользователи и записи старше окна читаются с реплики
+	s.replicas.writes.Delete(userId)
+	assert.Equal(t, fromReplica, inventory())
+
+	// после покупки -- снова с основной базы
+	_, price, itemId, err := s.GetUserCoinsAndItemPrice(ctx, userId, "pen")
+	require.NoError(t, err)
+	_, err = s.UpdateUserBalanceAndInventory(ctx, userId, price, itemId)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pen", Quantity: 1}}, inventory())
+	s.replicas.writes.Delete(userId)
+
+	// отстающая или недоступная реплика не используется
+	lag = 10 * time.Second
+	s.replicas.check(ctx)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pen", Quantity: 1}}, inventory())
+
+	lag, lagErr = 0, errors.New("connection refused")
+	s.replicas.check(ctx)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "pen", Quantity: 1}}, inventory())
+
+	lagErr = nil
+	s.replicas.check(ctx)
+	assert.Equal(t, fromReplica, inventory())
+}
+
+func TestReplicaWritesExpire(t *testing.T) {
+	r := newReplicaSet(time.Millisecond, time.Millisecond, func(context.Context, *sql.DB) (time.Duration, error) {
+		return 0, nil
+	})
+	r.add("replica-1", nil)
+	r.check(context.Background())
+
+	r.wrote(1)
+	assert.Nil(t, r.pick(1))
+	assert.NotNil(t, r.pick(2))
+
+	// по истечении окна записи забываются
+	time.Sleep(5 * time.Millisecond)
+	r.check(context.Background())
+	assert.NotNil(t, r.pick(1))
+	_, ok := r.writes.Load(int64(1))
+	assert.False(t, ok)
+}
+
+func TestReceiverLag(t *testing.T) {
+	lag, err := receiverLag(true, 10*time.Second, 2*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, lag)
+
+	// остановившийся приём WAL не даёт отставания применения, но реплика всё равно отстала
+	_, err = receiverLag(false, 0, 0)
+	assert.ErrorIs(t, err, errReplicaNotStreaming)
+	_, err = receiverLag(true, 2*time.Hour, 0)
+	assert.ErrorIs(t, err, errReplicaNotStreaming)
+}
@@ -0,0 +1,2306 @@
+package database
+
+import (
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"crypto/md5"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqlDatabase -- реализация Database поверх database/sql, общая для Postgres и SQLite.
+// Запросы пишутся на общем подмножестве SQL, различия СУБД сведены к разбору ошибок
+type sqlDatabase struct {
+	db       *sql.DB
+	system   attribute.KeyValue
+	mapError func(error) error
+	// реплики для чтения, nil -- все запросы идут в db
+	replicas *replicaSet
+}
+
+func newSQLDatabase(db *sql.DB, system attribute.KeyValue, mapError func(error) error) *sqlDatabase {
+	return &sqlDatabase{db: db, system: system, mapError: mapError}
+}
+
+// DB возвращает пул соединений, например для сбора статистики
+func (s *sqlDatabase) DB() *sql.DB {
+	return s.db
+}
+
+func (s *sqlDatabase) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return startMethodSpan(ctx, s.system, method)
+}
+
+// conn возвращает пул соединений, трассирующий каждый запрос
+func (s *sqlDatabase) conn() tracedQuerier {
+	return traced(s.db, s.system)
+}
+
+// inTx возвращает транзакцию, трассирующую каждый запрос
+func (s *sqlDatabase) inTx(tx *sql.Tx) tracedQuerier {
+	return traced(tx, s.system)
+}
+
+// reader возвращает соединение для чтения, которое допускает небольшое отставание: реплику,
+// если она достаточно свежая и пользователь userId недавно ничего не менял, иначе основную базу
+func (s *sqlDatabase) reader(ctx context.Context, userId int64) tracedQuerier {
+	if s.replicas == nil {
+		return s.conn()
+	}
+	rep := s.replicas.pick(userId)
+	span := trace.SpanFromContext(ctx)
+	if rep == nil {
+		span.SetAttributes(attribute.String("db.replica", "primary"))
+		return s.conn()
+	}
+	span.SetAttributes(attribute.String("db.replica", rep.name))
+	return traced(rep.db, s.system)
+}
+
+// wrote отмечает изменение данных пользователей для read-your-writes
+func (s *sqlDatabase) wrote(userIds ...int64) {
+	if s.replicas != nil {
+		s.replicas.wrote(userIds...)
+	}
+}
+
+// Close закрывает пул соединений и соединения с репликами
+func (s *sqlDatabase) Close() error {
+	if s.replicas != nil {
+		return errors.Join(s.replicas.Close(), s.db.Close())
+	}
+	return s.db.Close()
+}
+
+// Ping проверяет доступность базы данных
+func (s *sqlDatabase) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func convertPassToMd5(password string) (string, error) {
+	hasher := md5.New()
+
+	_, err := io.WriteString(hasher, password)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при записи данных в хешер: %w", err)
+	}
+	hash := hasher.Sum(nil)
+	hashStr := fmt.Sprintf("%x", hash)
+	return hashStr, nil
+}
+
+func (s *sqlDatabase) AddNewUser(ctx context.Context, username, password string, balance float64) (_ bool, err error) {
+	ctx, span := s.startSpan(ctx, "AddNewUser")
+	defer func() { tracing.End(span, err) }()
+
+	hashStr, err := convertPassToMd5(password)
+	if err != nil {
+		return false, err
+	}
+
+	// уникальность имени обеспечивает база, поэтому параллельные регистрации
+	// одного имени не создадут дубликатов: вторая вставка не вернёт строку
+	var lastInsertId int64
+	err = s.conn().QueryRowContext(ctx,
+		"INSERT INTO users (name, md5, balance) VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING RETURNING id",
+		username, hashStr, balance).Scan(&lastInsertId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("ошибка при добавлении нового пользователя: %w", s.mapError(err))
+	}
+	slog.InfoContext(ctx, "user created", "new_user_id", lastInsertId)
+	s.wrote(lastInsertId)
+
+	return true, nil
+}
+
+func (s *sqlDatabase) AuthorizeUser(ctx context.Context, username, password string) (_ bool, _ int64, err error) {
+	ctx, span := s.startSpan(ctx, "AuthorizeUser")
+	defer func() { tracing.End(span, err) }()
+
+	var id int64
+	var md5Pass string
+	var frozen bool
+	err = s.conn().QueryRowContext(ctx, "SELECT id, md5, frozen_at IS NOT NULL FROM users WHERE name=$1", username).Scan(&id, &md5Pass, &frozen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, 0, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+		}
+		return false, 0, fmt.Errorf("ошибка при запросе пароля из базы данных: %v", err)
+	}
+
+	hashStr, err := convertPassToMd5(password)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if md5Pass != hashStr {
+		return false, id, nil
+	}
+	// блокировка проверяется после пароля, чтобы без пароля нельзя было узнать, что пользователь заблокирован
+	if frozen {
+		return false, id, fmt.Errorf("%w: %s", ErrUserFrozen, username)
+	}
+	return true, id, nil
+}
+
+func (s *sqlDatabase) GetUserCoinsAndItemPrice(ctx context.Context, userId int64, item string) (_, _ float64, _ int64, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserCoinsAndItemPrice")
+	defer func() { tracing.End(span, err) }()
+
+	var coins float64
+	err = s.conn().QueryRowContext(ctx, "SELECT balance FROM users WHERE id=$1", userId).Scan(&coins)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, 0, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+		}
+		return 0, 0, 0, fmt.Errorf("ошибка при запросе баланса пользователя из базы данных: %v", err)
+	}
+
+	var price float64
+	var itemId int64
+	// каталог меняется редко, поэтому цену можно читать с реплики
+	err = s.reader(ctx, 0).QueryRowContext(ctx, "SELECT id, price FROM products WHERE name=$1", item).Scan(&itemId, &price)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, 0, fmt.Errorf("%w: %s", ErrProductNotFound, item)
+		}
+		return 0, 0, 0, fmt.Errorf("ошибка при запросе стоимости товара из базы данных: %v", err)
+	}
+
+	return coins, price, itemId, nil
+}
+
+func (s *sqlDatabase) UpdateUserBalanceAndInventory(ctx context.Context, userId int64, price float64, itemId int64) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "UpdateUserBalanceAndInventory")
+	defer func() { tracing.End(span, err) }()
+
+	purchaseId, _, err := s.purchase(ctx, userId, "", price, itemId, "", "")
+	return purchaseId, err
+}
+
+func (s *sqlDatabase) GiftItem(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message string) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "GiftItem")
+	defer func() { tracing.End(span, err) }()
+
+	purchaseId, _, err := s.purchase(ctx, userId, toUser, price, itemId, message, "")
+	return purchaseId, err
+}
+
+func (s *sqlDatabase) BuyWithPromoCode(ctx context.Context, userId int64, price float64, itemId int64, code string) (_ int64, _ float64, err error) {
+	ctx, span := s.startSpan(ctx, "BuyWithPromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	return s.purchase(ctx, userId, "", price, itemId, "", code)
+}
+
+// purchase покупает товар за счёт userId в инвентарь toUser, пустое имя -- в инвентарь самого покупателя.
+// Непустой code применяет промокод, вторым значением возвращается скидка
+func (s *sqlDatabase) purchase(ctx context.Context, userId int64, toUser string, price float64, itemId int64, message, code string) (int64, float64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	var promoId sql.NullInt64
+	var discount float64
+	if code != "" {
+		var promo *PromoCode
+		promo, promoId.Int64, err = s.usePromoCode(ctx, q, code, itemId)
+		if err != nil {
+			return 0, 0, err
+		}
+		promoId.Valid = true
+		discount = promo.Discount(price)
+		price -= discount
+	}
+
+	ownerId := userId
+	var recipientId sql.NullInt64
+	if toUser != "" {
+		err = q.QueryRowContext(ctx, "SELECT id FROM users WHERE name=$1", toUser).Scan(&ownerId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, 0, fmt.Errorf("%w: %s", ErrUserNotFound, toUser)
+			}
+			return 0, 0, fmt.Errorf("ошибка при поиске получателя: %w", err)
+		}
+		if ownerId == userId {
+			return 0, 0, fmt.Errorf("%w: подарок самому себе", ErrInvalidAmount)
+		}
+		recipientId = sql.NullInt64{Int64: ownerId, Valid: true}
+	}
+
+	// обновим баланс юзера, уход в минус отсекает ограничение users_balance_check
+	var newBalance float64
+	var frozen bool
+	err = q.QueryRowContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id=$2 RETURNING balance, frozen_at IS NOT NULL", price, userId).Scan(&newBalance, &frozen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+		}
+		return 0, 0, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+	}
+	if frozen {
+		return 0, 0, fmt.Errorf("%w: %d", ErrUserFrozen, userId)
+	}
+	spent, err := spendGrants(ctx, q, userId, price)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// уменьшим остаток, если товар ограничен; уход в минус отсекает ограничение products_stock_check
+	_, err = q.ExecContext(ctx, "UPDATE products SET stock = stock - 1 WHERE id=$1 AND stock IS NOT NULL", itemId)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка при обновлении остатка товара: %w", s.mapError(err))
+	}
+
+	// обновим инвентарь владельца; товар выбирается из каталога, чтобы неизвестный id давал пустой результат,
+	// а не нарушение внешнего ключа, которое не во всех СУБД указывает имя ограничения
+	var quantity int64
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO inventory (user_id, product_id, quantity) SELECT $1, id, 1 FROM products WHERE id=$2 ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = inventory.quantity + 1 RETURNING quantity",
+		ownerId, itemId).Scan(&quantity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, fmt.Errorf("%w: %d", ErrProductNotFound, itemId)
+		}
+		return 0, 0, fmt.Errorf("ошибка при обновлении инвентаря: %w", s.mapError(err))
+	}
+
+	// строка инвентаря заблокирована до конца транзакции, поэтому параллельные покупки того же товара
+	// тем же пользователем видят уже увеличенное количество и не обходят лимит. Лимит считается по инвентарю
+	// владельца, поэтому подарком нельзя вручить больше, чем получатель мог бы купить сам
+	var maxPerUser sql.NullInt64
+	err = q.QueryRowContext(ctx, "SELECT max_per_user FROM products WHERE id=$1", itemId).Scan(&maxPerUser)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка при запросе лимита покупок: %w", err)
+	}
+	if maxPerUser.Valid && quantity > maxPerUser.Int64 {
+		return 0, 0, fmt.Errorf("%w: %d, не больше %d", ErrPurchaseLimit, itemId, maxPerUser.Int64)
+	}
+
+	var purchaseId int64
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO purchases (user_id, product_id, price, recipient_id, message, promo_code_id, discount) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		userId, itemId, price, recipientId, sql.NullString{String: message, Valid: message != ""}, promoId, discount).Scan(&purchaseId)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка при сохранении покупки: %w", s.mapError(err))
+	}
+	if err := addPurchaseGrants(ctx, q, purchaseId, spent); err != nil {
+		return 0, 0, err
+	}
+
+	// commit
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId, ownerId)
+	slog.DebugContext(ctx, "inventory updated", "product_id", itemId, "owner_id", ownerId, "quantity", quantity, "balance", newBalance, "purchase_id", purchaseId, "discount", discount)
+
+	return purchaseId, discount, nil
+}
+
+func (s *sqlDatabase) GetUserCoins(ctx context.Context, username string) (_ float64, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserCoins")
+	defer func() { tracing.End(span, err) }()
+
+	var coins float64
+	err = s.conn().QueryRowContext(ctx, "SELECT balance FROM users WHERE name=$1", username).Scan(&coins)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("ошибка запросе баланса пользователя: %w", err)
+	}
+
+	return coins, nil
+}
+
+func (s *sqlDatabase) SendCoins(ctx context.Context, userFrom, userTo string, amount float64) (err error) {
+	ctx, span := s.startSpan(ctx, "SendCoins")
+	defer func() { tracing.End(span, err) }()
+
+	// отрицательная сумма списала бы монеты у получателя раньше, чем сработает ограничение на transactions
+	if amount <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, amount)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// баланс меняется одним запросом, уход в минус отсекает ограничение users_balance_check.
+	// Строки блокируются в порядке имён, чтобы встречные переводы не приводили к взаимной блокировке
+	var userId1, userId2 int64
+	changes := []struct {
+		name  string
+		delta float64
+		id    *int64
+	}{
+		{userFrom, -amount, &userId1},
+		{userTo, amount, &userId2},
+	}
+	if userTo < userFrom {
+		changes[0], changes[1] = changes[1], changes[0]
+	}
+	for _, change := range changes {
+		var frozen bool
+		err = q.QueryRowContext(ctx, "UPDATE users SET balance = balance + $1 WHERE name=$2 RETURNING id, frozen_at IS NOT NULL", change.delta, change.name).Scan(change.id, &frozen)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", ErrUserNotFound, change.name)
+			}
+			return fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+		}
+		// заблокированный пользователь может получать монеты, но не отправлять их
+		if frozen && change.name == userFrom {
+			return fmt.Errorf("%w: %s", ErrUserFrozen, userFrom)
+		}
+	}
+	spent, err := spendGrants(ctx, q, userId1, amount)
+	if err != nil {
+		return err
+	}
+
+	// запишем транзакцию
+	var transactionId int64
+	err = q.QueryRowContext(ctx, "INSERT INTO transactions (src, dst, amount) VALUES ($1, $2, $3) RETURNING id", userId1, userId2, amount).Scan(&transactionId)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи транзакции: %w", s.mapError(err))
+	}
+	if err := carryGrants(ctx, q, userId2, transactionId, spent); err != nil {
+		return err
+	}
+
+	// commit
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId1, userId2)
+	slog.DebugContext(ctx, "coins transferred", "from_user_id", userId1, "to_user_id", userId2, "amount", amount)
+
+	return nil
+}
+
+func (s *sqlDatabase) SendItem(ctx context.Context, userFrom, userTo, item string, quantity int64) (err error) {
+	ctx, span := s.startSpan(ctx, "SendItem")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 || quantity > math.MaxInt32 || userFrom == userTo {
+		return fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	var productId int64
+	err = q.QueryRowContext(ctx, "SELECT id FROM products WHERE name=$1", item).Scan(&productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrProductNotFound, item)
+		}
+		return fmt.Errorf("ошибка при запросе товара: %w", err)
+	}
+	var fromId, toId int64
+	for _, user := range []struct {
+		name string
+		id   *int64
+	}{{userFrom, &fromId}, {userTo, &toId}} {
+		var frozen bool
+		err = q.QueryRowContext(ctx, "SELECT id, frozen_at IS NOT NULL FROM users WHERE name=$1", user.name).Scan(user.id, &frozen)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", ErrUserNotFound, user.name)
+			}
+			return fmt.Errorf("ошибка при поиске пользователя: %w", err)
+		}
+		if frozen && user.name == userFrom {
+			return fmt.Errorf("%w: %s", ErrUserFrozen, userFrom)
+		}
+	}
+
+	// строки инвентаря блокируются в порядке имён, как балансы в SendCoins,
+	// чтобы встречные передачи одного товара не приводили к взаимной блокировке
+	take := func() error {
+		taken, err := takeFromInventory(ctx, q, fromId, productId, quantity)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			return fmt.Errorf("%w: %s, нужно %d", ErrItemNotOwned, item, quantity)
+		}
+		return nil
+	}
+	put := func() error {
+		return s.putToInventory(ctx, q, toId, productId, item, quantity)
+	}
+	steps := []func() error{take, put}
+	if userTo < userFrom {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	_, err = q.ExecContext(ctx, "INSERT INTO item_transfers (src, dst, product_id, quantity) VALUES ($1, $2, $3, $4)", fromId, toId, productId, quantity)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи передачи: %w", s.mapError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(fromId, toId)
+	slog.DebugContext(ctx, "items transferred", "from_user_id", fromId, "to_user_id", toId, "product_id", productId, "quantity", quantity)
+
+	return nil
+}
+
+const tradeQuery = `SELECT t.id, f.name, r.name, p.name, t.quantity, COALESCE(w.name, ''), t.want_quantity, t.coins, t.status,
+	t.created_at, t.closed_at, t.from_id, t.to_id, t.product_id, COALESCE(t.want_product_id, 0)
+FROM trades AS t
+JOIN users AS f ON f.id = t.from_id
+JOIN users AS r ON r.id = t.to_id
+JOIN products AS p ON p.id = t.product_id
+LEFT JOIN products AS w ON w.id = t.want_product_id`
+
+// tradeIds -- id участников и товаров предложения обмена, want = 0 -- в обмен нужны только монеты
+type tradeIds struct {
+	from, to, product, want int64
+}
+
+// scanTrade читает предложение, выбранное запросом tradeQuery
+func scanTrade(row interface{ Scan(...any) error }) (*Trade, *tradeIds, error) {
+	var t Trade
+	var ids tradeIds
+	var closedAt sql.NullTime
+	err := row.Scan(&t.Id, &t.From, &t.To, &t.Item, &t.Quantity, &t.WantItem, &t.WantQuantity, &t.Coins, &t.Status,
+		&t.CreatedAt, &closedAt, &ids.from, &ids.to, &ids.product, &ids.want)
+	if err != nil {
+		return nil, nil, err
+	}
+	if closedAt.Valid {
+		t.ClosedAt = &closedAt.Time
+	}
+	return &t, &ids, nil
+}
+
+func (s *sqlDatabase) AddTrade(ctx context.Context, trade Trade) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "AddTrade")
+	defer func() { tracing.End(span, err) }()
+
+	if err := trade.validate(); err != nil {
+		return 0, err
+	}
+	q := s.conn()
+
+	var fromId, toId int64
+	for _, user := range []struct {
+		name string
+		id   *int64
+	}{{trade.From, &fromId}, {trade.To, &toId}} {
+		var frozen bool
+		err = q.QueryRowContext(ctx, "SELECT id, frozen_at IS NOT NULL FROM users WHERE name=$1", user.name).Scan(user.id, &frozen)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, fmt.Errorf("%w: %s", ErrUserNotFound, user.name)
+			}
+			return 0, fmt.Errorf("ошибка при поиске пользователя: %w", err)
+		}
+		if frozen && user.name == trade.From {
+			return 0, fmt.Errorf("%w: %s", ErrUserFrozen, trade.From)
+		}
+	}
+	var productId int64
+	var wantId sql.NullInt64
+	for _, product := range []struct {
+		name string
+		id   *int64
+	}{{trade.Item, &productId}, {trade.WantItem, &wantId.Int64}} {
+		if product.name == "" {
+			continue
+		}
+		err = q.QueryRowContext(ctx, "SELECT id FROM products WHERE name=$1", product.name).Scan(product.id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, &ItemError{Item: product.name, Err: ErrProductNotFound}
+			}
+			return 0, fmt.Errorf("ошибка при запросе товара: %w", err)
+		}
+	}
+	wantId.Valid = trade.WantItem != ""
+
+	var id int64
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO trades (from_id, to_id, product_id, quantity, want_product_id, want_quantity, coins) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		fromId, toId, productId, trade.Quantity, wantId, trade.WantQuantity, trade.Coins).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сохранении предложения обмена: %w", s.mapError(err))
+	}
+	slog.DebugContext(ctx, "trade added", "trade_id", id, "from_user_id", fromId, "to_user_id", toId)
+	return id, nil
+}
+
+func (s *sqlDatabase) GetTrades(ctx context.Context, username string) (_ []Trade, err error) {
+	ctx, span := s.startSpan(ctx, "GetTrades")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx, tradeQuery+" WHERE f.name = $1 OR r.name = $1 ORDER BY t.id", username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе предложений обмена: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []Trade{}
+	for rows.Next() {
+		trade, _, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении предложений обмена: %w", err)
+		}
+		trades = append(trades, *trade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении предложений обмена: %w", err)
+	}
+	return trades, nil
+}
+
+// closeTrade закрывает открытое предложение id от имени участника username и возвращает его: accept -- принимает,
+// иначе автор отменяет, а получатель отклоняет. Строка предложения блокируется до конца транзакции,
+// поэтому из одновременных принятия, отклонения и отмены выполняется только одно
+func (s *sqlDatabase) closeTrade(ctx context.Context, q tracedQuerier, id int64, username string, accept bool) (*Trade, *tradeIds, error) {
+	trade, ids, err := scanTrade(q.QueryRowContext(ctx, tradeQuery+" WHERE t.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("%w: %d", ErrTradeNotFound, id)
+		}
+		return nil, nil, fmt.Errorf("ошибка при запросе предложения обмена: %w", err)
+	}
+	// принять или отклонить предложение может только получатель, отменить -- только автор,
+	// для остальных пользователей предложения нет
+	var status string
+	switch {
+	case accept && username == trade.To:
+		status = TradeAccepted
+	case !accept && username == trade.To:
+		status = TradeDeclined
+	case !accept && username == trade.From:
+		status = TradeCancelled
+	default:
+		return nil, nil, fmt.Errorf("%w: %d", ErrTradeNotFound, id)
+	}
+
+	closedAt := time.Now().UTC()
+	res, err := q.ExecContext(ctx, "UPDATE trades SET status = $1, closed_at = $2 WHERE id = $3 AND status = $4", status, closedAt, id, TradeOpen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при закрытии предложения обмена: %w", s.mapError(err))
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при закрытии предложения обмена: %w", err)
+	}
+	if changed == 0 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrTradeClosed, id)
+	}
+	trade.Status = status
+	trade.ClosedAt = &closedAt
+	return trade, ids, nil
+}
+
+func (s *sqlDatabase) AcceptTrade(ctx context.Context, id int64, username string) (_ *Trade, err error) {
+	ctx, span := s.startSpan(ctx, "AcceptTrade")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	trade, ids, err := s.closeTrade(ctx, q, id, username, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// балансы меняются в порядке имён, как в SendCoins, затем строки инвентаря в порядке имён и товаров, как в SendItem.
+	// Обе стороны что-то отдают, поэтому заблокированный участник не может ни принять предложение, ни получить по нему
+	changes := []struct {
+		name  string
+		id    int64
+		delta float64
+	}{
+		{trade.From, ids.from, trade.Coins},
+		{trade.To, ids.to, -trade.Coins},
+	}
+	if trade.To < trade.From {
+		changes[0], changes[1] = changes[1], changes[0]
+	}
+	for _, change := range changes {
+		var frozen bool
+		err = q.QueryRowContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id=$2 RETURNING frozen_at IS NOT NULL", change.delta, change.id).Scan(&frozen)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+		}
+		if frozen {
+			return nil, fmt.Errorf("%w: %s", ErrUserFrozen, change.name)
+		}
+	}
+	var spent []spentGrant
+	if trade.Coins > 0 {
+		if spent, err = spendGrants(ctx, q, ids.to, trade.Coins); err != nil {
+			return nil, err
+		}
+	}
+
+	type give struct {
+		from, to         int64
+		product          int64
+		item             string
+		quantity         int64
+		fromName, toName string
+	}
+	gives := []give{{ids.from, ids.to, ids.product, trade.Item, trade.Quantity, trade.From, trade.To}}
+	if ids.want != 0 {
+		gives = append(gives, give{ids.to, ids.from, ids.want, trade.WantItem, trade.WantQuantity, trade.To, trade.From})
+	}
+	type inventoryStep struct {
+		username, item string
+		run            func() error
+	}
+	var steps []inventoryStep
+	for _, g := range gives {
+		steps = append(steps, inventoryStep{g.fromName, g.item, func() error {
+			taken, err := takeFromInventory(ctx, q, g.from, g.product, g.quantity)
+			if err != nil {
+				return err
+			}
+			if !taken {
+				return &ItemError{Item: g.item, Err: fmt.Errorf("%w: у %s, нужно %d", ErrItemNotOwned, g.fromName, g.quantity)}
+			}
+			return nil
+		}}, inventoryStep{g.toName, g.item, func() error {
+			if err := s.putToInventory(ctx, q, g.to, g.product, g.item, g.quantity); err != nil {
+				return &ItemError{Item: g.item, Err: err}
+			}
+			return nil
+		}})
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		if steps[i].username != steps[j].username {
+			return steps[i].username < steps[j].username
+		}
+		return steps[i].item < steps[j].item
+	})
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range gives {
+		_, err = q.ExecContext(ctx, "INSERT INTO item_transfers (src, dst, product_id, quantity) VALUES ($1, $2, $3, $4)", g.from, g.to, g.product, g.quantity)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при записи передачи: %w", s.mapError(err))
+		}
+	}
+	if trade.Coins > 0 {
+		var transactionId int64
+		err = q.QueryRowContext(ctx, "INSERT INTO transactions (src, dst, amount) VALUES ($1, $2, $3) RETURNING id", ids.to, ids.from, trade.Coins).Scan(&transactionId)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при записи транзакции: %w", s.mapError(err))
+		}
+		if err := carryGrants(ctx, q, ids.from, transactionId, spent); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(ids.from, ids.to)
+	slog.DebugContext(ctx, "trade accepted", "trade_id", id, "from_user_id", ids.from, "to_user_id", ids.to)
+	return trade, nil
+}
+
+func (s *sqlDatabase) CloseTrade(ctx context.Context, id int64, username string) (_ *Trade, err error) {
+	ctx, span := s.startSpan(ctx, "CloseTrade")
+	defer func() { tracing.End(span, err) }()
+
+	trade, _, err := s.closeTrade(ctx, s.conn(), id, username, false)
+	if err != nil {
+		return nil, err
+	}
+	slog.DebugContext(ctx, "trade closed", "trade_id", id, "status", trade.Status)
+	return trade, nil
+}
+
+// putToInventory добавляет quantity единиц товара в инвентарь пользователя. Лимит покупок товара действует
+// и на полученные предметы, иначе его можно было бы обойти через других пользователей
+func (s *sqlDatabase) putToInventory(ctx context.Context, q tracedQuerier, userId, productId int64, item string, quantity int64) error {
+	var total int64
+	var maxPerUser sql.NullInt64
+	err := q.QueryRowContext(ctx,
+		"INSERT INTO inventory (user_id, product_id, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = inventory.quantity + excluded.quantity RETURNING quantity",
+		userId, productId, quantity).Scan(&total)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении инвентаря: %w", s.mapError(err))
+	}
+	err = q.QueryRowContext(ctx, "SELECT max_per_user FROM products WHERE id=$1", productId).Scan(&maxPerUser)
+	if err != nil {
+		return fmt.Errorf("ошибка при запросе лимита покупок: %w", err)
+	}
+	if maxPerUser.Valid && total > maxPerUser.Int64 {
+		return fmt.Errorf("%w: %s, не больше %d", ErrPurchaseLimit, item, maxPerUser.Int64)
+	}
+	return nil
+}
+
+// takeFromInventory забирает quantity единиц товара из инвентаря пользователя и возвращает false, если их не хватает.
+// Ограничение inventory_quantity_check не допускает пустых позиций, поэтому последние единицы удаляются вместе со строкой
+func takeFromInventory(ctx context.Context, q tracedQuerier, userId, productId, quantity int64) (bool, error) {
+	res, err := q.ExecContext(ctx, "DELETE FROM inventory WHERE user_id = $1 AND product_id = $2 AND quantity = $3", userId, productId, quantity)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при обновлении инвентаря: %w", err)
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при обновлении инвентаря: %w", err)
+	}
+	if changed == 0 {
+		res, err = q.ExecContext(ctx, "UPDATE inventory SET quantity = quantity - $1 WHERE user_id = $2 AND product_id = $3 AND quantity > $1", quantity, userId, productId)
+		if err != nil {
+			return false, fmt.Errorf("ошибка при обновлении инвентаря: %w", err)
+		}
+		if changed, err = res.RowsAffected(); err != nil {
+			return false, fmt.Errorf("ошибка при обновлении инвентаря: %w", err)
+		}
+	}
+	return changed > 0, nil
+}
+
+func (s *sqlDatabase) GetUserInventory(ctx context.Context, userId int64) (_ *[]models.InfoResponseInventoryInner, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserInventory")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.reader(ctx, userId).QueryContext(ctx, "SELECT p.name, i.quantity FROM inventory AS i JOIN products AS p ON p.id = i.product_id WHERE i.user_id = $1", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	goods := make([]models.InfoResponseInventoryInner, 0)
+	for rows.Next() {
+		var productName string
+		var quantity int32
+		if err := rows.Scan(&productName, &quantity); err != nil {
+			return nil, fmt.Errorf("ошибка получения товаров: %w", err)
+		}
+		goods = append(goods, models.InfoResponseInventoryInner{Type: productName, Quantity: quantity})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	return &goods, nil
+}
+
+func (s *sqlDatabase) GetUserReceivedAndSentCoins(ctx context.Context, userId int64) (_ *models.InfoResponseCoinHistory, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserReceivedAndSentCoins")
+	defer func() { tracing.End(span, err) }()
+
+	q := s.reader(ctx, userId)
+	// начисления системы, исправления баланса и возвраты покупок попадают в полученные переводы, сгоревшие монеты и списания -- в отправленные
+	// начисления, полученные переводом, уже видны как сам перевод
+	rows, err := q.QueryContext(ctx, "SELECT u1.name, u2.name, t.amount FROM users AS u1 JOIN transactions AS t ON u1.id=t.src JOIN users AS u2 ON t.dst=u2.id WHERE u1.id=$1"+
+		" UNION ALL SELECT '', '"+SystemUser+"', g.expired FROM coin_grants AS g WHERE g.user_id=$1 AND g.expired > 0"+
+		" UNION ALL SELECT '', '"+SystemUser+"', -a.amount FROM balance_adjustments AS a WHERE a.user_id=$1 AND a.amount < 0", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	sent := make([]models.InfoResponseCoinHistorySentInner, 0)
+	for rows.Next() {
+		var user1 string
+		var user2 string
+		var amount float64
+		if err := rows.Scan(&user1, &user2, &amount); err != nil {
+			return nil, fmt.Errorf("ошибка при получении транзакций отправки: %w", err)
+		}
+		sent = append(sent, models.InfoResponseCoinHistorySentInner{ToUser: user2, Amount: int32(amount)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	rows, err = q.QueryContext(ctx, "SELECT u1.name, u2.name, t.amount FROM users AS u1 JOIN transactions AS t ON u1.id=t.dst JOIN users AS u2 ON t.src=u2.id WHERE u1.id=$1"+
+		" UNION ALL SELECT '', '"+SystemUser+"', g.amount FROM coin_grants AS g WHERE g.user_id=$1 AND g.transaction_id IS NULL"+
+		" UNION ALL SELECT '', '"+SystemUser+"', a.amount FROM balance_adjustments AS a WHERE a.user_id=$1 AND a.amount > 0"+
+		" UNION ALL SELECT '', '"+SystemUser+"', p.price FROM purchases AS p WHERE p.user_id=$1 AND p.refunded_at IS NOT NULL AND p.price > 0", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	received := make([]models.InfoResponseCoinHistoryReceivedInner, 0)
+	for rows.Next() {
+		var user1 string
+		var user2 string
+		var amount float64
+		if err := rows.Scan(&user1, &user2, &amount); err != nil {
+			return nil, fmt.Errorf("ошибка при получении транзакций получения: %w", err)
+		}
+		received = append(received, models.InfoResponseCoinHistoryReceivedInner{FromUser: user2, Amount: int32(amount)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	history := new(models.InfoResponseCoinHistory)
+	history.Received = received
+	history.Sent = sent
+	return history, nil
+}
+
+// userInfoQuery собирает сводку пользователя одним запросом: строка баланса, затем позиции инвентаря,
+// переводы монет и предметов, лимиты покупок и подарки, различаемые по первому столбцу. Начисления системы,
+// исправления баланса и возвраты покупок попадают в полученные переводы, сгоревшие монеты и списания -- в отправленные; предмет есть только
+// у передач предметов и подарков, сообщение -- только у подарков. Начисления, полученные переводом, показывает сам перевод.
+// Один запрос выполняется на одном снимке данных, поэтому баланс всегда согласован с историей, а лимиты -- с инвентарём
+const userInfoQuery = `SELECT 'balance', '', u.balance, '', '' FROM users AS u WHERE u.id = $1
+UNION ALL
+SELECT 'inventory', p.name, i.quantity, '', '' FROM inventory AS i JOIN products AS p ON p.id = i.product_id WHERE i.user_id = $1
+UNION ALL
+SELECT 'sent', u.name, t.amount, '', '' FROM transactions AS t JOIN users AS u ON u.id = t.dst WHERE t.src = $1
+UNION ALL
+SELECT 'received', u.name, t.amount, '', '' FROM transactions AS t JOIN users AS u ON u.id = t.src WHERE t.dst = $1
+UNION ALL
+SELECT 'item_sent', u.name, t.quantity, p.name, '' FROM item_transfers AS t
+	JOIN users AS u ON u.id = t.dst JOIN products AS p ON p.id = t.product_id WHERE t.src = $1
+UNION ALL
+SELECT 'item_received', u.name, t.quantity, p.name, '' FROM item_transfers AS t
+	JOIN users AS u ON u.id = t.src JOIN products AS p ON p.id = t.product_id WHERE t.dst = $1
+UNION ALL
+SELECT 'limit', p.name, p.max_per_user, '', '' FROM products AS p WHERE p.max_per_user IS NOT NULL
+UNION ALL
+SELECT 'gift_sent', u.name, g.price, p.name, COALESCE(g.message, '') FROM purchases AS g
+	JOIN users AS u ON u.id = g.recipient_id JOIN products AS p ON p.id = g.product_id
+	WHERE g.user_id = $1 AND g.refunded_at IS NULL
+UNION ALL
+SELECT 'gift_received', u.name, g.price, p.name, COALESCE(g.message, '') FROM purchases AS g
+	JOIN users AS u ON u.id = g.user_id JOIN products AS p ON p.id = g.product_id
+	WHERE g.recipient_id = $1 AND g.refunded_at IS NULL
+UNION ALL
+SELECT 'received', '` + SystemUser + `', g.amount, '', '' FROM coin_grants AS g WHERE g.user_id = $1 AND g.transaction_id IS NULL
+UNION ALL
+SELECT 'sent', '` + SystemUser + `', g.expired, '', '' FROM coin_grants AS g WHERE g.user_id = $1 AND g.expired > 0
+UNION ALL
+SELECT 'received', '` + SystemUser + `', a.amount, '', '' FROM balance_adjustments AS a WHERE a.user_id = $1 AND a.amount > 0
+UNION ALL
+SELECT 'sent', '` + SystemUser + `', -a.amount, '', '' FROM balance_adjustments AS a WHERE a.user_id = $1 AND a.amount < 0
+UNION ALL
+SELECT 'received', '` + SystemUser + `', p.price, '', '' FROM purchases AS p WHERE p.user_id = $1 AND p.refunded_at IS NOT NULL AND p.price > 0`
+
+func (s *sqlDatabase) GetUserInfo(ctx context.Context, userId int64) (_ *models.InfoResponse, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserInfo")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.reader(ctx, userId).QueryContext(ctx, userInfoQuery, userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	info := &models.InfoResponse{
+		Inventory: make([]models.InfoResponseInventoryInner, 0),
+		CoinHistory: models.InfoResponseCoinHistory{
+			Received: make([]models.InfoResponseCoinHistoryReceivedInner, 0),
+			Sent:     make([]models.InfoResponseCoinHistorySentInner, 0),
+		},
+		ItemHistory: models.InfoResponseItemHistory{
+			Received: make([]models.InfoResponseItemHistoryReceivedInner, 0),
+			Sent:     make([]models.InfoResponseItemHistorySentInner, 0),
+		},
+		Gifts: models.InfoResponseGifts{
+			Received: make([]models.InfoResponseGiftsReceivedInner, 0),
+			Sent:     make([]models.InfoResponseGiftsSentInner, 0),
+		},
+	}
+	found := false
+	for rows.Next() {
+		var kind, name, item, message string
+		var value float64
+		if err := rows.Scan(&kind, &name, &value, &item, &message); err != nil {
+			return nil, fmt.Errorf("ошибка при получении данных пользователя: %w", err)
+		}
+		switch kind {
+		case "balance":
+			found = true
+			info.Coins = int32(value)
+		case "inventory":
+			info.Inventory = append(info.Inventory, models.InfoResponseInventoryInner{Type: name, Quantity: int32(value)})
+		case "sent":
+			info.CoinHistory.Sent = append(info.CoinHistory.Sent, models.InfoResponseCoinHistorySentInner{ToUser: name, Amount: int32(value)})
+		case "received":
+			info.CoinHistory.Received = append(info.CoinHistory.Received, models.InfoResponseCoinHistoryReceivedInner{FromUser: name, Amount: int32(value)})
+		case "item_sent":
+			info.ItemHistory.Sent = append(info.ItemHistory.Sent, models.InfoResponseItemHistorySentInner{ToUser: name, Type: item, Quantity: int32(value)})
+		case "item_received":
+			info.ItemHistory.Received = append(info.ItemHistory.Received, models.InfoResponseItemHistoryReceivedInner{FromUser: name, Type: item, Quantity: int32(value)})
+		case "limit":
+			info.Limits = append(info.Limits, models.InfoResponseLimitsInner{Type: name, MaxPerUser: int32(value)})
+		case "gift_sent":
+			info.Gifts.Sent = append(info.Gifts.Sent, models.InfoResponseGiftsSentInner{ToUser: name, Item: item, Amount: int32(value), Message: message})
+		case "gift_received":
+			info.Gifts.Received = append(info.Gifts.Received, models.InfoResponseGiftsReceivedInner{FromUser: name, Item: item, Message: message})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	completeLimits(info)
+
+	return info, nil
+}
+
+func (s *sqlDatabase) GetProducts(ctx context.Context) (_ []Product, err error) {
+	ctx, span := s.startSpan(ctx, "GetProducts")
+	defer func() { tracing.End(span, err) }()
+
+	// остаток на реплике может немного отставать, окончательно его проверяет покупка
+	rows, err := s.reader(ctx, 0).QueryContext(ctx, "SELECT name, price, stock, max_per_user FROM products ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	products := make([]Product, 0)
+	for rows.Next() {
+		var p Product
+		var stock, maxPerUser sql.NullInt64
+		if err := rows.Scan(&p.Name, &p.Price, &stock, &maxPerUser); err != nil {
+			return nil, fmt.Errorf("ошибка при получении товаров: %w", err)
+		}
+		if stock.Valid {
+			p.Stock = &stock.Int64
+		}
+		if maxPerUser.Valid {
+			p.MaxPerUser = &maxPerUser.Int64
+		}
+		products = append(products, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	return products, nil
+}
+
+func (s *sqlDatabase) SetProductStock(ctx context.Context, name string, stock *int64) (err error) {
+	ctx, span := s.startSpan(ctx, "SetProductStock")
+	defer func() { tracing.End(span, err) }()
+
+	if stock != nil && *stock < 0 {
+		return fmt.Errorf("%w: остаток %d", ErrInvalidAmount, *stock)
+	}
+
+	res, err := s.conn().ExecContext(ctx, "UPDATE products SET stock=$1 WHERE name=$2", stock, name)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении остатка товара: %w", s.mapError(err))
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении остатка товара: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, name)
+	}
+
+	return nil
+}
+
+func (s *sqlDatabase) RestockProduct(ctx context.Context, name string, quantity int64) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "RestockProduct")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+
+	// остаток увеличивается одним запросом, поэтому параллельные покупки не теряются
+	var stock sql.NullInt64
+	err = s.conn().QueryRowContext(ctx, "UPDATE products SET stock = stock + $1 WHERE name=$2 RETURNING stock", quantity, name).Scan(&stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: %s", ErrProductNotFound, name)
+		}
+		return 0, fmt.Errorf("ошибка при пополнении товара: %w", s.mapError(err))
+	}
+	if !stock.Valid {
+		return 0, fmt.Errorf("%w: %s", ErrUnlimitedStock, name)
+	}
+	slog.DebugContext(ctx, "product restocked", "product", name, "stock", stock.Int64)
+
+	return stock.Int64, nil
+}
+
+func (s *sqlDatabase) SetProductLimit(ctx context.Context, name string, maxPerUser *int64) (err error) {
+	ctx, span := s.startSpan(ctx, "SetProductLimit")
+	defer func() { tracing.End(span, err) }()
+
+	if maxPerUser != nil && *maxPerUser <= 0 {
+		return fmt.Errorf("%w: лимит %d", ErrInvalidAmount, *maxPerUser)
+	}
+
+	res, err := s.conn().ExecContext(ctx, "UPDATE products SET max_per_user=$1 WHERE name=$2", maxPerUser, name)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении лимита покупок: %w", s.mapError(err))
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении лимита покупок: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, name)
+	}
+
+	return nil
+}
+
+const purchaseQuery = `SELECT p.id, p.user_id, u.name, pr.name, p.price, p.created_at, p.refunded_at, COALESCE(p.refunded_by, ''),
+	COALESCE(r.name, ''), COALESCE(p.message, ''), COALESCE(c.code, ''), p.discount
+FROM purchases AS p JOIN users AS u ON u.id = p.user_id JOIN products AS pr ON pr.id = p.product_id
+LEFT JOIN users AS r ON r.id = p.recipient_id LEFT JOIN promo_codes AS c ON c.id = p.promo_code_id WHERE p.id = $1`
+
+// getPurchase читает покупку через q, чтобы в транзакции возврата видеть её текущее состояние
+func getPurchase(ctx context.Context, q tracedQuerier, id int64) (*Purchase, error) {
+	var p Purchase
+	var refundedAt sql.NullTime
+	err := q.QueryRowContext(ctx, purchaseQuery, id).Scan(&p.Id, &p.UserId, &p.Username, &p.Item, &p.Price, &p.CreatedAt, &refundedAt, &p.RefundedBy, &p.Recipient, &p.Message, &p.PromoCode, &p.Discount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrPurchaseNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при запросе покупки: %w", err)
+	}
+	if refundedAt.Valid {
+		p.RefundedAt = &refundedAt.Time
+	}
+	return &p, nil
+}
+
+func (s *sqlDatabase) GetPurchase(ctx context.Context, id int64) (_ *Purchase, err error) {
+	ctx, span := s.startSpan(ctx, "GetPurchase")
+	defer func() { tracing.End(span, err) }()
+
+	// покупку проверяют сразу после её совершения, поэтому читаем из основной базы
+	return getPurchase(ctx, s.conn(), id)
+}
+
+func (s *sqlDatabase) RefundPurchase(ctx context.Context, id int64, by string) (_ *Purchase, _ bool, err error) {
+	ctx, span := s.startSpan(ctx, "RefundPurchase")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// отметка о возврате ставится первой и только на невозвращённую покупку, поэтому из параллельных
+	// возвратов одной покупки монеты начисляет ровно один, а остальные ждут его и видят отметку.
+	// Предмет подарка забирается у получателя, а монеты возвращаются покупателю
+	var userId, ownerId, productId int64
+	var price float64
+	err = q.QueryRowContext(ctx,
+		"UPDATE purchases SET refunded_at = CURRENT_TIMESTAMP, refunded_by = $1 WHERE id = $2 AND refunded_at IS NULL RETURNING user_id, COALESCE(recipient_id, user_id), product_id, price",
+		by, id).Scan(&userId, &ownerId, &productId, &price)
+	if err == sql.ErrNoRows {
+		// покупки нет или она уже возвращена
+		purchase, err := getPurchase(ctx, q, id)
+		if err != nil {
+			return nil, false, err
+		}
+		return purchase, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при отметке возврата: %w", err)
+	}
+
+	// строки блокируются в том же порядке, что и при покупке: пользователи, товар, инвентарь, иначе
+	// покупка и возврат одного товара ждали бы друг друга. Покупатель и получатель подарка блокируются
+	// в порядке имён, как в SendCoins, получатель -- обновлением без изменения баланса
+	rows, err := q.QueryContext(ctx, "SELECT id FROM users WHERE id IN ($1, $2) ORDER BY name", userId, ownerId)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+	}
+	var userIds []int64
+	for rows.Next() {
+		var lockId int64
+		if err := rows.Scan(&lockId); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+		}
+		userIds = append(userIds, lockId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+	}
+	for _, lockId := range userIds {
+		var credit float64
+		if lockId == userId {
+			credit = price
+		}
+		if _, err := q.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", credit, lockId); err != nil {
+			return nil, false, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+		}
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE products SET stock = stock + 1 WHERE id = $1 AND stock IS NOT NULL", productId); err != nil {
+		return nil, false, fmt.Errorf("ошибка при обновлении остатка товара: %w", s.mapError(err))
+	}
+	taken, err := takeFromInventory(ctx, q, ownerId, productId, 1)
+	if err != nil {
+		return nil, false, err
+	}
+	if !taken {
+		return nil, false, fmt.Errorf("%w: покупка %d", ErrItemNotOwned, id)
+	}
+
+	// монеты со сроком остаются со сроком, иначе покупка с возвратом продлевала бы их навсегда
+	_, err = q.ExecContext(ctx,
+		`UPDATE coin_grants SET remaining = remaining + (SELECT amount FROM purchase_grants WHERE purchase_id = $1 AND grant_id = coin_grants.id)
+		WHERE id IN (SELECT grant_id FROM purchase_grants WHERE purchase_id = $1)`, id)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при возврате начислений: %w", err)
+	}
+
+	purchase, err := getPurchase(ctx, q, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId, ownerId)
+	slog.DebugContext(ctx, "purchase refunded", "purchase_id", id, "price", price)
+
+	return purchase, true, nil
+}
+
+func (s *sqlDatabase) AddToCart(ctx context.Context, userId int64, item string, quantity int64) (err error) {
+	ctx, span := s.startSpan(ctx, "AddToCart")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 || quantity > math.MaxInt32 {
+		return fmt.Errorf("%w: %d", ErrInvalidAmount, quantity)
+	}
+
+	var productId int64
+	err = s.conn().QueryRowContext(ctx, "SELECT id FROM products WHERE name=$1", item).Scan(&productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrProductNotFound, item)
+		}
+		return fmt.Errorf("ошибка при запросе товара: %w", err)
+	}
+
+	// количество в корзине не должно выйти за INTEGER, иначе его нельзя будет купить одной позицией
+	var total int64
+	err = s.conn().QueryRowContext(ctx,
+		"INSERT INTO cart_items (user_id, product_id, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + excluded.quantity WHERE CAST(cart_items.quantity AS BIGINT) + excluded.quantity <= 2147483647 RETURNING quantity",
+		userId, productId, quantity).Scan(&total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s, слишком много в корзине", ErrInvalidAmount, item)
+		}
+		return fmt.Errorf("ошибка при обновлении корзины: %w", s.mapError(err))
+	}
+	slog.DebugContext(ctx, "cart updated", "product_id", productId, "quantity", total)
+
+	return nil
+}
+
+func (s *sqlDatabase) RemoveFromCart(ctx context.Context, userId int64, item string) (_ bool, err error) {
+	ctx, span := s.startSpan(ctx, "RemoveFromCart")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := s.conn().ExecContext(ctx,
+		"DELETE FROM cart_items WHERE user_id = $1 AND product_id = (SELECT id FROM products WHERE name = $2)", userId, item)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при обновлении корзины: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при обновлении корзины: %w", err)
+	}
+	return removed > 0, nil
+}
+
+func (s *sqlDatabase) GetCart(ctx context.Context, userId int64) (_ []CartItem, err error) {
+	ctx, span := s.startSpan(ctx, "GetCart")
+	defer func() { tracing.End(span, err) }()
+
+	// корзину смотрят сразу после изменения, поэтому читаем из основной базы
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT p.name, c.quantity, p.price FROM cart_items AS c JOIN products AS p ON p.id = c.product_id WHERE c.user_id = $1 ORDER BY p.name", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе корзины: %w", err)
+	}
+	defer rows.Close()
+
+	cart := []CartItem{}
+	for rows.Next() {
+		var item CartItem
+		if err := rows.Scan(&item.Item, &item.Quantity, &item.Price); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении корзины: %w", err)
+		}
+		cart = append(cart, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении корзины: %w", err)
+	}
+	return cart, nil
+}
+
+// checkoutLine -- позиция корзины, прочитанная при оформлении
+type checkoutLine struct {
+	productId  int64
+	maxPerUser sql.NullInt64
+	ReceiptItem
+}
+
+func (s *sqlDatabase) Checkout(ctx context.Context, userId int64, prices map[string]float64) (_ *Receipt, err error) {
+	ctx, span := s.startSpan(ctx, "Checkout")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// корзина очищается первой: параллельное оформление той же корзины ждёт этой транзакции
+	// и после её коммита находит корзину пустой, поэтому товары не покупаются дважды
+	rows, err := q.QueryContext(ctx,
+		"DELETE FROM cart_items WHERE user_id = $1 RETURNING product_id, quantity", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при очистке корзины: %w", err)
+	}
+	var lines []*checkoutLine
+	for rows.Next() {
+		line := &checkoutLine{}
+		if err := rows.Scan(&line.productId, &line.Quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка при чтении корзины: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении корзины: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrCartEmpty, userId)
+	}
+
+	receipt := &Receipt{}
+	for _, line := range lines {
+		err = q.QueryRowContext(ctx, "SELECT name, price, max_per_user FROM products WHERE id=$1", line.productId).Scan(&line.Item, &line.Price, &line.maxPerUser)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при запросе товара: %w", err)
+		}
+		if price, ok := prices[line.Item]; ok {
+			line.Price = price
+		}
+		receipt.Total += line.Price * float64(line.Quantity)
+	}
+	// строки товаров блокируются в порядке имён, чтобы параллельные оформления не приводили к взаимной блокировке
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Item < lines[j].Item })
+
+	// вся корзина оплачивается одним списанием, уход в минус отсекает ограничение users_balance_check
+	var frozen bool
+	err = q.QueryRowContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id=$2 RETURNING balance, frozen_at IS NOT NULL", receipt.Total, userId).Scan(&receipt.Balance, &frozen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+		}
+		return nil, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+	}
+	if frozen {
+		return nil, fmt.Errorf("%w: %d", ErrUserFrozen, userId)
+	}
+	err = q.QueryRowContext(ctx, "INSERT INTO receipts (user_id, total) VALUES ($1, $2) RETURNING id, created_at", userId, receipt.Total).Scan(&receipt.Id, &receipt.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении чека: %w", s.mapError(err))
+	}
+
+	for _, line := range lines {
+		if err := s.checkoutLine(ctx, q, userId, receipt.Id, line); err != nil {
+			return nil, &ItemError{Item: line.Item, Err: err}
+		}
+		receipt.Items = append(receipt.Items, line.ReceiptItem)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId)
+	slog.DebugContext(ctx, "cart checked out", "receipt_id", receipt.Id, "total", receipt.Total, "balance", receipt.Balance)
+
+	return receipt, nil
+}
+
+// checkoutLine покупает позицию корзины: уменьшает остаток, добавляет товар в инвентарь с проверкой лимита
+// и сохраняет по покупке на каждую единицу
+func (s *sqlDatabase) checkoutLine(ctx context.Context, q tracedQuerier, userId, receiptId int64, line *checkoutLine) error {
+	// уход остатка в минус отсекает ограничение products_stock_check
+	_, err := q.ExecContext(ctx, "UPDATE products SET stock = stock - $1 WHERE id=$2 AND stock IS NOT NULL", line.Quantity, line.productId)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении остатка товара: %w", s.mapError(err))
+	}
+
+	var quantity int64
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO inventory (user_id, product_id, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = inventory.quantity + excluded.quantity RETURNING quantity",
+		userId, line.productId, line.Quantity).Scan(&quantity)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении инвентаря: %w", s.mapError(err))
+	}
+	if line.maxPerUser.Valid && quantity > line.maxPerUser.Int64 {
+		return fmt.Errorf("%w: не больше %d", ErrPurchaseLimit, line.maxPerUser.Int64)
+	}
+
+	line.PurchaseIds = make([]int64, 0, line.Quantity)
+	for range line.Quantity {
+		var purchaseId int64
+		err = q.QueryRowContext(ctx,
+			"INSERT INTO purchases (user_id, product_id, price, receipt_id) VALUES ($1, $2, $3, $4) RETURNING id",
+			userId, line.productId, line.Price, receiptId).Scan(&purchaseId)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении покупки: %w", s.mapError(err))
+		}
+		// монеты со сроком списываются по покупкам, чтобы возврат одной покупки вернул именно её монеты
+		spent, err := spendGrants(ctx, q, userId, line.Price)
+		if err != nil {
+			return err
+		}
+		if err := addPurchaseGrants(ctx, q, purchaseId, spent); err != nil {
+			return err
+		}
+		line.PurchaseIds = append(line.PurchaseIds, purchaseId)
+	}
+	return nil
+}
+
+const promoCodeQuery = `SELECT c.id, c.code, COALESCE(c.percent, 0), COALESCE(c.amount, 0), COALESCE(p.name, ''),
+	c.starts_at, c.ends_at, c.max_uses, c.uses, c.disabled_at
+FROM promo_codes AS c LEFT JOIN products AS p ON p.id = c.product_id`
+
+// scanPromoCode читает промокод, выбранный запросом promoCodeQuery
+func scanPromoCode(row interface{ Scan(...any) error }) (int64, *PromoCode, error) {
+	var id int64
+	var p PromoCode
+	var startsAt, endsAt, disabledAt sql.NullTime
+	var maxUses sql.NullInt64
+	if err := row.Scan(&id, &p.Code, &p.Percent, &p.Amount, &p.Item, &startsAt, &endsAt, &maxUses, &p.Uses, &disabledAt); err != nil {
+		return 0, nil, err
+	}
+	for _, t := range []struct {
+		src sql.NullTime
+		dst **time.Time
+	}{{startsAt, &p.StartsAt}, {endsAt, &p.EndsAt}, {disabledAt, &p.DisabledAt}} {
+		if t.src.Valid {
+			v := t.src.Time
+			*t.dst = &v
+		}
+	}
+	if maxUses.Valid {
+		p.MaxUses = &maxUses.Int64
+	}
+	return id, &p, nil
+}
+
+// usePromoCode проверяет промокод для товара itemId и засчитывает применение. Параллельные покупки
+// с одним кодом ждут блокировки его строки, а применение сверх лимита отсекает ограничение promo_codes_uses_check
+func (s *sqlDatabase) usePromoCode(ctx context.Context, q tracedQuerier, code string, itemId int64) (*PromoCode, int64, error) {
+	id, promo, err := scanPromoCode(q.QueryRowContext(ctx, promoCodeQuery+" WHERE c.code = $1", code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, fmt.Errorf("%w: %s", ErrPromoCodeNotFound, code)
+		}
+		return nil, 0, fmt.Errorf("ошибка при запросе промокода: %w", err)
+	}
+	var item string
+	err = q.QueryRowContext(ctx, "SELECT name FROM products WHERE id=$1", itemId).Scan(&item)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, fmt.Errorf("%w: %d", ErrProductNotFound, itemId)
+		}
+		return nil, 0, fmt.Errorf("ошибка при запросе товара: %w", err)
+	}
+	if err := promo.check(item, time.Now()); err != nil {
+		return nil, 0, err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE promo_codes SET uses = uses + 1 WHERE id=$1", id); err != nil {
+		return nil, 0, fmt.Errorf("ошибка при применении промокода: %w", s.mapError(err))
+	}
+	return promo, id, nil
+}
+
+func (s *sqlDatabase) AddPromoCode(ctx context.Context, promo PromoCode) (err error) {
+	ctx, span := s.startSpan(ctx, "AddPromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	if err := promo.validate(); err != nil {
+		return err
+	}
+	var productId sql.NullInt64
+	if promo.Item != "" {
+		err = s.conn().QueryRowContext(ctx, "SELECT id FROM products WHERE name=$1", promo.Item).Scan(&productId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", ErrProductNotFound, promo.Item)
+			}
+			return fmt.Errorf("ошибка при запросе товара: %w", err)
+		}
+	}
+	// время хранится в UTC, как время переводов
+	utc := func(t *time.Time) sql.NullTime {
+		if t == nil {
+			return sql.NullTime{}
+		}
+		return sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	var maxUses sql.NullInt64
+	if promo.MaxUses != nil {
+		maxUses = sql.NullInt64{Int64: *promo.MaxUses, Valid: true}
+	}
+
+	var id int64
+	err = s.conn().QueryRowContext(ctx,
+		"INSERT INTO promo_codes (code, percent, amount, product_id, starts_at, ends_at, max_uses) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (code) DO NOTHING RETURNING id",
+		promo.Code, sql.NullInt64{Int64: promo.Percent, Valid: promo.Percent > 0}, sql.NullFloat64{Float64: promo.Amount, Valid: promo.Percent == 0},
+		productId, utc(promo.StartsAt), utc(promo.EndsAt), maxUses).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrPromoCodeExists, promo.Code)
+		}
+		return fmt.Errorf("ошибка при создании промокода: %w", s.mapError(err))
+	}
+	slog.DebugContext(ctx, "promo code added", "promo_code_id", id)
+	return nil
+}
+
+func (s *sqlDatabase) GetPromoCodes(ctx context.Context) (_ []PromoCode, err error) {
+	ctx, span := s.startSpan(ctx, "GetPromoCodes")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx, promoCodeQuery+" ORDER BY c.code")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе промокодов: %w", err)
+	}
+	defer rows.Close()
+
+	promos := []PromoCode{}
+	for rows.Next() {
+		_, promo, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении промокодов: %w", err)
+		}
+		promos = append(promos, *promo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении промокодов: %w", err)
+	}
+	return promos, nil
+}
+
+func (s *sqlDatabase) DisablePromoCode(ctx context.Context, code string) (_ *PromoCode, err error) {
+	ctx, span := s.startSpan(ctx, "DisablePromoCode")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx, "UPDATE promo_codes SET disabled_at = $1 WHERE code = $2 AND disabled_at IS NULL", time.Now().UTC(), code)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при отключении промокода: %w", err)
+	}
+	_, promo, err := scanPromoCode(s.conn().QueryRowContext(ctx, promoCodeQuery+" WHERE c.code = $1", code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrPromoCodeNotFound, code)
+		}
+		return nil, fmt.Errorf("ошибка при запросе промокода: %w", err)
+	}
+	return promo, nil
+}
+
+func (s *sqlDatabase) AddPriceCampaign(ctx context.Context, campaign PriceCampaign) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "AddPriceCampaign")
+	defer func() { tracing.End(span, err) }()
+
+	if err := campaign.validate(); err != nil {
+		return 0, err
+	}
+	var productId int64
+	err = s.conn().QueryRowContext(ctx, "SELECT id FROM products WHERE name=$1", campaign.Item).Scan(&productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: %s", ErrProductNotFound, campaign.Item)
+		}
+		return 0, fmt.Errorf("ошибка при запросе товара: %w", err)
+	}
+
+	// время хранится в UTC, как время переводов
+	var id int64
+	err = s.conn().QueryRowContext(ctx,
+		"INSERT INTO price_campaigns (name, product_id, price, starts_at, ends_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		campaign.Name, productId, campaign.Price, campaign.StartsAt.UTC(), campaign.EndsAt.UTC()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании акции: %w", s.mapError(err))
+	}
+	slog.DebugContext(ctx, "price campaign added", "price_campaign_id", id)
+	return id, nil
+}
+
+const priceCampaignQuery = `SELECT c.id, c.name, p.name, c.price, c.starts_at, c.ends_at
+FROM price_campaigns AS c JOIN products AS p ON p.id = c.product_id`
+
+func (s *sqlDatabase) GetPriceCampaigns(ctx context.Context, item string, since time.Time) (_ []PriceCampaign, err error) {
+	ctx, span := s.startSpan(ctx, "GetPriceCampaigns")
+	defer func() { tracing.End(span, err) }()
+
+	// акции меняются редко, как и каталог, поэтому их можно читать с реплики
+	rows, err := s.reader(ctx, 0).QueryContext(ctx,
+		priceCampaignQuery+" WHERE ($1 = '' OR p.name = $1) AND c.ends_at > $2 ORDER BY p.name, c.starts_at, c.id",
+		item, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе акций: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := []PriceCampaign{}
+	for rows.Next() {
+		var c PriceCampaign
+		if err := rows.Scan(&c.Id, &c.Name, &c.Item, &c.Price, &c.StartsAt, &c.EndsAt); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении акций: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении акций: %w", err)
+	}
+	return campaigns, nil
+}
+
+func (s *sqlDatabase) DeletePriceCampaign(ctx context.Context, id int64) (_ *PriceCampaign, err error) {
+	ctx, span := s.startSpan(ctx, "DeletePriceCampaign")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	var c PriceCampaign
+	err = q.QueryRowContext(ctx, priceCampaignQuery+" WHERE c.id = $1", id).Scan(&c.Id, &c.Name, &c.Item, &c.Price, &c.StartsAt, &c.EndsAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrPriceCampaignNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при запросе акции: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM price_campaigns WHERE id=$1", id); err != nil {
+		return nil, fmt.Errorf("ошибка при удалении акции: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	slog.DebugContext(ctx, "price campaign deleted", "price_campaign_id", id)
+	return &c, nil
+}
+
+// spentGrant -- сколько монет начисления id потрачено одним списанием
+type spentGrant struct {
+	id     int64
+	amount float64
+}
+
+// spendGrants списывает amount с монет пользователя, которые могут сгореть, в порядке начисления: они тратятся
+// раньше бессрочных. Вызывается в транзакции сразу после уменьшения баланса, поэтому строка пользователя уже
+// заблокирована, а непотраченных монет со сроком не больше баланса
+func spendGrants(ctx context.Context, q tracedQuerier, userId int64, amount float64) ([]spentGrant, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, remaining FROM coin_grants WHERE user_id = $1 AND remaining > 0 ORDER BY id", userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе начислений: %w", err)
+	}
+	var spent []spentGrant
+	for amount > 0 && rows.Next() {
+		var sp spentGrant
+		if err := rows.Scan(&sp.id, &sp.amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка при чтении начислений: %w", err)
+		}
+		sp.amount = min(sp.amount, amount)
+		amount -= sp.amount
+		spent = append(spent, sp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении начислений: %w", err)
+	}
+
+	for _, sp := range spent {
+		if _, err := q.ExecContext(ctx, "UPDATE coin_grants SET remaining = remaining - $1 WHERE id = $2", sp.amount, sp.id); err != nil {
+			return nil, fmt.Errorf("ошибка при списании начисления: %w", err)
+		}
+	}
+	return spent, nil
+}
+
+// addPurchaseGrants сохраняет, какими монетами со сроком оплачена покупка, для возврата
+func addPurchaseGrants(ctx context.Context, q tracedQuerier, purchaseId int64, spent []spentGrant) error {
+	for _, sp := range spent {
+		_, err := q.ExecContext(ctx, "INSERT INTO purchase_grants (purchase_id, grant_id, amount) VALUES ($1, $2, $3)", purchaseId, sp.id, sp.amount)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении оплаты начислением: %w", err)
+		}
+	}
+	return nil
+}
+
+// carryGrants передаёт получателю перевода монеты со сроком, которыми перевод оплачен: каждая часть становится
+// начислением получателя с тем же сроком, иначе переводы туда и обратно делали бы сгорающие монеты бессрочными
+func carryGrants(ctx context.Context, q tracedQuerier, userId, transactionId int64, spent []spentGrant) error {
+	for _, sp := range spent {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO coin_grants (user_id, amount, reason, expires_at, remaining, transaction_id)
+			SELECT $1, $2, reason, expires_at, $2, $3 FROM coin_grants WHERE id = $4`,
+			userId, sp.amount, transactionId, sp.id)
+		if err != nil {
+			return fmt.Errorf("ошибка при передаче начисления: %w", err)
+		}
+	}
+	return nil
+}
+
+// grantCoins начисляет монеты в транзакции q и возвращает id получателя
+func (s *sqlDatabase) grantCoins(ctx context.Context, q tracedQuerier, grant Grant, scheduleId sql.NullInt64) (int64, error) {
+	if grant.Amount <= 0 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidAmount, grant.Amount)
+	}
+	var userId int64
+	err := q.QueryRowContext(ctx, "UPDATE users SET balance = balance + $1 WHERE name=$2 RETURNING id", grant.Amount, grant.Username).Scan(&userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: %s", ErrUserNotFound, grant.Username)
+		}
+		return 0, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+	}
+
+	// у бессрочных монет нечему сгорать, поэтому их остаток не отслеживается
+	var expiresAt sql.NullTime
+	var remaining float64
+	if grant.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: grant.ExpiresAt.UTC(), Valid: true}
+		remaining = grant.Amount
+	}
+	_, err = q.ExecContext(ctx,
+		"INSERT INTO coin_grants (user_id, amount, reason, expires_at, remaining, schedule_id) VALUES ($1, $2, $3, $4, $5, $6)",
+		userId, grant.Amount, grant.Reason, expiresAt, remaining, scheduleId)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при записи начисления: %w", s.mapError(err))
+	}
+	return userId, nil
+}
+
+func (s *sqlDatabase) GrantCoins(ctx context.Context, grants []Grant) (err error) {
+	ctx, span := s.startSpan(ctx, "GrantCoins")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// строки пользователей блокируются в порядке имён, как в SendCoins
+	order := make([]int, len(grants))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return grants[order[i]].Username < grants[order[j]].Username })
+	userIds := make([]int64, 0, len(grants))
+	for _, i := range order {
+		userId, err := s.grantCoins(ctx, q, grants[i], sql.NullInt64{})
+		if err != nil {
+			return &GrantError{Index: i, Username: grants[i].Username, Err: err}
+		}
+		userIds = append(userIds, userId)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userIds...)
+	slog.DebugContext(ctx, "coins granted", "grants", len(grants))
+	return nil
+}
+
+func (s *sqlDatabase) ExpireGrants(ctx context.Context, now time.Time) (_ float64, err error) {
+	ctx, span := s.startSpan(ctx, "ExpireGrants")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT DISTINCT user_id FROM coin_grants WHERE remaining > 0 AND expires_at <= $1 ORDER BY user_id", now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при запросе начислений: %w", err)
+	}
+	var userIds []int64
+	for rows.Next() {
+		var userId int64
+		if err := rows.Scan(&userId); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("ошибка при чтении начислений: %w", err)
+		}
+		userIds = append(userIds, userId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("ошибка при чтении начислений: %w", err)
+	}
+
+	var total float64
+	for _, userId := range userIds {
+		expired, err := s.expireUserGrants(ctx, userId, now)
+		if err != nil {
+			return total, err
+		}
+		total += expired
+	}
+	return total, nil
+}
+
+// expireUserGrants сжигает истёкшие монеты одного пользователя. У каждого пользователя своя транзакция,
+// чтобы не держать блокировки всех пользователей разом
+func (s *sqlDatabase) expireUserGrants(ctx context.Context, userId int64, now time.Time) (float64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// строка пользователя блокируется раньше начислений, как при списаниях, иначе параллельная покупка
+	// потратила бы уже сгоревшие монеты
+	if _, err := q.ExecContext(ctx, "UPDATE users SET balance = balance WHERE id=$1", userId); err != nil {
+		return 0, fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+	// сумма считается отдельно: после возврата покупки начисление может сгорать повторно, и expired
+	// в RETURNING содержал бы и прошлые сгоревшие монеты
+	var expired float64
+	err = q.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(remaining), 0) FROM coin_grants WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2",
+		userId, now.UTC()).Scan(&expired)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при запросе сгорающих монет: %w", err)
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+	_, err = q.ExecContext(ctx,
+		"UPDATE coin_grants SET expired = expired + remaining, remaining = 0 WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2",
+		userId, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сжигании монет: %w", err)
+	}
+
+	// непотраченных монет со сроком не больше баланса, поэтому баланс не уходит в минус
+	if _, err := q.ExecContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id=$2", expired, userId); err != nil {
+		return 0, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId)
+	slog.DebugContext(ctx, "coins expired", "user_id", userId, "amount", expired)
+	return expired, nil
+}
+
+const grantScheduleQuery = `SELECT id, name, amount, reason, interval_seconds, COALESCE(expires_after_seconds, 0),
+	runs, next_run_at, stopped_at
+FROM grant_schedules`
+
+// scanGrantSchedule читает расписание, выбранное запросом grantScheduleQuery, и число выполненных периодов
+func scanGrantSchedule(row interface{ Scan(...any) error }) (*GrantSchedule, int64, error) {
+	var g GrantSchedule
+	var interval, expiresAfter, runs int64
+	var stoppedAt sql.NullTime
+	if err := row.Scan(&g.Id, &g.Name, &g.Amount, &g.Reason, &interval, &expiresAfter, &runs, &g.NextRunAt, &stoppedAt); err != nil {
+		return nil, 0, err
+	}
+	g.Interval = time.Duration(interval) * time.Second
+	g.ExpiresAfter = time.Duration(expiresAfter) * time.Second
+	if stoppedAt.Valid {
+		g.StoppedAt = &stoppedAt.Time
+	}
+	return &g, runs, nil
+}
+
+// grantScheduleUsers возвращает получателей по id расписания, id = 0 -- получателей всех расписаний
+func grantScheduleUsers(ctx context.Context, q tracedQuerier, id int64) (map[int64][]string, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT r.schedule_id, u.name FROM grant_schedule_users AS r JOIN users AS u ON u.id = r.user_id WHERE $1 = 0 OR r.schedule_id = $1 ORDER BY u.name",
+		id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе получателей: %w", err)
+	}
+	defer rows.Close()
+
+	users := map[int64][]string{}
+	for rows.Next() {
+		var scheduleId int64
+		var name string
+		if err := rows.Scan(&scheduleId, &name); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении получателей: %w", err)
+		}
+		users[scheduleId] = append(users[scheduleId], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении получателей: %w", err)
+	}
+	return users, nil
+}
+
+func (s *sqlDatabase) AddGrantSchedule(ctx context.Context, schedule GrantSchedule) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "AddGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	if err := schedule.validate(); err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	expiresAfter := sql.NullInt64{Int64: int64(schedule.ExpiresAfter / time.Second), Valid: schedule.ExpiresAfter > 0}
+	var id int64
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO grant_schedules (name, amount, reason, interval_seconds, expires_after_seconds, next_run_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		schedule.Name, schedule.Amount, schedule.Reason, int64(schedule.Interval/time.Second), expiresAfter, schedule.NextRunAt.UTC()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании расписания: %w", s.mapError(err))
+	}
+	for i, name := range schedule.Usernames {
+		var userId int64
+		err = q.QueryRowContext(ctx, "SELECT id FROM users WHERE name=$1", name).Scan(&userId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, &GrantError{Index: i, Username: name, Err: fmt.Errorf("%w: %s", ErrUserNotFound, name)}
+			}
+			return 0, fmt.Errorf("ошибка при поиске получателя: %w", err)
+		}
+		_, err = q.ExecContext(ctx,
+			"INSERT INTO grant_schedule_users (schedule_id, user_id) VALUES ($1, $2) ON CONFLICT (schedule_id, user_id) DO NOTHING", id, userId)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка при записи получателя: %w", s.mapError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	slog.DebugContext(ctx, "grant schedule added", "grant_schedule_id", id)
+	return id, nil
+}
+
+func (s *sqlDatabase) GetGrantSchedules(ctx context.Context) (_ []GrantSchedule, err error) {
+	ctx, span := s.startSpan(ctx, "GetGrantSchedules")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx, grantScheduleQuery+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе расписаний: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []GrantSchedule{}
+	for rows.Next() {
+		schedule, _, err := scanGrantSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении расписаний: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении расписаний: %w", err)
+	}
+	rows.Close()
+
+	users, err := grantScheduleUsers(ctx, s.conn(), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range schedules {
+		schedules[i].Usernames = users[schedules[i].Id]
+	}
+	return schedules, nil
+}
+
+func (s *sqlDatabase) StopGrantSchedule(ctx context.Context, id int64) (_ *GrantSchedule, err error) {
+	ctx, span := s.startSpan(ctx, "StopGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx, "UPDATE grant_schedules SET stopped_at = $1 WHERE id = $2 AND stopped_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при остановке расписания: %w", err)
+	}
+	schedule, _, err := scanGrantSchedule(s.conn().QueryRowContext(ctx, grantScheduleQuery+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrGrantScheduleNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при запросе расписания: %w", err)
+	}
+	users, err := grantScheduleUsers(ctx, s.conn(), id)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Usernames = users[id]
+	slog.DebugContext(ctx, "grant schedule stopped", "grant_schedule_id", id)
+	return schedule, nil
+}
+
+func (s *sqlDatabase) RunGrantSchedule(ctx context.Context, now time.Time) (_ *GrantSchedule, _ int64, err error) {
+	ctx, span := s.startSpan(ctx, "RunGrantSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	schedule, runs, err := scanGrantSchedule(q.QueryRowContext(ctx,
+		grantScheduleQuery+" WHERE stopped_at IS NULL AND next_run_at <= $1 ORDER BY next_run_at, id LIMIT 1", now.UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("ошибка при запросе расписания: %w", err)
+	}
+
+	// период занимается увеличением счётчика выполненных периодов: если период уже выполнил другой
+	// экземпляр сервиса, счётчик изменился, и обновление не найдёт строку
+	res, err := q.ExecContext(ctx, "UPDATE grant_schedules SET runs = runs + 1, next_run_at = $1 WHERE id = $2 AND runs = $3",
+		schedule.NextRunAt.Add(schedule.Interval).UTC(), schedule.Id, runs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка при обновлении расписания: %w", err)
+	}
+	if changed, err := res.RowsAffected(); err != nil || changed == 0 {
+		return nil, 0, err
+	}
+
+	users, err := grantScheduleUsers(ctx, q, schedule.Id)
+	if err != nil {
+		return nil, 0, err
+	}
+	schedule.Usernames = users[schedule.Id]
+	recipients := schedule.Usernames
+	if len(recipients) == 0 {
+		rows, err := q.QueryContext(ctx, "SELECT name FROM users ORDER BY name")
+		if err != nil {
+			return nil, 0, fmt.Errorf("ошибка при запросе пользователей: %w", err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, 0, fmt.Errorf("ошибка при чтении пользователей: %w", err)
+			}
+			recipients = append(recipients, name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, 0, fmt.Errorf("ошибка при чтении пользователей: %w", err)
+		}
+	}
+
+	// получатели идут в порядке имён, как в GrantCoins
+	userIds := make([]int64, 0, len(recipients))
+	for _, name := range recipients {
+		userId, err := s.grantCoins(ctx, q, schedule.grant(name), sql.NullInt64{Int64: schedule.Id, Valid: true})
+		if err != nil {
+			return nil, 0, &GrantError{Index: len(userIds), Username: name, Err: err}
+		}
+		userIds = append(userIds, userId)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userIds...)
+	slog.DebugContext(ctx, "grant schedule run", "grant_schedule_id", schedule.Id, "period", schedule.NextRunAt, "recipients", len(userIds))
+	return schedule, int64(len(userIds)), nil
+}
+
+func (s *sqlDatabase) AdjustBalance(ctx context.Context, adjustment Adjustment) (_ *Adjustment, err error) {
+	ctx, span := s.startSpan(ctx, "AdjustBalance")
+	defer func() { tracing.End(span, err) }()
+
+	if err := adjustment.validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.inTx(tx)
+
+	// списание больше баланса отсекает ограничение users_balance_check
+	var userId int64
+	err = q.QueryRowContext(ctx, "UPDATE users SET balance = balance + $1 WHERE name=$2 RETURNING id, balance",
+		adjustment.Amount, adjustment.Username).Scan(&userId, &adjustment.Balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, adjustment.Username)
+		}
+		return nil, fmt.Errorf("ошибка при обновлении баланса: %w", s.mapError(err))
+	}
+	if adjustment.Amount < 0 {
+		if _, err := spendGrants(ctx, q, userId, -adjustment.Amount); err != nil {
+			return nil, err
+		}
+	}
+	adjustment.CreatedAt = time.Now().UTC()
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO balance_adjustments (user_id, amount, reason, admin, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		userId, adjustment.Amount, adjustment.Reason, adjustment.Admin, adjustment.CreatedAt).Scan(&adjustment.Id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении исправления: %w", s.mapError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при коммите: %w", err)
+	}
+	s.wrote(userId)
+	slog.DebugContext(ctx, "balance adjusted", "user_id", userId, "amount", adjustment.Amount, "balance", adjustment.Balance)
+	return &adjustment, nil
+}
+
+// getUserStatus читает баланс и блокировку пользователя из основной базы или транзакции q
+func getUserStatus(ctx context.Context, q tracedQuerier, username string) (*UserStatus, error) {
+	status := &UserStatus{Username: username}
+	var frozenAt sql.NullTime
+	err := q.QueryRowContext(ctx, "SELECT id, balance, frozen_at, frozen_by, frozen_reason FROM users WHERE name=$1", username).
+		Scan(&status.Id, &status.Balance, &frozenAt, &status.FrozenBy, &status.FrozenReason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+		}
+		return nil, fmt.Errorf("ошибка при запросе пользователя: %w", err)
+	}
+	if frozenAt.Valid {
+		status.FrozenAt = &frozenAt.Time
+	}
+	return status, nil
+}
+
+func (s *sqlDatabase) GetUserStatus(ctx context.Context, username string) (_ *UserStatus, err error) {
+	ctx, span := s.startSpan(ctx, "GetUserStatus")
+	defer func() { tracing.End(span, err) }()
+
+	return getUserStatus(ctx, s.conn(), username)
+}
+
+func (s *sqlDatabase) FreezeUser(ctx context.Context, username, by, reason string) (_ *UserStatus, err error) {
+	ctx, span := s.startSpan(ctx, "FreezeUser")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx,
+		"UPDATE users SET frozen_at = $1, frozen_by = $2, frozen_reason = $3 WHERE name = $4 AND frozen_at IS NULL",
+		time.Now().UTC(), by, reason, username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+	status, err := getUserStatus(ctx, s.conn(), username)
+	if err != nil {
+		return nil, err
+	}
+	s.wrote(status.Id)
+	slog.DebugContext(ctx, "user frozen", "frozen_user_id", status.Id)
+	return status, nil
+}
+
+func (s *sqlDatabase) UnfreezeUser(ctx context.Context, username string) (_ *UserStatus, err error) {
+	ctx, span := s.startSpan(ctx, "UnfreezeUser")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx,
+		"UPDATE users SET frozen_at = NULL, frozen_by = '', frozen_reason = '' WHERE name = $1 AND frozen_at IS NOT NULL", username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при снятии блокировки: %w", err)
+	}
+	status, err := getUserStatus(ctx, s.conn(), username)
+	if err != nil {
+		return nil, err
+	}
+	s.wrote(status.Id)
+	slog.DebugContext(ctx, "user unfrozen", "frozen_user_id", status.Id)
+	return status, nil
+}
+
+func (s *sqlDatabase) AddAdminAction(ctx context.Context, action AdminAction) (err error) {
+	ctx, span := s.startSpan(ctx, "AddAdminAction")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx,
+		"INSERT INTO admin_actions (admin, action, method, path, status, request_id, body, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		action.Admin, action.Action, action.Method, action.Path, action.Status, action.RequestId, action.Body, action.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("ошибка при записи действия администратора: %w", s.mapError(err))
+	}
+	return nil
+}
+
+func (s *sqlDatabase) GetAdminActions(ctx context.Context, filter AdminActionFilter) (_ []AdminAction, err error) {
+	ctx, span := s.startSpan(ctx, "GetAdminActions")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT id, admin, action, method, path, status, request_id, body, created_at FROM admin_actions"+
+			" WHERE ($1 = '' OR admin = $1) AND ($2 = 0 OR id < $2) ORDER BY id DESC LIMIT $3",
+		filter.Admin, filter.Before, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе журнала: %w", err)
+	}
+	defer rows.Close()
+
+	actions := make([]AdminAction, 0)
+	for rows.Next() {
+		var a AdminAction
+		if err := rows.Scan(&a.Id, &a.Admin, &a.Action, &a.Method, &a.Path, &a.Status, &a.RequestId, &a.Body, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении журнала: %w", err)
+		}
+		actions = append(actions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении журнала: %w", err)
+	}
+	return actions, nil
+}
+
+func (s *sqlDatabase) GetSentTransfers(ctx context.Context, userId int64, since time.Time) (_ []Transfer, err error) {
+	ctx, span := s.startSpan(ctx, "GetSentTransfers")
+	defer func() { tracing.End(span, err) }()
+
+	// правила переводов не должны пропускать только что сделанные переводы, поэтому читаем из основной базы.
+	// Время передаётся в UTC: SQLite сравнивает его со временем перевода как строку
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT u.name, t.amount, t.created_at FROM transactions AS t JOIN users AS u ON u.id = t.dst WHERE t.src = $1 AND t.created_at >= $2 ORDER BY t.id",
+		userId, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	transfers := make([]Transfer, 0)
+	for rows.Next() {
+		var t Transfer
+		if err := rows.Scan(&t.ToUser, &t.Amount, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при получении переводов: %w", err)
+		}
+		transfers = append(transfers, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	return transfers, nil
+}
+
+func (s *sqlDatabase) AddTransferReview(ctx context.Context, review TransferReview) (err error) {
+	ctx, span := s.startSpan(ctx, "AddTransferReview")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.conn().ExecContext(ctx,
+		"INSERT INTO transfer_reviews (user_id, to_user, amount, rule) VALUES ($1, $2, $3, $4)",
+		review.UserId, review.ToUser, review.Amount, review.Rule)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи отклонённого перевода: %w", s.mapError(err))
+	}
+
+	return nil
+}
+
+func (s *sqlDatabase) GetTransferReviews(ctx context.Context, userId int64) (_ []TransferReview, err error) {
+	ctx, span := s.startSpan(ctx, "GetTransferReviews")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT user_id, to_user, amount, rule, created_at FROM transfer_reviews WHERE user_id = $1 ORDER BY id",
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %v", err)
+	}
+	defer rows.Close()
+
+	reviews := make([]TransferReview, 0)
+	for rows.Next() {
+		var r TransferReview
+		if err := rows.Scan(&r.UserId, &r.ToUser, &r.Amount, &r.Rule, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при получении отклонённых переводов: %w", err)
+		}
+		reviews = append(reviews, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("итерации завершились с ошибкой: %v", err)
+	}
+
+	return reviews, nil
+}
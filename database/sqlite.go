@@ -0,0 +1,70 @@
+package database
+
+import (
+	"api-avito-shop/config"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SQLite struct {
+	*sqlDatabase
+}
+
+// NewSQLite открывает файл базы данных SQLite по пути cfg.Path, ":memory:" -- база в памяти процесса
+func NewSQLite(cfg config.Database) (*SQLite, error) {
+	// внешние ключи в SQLite включаются на каждом соединении; immediate-транзакции сразу берут
+	// блокировку на запись, иначе параллельные транзакции падали бы с SQLITE_BUSY при первом UPDATE
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_txlock", "immediate")
+	if cfg.Path != ":memory:" {
+		params.Add("_pragma", "journal_mode(WAL)")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии базы данных: %v", err)
+	}
+	if cfg.Path == ":memory:" {
+		// у каждого соединения была бы своя пустая база
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка при открытии базы данных: %v", err)
+	}
+	slog.Info("database opened", "path", cfg.Path)
+	return &SQLite{sqlDatabase: newSQLDatabase(db, semconv.DBSystemSqlite, mapSQLiteError)}, nil
+}
+
+// mapSQLiteError оборачивает нарушение CHECK-ограничения в соответствующую ошибку хранилища.
+// SQLite пишет имя ограничения только в текст ошибки: "CHECK constraint failed: users_balance_check"
+func mapSQLiteError(err error) error {
+	var liteErr *sqlite.Error
+	if !errors.As(err, &liteErr) || liteErr.Code() != sqlite3.SQLITE_CONSTRAINT_CHECK {
+		return err
+	}
+	_, name, ok := strings.Cut(liteErr.Error(), "CHECK constraint failed: ")
+	if !ok {
+		return err
+	}
+	name, _, _ = strings.Cut(name, " ")
+	if mapped, ok := constraintErrors[name]; ok {
+		return fmt.Errorf("%w: %v", mapped, err)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"api-avito-shop/tracing"
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// querier -- общий интерфейс *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// tracedQuerier открывает отдельный спан на каждый SQL-запрос.
+// Текст запроса пишется в атрибуты без значений параметров
+type tracedQuerier struct {
+	querier
+	system attribute.KeyValue
+}
+
+func traced(q querier, system attribute.KeyValue) tracedQuerier {
+	return tracedQuerier{querier: q, system: system}
+}
+
+func (t tracedQuerier) startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := query
+	if i := strings.IndexByte(query, ' '); i > 0 {
+		operation = query[:i]
+	}
+	return tracing.Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.system, semconv.DBOperationName(operation), semconv.DBQueryText(query)),
+	)
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.startStatement(ctx, query)
+	result, err := t.querier.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.startStatement(ctx, query)
+	rows, err := t.querier.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.startStatement(ctx, query)
+	row := t.querier.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// startMethodSpan открывает спан метода хранилища, внутри которого будут спаны отдельных запросов
+func startMethodSpan(ctx context.Context, system attribute.KeyValue, name string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "database."+name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(system, attribute.String("db.method", name)),
+	)
+}
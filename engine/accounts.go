@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleAdminAdjustBalance исправляет баланс пользователя: положительная сумма начисляет монеты,
+// отрицательная списывает. Исправление сохраняется с причиной и именем администратора и видно в истории пользователя
+func (e *Engine) HandleAdminAdjustBalance(ctx context.Context, username string, request models.AdjustmentRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminAdjustBalance")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("username", username))
+
+	switch {
+	case request.Amount == 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAdjustmentAmount}), nil
+	case request.Reason == "":
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAdjustmentReason}), nil
+	}
+
+	adjustment, err := e.db.AdjustBalance(ctx, database.Adjustment{
+		Username: username,
+		Amount:   float64(request.Amount),
+		Reason:   request.Reason,
+		Admin:    logging.Admin(ctx),
+	})
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + username}), nil
+	case errors.Is(err, database.ErrInsufficientFunds):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "adjust balance", "username", username, "amount", request.Amount, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorAdjustment}), nil
+	}
+	e.info.invalidate(username)
+	slog.InfoContext(ctx, "balance adjusted", "username", username, "amount", request.Amount, "balance", adjustment.Balance)
+	return models.Response(200, models.AdjustmentResponse{
+		Id:        adjustment.Id,
+		Username:  adjustment.Username,
+		Amount:    int32(adjustment.Amount),
+		Reason:    adjustment.Reason,
+		Admin:     adjustment.Admin,
+		Balance:   int32(adjustment.Balance),
+		CreatedAt: adjustment.CreatedAt,
+	}), nil
+}
+
+// HandleAdminUserStatus возвращает баланс пользователя и сведения о блокировке
+func (e *Engine) HandleAdminUserStatus(ctx context.Context, username string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminUserStatus")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("username", username))
+
+	status, err := e.db.GetUserStatus(ctx, username)
+	return e.userStatusResult(ctx, username, status, err, "get user status", ErrorUserStatus), nil
+}
+
+// HandleAdminFreezeUser блокирует пользователя: он не может войти, переводить монеты и предметы и покупать.
+// Полученные переводы и начисления продолжают зачисляться. Повторная блокировка сохраняет первую причину
+func (e *Engine) HandleAdminFreezeUser(ctx context.Context, username string, request models.FreezeRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminFreezeUser")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("username", username))
+
+	if request.Reason == "" {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorFreezeReason}), nil
+	}
+	status, err := e.db.FreezeUser(ctx, username, logging.Admin(ctx), request.Reason)
+	if err == nil {
+		// закешированная проверка пароля иначе пропустила бы пользователя до истечения cache.ttl
+		e.InvalidateUsers(ctx, username)
+		slog.InfoContext(ctx, "user frozen", "username", username, "reason", request.Reason)
+	}
+	return e.userStatusResult(ctx, username, status, err, "freeze user", ErrorFreeze), nil
+}
+
+// HandleAdminUnfreezeUser снимает блокировку пользователя
+func (e *Engine) HandleAdminUnfreezeUser(ctx context.Context, username string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminUnfreezeUser")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("username", username))
+
+	status, err := e.db.UnfreezeUser(ctx, username)
+	if err == nil {
+		slog.InfoContext(ctx, "user unfrozen", "username", username)
+	}
+	return e.userStatusResult(ctx, username, status, err, "unfreeze user", ErrorFreeze), nil
+}
+
+// userStatusResult переводит результат операции op над пользователем в ответ, msg -- текст ответа при ошибке хранилища
+func (e *Engine) userStatusResult(ctx context.Context, username string, status *database.UserStatus, err error, op, msg string) models.ImplResponse {
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + username})
+	case err != nil:
+		slog.ErrorContext(ctx, op, "username", username, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: msg})
+	}
+	return models.Response(200, models.UserStatusResponse{
+		Username:     status.Username,
+		Balance:      int32(status.Balance),
+		FrozenAt:     status.FrozenAt,
+		FrozenBy:     status.FrozenBy,
+		FrozenReason: status.FrozenReason,
+	})
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"api-avito-shop/cache"
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalance(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.AdjustBalanceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	cfg := config.Default()
+	cfg.Cache.InfoTTL = time.Minute
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg))
+	admin := logging.WithAdmin(context.Background(), "alice")
+	info := func() models.InfoResponse {
+		resp, _ := e.HandleApiInfo(ctx)
+		require.Equal(t, 200, resp.Code)
+		return resp.Body.(models.InfoResponse)
+	}
+	assert.Equal(t, int32(1000), info().Coins)
+
+	for _, tc := range []struct {
+		request models.AdjustmentRequest
+		errors  string
+	}{
+		{models.AdjustmentRequest{Amount: 0, Reason: "ошибка"}, ErrorAdjustmentAmount},
+		{models.AdjustmentRequest{Amount: 10}, ErrorAdjustmentReason},
+		{models.AdjustmentRequest{Amount: -2000, Reason: "ошибка"}, ErrorUserBalance},
+	} {
+		resp, _ := e.HandleAdminAdjustBalance(admin, "test_user1", tc.request)
+		assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: tc.errors}), resp)
+	}
+	resp, _ := e.HandleAdminAdjustBalance(admin, "unknown", models.AdjustmentRequest{Amount: 10, Reason: "ошибка"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + "unknown"}), resp)
+
+	// списание сбрасывает кеш сводки и видно в истории как перевод системе
+	resp, _ = e.HandleAdminAdjustBalance(admin, "test_user1", models.AdjustmentRequest{Amount: -300, Reason: "двойное начисление"})
+	require.Equal(t, 200, resp.Code)
+	adjustment := resp.Body.(models.AdjustmentResponse)
+	assert.Equal(t, int32(-300), adjustment.Amount)
+	assert.Equal(t, int32(700), adjustment.Balance)
+	assert.Equal(t, "alice", adjustment.Admin)
+	assert.Equal(t, int32(700), info().Coins)
+	assert.Equal(t, []models.InfoResponseCoinHistorySentInner{{ToUser: database.SystemUser, Amount: 300}}, info().CoinHistory.Sent)
+
+	resp, _ = e.HandleAdminAdjustBalance(admin, "test_user1", models.AdjustmentRequest{Amount: 50, Reason: "компенсация"})
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(750), info().Coins)
+	assert.Equal(t, []models.InfoResponseCoinHistoryReceivedInner{{FromUser: database.SystemUser, Amount: 50}}, info().CoinHistory.Received)
+}
+
+func TestFreezeUser(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.UserStatusKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SentTransfersKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb, WithCache(cache.NewLRU(100)))
+	admin := logging.WithAdmin(context.Background(), "alice")
+	resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+
+	resp, _ = e.HandleAdminFreezeUser(admin, "test_user1", models.FreezeRequest{})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorFreezeReason}), resp)
+	resp, _ = e.HandleAdminFreezeUser(admin, "unknown", models.FreezeRequest{Reason: "взлом"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + "unknown"}), resp)
+
+	resp, _ = e.HandleAdminFreezeUser(admin, "test_user1", models.FreezeRequest{Reason: "взлом"})
+	require.Equal(t, 200, resp.Code)
+	status := resp.Body.(models.UserStatusResponse)
+	require.NotNil(t, status.FrozenAt)
+	assert.Equal(t, "alice", status.FrozenBy)
+	assert.Equal(t, "взлом", status.FrozenReason)
+
+	// блокировка сбрасывает закешированную проверку пароля
+	frozen := models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen})
+	resp, _ = e.HandleApiInfo(ctx)
+	assert.Equal(t, frozen, resp)
+	resp, _ = e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.Equal(t, frozen, resp)
+
+	// заблокированному пользователю по-прежнему можно переводить монеты
+	user2 := context.Background()
+	resp, _ = e.HandleApiAuth(user2, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	addTokenToCtx(&user2, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiSendCoin(user2, models.SendCoinRequest{ToUser: "test_user1", Amount: 10})
+	assert.Equal(t, 200, resp.Code)
+
+	resp, _ = e.HandleAdminUserStatus(admin, "test_user1")
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(1010), resp.Body.(models.UserStatusResponse).Balance)
+
+	resp, _ = e.HandleAdminUnfreezeUser(admin, "test_user1")
+	require.Equal(t, 200, resp.Code)
+	assert.Nil(t, resp.Body.(models.UserStatusResponse).FrozenAt)
+	resp, _ = e.HandleApiInfo(ctx)
+	assert.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleAdminUserStatus(admin, "unknown")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + "unknown"}), resp)
+}
+
+func TestAccountsErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.AdjustBalanceKey).Return(errors.New("error"))
+	mockDb.On("ErrorWithDb", database.UserStatusKey).Return(errors.New("error"))
+	e := NewEngine(mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	resp, _ := e.HandleAdminAdjustBalance(admin, "test_user1", models.AdjustmentRequest{Amount: 10, Reason: "ошибка"})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorAdjustment}), resp)
+	resp, _ = e.HandleAdminUserStatus(admin, "test_user1")
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorUserStatus}), resp)
+	resp, _ = e.HandleAdminFreezeUser(admin, "test_user1", models.FreezeRequest{Reason: "взлом"})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorFreeze}), resp)
+	resp, _ = e.HandleAdminUnfreezeUser(admin, "test_user1")
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorFreeze}), resp)
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"log/slog"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// defaultAdminActions -- размер страницы журнала, если limit не указан
+	defaultAdminActions = 100
+	maxAdminActions     = 1000
+)
+
+// RecordAdminAction сохраняет запрос администратора в журнал. Администратор, имя маршрута и идентификатор
+// запроса берутся из контекста. Ответ уже отправлен, поэтому ошибка сохранения только логируется
+func (e *Engine) RecordAdminAction(ctx context.Context, method, path string, status int, body string) {
+	action := database.AdminAction{
+		Admin:     logging.Admin(ctx),
+		Action:    logging.Route(ctx),
+		Method:    method,
+		Path:      path,
+		Status:    status,
+		RequestId: logging.RequestID(ctx),
+		Body:      body,
+		CreatedAt: e.now(),
+	}
+	if err := e.db.AddAdminAction(ctx, action); err != nil {
+		slog.ErrorContext(ctx, "add admin action", "action", action.Action, "status", status, "error", err)
+	}
+}
+
+// HandleAdminActions возвращает страницу журнала действий администраторов от новых записей к старым.
+// Следующая страница запрашивается с before, равным id последней записи страницы
+func (e *Engine) HandleAdminActions(ctx context.Context, admin string, before int64, limit int32) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminActions")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("admin_action.admin", admin))
+
+	if limit == 0 {
+		limit = defaultAdminActions
+	}
+	switch {
+	case limit < 0 || limit > maxAdminActions:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAdminActionsLimit + strconv.Itoa(maxAdminActions)}), nil
+	case before < 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAdminActionsBefore}), nil
+	}
+
+	actions, err := e.db.GetAdminActions(ctx, database.AdminActionFilter{Admin: admin, Before: before, Limit: int(limit)})
+	if err != nil {
+		slog.ErrorContext(ctx, "get admin actions", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorAdminActions}), nil
+	}
+	body := models.AdminActionsResponse{Items: make([]models.AdminActionResponse, 0, len(actions))}
+	for _, a := range actions {
+		body.Items = append(body.Items, models.AdminActionResponse{
+			Id:        a.Id,
+			Admin:     a.Admin,
+			Action:    a.Action,
+			Method:    a.Method,
+			Path:      a.Path,
+			Status:    int32(a.Status),
+			RequestId: a.RequestId,
+			Body:      a.Body,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return models.Response(200, body), nil
+}
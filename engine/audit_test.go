@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminActions(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.AdminActionsKey).Return(nil)
+	e := NewEngine(mockDb)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	for _, admin := range []string{"alice", "bob", "alice"} {
+		ctx := logging.WithRoute(logging.WithRequestID(logging.WithAdmin(context.Background(), admin), "req-"+admin), "ApiAdminFreezeUser")
+		e.RecordAdminAction(ctx, "POST", "/api/admin/users/test_user1/freeze", 200, `{"reason":"взлом"}`)
+	}
+
+	admin := logging.WithAdmin(context.Background(), "alice")
+	resp, _ := e.HandleAdminActions(admin, "", 0, 0)
+	require.Equal(t, 200, resp.Code)
+	items := resp.Body.(models.AdminActionsResponse).Items
+	require.Len(t, items, 3)
+	assert.Equal(t, models.AdminActionResponse{
+		Id:        3,
+		Admin:     "alice",
+		Action:    "ApiAdminFreezeUser",
+		Method:    "POST",
+		Path:      "/api/admin/users/test_user1/freeze",
+		Status:    200,
+		RequestId: "req-alice",
+		Body:      `{"reason":"взлом"}`,
+		CreatedAt: now,
+	}, items[0])
+
+	// следующая страница начинается после последней записи предыдущей
+	resp, _ = e.HandleAdminActions(admin, "alice", 0, 1)
+	require.Equal(t, 200, resp.Code)
+	items = resp.Body.(models.AdminActionsResponse).Items
+	require.Len(t, items, 1)
+	resp, _ = e.HandleAdminActions(admin, "alice", items[0].Id, 1)
+	require.Equal(t, 200, resp.Code)
+	items = resp.Body.(models.AdminActionsResponse).Items
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(1), items[0].Id)
+
+	resp, _ = e.HandleAdminActions(admin, "", 0, maxAdminActions+1)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorAdminActionsLimit + "1000"}), resp)
+	resp, _ = e.HandleAdminActions(admin, "", -1, 10)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorAdminActionsBefore}), resp)
+}
+
+func TestAdminActionsErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.AdminActionsKey).Return(errors.New("error"))
+	e := NewEngine(mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	// ошибка записи журнала только логируется
+	e.RecordAdminAction(admin, "POST", "/api/admin/grants", 200, "")
+	resp, _ := e.HandleAdminActions(admin, "", 0, 0)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorAdminActions}), resp)
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/migrations"
+	"api-avito-shop/models"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkHandleApiInfo меряет /api/info поверх SQLite с кешем и без:
+//
+//	go test ./engine -run '^$' -bench HandleApiInfo
+func BenchmarkHandleApiInfo(b *testing.B) {
+	cfg := config.Default().Database
+	cfg.Path = filepath.Join(b.TempDir(), "shop.db")
+	db, err := database.NewSQLite(cfg)
+	require.NoError(b, err)
+	defer db.Close()
+	migrator, err := migrations.NewSQLite(db.DB())
+	require.NoError(b, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(b, err)
+
+	// логи покупок при подготовке мешают читать результаты
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		name := "NoCache"
+		if ttl > 0 {
+			name = "Cache"
+		}
+		cfg := config.Default()
+		cfg.Cache.InfoTTL = ttl
+		e := NewEngine(db, WithConfig(cfg))
+
+		ctx := context.Background()
+		resp, _ := e.HandleApiAuth(ctx, models.AuthRequest{Username: name, Password: "pass"})
+		require.Equal(b, 200, resp.Code)
+		addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
+		for _, item := range []string{"cup", "pen", "book"} {
+			resp, _ = e.HandleApiByuItem(ctx, item)
+			require.Equal(b, 200, resp.Code)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if resp, _ := e.HandleApiInfo(ctx); resp.Code != 200 {
+					b.Fatal(resp.Body)
+				}
+			}
+		})
+	}
+}
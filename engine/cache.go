@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"api-avito-shop/models"
+	"sync"
+	"time"
+)
+
+// maxInfoEntries ограничивает размер кеша: при переполнении сначала удаляются устаревшие записи,
+// а если их нет -- кеш очищается целиком
+const maxInfoEntries = 100_000
+
+type infoEntry struct {
+	info    *models.InfoResponse
+	version uint64
+	expires time.Time
+}
+
+// infoCache хранит ответы /api/info по имени пользователя не дольше ttl.
+// Покупки и переводы сбрасывают записи своих участников через invalidate. Чтобы ответ, прочитанный
+// из хранилища до сброса, не попал в кеш после него, сброс оставляет запись с новой версией без данных,
+// а put сохраняет ответ, только если версия не изменилась с момента get.
+// Кеш локален для процесса: при нескольких репликах сервиса получатель перевода, обслуженного
+// другой репликой, может видеть старый баланс до истечения ttl.
+// Методы безопасно вызывать на nil -- кеш выключен
+type infoCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]infoEntry
+	version uint64
+	// версия последнего сброса всего кеша, её получают пользователи без записи
+	flushed uint64
+	now     func() time.Time
+}
+
+func newInfoCache(ttl time.Duration) *infoCache {
+	return &infoCache{
+		ttl:     ttl,
+		entries: make(map[string]infoEntry),
+		now:     time.Now,
+	}
+}
+
+// get возвращает закешированный ответ или nil и версию, которую нужно передать в put
+func (c *infoCache) get(username string) (*models.InfoResponse, uint64) {
+	if c == nil {
+		return nil, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[username]
+	if !ok {
+		return nil, c.flushed
+	}
+	if c.now().After(entry.expires) {
+		return nil, entry.version
+	}
+	return entry.info, entry.version
+}
+
+// put сохраняет ответ, если запись пользователя не сбрасывали после get, вернувшего version
+func (c *infoCache) put(username string, info *models.InfoResponse, version uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.flushed
+	if entry, ok := c.entries[username]; ok {
+		current = entry.version
+	}
+	if current != version {
+		return
+	}
+	c.makeRoom()
+	c.entries[username] = infoEntry{info: info, version: version, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate сбрасывает записи пользователей, данные которых изменились
+func (c *infoCache) invalidate(usernames ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// записи без данных нужны, пока не завершатся чтения, начатые до сброса, ttl для этого с запасом
+	expires := c.now().Add(c.ttl)
+	for _, username := range usernames {
+		c.makeRoom()
+		c.version++
+		c.entries[username] = infoEntry{version: c.version, expires: expires}
+	}
+}
+
+func (c *infoCache) makeRoom() {
+	if len(c.entries) < maxInfoEntries {
+		return
+	}
+	now := c.now()
+	for username, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, username)
+		}
+	}
+	if len(c.entries) >= maxInfoEntries {
+		c.flush()
+	}
+}
+
+// invalidateAll сбрасывает записи всех пользователей, например когда меняются лимиты покупок
+func (c *infoCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flush()
+}
+
+// flush удаляет все записи; чтения, начатые до сброса, получили старую версию и не попадут в кеш
+func (c *infoCache) flush() {
+	c.version++
+	c.flushed = c.version
+	clear(c.entries)
+}
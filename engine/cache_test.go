@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"api-avito-shop/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfoCache(t *testing.T) {
+	now := time.Now()
+	c := newInfoCache(time.Minute)
+	c.now = func() time.Time { return now }
+	info := &models.InfoResponse{Coins: 100}
+
+	cached, version := c.get("user1")
+	assert.Nil(t, cached)
+	c.put("user1", info, version)
+	cached, _ = c.get("user1")
+	assert.Equal(t, info, cached)
+
+	// запись устаревает через ttl
+	now = now.Add(2 * time.Minute)
+	cached, _ = c.get("user1")
+	assert.Nil(t, cached)
+}
+
+func TestInfoCacheInvalidateDuringRead(t *testing.T) {
+	c := newInfoCache(time.Minute)
+	stale := &models.InfoResponse{Coins: 100}
+
+	// чтение началось до перевода, а закончилось после: устаревший ответ не должен попасть в кеш
+	_, version := c.get("user1")
+	c.invalidate("user1", "user2")
+	c.put("user1", stale, version)
+	cached, version := c.get("user1")
+	assert.Nil(t, cached)
+
+	fresh := &models.InfoResponse{Coins: 80}
+	c.put("user1", fresh, version)
+	cached, _ = c.get("user1")
+	assert.Equal(t, fresh, cached)
+}
+
+func TestInfoCacheInvalidateAll(t *testing.T) {
+	c := newInfoCache(time.Minute)
+	_, version := c.get("user1")
+	c.put("user1", &models.InfoResponse{Coins: 100}, version)
+
+	// чтение второго пользователя началось до сброса всего кеша
+	_, version = c.get("user2")
+	c.invalidateAll()
+	c.put("user2", &models.InfoResponse{Coins: 50}, version)
+	cached, _ := c.get("user1")
+	assert.Nil(t, cached)
+	cached, version = c.get("user2")
+	assert.Nil(t, cached)
+
+	fresh := &models.InfoResponse{Coins: 80}
+	c.put("user2", fresh, version)
+	cached, _ = c.get("user2")
+	assert.Equal(t, fresh, cached)
+}
+
+func TestInfoCacheDisabled(t *testing.T) {
+	var c *infoCache
+	c.put("user1", &models.InfoResponse{}, 0)
+	c.invalidate("user1")
+	c.invalidateAll()
+	cached, _ := c.get("user1")
+	assert.Nil(t, cached)
+	assert.Nil(t, NewEngine(nil).info)
+}
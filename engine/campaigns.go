@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleAdminAddPriceCampaign создаёт акцию: цену товара на время с startsAt до endsAt.
+// Закешированный товар сбрасывается, чтобы акция начала действовать без ожидания cache.ttl
+func (e *Engine) HandleAdminAddPriceCampaign(ctx context.Context, request models.PriceCampaignRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminAddPriceCampaign")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("item", request.Item))
+
+	switch {
+	case request.Price <= 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCampaignPrice}), nil
+	case !request.StartsAt.Before(request.EndsAt):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCampaignWindow}), nil
+	}
+	campaign := database.PriceCampaign{
+		Name:     request.Name,
+		Item:     request.Item,
+		Price:    float64(request.Price),
+		StartsAt: request.StartsAt,
+		EndsAt:   request.EndsAt,
+	}
+	id, err := e.db.AddPriceCampaign(ctx, campaign)
+	switch {
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + request.Item}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "add price campaign", "item", request.Item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), nil
+	}
+	campaign.Id = id
+	e.InvalidateProducts(ctx, request.Item)
+	slog.InfoContext(ctx, "price campaign added", "price_campaign_id", id, "item", request.Item, "price", request.Price,
+		"starts_at", request.StartsAt, "ends_at", request.EndsAt)
+	return models.Response(200, e.priceCampaignResponse(&campaign)), nil
+}
+
+// HandleAdminPriceCampaigns возвращает действующие и будущие акции, закончившиеся не показываются
+func (e *Engine) HandleAdminPriceCampaigns(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminPriceCampaigns")
+	defer func() { endSpan(span, result) }()
+
+	campaigns, err := e.db.GetPriceCampaigns(ctx, "", e.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "get price campaigns", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), nil
+	}
+	body := models.PriceCampaignsResponse{Items: make([]models.PriceCampaignResponse, 0, len(campaigns))}
+	for i := range campaigns {
+		body.Items = append(body.Items, e.priceCampaignResponse(&campaigns[i]))
+	}
+	return models.Response(200, body), nil
+}
+
+// HandleAdminDeletePriceCampaign удаляет акцию, товар снова продаётся по цене каталога.
+// Покупки, сделанные по акции, сохраняют уплаченную цену
+func (e *Engine) HandleAdminDeletePriceCampaign(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminDeletePriceCampaign")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.Int64("price_campaign.id", id))
+
+	campaign, err := e.db.DeletePriceCampaign(ctx, id)
+	if errors.Is(err, database.ErrPriceCampaignNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCampaignNotFound + strconv.FormatInt(id, 10)}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "delete price campaign", "price_campaign_id", id, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), nil
+	}
+	e.InvalidateProducts(ctx, campaign.Item)
+	slog.InfoContext(ctx, "price campaign deleted", "price_campaign_id", id, "item", campaign.Item)
+	return models.Response(200, e.priceCampaignResponse(campaign)), nil
+}
+
+func (e *Engine) priceCampaignResponse(campaign *database.PriceCampaign) models.PriceCampaignResponse {
+	return models.PriceCampaignResponse{
+		Id:       campaign.Id,
+		Name:     campaign.Name,
+		Item:     campaign.Item,
+		Price:    int32(campaign.Price),
+		StartsAt: campaign.StartsAt,
+		EndsAt:   campaign.EndsAt,
+		Active:   campaign.Active(e.now()),
+	}
+}
+
+// activeSale возвращает акцию, действующую в момент now, или nil.
+// Из нескольких одновременных акций действует акция с самой низкой ценой
+func activeSale(campaigns []database.PriceCampaign, now time.Time) *database.PriceCampaign {
+	var sale *database.PriceCampaign
+	for i := range campaigns {
+		if campaigns[i].Active(now) && (sale == nil || campaigns[i].Price < sale.Price) {
+			sale = &campaigns[i]
+		}
+	}
+	return sale
+}
+
+// activeSales возвращает действующие в момент now акции по имени товара
+func (e *Engine) activeSales(ctx context.Context, now time.Time) (map[string]*database.PriceCampaign, error) {
+	campaigns, err := e.db.GetPriceCampaigns(ctx, "", now)
+	if err != nil {
+		return nil, err
+	}
+	byItem := make(map[string][]database.PriceCampaign)
+	for _, c := range campaigns {
+		byItem[c.Item] = append(byItem[c.Item], c)
+	}
+	sales := make(map[string]*database.PriceCampaign, len(byItem))
+	for item, campaigns := range byItem {
+		if sale := activeSale(campaigns, now); sale != nil {
+			sales[item] = sale
+		}
+	}
+	return sales, nil
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"api-avito-shop/cache"
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceCampaigns(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.CartKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.CheckoutKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	now := time.Now()
+	ends := now.Add(7 * 24 * time.Hour)
+	resp, _ := e.HandleAdminAddPriceCampaign(admin, models.PriceCampaignRequest{Name: "half", Item: "t-shirt", Price: 50, StartsAt: now.Add(-time.Hour), EndsAt: ends})
+	require.Equal(t, 200, resp.Code)
+	campaign := resp.Body.(models.PriceCampaignResponse)
+	assert.Equal(t, int32(50), campaign.Price)
+	assert.True(t, campaign.Active)
+
+	for _, tc := range []struct {
+		request models.PriceCampaignRequest
+		code    int
+		errors  string
+	}{
+		{models.PriceCampaignRequest{Name: "bad", Item: "cup", Price: -1, StartsAt: now, EndsAt: ends}, 400, ErrorCampaignPrice},
+		{models.PriceCampaignRequest{Name: "bad", Item: "cup", Price: 10, StartsAt: ends, EndsAt: now}, 400, ErrorCampaignWindow},
+		{models.PriceCampaignRequest{Name: "bad", Item: "unknown", Price: 10, StartsAt: now, EndsAt: ends}, 400, ErrorProductNotFound + "unknown"},
+	} {
+		resp, _ = e.HandleAdminAddPriceCampaign(admin, tc.request)
+		assert.Equal(t, models.Response(tc.code, models.ErrorResponse{Errors: tc.errors}), resp)
+	}
+
+	// каталог показывает цену каталога, цену по акции и окончание акции
+	resp, _ = e.HandleApiProducts(ctx)
+	require.Equal(t, 200, resp.Code)
+	for _, item := range resp.Body.(models.CatalogResponse).Items {
+		if item.Name != "t-shirt" {
+			assert.Nil(t, item.Sale, item.Name)
+			continue
+		}
+		assert.Equal(t, int32(100), item.Price)
+		require.NotNil(t, item.Sale)
+		assert.Equal(t, models.CatalogItemSale{Name: "half", Price: 50, EndsAt: campaign.EndsAt}, *item.Sale)
+	}
+
+	// покупка, корзина и оформление корзины идут по цене акции
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(50), resp.Body.(models.PurchaseResponse).Price)
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "t-shirt", Quantity: 2})
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(100), resp.Body.(models.CartResponse).Total)
+	resp, _ = e.HandleApiCheckout(ctx)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(100), resp.Body.(models.ReceiptResponse).Total)
+	coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+	require.NoError(t, err)
+	assert.Equal(t, float64(850), coins)
+
+	// после окончания акции товар снова продаётся по цене каталога
+	e.now = func() time.Time { return ends.Add(time.Minute) }
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(100), resp.Body.(models.PurchaseResponse).Price)
+	resp, _ = e.HandleAdminPriceCampaigns(admin)
+	require.Equal(t, 200, resp.Code)
+	assert.Empty(t, resp.Body.(models.PriceCampaignsResponse).Items)
+	e.now = time.Now
+
+	resp, _ = e.HandleAdminPriceCampaigns(admin)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, []models.PriceCampaignResponse{campaign}, resp.Body.(models.PriceCampaignsResponse).Items)
+
+	resp, _ = e.HandleAdminDeletePriceCampaign(admin, campaign.Id)
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(100), resp.Body.(models.PurchaseResponse).Price)
+	resp, _ = e.HandleAdminDeletePriceCampaign(admin, campaign.Id)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorCampaignNotFound + "1"}), resp)
+}
+
+func TestPriceCampaignsCached(t *testing.T) {
+	mockDb := database.NewMockDb()
+	e, ctx := newProductsEngine(t, mockDb, WithCache(cache.NewLRU(100)))
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	now := time.Now()
+	starts, ends := now.Add(time.Hour), now.Add(2*time.Hour)
+	resp, _ := e.HandleAdminAddPriceCampaign(admin, models.PriceCampaignRequest{Name: "cheap", Item: "cup", Price: 10, StartsAt: starts, EndsAt: ends})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleAdminAddPriceCampaign(admin, models.PriceCampaignRequest{Name: "cheaper", Item: "cup", Price: 5, StartsAt: starts, EndsAt: starts.Add(time.Minute)})
+	require.Equal(t, 200, resp.Code)
+
+	resp, _ = e.HandleApiByuItem(ctx, "cup")
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(20), resp.Body.(models.PurchaseResponse).Price)
+
+	// товар с акциями уже в кеше: цена меняется по расписанию акций без обращения к хранилищу,
+	// из одновременных акций действует самая низкая цена
+	mockDb.ExpectedCalls = nil
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
+	for _, tc := range []struct {
+		at    time.Time
+		price int32
+	}{{starts, 5}, {starts.Add(time.Minute), 10}, {ends, 20}} {
+		e.now = func() time.Time { return tc.at }
+		resp, _ = e.HandleApiByuItem(ctx, "cup")
+		require.Equal(t, 200, resp.Code)
+		assert.Equal(t, tc.price, resp.Body.(models.PurchaseResponse).Price, tc.at)
+	}
+}
+
+func TestPriceCampaignBalance(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"cold cache", nil},
+		{"warm cache", []Option{WithCache(cache.NewLRU(100))}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDb := database.NewMockDb()
+			mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+			mockDb.On("ErrorWithDb", database.AdjustBalanceKey).Return(nil)
+			e, ctx := newProductsEngine(t, mockDb, tc.opts...)
+			admin := logging.WithAdmin(context.Background(), "alice")
+			now := time.Now()
+			resp, _ := e.HandleAdminAddPriceCampaign(admin, models.PriceCampaignRequest{Name: "half", Item: "t-shirt", Price: 50, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+			require.Equal(t, 200, resp.Code)
+			adjust := func(amount float64) {
+				_, err := mockDb.AdjustBalance(ctx, database.Adjustment{Username: "test_user1", Amount: amount, Reason: "test"})
+				require.NoError(t, err)
+			}
+
+			// баланса хватает на цену по акции, но не на цену каталога; во второй раз товар уже может быть в кеше
+			adjust(-920)
+			resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+			require.Equal(t, 200, resp.Code)
+			assert.Equal(t, int32(50), resp.Body.(models.PurchaseResponse).Price)
+			adjust(50)
+			resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+			require.Equal(t, 200, resp.Code)
+			assert.Equal(t, int32(50), resp.Body.(models.PurchaseResponse).Price)
+		})
+	}
+}
+
+func TestPriceCampaignsErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+	now := time.Now()
+	mockDb.ExpectedCalls = nil
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(errors.New("error"))
+
+	resp, _ := e.HandleAdminAddPriceCampaign(admin, models.PriceCampaignRequest{Name: "sale", Item: "cup", Price: 10, StartsAt: now, EndsAt: now.Add(time.Hour)})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), resp)
+	resp, _ = e.HandleAdminPriceCampaigns(admin)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), resp)
+	resp, _ = e.HandleAdminDeletePriceCampaign(admin, 1)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorCampaign}), resp)
+	resp, _ = e.HandleApiProducts(ctx)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorProducts}), resp)
+	resp, _ = e.HandleApiByuItem(ctx, "cup")
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), resp)
+}
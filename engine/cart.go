@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Корзина оформляется одной транзакцией, а каждая единица товара сохраняется отдельной покупкой,
+// поэтому в позиции может быть не больше maxCartLine единиц, а во всей корзине -- не больше maxCartUnits
+const (
+	maxCartLine  = 100
+	maxCartUnits = 500
+)
+
+// HandleApiCart возвращает корзину пользователя с текущими ценами, включая цены по акциям
+func (e *Engine) HandleApiCart(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiCart")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	return e.cart(ctx, data.Id), nil
+}
+
+// HandleApiAddToCart добавляет товар в корзину, повторное добавление увеличивает количество.
+// Остаток, лимит и баланс проверяются только при оформлении
+func (e *Engine) HandleApiAddToCart(ctx context.Context, request models.CartItemRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiAddToCart")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id), attribute.String("item", request.Item))
+
+	if request.Quantity <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorItemQuantity}), nil
+	}
+	cart, err := e.db.GetCart(ctx, data.Id)
+	if err != nil {
+		slog.ErrorContext(ctx, "get cart", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart}), nil
+	}
+	if msg := cartLimitError(cart, request.Item, int64(request.Quantity)); msg != "" {
+		return models.Response(400, models.ErrorResponse{Errors: msg}), nil
+	}
+	err = e.db.AddToCart(ctx, data.Id, request.Item, int64(request.Quantity))
+	switch {
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + request.Item}), nil
+	case errors.Is(err, database.ErrInvalidAmount):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCartQuantity + request.Item}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "add to cart", "item", request.Item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart}), nil
+	}
+	return e.cart(ctx, data.Id), nil
+}
+
+// HandleApiRemoveFromCart убирает товар из корзины целиком
+func (e *Engine) HandleApiRemoveFromCart(ctx context.Context, item string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiRemoveFromCart")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id), attribute.String("item", item))
+
+	removed, err := e.db.RemoveFromCart(ctx, data.Id, item)
+	if err != nil {
+		slog.ErrorContext(ctx, "remove from cart", "item", item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart}), nil
+	}
+	if !removed {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorNotInCart + item}), nil
+	}
+	return e.cart(ctx, data.Id), nil
+}
+
+// HandleApiCheckout покупает всё содержимое корзины одной транзакцией и возвращает чек.
+// Если не хватает монет, товар закончился или превышен лимит, не покупается ничего и корзина остаётся
+func (e *Engine) HandleApiCheckout(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiCheckout")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	// лимиты проверяются и при оформлении: корзину могли собрать одновременными запросами
+	cart, err := e.db.GetCart(ctx, data.Id)
+	if err != nil {
+		slog.ErrorContext(ctx, "get cart", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart}), nil
+	}
+	if msg := cartLimitError(cart, "", 0); msg != "" {
+		return models.Response(400, models.ErrorResponse{Errors: msg}), nil
+	}
+
+	// цены по акциям выбираются в момент оформления, как при покупке одного товара
+	sales, err := e.activeSales(ctx, e.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "get price campaigns", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart}), nil
+	}
+	prices := make(map[string]float64, len(sales))
+	for item, sale := range sales {
+		prices[item] = sale.Price
+	}
+	receipt, err := e.db.Checkout(ctx, data.Id, prices)
+	if errors.Is(err, database.ErrCartEmpty) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCartEmpty}), nil
+	}
+	if err != nil {
+		// ошибка позиции переводится в ответ так же, как при покупке одного товара
+		var itemErr *database.ItemError
+		var item string
+		if errors.As(err, &itemErr) {
+			item = itemErr.Item
+		}
+		return e.purchaseError(ctx, item, err), nil
+	}
+	e.info.invalidate(data.Username)
+	slog.InfoContext(ctx, "cart checked out", "receipt_id", receipt.Id, "total", receipt.Total)
+
+	body := models.ReceiptResponse{
+		Id:        receipt.Id,
+		Items:     make([]models.ReceiptResponseItemsInner, 0, len(receipt.Items)),
+		Total:     int32(receipt.Total),
+		Balance:   int32(receipt.Balance),
+		CreatedAt: receipt.CreatedAt,
+	}
+	for _, item := range receipt.Items {
+		for range item.Quantity {
+			e.metrics.ItemBought(item.Item)
+		}
+		body.Items = append(body.Items, models.ReceiptResponseItemsInner{
+			Item:        item.Item,
+			Quantity:    int32(item.Quantity),
+			Price:       int32(item.Price),
+			PurchaseIds: item.PurchaseIds,
+		})
+	}
+	return models.Response(200, body), nil
+}
+
+// cartLimitError проверяет лимиты корзины cart после добавления quantity единиц товара item
+// и возвращает текст ошибки, пустая строка -- лимиты не превышены
+func cartLimitError(cart []database.CartItem, item string, quantity int64) string {
+	units, line := quantity, quantity
+	for _, c := range cart {
+		units += c.Quantity
+		if c.Item == item {
+			line += c.Quantity
+		} else if c.Quantity > maxCartLine {
+			return ErrorCartQuantity + c.Item + ", не больше " + strconv.Itoa(maxCartLine)
+		}
+	}
+	switch {
+	case line > maxCartLine:
+		return ErrorCartQuantity + item + ", не больше " + strconv.Itoa(maxCartLine)
+	case units > maxCartUnits:
+		return ErrorCartTooLarge + strconv.Itoa(maxCartUnits)
+	}
+	return ""
+}
+
+// cart возвращает ответ с корзиной пользователя, цены товаров по акциям заменяют цены каталога
+func (e *Engine) cart(ctx context.Context, userId int64) models.ImplResponse {
+	cart, err := e.db.GetCart(ctx, userId)
+	if err != nil {
+		slog.ErrorContext(ctx, "get cart", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart})
+	}
+	sales, err := e.activeSales(ctx, e.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "get price campaigns", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorCart})
+	}
+	body := models.CartResponse{Items: make([]models.CartResponseItemsInner, 0, len(cart))}
+	var total float64
+	for _, item := range cart {
+		if sale, ok := sales[item.Item]; ok {
+			item.Price = sale.Price
+		}
+		body.Items = append(body.Items, models.CartResponseItemsInner{Item: item.Item, Quantity: int32(item.Quantity), Price: int32(item.Price)})
+		total += item.Price * float64(item.Quantity)
+	}
+	body.Total = int32(total)
+	return models.Response(200, body)
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartCheckout(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.CartKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.CheckoutKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.ProductStockKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	e.info = newInfoCache(time.Minute)
+
+	resp, _ := e.HandleApiCheckout(ctx)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorCartEmpty}), resp)
+
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: 2})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "t-shirt", Quantity: 1})
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, models.CartResponse{
+		Items: []models.CartResponseItemsInner{{Item: "cup", Quantity: 2, Price: 20}, {Item: "t-shirt", Quantity: 1, Price: 100}},
+		Total: 140,
+	}, resp.Body)
+
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "unknown", Quantity: 1})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"}), resp)
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: -1})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorItemQuantity}), resp)
+	resp, _ = e.HandleApiRemoveFromCart(ctx, "something_else")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorNotInCart + "something_else"}), resp)
+
+	// сводка попадает в кеш до оформления
+	resp, _ = e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+
+	// закончившийся товар отменяет оформление всей корзины
+	zero := int64(0)
+	require.NoError(t, mockDb.SetProductStock(ctx, "t-shirt", &zero))
+	resp, _ = e.HandleApiCheckout(ctx)
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: ErrorSoldOut + "t-shirt"}), resp)
+	require.NoError(t, mockDb.SetProductStock(ctx, "t-shirt", nil))
+
+	resp, _ = e.HandleApiCheckout(ctx)
+	require.Equal(t, 200, resp.Code)
+	receipt := resp.Body.(models.ReceiptResponse)
+	assert.Equal(t, int32(140), receipt.Total)
+	assert.Equal(t, int32(860), receipt.Balance)
+	require.Len(t, receipt.Items, 2)
+	assert.Len(t, receipt.Items[0].PurchaseIds, 2)
+
+	resp, _ = e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+	info := resp.Body.(models.InfoResponse)
+	assert.Equal(t, int32(860), info.Coins)
+	assert.ElementsMatch(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 2}, {Type: "t-shirt", Quantity: 1}}, info.Inventory)
+
+	resp, _ = e.HandleApiCart(ctx)
+	assert.Equal(t, models.Response(200, models.CartResponse{Items: []models.CartResponseItemsInner{}}), resp)
+
+	// корзина дороже баланса не покупается
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "t-shirt", Quantity: 9})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiCheckout(ctx)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), resp)
+	resp, _ = e.HandleApiRemoveFromCart(ctx, "t-shirt")
+	assert.Equal(t, models.Response(200, models.CartResponse{Items: []models.CartResponseItemsInner{}}), resp)
+}
+
+func TestCartLimits(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.CartKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.CheckoutKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	lineError := func(item string) models.ImplResponse {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorCartQuantity + item + ", не больше " + strconv.Itoa(maxCartLine)})
+	}
+
+	// лимит позиции учитывает то, что уже лежит в корзине
+	resp, _ := e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: maxCartLine + 1})
+	assert.Equal(t, lineError("cup"), resp)
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: maxCartLine})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: 1})
+	assert.Equal(t, lineError("cup"), resp)
+
+	// корзину, собранную в обход лимита, нельзя оформить, и она остаётся как была
+	_, userId, err := mockDb.AuthorizeUser(ctx, "test_user1", "test_pass1")
+	require.NoError(t, err)
+	require.NoError(t, mockDb.AddToCart(ctx, userId, "t-shirt", maxCartLine+1))
+	resp, _ = e.HandleApiCheckout(ctx)
+	assert.Equal(t, lineError("t-shirt"), resp)
+	cart, err := mockDb.GetCart(ctx, userId)
+	require.NoError(t, err)
+	assert.Len(t, cart, 2)
+
+	// лимит всей корзины
+	full := make([]database.CartItem, 0, maxCartUnits/maxCartLine)
+	for i := 0; i < maxCartUnits/maxCartLine; i++ {
+		full = append(full, database.CartItem{Item: strconv.Itoa(i), Quantity: maxCartLine})
+	}
+	assert.Empty(t, cartLimitError(full, "", 0))
+	assert.Equal(t, ErrorCartTooLarge+strconv.Itoa(maxCartUnits), cartLimitError(full, "pen", 1))
+}
+
+func TestCartErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.CartKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.CheckoutKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+
+	resp, _ := e.HandleApiAddToCart(ctx, models.CartItemRequest{Item: "cup", Quantity: 1})
+	require.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiCheckout(ctx)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorUpdateUserBalance}), resp)
+
+	mockDb = database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.CartKey).Return(errors.New("error"))
+	e, ctx = newProductsEngine(t, mockDb)
+	resp, _ = e.HandleApiCart(ctx)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorCart}), resp)
+}
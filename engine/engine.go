@@ -1,16 +1,44 @@
 package engine
 
 import (
+	"api-avito-shop/cache"
+	"api-avito-shop/config"
 	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/metrics"
 	"api-avito-shop/models"
+	"api-avito-shop/tracing"
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/form3tech-oss/jwt-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Engine struct {
-	db database.Database
+	db      database.Database
+	cfg     *config.Config
+	metrics *metrics.Metrics
+	info    *infoCache
+	cache   cache.Cache
+	// правила переводов, nil -- переводы проверяются только на баланс
+	transfers *transferRules
+	now       func() time.Time
+}
+
+// Option настраивает движок при создании
+type Option func(*Engine)
+
+// WithConfig задаёт конфигурацию движка, по умолчанию используется config.Default()
+func WithConfig(cfg *config.Config) Option {
+	return func(e *Engine) {
+		e.cfg = cfg
+	}
 }
 
 type AccountData struct {
@@ -19,12 +47,38 @@ type AccountData struct {
 	Password string
 }
 
-func NewEngine(db database.Database) *Engine {
-	return &Engine{
-		db: db,
+// WithMetrics включает учёт бизнес-метрик: переводов, покупок и регистраций
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(e *Engine) {
+		e.metrics = m
 	}
 }
 
+// WithCache включает кеширование товаров каталога и успешных проверок паролей
+func WithCache(c cache.Cache) Option {
+	return func(e *Engine) {
+		e.cache = c
+	}
+}
+
+func NewEngine(db database.Database, opts ...Option) *Engine {
+	e := &Engine{
+		db:  db,
+		cfg: config.Default(),
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.cfg.Cache.InfoTTL > 0 {
+		e.info = newInfoCache(e.cfg.Cache.InfoTTL)
+	}
+	e.transfers = newTransferRules(e.cfg.Transfers)
+
+	return e
+}
+
 func extractTokenFromContext(ctx context.Context) *jwt.Token {
 	if ctx == nil {
 		return nil
@@ -43,6 +97,15 @@ func extractTokenFromContext(ctx context.Context) *jwt.Token {
 	return token
 }
 
+// endSpan завершает спан обработчика, помечая ошибкой ответы с кодом 5xx
+func endSpan(span trace.Span, result models.ImplResponse) {
+	span.SetAttributes(attribute.Int("response.code", result.Code))
+	if result.Code >= 500 {
+		span.SetStatus(codes.Error, fmt.Sprint(result.Body))
+	}
+	span.End()
+}
+
 func (e *Engine) getAccountData(ctx context.Context) (*AccountData, models.ImplResponse) {
 	token := extractTokenFromContext(ctx).Claims.(jwt.MapClaims)
 	password, ok := token["password"].(string)
@@ -54,8 +117,12 @@ func (e *Engine) getAccountData(ctx context.Context) (*AccountData, models.ImplR
 		return nil, models.Response(500, models.ErrorResponse{Errors: ErrorUserName})
 	}
 
-	isAuthorize, userId, err := e.db.AuthorizeUser(username, password)
+	isAuthorize, userId, err := e.authorizeUser(ctx, username, password)
+	if errors.Is(err, database.ErrUserFrozen) {
+		return nil, models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen})
+	}
 	if err != nil {
+		slog.ErrorContext(ctx, "authorize user", "error", err)
 		return nil, models.Response(500, models.ErrorResponse{Errors: ErrorUserAuthorize})
 	}
 	if !isAuthorize {
@@ -70,44 +137,58 @@ func (e *Engine) getAccountData(ctx context.Context) (*AccountData, models.ImplR
 	return data, models.ImplResponse{}
 }
 
-func (e *Engine) HandleApiInfo(ctx context.Context) (models.ImplResponse, error) {
+func (e *Engine) HandleApiInfo(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiInfo")
+	defer func() { endSpan(span, result) }()
+
 	data, response := e.getAccountData(ctx)
 	if data == nil {
 		return response, nil
 	}
-	coins, err := e.db.GetUserCoins(data.Username)
-	if err != nil {
-		return models.Response(500, models.ErrorResponse{Errors: ErrorUserData + data.Username}), nil
-	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
 
-	goods, err := e.db.GetUserInventory(data.Id)
-	if err != nil || goods == nil {
-		return models.Response(500, models.ErrorResponse{Errors: ErrorInventory}), nil
-	}
-	history, err := e.db.GetUserReceivedAndSentCoins(data.Id)
-	if err != nil || history == nil {
-		return models.Response(500, models.ErrorResponse{Errors: ErrorTransactions}), nil
+	info, version := e.info.get(data.Username)
+	span.SetAttributes(attribute.Bool("cache.hit", info != nil))
+	if info == nil {
+		var err error
+		info, err = e.db.GetUserInfo(ctx, data.Id)
+		if err != nil {
+			slog.ErrorContext(ctx, "get user info", "error", err)
+			return models.Response(500, models.ErrorResponse{Errors: ErrorUserData + data.Username}), nil
+		}
+		e.info.put(data.Username, info, version)
 	}
-	return models.Response(200, models.InfoResponse{Coins: int32(coins), Inventory: *goods, CoinHistory: *history}), nil
+	return models.Response(200, *info), nil
 }
 
-func (e *Engine) HandleApiSendCoin(ctx context.Context, sendCoinRequest models.SendCoinRequest) (models.ImplResponse, error) {
+func (e *Engine) HandleApiSendCoin(ctx context.Context, sendCoinRequest models.SendCoinRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiSendCoin")
+	defer func() { endSpan(span, result) }()
+
 	data, response := e.getAccountData(ctx)
 	if data == nil {
 		return response, nil
 	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
 
 	if data.Username == sendCoinRequest.ToUser {
 		return models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), nil
 	}
+	if sendCoinRequest.Amount <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAmount}), nil
+	}
 
-	coinsFrom, err := e.db.GetUserCoins(data.Username)
+	coinsFrom, err := e.db.GetUserCoins(ctx, data.Username)
 	if err != nil {
+		slog.ErrorContext(ctx, "get sender coins", "error", err)
 		return models.Response(500, models.ErrorResponse{Errors: ErrorUserData + data.Username}), nil
 	}
 
-	_, err = e.db.GetUserCoins(sendCoinRequest.ToUser)
+	_, err = e.db.GetUserCoins(ctx, sendCoinRequest.ToUser)
 	if err != nil {
+		slog.ErrorContext(ctx, "get recipient coins", "to_user", sendCoinRequest.ToUser, "error", err)
 		return models.Response(500, models.ErrorResponse{Errors: ErrorUserData + sendCoinRequest.ToUser}), nil
 	}
 
@@ -115,60 +196,186 @@ func (e *Engine) HandleApiSendCoin(ctx context.Context, sendCoinRequest models.S
 		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), nil
 	}
 
-	err = e.db.SendCoins(data.Username, sendCoinRequest.ToUser, float64(sendCoinRequest.Amount))
+	transfer := &pendingTransfer{fromId: data.Id, from: data.Username, to: sendCoinRequest.ToUser, amount: float64(sendCoinRequest.Amount)}
+	rule, err := e.transfers.check(ctx, e.db, transfer)
 	if err != nil {
+		slog.ErrorContext(ctx, "check transfer rules", "to_user", sendCoinRequest.ToUser, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorTransferRules}), nil
+	}
+	if rule != nil {
+		e.denyTransfer(ctx, transfer, rule)
+		return models.Response(400, models.ErrorResponse{Errors: rule.message, Code: rule.code}), nil
+	}
+
+	err = e.db.SendCoins(ctx, data.Username, sendCoinRequest.ToUser, float64(sendCoinRequest.Amount))
+	// баланс мог измениться после проверки выше, окончательно его проверяет хранилище
+	switch {
+	case errors.Is(err, database.ErrInsufficientFunds):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), nil
+	case errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + sendCoinRequest.ToUser}), nil
+	case errors.Is(err, database.ErrInvalidAmount):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorAmount}), nil
+	case errors.Is(err, database.ErrUserFrozen):
+		return models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "send coins", "to_user", sendCoinRequest.ToUser, "error", err)
 		return models.Response(500, models.ErrorResponse{Errors: ErrorSendCoin}), nil
 	}
+	e.info.invalidate(data.Username, sendCoinRequest.ToUser)
+	slog.InfoContext(ctx, "coins sent", "to_user", sendCoinRequest.ToUser, "amount", sendCoinRequest.Amount)
+	e.metrics.CoinsTransferred(float64(sendCoinRequest.Amount))
 	return models.Response(200, models.ImplResponse{}), nil
 }
 
-func (e *Engine) HandleApiByuItem(ctx context.Context, item string) (models.ImplResponse, error) {
+func (e *Engine) HandleApiByuItem(ctx context.Context, item string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiByuItem")
+	defer func() { endSpan(span, result) }()
+
+	return e.buyItem(ctx, span, item, ""), nil
+}
+
+// HandleApiBuyItemWithPromoCode покупает товар со скидкой по промокоду. Код проверяется и засчитывается
+// в транзакции покупки, скидка сохраняется в покупке и возвращается в ответе
+func (e *Engine) HandleApiBuyItemWithPromoCode(ctx context.Context, item, code string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiBuyItemWithPromoCode")
+	defer func() { endSpan(span, result) }()
+
+	return e.buyItem(ctx, span, item, code), nil
+}
+
+// buyItem покупает товар за монеты пользователя, непустой code применяет промокод
+func (e *Engine) buyItem(ctx context.Context, span trace.Span, item, code string) models.ImplResponse {
 	data, response := e.getAccountData(ctx)
 	if data == nil {
-		return response, nil
+		return response
 	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
 
-	coins, price, itemId, err := e.db.GetUserCoinsAndItemPrice(data.Id, item)
-	if err != nil {
-		return models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), nil
+	product, response, ok := e.purchaseProduct(ctx, data.Id, item, code != "")
+	if !ok {
+		return response
 	}
 
-	if coins < price {
-		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), nil
+	var purchaseId int64
+	var discount float64
+	var err error
+	if code == "" {
+		purchaseId, err = e.db.UpdateUserBalanceAndInventory(ctx, data.Id, product.Price, product.Id)
+	} else {
+		purchaseId, discount, err = e.db.BuyWithPromoCode(ctx, data.Id, product.Price, product.Id, code)
+	}
+	if err != nil {
+		return e.purchaseError(ctx, item, err)
 	}
+	e.info.invalidate(data.Username)
+	slog.InfoContext(ctx, "item bought", "item", item, "price", product.Price-discount, "discount", discount, "purchase_id", purchaseId)
+	e.metrics.ItemBought(item)
 
-	err = e.db.UpdateUserBalanceAndInventory(data.Id, price, itemId)
+	return models.Response(200, models.PurchaseResponse{PurchaseId: purchaseId, Price: int32(product.Price - discount), Discount: int32(discount)})
+}
+
+// purchaseProduct возвращает id товара из кеша или хранилища и цену, действующую сейчас с учётом акций.
+// С ценой из кеша баланс заранее не проверяется, его окончательно проверяет хранилище.
+// При покупке со скидкой (discounted) итоговая цена известна только хранилищу, поэтому баланс тоже не проверяется
+func (e *Engine) purchaseProduct(ctx context.Context, userId int64, item string, discounted bool) (cachedProduct, models.ImplResponse, bool) {
+	var product cachedProduct
+	if e.cacheGet(ctx, cacheKindProduct, productKey(item), &product) {
+		product.Price = product.price(e.now())
+		return product, models.ImplResponse{}, true
+	}
+	coins, price, itemId, err := e.db.GetUserCoinsAndItemPrice(ctx, userId, item)
+	if errors.Is(err, database.ErrProductNotFound) {
+		return product, models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item}), false
+	}
 	if err != nil {
-		return models.Response(500, models.ErrorResponse{Errors: ErrorUpdateUserBalance}), nil
+		slog.ErrorContext(ctx, "get user coins and item price", "item", item, "error", err)
+		return product, models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), false
 	}
+	campaigns, err := e.db.GetPriceCampaigns(ctx, item, e.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "get price campaigns", "item", item, "error", err)
+		return product, models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), false
+	}
+	product = cachedProduct{Id: itemId, Price: price, Campaigns: campaigns}
+	e.cacheSet(ctx, productKey(item), product)
 
-	return models.Response(200, models.ImplResponse{}), nil
+	product.Price = product.price(e.now())
+	if !discounted && coins < product.Price {
+		return product, models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), false
+	}
+	return product, models.ImplResponse{}, true
+}
+
+// purchaseError переводит ошибку покупки товара item в ответ
+func (e *Engine) purchaseError(ctx context.Context, item string, err error) models.ImplResponse {
+	switch {
+	case errors.Is(err, database.ErrInsufficientFunds):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance})
+	case errors.Is(err, database.ErrUserFrozen):
+		return models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen})
+	case errors.Is(err, database.ErrSoldOut):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorSoldOut + item})
+	case errors.Is(err, database.ErrPurchaseLimit):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorPurchaseLimit + item})
+	case errors.Is(err, database.ErrPromoCodeNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotFound})
+	case errors.Is(err, database.ErrPromoCodeInactive):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeInactive})
+	case errors.Is(err, database.ErrPromoCodeExhausted):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorPromoCodeExhausted})
+	case errors.Is(err, database.ErrPromoCodeNotApplicable):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotApplicable + item})
+	case errors.Is(err, database.ErrProductNotFound):
+		// товар удалили из каталога, пока он был в кеше
+		e.InvalidateProducts(ctx, item)
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item})
+	}
+	slog.ErrorContext(ctx, "update user balance and inventory", "item", item, "error", err)
+	return models.Response(500, models.ErrorResponse{Errors: ErrorUpdateUserBalance})
 }
 
-func (e *Engine) HandleApiAuth(ctx context.Context, authRequest models.AuthRequest) (models.ImplResponse, error) {
-	var key = []byte(models.JwtUniqueKey)
+func (e *Engine) HandleApiAuth(ctx context.Context, authRequest models.AuthRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiAuth")
+	defer func() { endSpan(span, result) }()
+
+	// от имени system в истории переводов записываются начисления и сгоревшие монеты
+	if authRequest.Username == database.SystemUser {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorReservedUsername + authRequest.Username}), nil
+	}
+
+	var key = []byte(e.cfg.Auth.JwtKey)
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["password"] = authRequest.Password
 	claims["username"] = authRequest.Username
 	claims["admin"] = false
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // Токен истекает через 24 часа
+	claims["exp"] = time.Now().Add(e.cfg.Auth.TokenTTL).Unix()
 
 	tokenString, err := token.SignedString(key)
 	if err != nil {
+		slog.ErrorContext(ctx, "sign token", "error", err)
 		return models.Response(500, models.ErrorResponse{}), nil
 	}
-	isAdd, err := e.db.AddNewUser(authRequest.Username, authRequest.Password)
+	isAdd, err := e.db.AddNewUser(ctx, authRequest.Username, authRequest.Password, e.cfg.Shop.StartingBalance)
 	if err != nil {
+		slog.ErrorContext(ctx, "add new user", "username", authRequest.Username, "error", err)
 		return models.Response(500, models.ErrorResponse{Errors: ErrorAddNewUser}), nil
 	}
 	if isAdd {
+		slog.InfoContext(ctx, "user registered", "username", authRequest.Username)
+		e.metrics.UserRegistered()
 		return models.Response(200, models.AuthResponse{Token: tokenString}), nil
 	}
 
-	isAuthorize, _, err := e.db.AuthorizeUser(authRequest.Username, authRequest.Password)
+	isAuthorize, _, err := e.authorizeUser(ctx, authRequest.Username, authRequest.Password)
+	if errors.Is(err, database.ErrUserFrozen) {
+		return models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen}), nil
+	}
 	if err != nil {
+		slog.ErrorContext(ctx, "authorize user", "username", authRequest.Username, "error", err)
 		return models.Response(500, models.ErrorResponse{Errors: ErrorUserAuthorize}), nil
 	}
 	if !isAuthorize {
@@ -176,3 +383,14 @@ func (e *Engine) HandleApiAuth(ctx context.Context, authRequest models.AuthReque
 	}
 	return models.Response(200, models.AuthResponse{Token: tokenString}), nil
 }
+
+// denyTransfer сохраняет перевод, отклонённый правилом, для разбора. Перевод отклоняется,
+// даже если сохранить его не удалось, поэтому ошибка только логируется
+func (e *Engine) denyTransfer(ctx context.Context, t *pendingTransfer, rule *transferRule) {
+	slog.WarnContext(ctx, "transfer denied", "to_user", t.to, "amount", t.amount, "rule", rule.code)
+	e.metrics.TransferDenied(rule.code)
+	review := database.TransferReview{UserId: t.fromId, ToUser: t.to, Amount: t.amount, Rule: rule.code}
+	if err := e.db.AddTransferReview(ctx, review); err != nil {
+		slog.ErrorContext(ctx, "add transfer review", "rule", rule.code, "error", err)
+	}
+}
@@ -1,20 +1,29 @@
 package engine
 
 import (
+	"api-avito-shop/cache"
+	"api-avito-shop/config"
 	"api-avito-shop/database"
+	"api-avito-shop/metrics"
 	"api-avito-shop/models"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/form3tech-oss/jwt-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
+// addTokenToCtx кладёт токен в контекст, как это делает JWT-middleware. Подпись проверяет middleware,
+// движку нужны только claims, поэтому токен разбирается без проверки
 func addTokenToCtx(ctx *context.Context, tokenString string) {
-	parsedToken, _ := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(models.JwtUniqueKey), nil
-	})
+	parsedToken, _, _ := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
 	*ctx = context.WithValue(*ctx, models.JwtUserKey, parsedToken)
 }
 
@@ -33,7 +42,7 @@ func TestHandleApiAuthWitAddNewUser(t *testing.T) {
 	req := models.AuthRequest{Username: username, Password: password}
 	resp, _ := e.HandleApiAuth(ctx, req)
 	assert.True(t, int(200) == resp.Code)
-	coins, _ := mockDb.GetUserCoins(username)
+	coins, _ := mockDb.GetUserCoins(ctx, username)
 	assert.True(t, coins == float64(1000))
 
 	// теперь попробуем позвать добавленного пользователя с другим паролем
@@ -96,6 +105,7 @@ func TestHandleApiBuyItem(t *testing.T) {
 	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
 	mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
 	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
 	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
 	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
 
@@ -169,10 +179,10 @@ func TestHandleApiBuyItemErrorDbWithUserInventory(t *testing.T) {
 	assert.True(t, int(200) == resp.Code)
 	addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
 
-	// проверим, что вернется ошибка 500
+	// сводка читается одним запросом, поэтому ошибка общая для всех её частей
 	resp, _ = e.HandleApiInfo(ctx)
 	assert.True(t, int(500) == resp.Code)
-	assert.True(t, models.ErrorResponse{Errors: ErrorInventory} == resp.Body)
+	assert.True(t, models.ErrorResponse{Errors: ErrorUserData + username} == resp.Body)
 }
 
 func TestHandleApiBuyItemErrorDbWithUserTransactions(t *testing.T) {
@@ -199,7 +209,7 @@ func TestHandleApiBuyItemErrorDbWithUserTransactions(t *testing.T) {
 	// проверим, что вернется ошибка 500
 	resp, _ = e.HandleApiInfo(ctx)
 	assert.True(t, int(500) == resp.Code)
-	assert.True(t, models.ErrorResponse{Errors: ErrorTransactions} == resp.Body)
+	assert.True(t, models.ErrorResponse{Errors: ErrorUserData + username} == resp.Body)
 }
 
 func TestHandleApiSendCoin(t *testing.T) {
@@ -431,3 +441,236 @@ func TestHandleApiSendCoinErrorDbWithSendCoins(t *testing.T) {
 	assert.True(t, int(500) == resp.Code)
 	assert.True(t, models.ErrorResponse{Errors: ErrorSendCoin} == resp.Body)
 }
+
+func TestHandleApiSendCoinNonPositiveAmount(t *testing.T) {
+	ctx1 := context.Background()
+	ctx2 := context.Background()
+	mockDb := database.NewMockDb()
+	e := NewEngine(mockDb)
+
+	// мокируем, что не будет ошибок БД
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+
+	resp, _ := e.HandleApiAuth(ctx1, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx1, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiAuth(ctx2, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	assert.True(t, int(200) == resp.Code)
+
+	// отрицательный перевод списал бы монеты у получателя
+	resp, _ = e.HandleApiSendCoin(ctx1, models.SendCoinRequest{ToUser: "test_user2", Amount: -100})
+	assert.True(t, int(400) == resp.Code)
+	assert.True(t, models.ErrorResponse{Errors: ErrorAmount} == resp.Body)
+}
+
+func TestHandleApiSendCoinInsufficientFundsInDb(t *testing.T) {
+	ctx1 := context.Background()
+	ctx2 := context.Background()
+	mockDb := database.NewMockDb()
+	e := NewEngine(mockDb)
+
+	// баланс изменился между проверкой в движке и переводом, перевод отклонило ограничение в БД
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(fmt.Errorf("%w: users_balance_check", database.ErrInsufficientFunds))
+
+	resp, _ := e.HandleApiAuth(ctx1, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx1, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiAuth(ctx2, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	assert.True(t, int(200) == resp.Code)
+
+	resp, _ = e.HandleApiSendCoin(ctx1, models.SendCoinRequest{ToUser: "test_user2", Amount: 100})
+	assert.True(t, int(400) == resp.Code)
+	assert.True(t, models.ErrorResponse{Errors: ErrorUserBalance} == resp.Body)
+}
+
+func TestHandleApiBuyUnknownItem(t *testing.T) {
+	ctx := context.Background()
+	mockDb := database.NewMockDb()
+	e := NewEngine(mockDb)
+
+	// мокируем, что не будет ошибок БД
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
+
+	resp, _ := e.HandleApiAuth(ctx, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
+
+	resp, _ = e.HandleApiByuItem(ctx, "unknown")
+	assert.True(t, int(400) == resp.Code)
+	assert.True(t, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"} == resp.Body)
+}
+
+func TestHandleApiMetrics(t *testing.T) {
+	ctx1 := context.Background()
+	ctx2 := context.Background()
+	mockDb := database.NewMockDb()
+	registry := prometheus.NewRegistry()
+	e := NewEngine(mockDb, WithMetrics(metrics.New(registry)))
+
+	// мокируем, что не будет ошибок БД
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
+
+	// регистрация двух пользователей, покупка и перевод должны попасть в метрики
+	resp, _ := e.HandleApiAuth(ctx1, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx1, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiAuth(ctx2, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	assert.True(t, int(200) == resp.Code)
+
+	resp, _ = e.HandleApiByuItem(ctx1, "cup")
+	assert.True(t, int(200) == resp.Code)
+	resp, _ = e.HandleApiSendCoin(ctx1, models.SendCoinRequest{ToUser: "test_user2", Amount: 150})
+	assert.True(t, int(200) == resp.Code)
+
+	expected := `
+# HELP avito_shop_users_registered_total Количество зарегистрированных пользователей.
+# TYPE avito_shop_users_registered_total counter
+avito_shop_users_registered_total 2
+# HELP avito_shop_items_bought_total Количество купленных товаров по продуктам.
+# TYPE avito_shop_items_bought_total counter
+avito_shop_items_bought_total{product="cup"} 1
+# HELP avito_shop_coins_transferred_total Суммарное количество переведённых монет.
+# TYPE avito_shop_coins_transferred_total counter
+avito_shop_coins_transferred_total 150
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"avito_shop_users_registered_total", "avito_shop_items_bought_total", "avito_shop_coins_transferred_total")
+	assert.NoError(t, err)
+}
+
+func TestHandleApiInfoCache(t *testing.T) {
+	ctx1 := context.Background()
+	ctx2 := context.Background()
+	mockDb := database.NewMockDb()
+	cfg := config.Default()
+	cfg.Cache.InfoTTL = time.Minute
+	e := NewEngine(mockDb, WithConfig(cfg))
+
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+
+	resp, _ := e.HandleApiAuth(ctx1, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx1, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiAuth(ctx2, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	assert.True(t, int(200) == resp.Code)
+	addTokenToCtx(&ctx2, resp.Body.(models.AuthResponse).Token)
+
+	info := func(ctx context.Context) models.InfoResponse {
+		resp, _ := e.HandleApiInfo(ctx)
+		assert.Equal(t, 200, resp.Code)
+		return resp.Body.(models.InfoResponse)
+	}
+	assert.Equal(t, int32(1000), info(ctx1).Coins)
+	assert.Equal(t, int32(1000), info(ctx2).Coins)
+
+	// изменение в обход движка не видно, пока жива запись кеша
+	_, userId, _ := mockDb.AuthorizeUser(ctx1, "test_user1", "test_pass1")
+	_, price, cupId, _ := mockDb.GetUserCoinsAndItemPrice(ctx1, userId, "cup")
+	_, err := mockDb.UpdateUserBalanceAndInventory(ctx1, userId, price, cupId)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1000), info(ctx1).Coins)
+
+	// покупка через движок сбрасывает кеш покупателя
+	resp, _ = e.HandleApiByuItem(ctx1, "cup")
+	assert.True(t, int(200) == resp.Code)
+	assert.Equal(t, int32(1000-20-20), info(ctx1).Coins)
+
+	// перевод сбрасывает кеш отправителя и получателя
+	resp, _ = e.HandleApiSendCoin(ctx1, models.SendCoinRequest{ToUser: "test_user2", Amount: 100})
+	assert.True(t, int(200) == resp.Code)
+	assert.Equal(t, int32(860), info(ctx1).Coins)
+	assert.Equal(t, int32(1100), info(ctx2).Coins)
+
+	// при ошибке хранилища кешированный ответ продолжает отдаваться
+	mockDb.ExpectedCalls = nil
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(errors.New("error"))
+	assert.Equal(t, int32(1100), info(ctx2).Coins)
+}
+
+func TestEngineCache(t *testing.T) {
+	backends := map[string]func(t *testing.T) cache.Cache{
+		"memory": func(t *testing.T) cache.Cache {
+			return cache.NewLRU(100)
+		},
+		"redis": func(t *testing.T) cache.Cache {
+			c, err := cache.NewRedis(context.Background(), config.Cache{RedisAddr: miniredis.RunT(t).Addr()})
+			assert.NoError(t, err)
+			t.Cleanup(func() { c.Close() })
+			return c
+		},
+	}
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			mockDb := database.NewMockDb()
+			registry := prometheus.NewRegistry()
+			e := NewEngine(mockDb, WithCache(newCache(t)), WithMetrics(metrics.New(registry)))
+
+			mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+			mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil).Once()
+			mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(errors.New("error"))
+			mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil).Once()
+			mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
+			mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(errors.New("error"))
+			mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
+			mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+
+			resp, _ := e.HandleApiAuth(ctx, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+			assert.True(t, int(200) == resp.Code)
+			addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
+
+			// первая покупка проверяет пароль и читает цену из хранилища, следующие -- из кеша
+			resp, _ = e.HandleApiByuItem(ctx, "cup")
+			assert.Equal(t, 200, resp.Code)
+			resp, _ = e.HandleApiByuItem(ctx, "cup")
+			assert.Equal(t, 200, resp.Code)
+			coins, _ := mockDb.GetUserCoins(ctx, "test_user1")
+			assert.Equal(t, float64(960), coins)
+
+			// другой товар и другой пароль в кеше не найдены
+			resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+			assert.Equal(t, 500, resp.Code)
+			resp, _ = e.HandleApiAuth(ctx, models.AuthRequest{Username: "test_user1", Password: "other"})
+			assert.Equal(t, 500, resp.Code)
+
+			// после сброса пароль снова проверяет хранилище
+			e.InvalidateUsers(ctx, "test_user1")
+			resp, _ = e.HandleApiByuItem(ctx, "cup")
+			assert.Equal(t, 500, resp.Code)
+			assert.True(t, models.ErrorResponse{Errors: ErrorUserAuthorize} == resp.Body)
+
+			expected := `
+# HELP avito_shop_cache_lookups_total Количество обращений к кешу по видам данных и результатам: hit, miss, error.
+# TYPE avito_shop_cache_lookups_total counter
+avito_shop_cache_lookups_total{kind="product",result="hit"} 1
+avito_shop_cache_lookups_total{kind="product",result="miss"} 2
+avito_shop_cache_lookups_total{kind="user",result="hit"} 3
+avito_shop_cache_lookups_total{kind="user",result="miss"} 2
+`
+			err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "avito_shop_cache_lookups_total")
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -6,12 +6,92 @@ var (
 	ErrorUserAuthorize     = "ошибка проверки аутентификации пользователя"
 	ErrorPassword          = "неверный пароль"
 	ErrorUserData          = "не удалось получить данные пользователя "
-	ErrorInventory         = "ошибка при получении инвентаря"
-	ErrorTransactions      = "ошибка при получении транзакций"
 	ErrorUserBalance       = "недостаточный баланс пользователя"
 	ErrorSendCoin          = "ошибка при отправке монет"
 	ErrorDatabase          = "ошибка при обращении в базу данных"
 	ErrorUpdateUserBalance = "ошибка при обновлении баланса"
 	ErrorAddNewUser        = "ошибка добавления нового пользователя"
 	ErrorSameUser          = "ошибка отправки коинов самому себе"
+	ErrorAmount            = "сумма перевода должна быть положительной"
+	ErrorUserNotFound      = "пользователь не найден: "
+	ErrorProductNotFound   = "товар не найден: "
+	ErrorSoldOut           = "товар закончился: "
+	ErrorPurchaseLimit     = "достигнут лимит покупок товара: "
+	ErrorProductLimit      = "лимит покупок должен быть положительным"
+	ErrorUpdateLimit       = "ошибка изменения лимита покупок"
+	ErrorProducts          = "ошибка получения каталога"
+	ErrorStock             = "остаток товара не может быть отрицательным"
+	ErrorRestockQuantity   = "количество должно быть положительным"
+	ErrorUnlimitedStock    = "количество товара не ограничено: "
+	ErrorUpdateStock       = "ошибка изменения остатка товара"
+	ErrorPurchaseNotFound  = "покупка не найдена: "
+	ErrorRefundWindow      = "срок самостоятельного возврата покупки истёк"
+	ErrorRefundDisabled    = "самостоятельный возврат покупок отключён"
+	ErrorRefundGift        = "подарок может вернуть только администратор"
+	ErrorItemNotOwned      = "предмета покупки уже нет в инвентаре"
+	ErrorRefund            = "ошибка возврата покупки"
+	ErrorGiftMessage       = "сообщение к подарку не может быть длиннее 200 символов"
+	ErrorItemQuantity      = "количество предметов должно быть положительным"
+	ErrorNotEnoughItems    = "недостаточно предметов в инвентаре: "
+	ErrorRecipientLimit    = "получатель достиг лимита покупок товара: "
+	ErrorSendItem          = "ошибка при передаче предметов"
+	ErrorCartQuantity      = "слишком много единиц товара в корзине: "
+	ErrorCartTooLarge      = "слишком много единиц товаров в корзине, не больше "
+	ErrorNotInCart         = "товара нет в корзине: "
+	ErrorCartEmpty         = "корзина пуста"
+	ErrorCart              = "ошибка при работе с корзиной"
+	ErrorTradeWant         = "в обмен нужен другой предмет с количеством или монеты"
+	ErrorTradeCoins        = "сумма обмена не может быть отрицательной"
+	ErrorTradeNotFound     = "предложение обмена не найдено: "
+	ErrorTradeClosed       = "предложение обмена уже закрыто: "
+	ErrorTradeItems        = "у участника обмена недостаточно предметов: "
+	ErrorTradeLimit        = "обмен превысит лимит покупок товара: "
+	ErrorTradeUserFrozen   = "участник обмена заблокирован"
+	ErrorTrade             = "ошибка при обмене предметами"
+
+	ErrorPromoCodeNotFound      = "промокод не найден"
+	ErrorPromoCodeInactive      = "промокод не действует"
+	ErrorPromoCodeExhausted     = "промокод больше нельзя применить"
+	ErrorPromoCodeNotApplicable = "промокод не действует на товар: "
+	ErrorPromoCodeExists        = "промокод уже существует: "
+	ErrorPromoCodeDiscount      = "нужна скидка в процентах от 1 до 100 или положительная сумма"
+	ErrorPromoCodeWindow        = "окно действия промокода пустое"
+	ErrorPromoCodeMaxUses       = "число применений промокода должно быть положительным"
+	ErrorPromoCode              = "ошибка при работе с промокодами"
+
+	ErrorCampaignPrice    = "цена по акции должна быть положительной"
+	ErrorCampaignWindow   = "окно действия акции пустое"
+	ErrorCampaignNotFound = "акция не найдена: "
+	ErrorCampaign         = "ошибка при работе с акциями"
+
+	ErrorGrantUsername         = "не указан получатель начисления"
+	ErrorGrantAmount           = "начисление должно быть положительным"
+	ErrorGrantExpiresAt        = "срок монет должен быть в будущем"
+	ErrorGrantsEmpty           = "пакет начислений пуст"
+	ErrorGrantsTooMany         = "слишком много начислений в пакете, не больше "
+	ErrorGrantLine             = "начисление "
+	ErrorGrant                 = "ошибка при начислении монет"
+	ErrorGrantScheduleEvery    = "период начисления должен быть не меньше секунды"
+	ErrorGrantScheduleExpires  = "срок монет должен быть не меньше секунды"
+	ErrorGrantScheduleNotFound = "регулярное начисление не найдено: "
+	ErrorGrantSchedule         = "ошибка при работе с регулярными начислениями"
+	ErrorReservedUsername      = "имя пользователя зарезервировано: "
+
+	ErrorTransferBlocked        = "переводы для этого пользователя запрещены"
+	ErrorTransferMaxAmount      = "сумма перевода больше допустимой: "
+	ErrorTransferDailyLimit     = "превышен суточный лимит переводов: "
+	ErrorTransferRecipientLimit = "превышен суточный лимит переводов одному получателю: "
+	ErrorTransferVelocity       = "слишком много переводов, повторите позже"
+	ErrorTransferRules          = "ошибка проверки перевода"
+
+	ErrorUserFrozen         = "учётная запись заблокирована"
+	ErrorAdjustmentAmount   = "сумма исправления не может быть нулевой"
+	ErrorAdjustmentReason   = "не указана причина исправления"
+	ErrorAdjustment         = "ошибка при исправлении баланса"
+	ErrorFreezeReason       = "не указана причина блокировки"
+	ErrorFreeze             = "ошибка при блокировке пользователя"
+	ErrorUserStatus         = "ошибка при получении состояния пользователя"
+	ErrorAdminActionsLimit  = "размер страницы журнала должен быть от 1 до "
+	ErrorAdminActionsBefore = "курсор журнала не может быть отрицательным"
+	ErrorAdminActions       = "ошибка при чтении журнала действий администраторов"
 )
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxGiftMessage -- максимальная длина сообщения к подарку в символах
+const maxGiftMessage = 200
+
+// HandleApiGiftItem покупает товар в подарок: монеты списываются у пользователя, а предмет попадает
+// в инвентарь получателя. Подарок с сообщением виден в /api/info обоих пользователей
+func (e *Engine) HandleApiGiftItem(ctx context.Context, request models.GiftRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiGiftItem")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	if data.Username == request.ToUser {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), nil
+	}
+	if utf8.RuneCountInString(request.Message) > maxGiftMessage {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGiftMessage}), nil
+	}
+
+	product, response, ok := e.purchaseProduct(ctx, data.Id, request.Item, false)
+	if !ok {
+		return response, nil
+	}
+
+	purchaseId, err := e.db.GiftItem(ctx, data.Id, request.ToUser, product.Price, product.Id, request.Message)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + request.ToUser}), nil
+	}
+	if err != nil {
+		return e.purchaseError(ctx, request.Item, err), nil
+	}
+	e.info.invalidate(data.Username, request.ToUser)
+	slog.InfoContext(ctx, "item gifted", "item", request.Item, "to_user", request.ToUser, "price", product.Price, "purchase_id", purchaseId)
+	e.metrics.ItemBought(request.Item)
+
+	return models.Response(200, models.PurchaseResponse{PurchaseId: purchaseId, Price: int32(product.Price)}), nil
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGiftItem(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GiftItemKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PurchaseKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.RefundPurchaseKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	e.info = newInfoCache(time.Minute)
+
+	other := context.Background()
+	resp, _ := e.HandleApiAuth(other, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+	addTokenToCtx(&other, resp.Body.(models.AuthResponse).Token)
+	info := func(ctx context.Context) models.InfoResponse {
+		resp, _ := e.HandleApiInfo(ctx)
+		require.Equal(t, 200, resp.Code)
+		return resp.Body.(models.InfoResponse)
+	}
+	// сводка получателя попадает в кеш до подарка
+	assert.Empty(t, info(other).Inventory)
+
+	resp, _ = e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "test_user2", Item: "t-shirt", Message: "спасибо за помощь"})
+	assert.Equal(t, 200, resp.Code)
+	purchaseId := resp.Body.(models.PurchaseResponse).PurchaseId
+
+	sender := info(ctx)
+	assert.Equal(t, int32(1000-100), sender.Coins)
+	assert.Empty(t, sender.Inventory)
+	assert.Equal(t, []models.InfoResponseGiftsSentInner{{ToUser: "test_user2", Item: "t-shirt", Amount: 100, Message: "спасибо за помощь"}}, sender.Gifts.Sent)
+	recipient := info(other)
+	assert.Equal(t, int32(1000), recipient.Coins)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "t-shirt", Quantity: 1}}, recipient.Inventory)
+	assert.Equal(t, []models.InfoResponseGiftsReceivedInner{{FromUser: "test_user1", Item: "t-shirt", Message: "спасибо за помощь"}}, recipient.Gifts.Received)
+
+	// покупатель не может сам забрать подарок у получателя, возвращает его администратор
+	resp, _ = e.HandleApiRefundPurchase(other, purchaseId)
+	assert.Equal(t, 400, resp.Code)
+	resp, _ = e.HandleApiRefundPurchase(ctx, purchaseId)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorRefundGift}), resp)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "t-shirt", Quantity: 1}}, info(other).Inventory)
+	resp, _ = e.HandleAdminRefundPurchase(logging.WithAdmin(context.Background(), "alice"), purchaseId)
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(1000), info(ctx).Coins)
+	recipient = info(other)
+	assert.Empty(t, recipient.Inventory)
+	assert.Empty(t, recipient.Gifts.Received)
+}
+
+func TestGiftItemInvalid(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GiftItemKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+
+	resp, _ := e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "test_user1", Item: "cup"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), resp)
+	resp, _ = e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "unknown", Item: "cup"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + "unknown"}), resp)
+	resp, _ = e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "test_user2", Item: "unknown"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"}), resp)
+	resp, _ = e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "test_user2", Item: "cup", Message: strings.Repeat("я", maxGiftMessage+1)})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorGiftMessage}), resp)
+
+	coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1000), coins)
+}
+
+func TestGiftItemErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GiftItemKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+	resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+
+	resp, _ = e.HandleApiGiftItem(ctx, models.GiftRequest{ToUser: "test_user2", Item: "cup"})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorUpdateUserBalance}), resp)
+}
@@ -0,0 +1,270 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxBulkGrants ограничивает пакет начислений: пакет выполняется одной транзакцией
+const maxBulkGrants = 10_000
+
+// HandleAdminGrantCoins начисляет монеты пользователю от имени системы
+func (e *Engine) HandleAdminGrantCoins(ctx context.Context, request models.GrantRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminGrantCoins")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("username", request.Username))
+
+	if msg := e.checkGrant(&request); msg != "" {
+		return models.Response(400, models.ErrorResponse{Errors: msg}), nil
+	}
+	return e.grantCoins(ctx, []models.GrantRequest{request}, false), nil
+}
+
+// HandleAdminBulkGrantCoins начисляет монеты пакетом, например зарплату всему отделу: либо выполняются
+// все начисления пакета, либо ни одного. Ошибка указывает номер начисления в пакете, начиная с единицы
+func (e *Engine) HandleAdminBulkGrantCoins(ctx context.Context, request models.BulkGrantRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminBulkGrantCoins")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.Int("grants", len(request.Grants)))
+
+	switch {
+	case len(request.Grants) == 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGrantsEmpty}), nil
+	case len(request.Grants) > maxBulkGrants:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGrantsTooMany + strconv.Itoa(maxBulkGrants)}), nil
+	}
+	for i := range request.Grants {
+		if msg := e.checkGrant(&request.Grants[i]); msg != "" {
+			return models.Response(400, models.ErrorResponse{Errors: grantLineError(i, msg)}), nil
+		}
+	}
+	return e.grantCoins(ctx, request.Grants, true), nil
+}
+
+// checkGrant возвращает текст ошибки, если начисление заполнено неверно
+func (e *Engine) checkGrant(request *models.GrantRequest) string {
+	switch {
+	case request.Username == "":
+		return ErrorGrantUsername
+	case request.Amount <= 0:
+		return ErrorGrantAmount
+	case request.ExpiresAt != nil && !request.ExpiresAt.After(e.now()):
+		return ErrorGrantExpiresAt
+	}
+	return ""
+}
+
+// grantLineError добавляет к ошибке номер начисления в пакете
+func grantLineError(index int, msg string) string {
+	return fmt.Sprintf("%s%d: %s", ErrorGrantLine, index+1, msg)
+}
+
+// grantCoins выполняет проверенные начисления; для пакета ошибка получателя указывает номер начисления
+func (e *Engine) grantCoins(ctx context.Context, requests []models.GrantRequest, bulk bool) models.ImplResponse {
+	grants := make([]database.Grant, 0, len(requests))
+	usernames := make([]string, 0, len(requests))
+	var total float64
+	for _, r := range requests {
+		grants = append(grants, database.Grant{Username: r.Username, Amount: float64(r.Amount), Reason: r.Reason, ExpiresAt: r.ExpiresAt})
+		usernames = append(usernames, r.Username)
+		total += float64(r.Amount)
+	}
+
+	err := e.db.GrantCoins(ctx, grants)
+	var grantErr *database.GrantError
+	if errors.As(err, &grantErr) && errors.Is(err, database.ErrUserNotFound) {
+		msg := ErrorUserNotFound + grantErr.Username
+		if bulk {
+			msg = grantLineError(grantErr.Index, msg)
+		}
+		return models.Response(400, models.ErrorResponse{Errors: msg})
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "grant coins", "grants", len(grants), "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorGrant})
+	}
+	e.info.invalidate(usernames...)
+	e.metrics.CoinsGranted(total)
+	slog.InfoContext(ctx, "coins granted", "grants", len(grants), "total", total)
+	return models.Response(200, models.GrantsResponse{Count: int32(len(grants)), Total: int32(total)})
+}
+
+// HandleAdminAddGrantSchedule создаёт регулярное начисление: amount монет каждому получателю каждые every,
+// начиная с startsAt. Без получателей монеты получают все пользователи, зарегистрированные к периоду
+func (e *Engine) HandleAdminAddGrantSchedule(ctx context.Context, request models.GrantScheduleRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminAddGrantSchedule")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("grant_schedule.name", request.Name))
+
+	if request.Amount <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGrantAmount}), nil
+	}
+	every, err := time.ParseDuration(request.Every)
+	if err != nil || every < time.Second {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGrantScheduleEvery}), nil
+	}
+	var expiresAfter time.Duration
+	if request.ExpiresAfter != "" {
+		expiresAfter, err = time.ParseDuration(request.ExpiresAfter)
+		if err != nil || expiresAfter < time.Second {
+			return models.Response(400, models.ErrorResponse{Errors: ErrorGrantScheduleExpires}), nil
+		}
+	}
+	schedule := database.GrantSchedule{
+		Name:         request.Name,
+		Amount:       float64(request.Amount),
+		Reason:       request.Reason,
+		Interval:     every.Truncate(time.Second),
+		ExpiresAfter: expiresAfter.Truncate(time.Second),
+		Usernames:    uniqueSorted(request.Usernames),
+		NextRunAt:    e.now(),
+	}
+	if request.StartsAt != nil {
+		schedule.NextRunAt = *request.StartsAt
+	}
+
+	schedule.Id, err = e.db.AddGrantSchedule(ctx, schedule)
+	var grantErr *database.GrantError
+	switch {
+	case errors.As(err, &grantErr) && errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + grantErr.Username}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "add grant schedule", "name", request.Name, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), nil
+	}
+	slog.InfoContext(ctx, "grant schedule added", "grant_schedule_id", schedule.Id, "amount", request.Amount,
+		"every", schedule.Interval, "recipients", len(schedule.Usernames), "next_run_at", schedule.NextRunAt)
+	return models.Response(200, grantScheduleResponse(&schedule)), nil
+}
+
+// HandleAdminGrantSchedules возвращает все регулярные начисления, включая остановленные
+func (e *Engine) HandleAdminGrantSchedules(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminGrantSchedules")
+	defer func() { endSpan(span, result) }()
+
+	schedules, err := e.db.GetGrantSchedules(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "get grant schedules", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), nil
+	}
+	body := models.GrantSchedulesResponse{Items: make([]models.GrantScheduleResponse, 0, len(schedules))}
+	for i := range schedules {
+		body.Items = append(body.Items, grantScheduleResponse(&schedules[i]))
+	}
+	return models.Response(200, body), nil
+}
+
+// HandleAdminStopGrantSchedule останавливает регулярное начисление. Уже начисленные монеты остаются у получателей
+func (e *Engine) HandleAdminStopGrantSchedule(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminStopGrantSchedule")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.Int64("grant_schedule.id", id))
+
+	schedule, err := e.db.StopGrantSchedule(ctx, id)
+	if errors.Is(err, database.ErrGrantScheduleNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorGrantScheduleNotFound + strconv.FormatInt(id, 10)}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "stop grant schedule", "grant_schedule_id", id, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), nil
+	}
+	slog.InfoContext(ctx, "grant schedule stopped", "grant_schedule_id", id)
+	return models.Response(200, grantScheduleResponse(schedule)), nil
+}
+
+func grantScheduleResponse(schedule *database.GrantSchedule) models.GrantScheduleResponse {
+	response := models.GrantScheduleResponse{
+		Id:        schedule.Id,
+		Name:      schedule.Name,
+		Amount:    int32(schedule.Amount),
+		Reason:    schedule.Reason,
+		Every:     schedule.Interval.String(),
+		Usernames: schedule.Usernames,
+		NextRunAt: schedule.NextRunAt,
+		StoppedAt: schedule.StoppedAt,
+	}
+	if schedule.ExpiresAfter > 0 {
+		response.ExpiresAfter = schedule.ExpiresAfter.String()
+	}
+	return response
+}
+
+// uniqueSorted возвращает имена без повторов в порядке имён, пустой список -- nil
+func uniqueSorted(names []string) []string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	var unique []string
+	for i, name := range sorted {
+		if i == 0 || name != sorted[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// RunScheduler каждые shop.grant_check_interval выполняет наступившие периоды регулярных начислений
+// и сжигает монеты с истёкшим сроком, пока не отменён ctx. Экземпляры сервиса с общей базой могут работать
+// одновременно: каждый период выполняется ровно один раз
+func (e *Engine) RunScheduler(ctx context.Context) {
+	interval := e.cfg.Shop.GrantCheckInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.runGrants(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runGrants выполняет все наступившие периоды, включая пропущенные, пока сервис не работал, затем сжигает
+// монеты с истёкшим сроком. Ошибки только логируются, следующая проверка повторит работу
+func (e *Engine) runGrants(ctx context.Context) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.runGrants")
+	defer span.End()
+
+	now := e.now()
+	for ctx.Err() == nil {
+		schedule, recipients, err := e.db.RunGrantSchedule(ctx, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "run grant schedule", "error", err)
+			return
+		}
+		if schedule == nil {
+			break
+		}
+		if len(schedule.Usernames) == 0 {
+			e.info.invalidateAll()
+		} else {
+			e.info.invalidate(schedule.Usernames...)
+		}
+		e.metrics.CoinsGranted(schedule.Amount * float64(recipients))
+		slog.InfoContext(ctx, "grant schedule run", "grant_schedule_id", schedule.Id, "period", schedule.NextRunAt, "recipients", recipients)
+	}
+
+	expired, err := e.db.ExpireGrants(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "expire grants", "error", err)
+		return
+	}
+	if expired > 0 {
+		e.info.invalidateAll()
+		e.metrics.CoinsExpired(expired)
+		slog.InfoContext(ctx, "coins expired", "amount", expired)
+	}
+}
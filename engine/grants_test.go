@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/metrics"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrantCoins(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GrantsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GrantSchedulesKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	cfg := config.Default()
+	cfg.Cache.InfoTTL = time.Minute
+	registry := prometheus.NewRegistry()
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg), WithMetrics(metrics.New(registry)))
+	resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+	admin := logging.WithAdmin(context.Background(), "alice")
+	info := func() models.InfoResponse {
+		resp, _ := e.HandleApiInfo(ctx)
+		require.Equal(t, 200, resp.Code)
+		return resp.Body.(models.InfoResponse)
+	}
+	assert.Equal(t, int32(1000), info().Coins)
+
+	// начисление сбрасывает кеш сводки и видно в истории как перевод от системы
+	resp, _ = e.HandleAdminGrantCoins(admin, models.GrantRequest{Username: "test_user1", Amount: 100, Reason: "bonus"})
+	assert.Equal(t, models.Response(200, models.GrantsResponse{Count: 1, Total: 100}), resp)
+	assert.Equal(t, int32(1100), info().Coins)
+	assert.Equal(t, []models.InfoResponseCoinHistoryReceivedInner{{FromUser: database.SystemUser, Amount: 100}}, info().CoinHistory.Received)
+
+	now := time.Now()
+	past, hour := now.Add(-time.Minute), now.Add(time.Hour)
+	for _, tc := range []struct {
+		request models.GrantRequest
+		errors  string
+	}{
+		{models.GrantRequest{Amount: 10}, ErrorGrantUsername},
+		{models.GrantRequest{Username: "test_user1", Amount: -10}, ErrorGrantAmount},
+		{models.GrantRequest{Username: "test_user1", Amount: 10, ExpiresAt: &past}, ErrorGrantExpiresAt},
+		{models.GrantRequest{Username: "unknown", Amount: 10}, ErrorUserNotFound + "unknown"},
+	} {
+		resp, _ = e.HandleAdminGrantCoins(admin, tc.request)
+		assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: tc.errors}), resp)
+	}
+
+	// пакет с ошибкой не начисляет ничего, ошибка указывает номер начисления
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{Grants: []models.GrantRequest{
+		{Username: "test_user1", Amount: 10}, {Username: "unknown", Amount: 5},
+	}})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: "начисление 2: " + ErrorUserNotFound + "unknown"}), resp)
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{Grants: []models.GrantRequest{{Username: "test_user1", Amount: 10}, {Amount: 5}}})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: "начисление 2: " + ErrorGrantUsername}), resp)
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorGrantsEmpty}), resp)
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{Grants: make([]models.GrantRequest, maxBulkGrants+1)})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorGrantsTooMany + "10000"}), resp)
+	assert.Equal(t, int32(1100), info().Coins)
+
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{Grants: []models.GrantRequest{
+		{Username: "test_user1", Amount: 50, ExpiresAt: &hour}, {Username: "test_user2", Amount: 50},
+	}})
+	assert.Equal(t, models.Response(200, models.GrantsResponse{Count: 2, Total: 100}), resp)
+	assert.Equal(t, int32(1150), info().Coins)
+
+	// непотраченные монеты сгорают после срока и видны в истории как перевод системе
+	e.now = func() time.Time { return now.Add(2 * time.Hour) }
+	e.runGrants(context.Background())
+	assert.Equal(t, int32(1100), info().Coins)
+	assert.Equal(t, []models.InfoResponseCoinHistorySentInner{{ToUser: database.SystemUser, Amount: 50}}, info().CoinHistory.Sent)
+
+	expected := `
+# HELP avito_shop_coins_granted_total Суммарное количество монет, начисленных системой.
+# TYPE avito_shop_coins_granted_total counter
+avito_shop_coins_granted_total 200
+# HELP avito_shop_coins_expired_total Суммарное количество сгоревших монет.
+# TYPE avito_shop_coins_expired_total counter
+avito_shop_coins_expired_total 50
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "avito_shop_coins_granted_total", "avito_shop_coins_expired_total")
+	assert.NoError(t, err)
+
+	// имя system занято источником начислений
+	resp, _ = e.HandleApiAuth(context.Background(), models.AuthRequest{Username: database.SystemUser, Password: "pass"})
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorReservedUsername + database.SystemUser}), resp)
+}
+
+func TestGrantSchedules(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GrantsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GrantSchedulesKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	start := now.Add(-36 * time.Hour)
+
+	for _, tc := range []struct {
+		request models.GrantScheduleRequest
+		errors  string
+	}{
+		{models.GrantScheduleRequest{Name: "bad", Amount: 0, Every: "1h"}, ErrorGrantAmount},
+		{models.GrantScheduleRequest{Name: "bad", Amount: 10, Every: "month"}, ErrorGrantScheduleEvery},
+		{models.GrantScheduleRequest{Name: "bad", Amount: 10, Every: "1ms"}, ErrorGrantScheduleEvery},
+		{models.GrantScheduleRequest{Name: "bad", Amount: 10, Every: "1h", ExpiresAfter: "-1h"}, ErrorGrantScheduleExpires},
+		{models.GrantScheduleRequest{Name: "bad", Amount: 10, Every: "1h", Usernames: []string{"test_user1", "unknown"}}, ErrorUserNotFound + "unknown"},
+	} {
+		resp, _ := e.HandleAdminAddGrantSchedule(admin, tc.request)
+		assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: tc.errors}), resp)
+	}
+
+	resp, _ := e.HandleAdminAddGrantSchedule(admin, models.GrantScheduleRequest{
+		Name: "salary", Amount: 100, Every: "24h", ExpiresAfter: "48h", Usernames: []string{"test_user1", "test_user1"}, StartsAt: &start,
+	})
+	require.Equal(t, 200, resp.Code)
+	schedule := resp.Body.(models.GrantScheduleResponse)
+	assert.Equal(t, "24h0m0s", schedule.Every)
+	assert.Equal(t, "48h0m0s", schedule.ExpiresAfter)
+	assert.Equal(t, []string{"test_user1"}, schedule.Usernames)
+
+	// пропущенные периоды выполняются при первой же проверке
+	e.runGrants(context.Background())
+	coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1200), coins)
+	resp, _ = e.HandleAdminGrantSchedules(admin)
+	require.Equal(t, 200, resp.Code)
+	schedules := resp.Body.(models.GrantSchedulesResponse).Items
+	require.Len(t, schedules, 1)
+	assert.Equal(t, start.Add(48*time.Hour), schedules[0].NextRunAt)
+
+	resp, _ = e.HandleAdminStopGrantSchedule(admin, schedule.Id)
+	require.Equal(t, 200, resp.Code)
+	assert.NotNil(t, resp.Body.(models.GrantScheduleResponse).StoppedAt)
+	resp, _ = e.HandleAdminStopGrantSchedule(admin, 99)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorGrantScheduleNotFound + "99"}), resp)
+
+	// остановленное расписание больше не начисляет, а начисленные монеты сгорают в свой срок
+	e.now = func() time.Time { return now.Add(48 * time.Hour) }
+	e.runGrants(context.Background())
+	coins, err = mockDb.GetUserCoins(ctx, "test_user1")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1000), coins)
+}
+
+func TestRunScheduler(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GrantsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GrantSchedulesKey).Return(nil)
+	cfg := config.Default()
+	cfg.Shop.GrantCheckInterval = 10 * time.Millisecond
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg))
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	start := time.Now().Add(-1500 * time.Millisecond)
+	resp, _ := e.HandleAdminAddGrantSchedule(admin, models.GrantScheduleRequest{Name: "salary", Amount: 100, Every: "1s", StartsAt: &start})
+	require.Equal(t, 200, resp.Code)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.RunScheduler(runCtx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+		return err == nil && coins == 1200
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// без периода проверки планировщик не запускается
+	cfg.Shop.GrantCheckInterval = 0
+	e.RunScheduler(context.Background())
+}
+
+func TestGrantsErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.GrantsKey).Return(errors.New("error"))
+	mockDb.On("ErrorWithDb", database.GrantSchedulesKey).Return(errors.New("error"))
+	e := NewEngine(mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	resp, _ := e.HandleAdminGrantCoins(admin, models.GrantRequest{Username: "test_user1", Amount: 10})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorGrant}), resp)
+	resp, _ = e.HandleAdminBulkGrantCoins(admin, models.BulkGrantRequest{Grants: []models.GrantRequest{{Username: "test_user1", Amount: 10}}})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorGrant}), resp)
+	resp, _ = e.HandleAdminAddGrantSchedule(admin, models.GrantScheduleRequest{Name: "salary", Amount: 10, Every: "1h"})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), resp)
+	resp, _ = e.HandleAdminGrantSchedules(admin)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), resp)
+	resp, _ = e.HandleAdminStopGrantSchedule(admin, 1)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorGrantSchedule}), resp)
+
+	// ошибки планировщика только логируются
+	e.runGrants(context.Background())
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleApiSendItem передаёт предметы из инвентаря пользователя другому пользователю.
+// Передача видна в разделе itemHistory ответа /api/info обоих пользователей
+func (e *Engine) HandleApiSendItem(ctx context.Context, request models.SendItemRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiSendItem")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	if data.Username == request.ToUser {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), nil
+	}
+	if request.Quantity <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorItemQuantity}), nil
+	}
+
+	err := e.db.SendItem(ctx, data.Username, request.ToUser, request.Item, int64(request.Quantity))
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + request.ToUser}), nil
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + request.Item}), nil
+	case errors.Is(err, database.ErrItemNotOwned):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorNotEnoughItems + request.Item}), nil
+	case errors.Is(err, database.ErrPurchaseLimit):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorRecipientLimit + request.Item}), nil
+	case errors.Is(err, database.ErrUserFrozen):
+		return models.Response(403, models.ErrorResponse{Errors: ErrorUserFrozen}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "send item", "to_user", request.ToUser, "item", request.Item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorSendItem}), nil
+	}
+	e.info.invalidate(data.Username, request.ToUser)
+	slog.InfoContext(ctx, "items sent", "to_user", request.ToUser, "item", request.Item, "quantity", request.Quantity)
+	return models.Response(200, models.ImplResponse{}), nil
+}
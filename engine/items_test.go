@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendItem(e *Engine, ctx context.Context, toUser, item string, quantity int32) models.ImplResponse {
+	resp, _ := e.HandleApiSendItem(ctx, models.SendItemRequest{ToUser: toUser, Item: item, Quantity: quantity})
+	return resp
+}
+
+func TestSendItem(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.SendItemKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.ProductLimitKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+
+	buyItem(t, e, ctx, "cup")
+	buyItem(t, e, ctx, "cup")
+	assert.Equal(t, 200, sendItem(e, ctx, "test_user2", "cup", 1).Code)
+
+	resp, _ = e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+	info := resp.Body.(models.InfoResponse)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []models.InfoResponseItemHistorySentInner{{ToUser: "test_user2", Type: "cup", Quantity: 1}}, info.ItemHistory.Sent)
+
+	resp = sendItem(e, ctx, "test_user2", "cup", 2)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorNotEnoughItems + "cup"}), resp)
+	resp = sendItem(e, ctx, "test_user1", "cup", 1)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), resp)
+	resp = sendItem(e, ctx, "test_user2", "cup", -1)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorItemQuantity}), resp)
+	resp = sendItem(e, ctx, "unknown", "cup", 1)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + "unknown"}), resp)
+	resp = sendItem(e, ctx, "test_user2", "unknown", 1)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"}), resp)
+
+	one := int64(1)
+	require.NoError(t, mockDb.SetProductLimit(ctx, "cup", &one))
+	resp = sendItem(e, ctx, "test_user2", "cup", 1)
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: ErrorRecipientLimit + "cup"}), resp)
+}
+
+func TestSendItemErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.SendItemKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+
+	resp := sendItem(e, ctx, "test_user2", "cup", 1)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorSendItem}), resp)
+}
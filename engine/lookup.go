@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// cacheKeyPrefix отделяет ключи сервиса от чужих ключей в общем Redis
+const cacheKeyPrefix = "avito-shop:"
+
+// виды данных в кеше, они же значения метки kind в метриках
+const (
+	cacheKindProduct = "product"
+	cacheKindUser    = "user"
+)
+
+// cachedProduct -- товар каталога, как его возвращает GetUserCoinsAndItemPrice, вместе с незакончившимися акциями.
+// Акции хранятся целиком, поэтому закешированный товар дешевеет и дорожает точно по их расписанию
+type cachedProduct struct {
+	Id        int64                    `json:"id"`
+	Price     float64                  `json:"price"`
+	Campaigns []database.PriceCampaign `json:"campaigns,omitempty"`
+}
+
+// price возвращает цену товара в момент now с учётом акций
+func (p *cachedProduct) price(now time.Time) float64 {
+	if sale := activeSale(p.Campaigns, now); sale != nil {
+		return sale.Price
+	}
+	return p.Price
+}
+
+// cachedUser -- результат успешной проверки пароля. Сам пароль не хранится, только HMAC
+// с ключом подписи JWT: без ключа содержимое кеша не позволяет подобрать пароль
+type cachedUser struct {
+	Id     int64  `json:"id"`
+	Digest string `json:"digest"`
+}
+
+func productKey(name string) string {
+	return cacheKeyPrefix + cacheKindProduct + ":" + name
+}
+
+func userKey(username string) string {
+	return cacheKeyPrefix + cacheKindUser + ":" + username
+}
+
+// cacheGet читает значение ключа key в v и возвращает true при попадании.
+// Недоступный кеш не мешает обработке запроса: ошибка логируется и считается промахом
+func (e *Engine) cacheGet(ctx context.Context, kind, key string, v any) bool {
+	if e.cache == nil {
+		return false
+	}
+	data, ok, err := e.cache.Get(ctx, key)
+	if err == nil && ok {
+		err = json.Unmarshal(data, v)
+	}
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "cache get", "key", key, "error", err)
+		e.metrics.CacheLookup(kind, "error")
+		return false
+	case !ok:
+		e.metrics.CacheLookup(kind, "miss")
+		return false
+	}
+	e.metrics.CacheLookup(kind, "hit")
+	return true
+}
+
+func (e *Engine) cacheSet(ctx context.Context, key string, v any) {
+	if e.cache == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = e.cache.Set(ctx, key, data, e.cfg.Cache.TTL)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "cache set", "key", key, "error", err)
+	}
+}
+
+func (e *Engine) cacheDelete(ctx context.Context, keys ...string) {
+	if e.cache == nil || len(keys) == 0 {
+		return
+	}
+	if err := e.cache.Delete(ctx, keys...); err != nil {
+		slog.WarnContext(ctx, "cache delete", "keys", keys, "error", err)
+	}
+}
+
+// InvalidateProducts сбрасывает закешированные товары каталога.
+// Вызывается при изменении цены или акций и удалении товара, иначе старая цена действует до истечения cache.ttl
+func (e *Engine) InvalidateProducts(ctx context.Context, names ...string) {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = productKey(name)
+	}
+	e.cacheDelete(ctx, keys...)
+}
+
+// InvalidateUsers сбрасывает закешированные проверки паролей пользователей,
+// например при блокировке, чтобы следующий запрос снова прошёл через хранилище
+func (e *Engine) InvalidateUsers(ctx context.Context, usernames ...string) {
+	keys := make([]string, len(usernames))
+	for i, username := range usernames {
+		keys[i] = userKey(username)
+	}
+	e.cacheDelete(ctx, keys...)
+}
+
+func (e *Engine) passwordDigest(username, password string) string {
+	mac := hmac.New(sha256.New, []byte(e.cfg.Auth.JwtKey))
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorizeUser проверяет пароль пользователя. Успешные проверки кешируются, неудачные всегда идут в хранилище
+func (e *Engine) authorizeUser(ctx context.Context, username, password string) (bool, int64, error) {
+	if e.cache == nil {
+		return e.db.AuthorizeUser(ctx, username, password)
+	}
+
+	digest := e.passwordDigest(username, password)
+	var user cachedUser
+	if e.cacheGet(ctx, cacheKindUser, userKey(username), &user) && hmac.Equal([]byte(user.Digest), []byte(digest)) {
+		return true, user.Id, nil
+	}
+
+	ok, id, err := e.db.AuthorizeUser(ctx, username, password)
+	if err == nil && ok {
+		e.cacheSet(ctx, userKey(username), cachedUser{Id: id, Digest: digest})
+	}
+	return ok, id, err
+}
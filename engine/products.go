@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleApiProducts возвращает каталог с ценами и остатками товаров. У товаров с действующей акцией
+// цена каталога остаётся в price, а цена по акции и её окончание -- в sale
+func (e *Engine) HandleApiProducts(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiProducts")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	products, err := e.db.GetProducts(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "get products", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorProducts}), nil
+	}
+	sales, err := e.activeSales(ctx, e.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "get price campaigns", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorProducts}), nil
+	}
+	catalog := models.CatalogResponse{Items: make([]models.CatalogItem, 0, len(products))}
+	for _, p := range products {
+		item := models.CatalogItem{Name: p.Name, Price: int32(p.Price), Stock: p.Stock, MaxPerUser: p.MaxPerUser}
+		if sale, ok := sales[p.Name]; ok {
+			item.Sale = &models.CatalogItemSale{Name: sale.Name, Price: int32(sale.Price), EndsAt: sale.EndsAt}
+		}
+		catalog.Items = append(catalog.Items, item)
+	}
+	return models.Response(200, catalog), nil
+}
+
+// HandleAdminSetProductStock задаёт остаток товара или снимает ограничение количества.
+// Администратор уже проверен middleware, его имя лежит в контексте
+func (e *Engine) HandleAdminSetProductStock(ctx context.Context, item string, request models.ProductStockRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminSetProductStock")
+	defer func() { endSpan(span, result) }()
+
+	if request.Stock != nil && *request.Stock < 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorStock}), nil
+	}
+	err := e.db.SetProductStock(ctx, item, request.Stock)
+	if errors.Is(err, database.ErrProductNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "set product stock", "item", item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorUpdateStock}), nil
+	}
+	if request.Stock == nil {
+		slog.InfoContext(ctx, "product stock set", "item", item, "stock", "unlimited")
+	} else {
+		slog.InfoContext(ctx, "product stock set", "item", item, "stock", *request.Stock)
+	}
+	return models.Response(200, models.ProductStockResponse{Name: item, Stock: request.Stock}), nil
+}
+
+// HandleAdminRestockProduct добавляет единицы к остатку товара с ограниченным количеством
+func (e *Engine) HandleAdminRestockProduct(ctx context.Context, item string, request models.RestockRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminRestockProduct")
+	defer func() { endSpan(span, result) }()
+
+	if request.Quantity <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorRestockQuantity}), nil
+	}
+	stock, err := e.db.RestockProduct(ctx, item, request.Quantity)
+	switch {
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item}), nil
+	case errors.Is(err, database.ErrUnlimitedStock):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorUnlimitedStock + item}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "restock product", "item", item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorUpdateStock}), nil
+	}
+	slog.InfoContext(ctx, "product restocked", "item", item, "quantity", request.Quantity, "stock", stock)
+	return models.Response(200, models.ProductStockResponse{Name: item, Stock: &stock}), nil
+}
+
+// HandleAdminSetProductLimit задаёт, сколько единиц товара может купить один пользователь, или снимает лимит.
+// Лимиты есть в /api/info каждого пользователя, поэтому кеш сводок сбрасывается целиком
+func (e *Engine) HandleAdminSetProductLimit(ctx context.Context, item string, request models.ProductLimitRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminSetProductLimit")
+	defer func() { endSpan(span, result) }()
+
+	if request.MaxPerUser != nil && *request.MaxPerUser <= 0 {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductLimit}), nil
+	}
+	err := e.db.SetProductLimit(ctx, item, request.MaxPerUser)
+	if errors.Is(err, database.ErrProductNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "set product limit", "item", item, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorUpdateLimit}), nil
+	}
+	e.info.invalidateAll()
+	if request.MaxPerUser == nil {
+		slog.InfoContext(ctx, "product limit set", "item", item, "max_per_user", "unlimited")
+	} else {
+		slog.InfoContext(ctx, "product limit set", "item", item, "max_per_user", *request.MaxPerUser)
+	}
+	return models.Response(200, models.ProductLimitResponse{Name: item, MaxPerUser: request.MaxPerUser}), nil
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProductsEngine создаёт движок и пользователя, возвращает контекст с его токеном
+func newProductsEngine(t *testing.T, mockDb *database.MockDatabase, opts ...Option) (*Engine, context.Context) {
+	e := NewEngine(mockDb, opts...)
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserCoinsAndItemPriceKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PriceCampaignsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UpdateUserBalanceAndInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+
+	ctx := context.Background()
+	resp, _ := e.HandleApiAuth(ctx, models.AuthRequest{Username: "test_user1", Password: "test_pass1"})
+	require.Equal(t, 200, resp.Code)
+	addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
+	return e, ctx
+}
+
+func stockOf(t *testing.T, e *Engine, ctx context.Context, item string) *int64 {
+	resp, _ := e.HandleApiProducts(ctx)
+	require.Equal(t, 200, resp.Code)
+	for _, p := range resp.Body.(models.CatalogResponse).Items {
+		if p.Name == item {
+			return p.Stock
+		}
+	}
+	t.Fatalf("товара %s нет в каталоге", item)
+	return nil
+}
+
+func TestProductStock(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.ProductStockKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+
+	// по умолчанию количество товаров не ограничено
+	resp, _ := e.HandleApiProducts(ctx)
+	assert.Equal(t, 200, resp.Code)
+	catalog := resp.Body.(models.CatalogResponse)
+	assert.Equal(t, []models.CatalogItem{{Name: "cup", Price: 20}, {Name: "something_else", Price: 30}, {Name: "t-shirt", Price: 100}}, catalog.Items)
+
+	one := int64(1)
+	resp, _ = e.HandleAdminSetProductStock(ctx, "t-shirt", models.ProductStockRequest{Stock: &one})
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, models.ProductStockResponse{Name: "t-shirt", Stock: &one}, resp.Body)
+
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	assert.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	assert.Equal(t, 409, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorSoldOut + "t-shirt"}, resp.Body)
+	assert.Equal(t, int64(0), *stockOf(t, e, ctx, "t-shirt"))
+
+	// покупка распроданного товара не списывает монеты
+	coins, _ := mockDb.GetUserCoins(ctx, "test_user1")
+	assert.Equal(t, float64(900), coins)
+
+	resp, _ = e.HandleAdminRestockProduct(ctx, "t-shirt", models.RestockRequest{Quantity: 2})
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, int64(2), *resp.Body.(models.ProductStockResponse).Stock)
+	resp, _ = e.HandleApiByuItem(ctx, "t-shirt")
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, int64(1), *stockOf(t, e, ctx, "t-shirt"))
+
+	// снятие ограничения
+	resp, _ = e.HandleAdminSetProductStock(ctx, "t-shirt", models.ProductStockRequest{})
+	assert.Equal(t, 200, resp.Code)
+	assert.Nil(t, stockOf(t, e, ctx, "t-shirt"))
+}
+
+func TestProductStockInvalid(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductStockKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+
+	negative := int64(-1)
+	resp, _ := e.HandleAdminSetProductStock(ctx, "cup", models.ProductStockRequest{Stock: &negative})
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorStock}, resp.Body)
+
+	resp, _ = e.HandleAdminSetProductStock(ctx, "unknown", models.ProductStockRequest{})
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"}, resp.Body)
+
+	resp, _ = e.HandleAdminRestockProduct(ctx, "cup", models.RestockRequest{Quantity: 0})
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorRestockQuantity}, resp.Body)
+
+	resp, _ = e.HandleAdminRestockProduct(ctx, "unknown", models.RestockRequest{Quantity: 1})
+	assert.Equal(t, 400, resp.Code)
+
+	// пополнять можно только товар с ограниченным остатком
+	resp, _ = e.HandleAdminRestockProduct(ctx, "cup", models.RestockRequest{Quantity: 1})
+	assert.Equal(t, 409, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorUnlimitedStock + "cup"}, resp.Body)
+}
+
+func TestProductStockErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(errors.New("error"))
+	mockDb.On("ErrorWithDb", database.ProductStockKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+
+	resp, _ := e.HandleApiProducts(ctx)
+	assert.Equal(t, 500, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorProducts}, resp.Body)
+
+	resp, _ = e.HandleAdminSetProductStock(ctx, "cup", models.ProductStockRequest{})
+	assert.Equal(t, 500, resp.Code)
+	resp, _ = e.HandleAdminRestockProduct(ctx, "cup", models.RestockRequest{Quantity: 1})
+	assert.Equal(t, 500, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorUpdateStock}, resp.Body)
+}
+
+func TestProductLimit(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.ProductLimitKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	cfg := config.Default()
+	cfg.Cache.InfoTTL = time.Minute
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg))
+
+	// сводка попадает в кеш до установки лимита
+	resp, _ := e.HandleApiInfo(ctx)
+	assert.Equal(t, 200, resp.Code)
+	assert.Empty(t, resp.Body.(models.InfoResponse).Limits)
+
+	two := int64(2)
+	resp, _ = e.HandleAdminSetProductLimit(ctx, "cup", models.ProductLimitRequest{MaxPerUser: &two})
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, models.ProductLimitResponse{Name: "cup", MaxPerUser: &two}, resp.Body)
+	resp, _ = e.HandleApiProducts(ctx)
+	assert.Equal(t, models.CatalogItem{Name: "cup", Price: 20, MaxPerUser: &two}, resp.Body.(models.CatalogResponse).Items[0])
+
+	resp, _ = e.HandleApiInfo(ctx)
+	assert.Equal(t, []models.InfoResponseLimitsInner{{Type: "cup", MaxPerUser: 2, Remaining: 2}}, resp.Body.(models.InfoResponse).Limits)
+
+	for i := 0; i < 2; i++ {
+		resp, _ = e.HandleApiByuItem(ctx, "cup")
+		assert.Equal(t, 200, resp.Code)
+	}
+	resp, _ = e.HandleApiByuItem(ctx, "cup")
+	assert.Equal(t, 409, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorPurchaseLimit + "cup"}, resp.Body)
+
+	resp, _ = e.HandleApiInfo(ctx)
+	info := resp.Body.(models.InfoResponse)
+	assert.Equal(t, int32(960), info.Coins)
+	assert.Equal(t, []models.InfoResponseLimitsInner{{Type: "cup", MaxPerUser: 2, Remaining: 0}}, info.Limits)
+
+	// снятие лимита
+	resp, _ = e.HandleAdminSetProductLimit(ctx, "cup", models.ProductLimitRequest{})
+	assert.Equal(t, 200, resp.Code)
+	resp, _ = e.HandleApiByuItem(ctx, "cup")
+	assert.Equal(t, 200, resp.Code)
+}
+
+func TestProductLimitInvalid(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductLimitKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+
+	zero := int64(0)
+	resp, _ := e.HandleAdminSetProductLimit(ctx, "cup", models.ProductLimitRequest{MaxPerUser: &zero})
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorProductLimit}, resp.Body)
+
+	resp, _ = e.HandleAdminSetProductLimit(ctx, "unknown", models.ProductLimitRequest{})
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorProductNotFound + "unknown"}, resp.Body)
+
+	mockDb = database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.ProductLimitKey).Return(errors.New("error"))
+	e, ctx = newProductsEngine(t, mockDb)
+	resp, _ = e.HandleAdminSetProductLimit(ctx, "cup", models.ProductLimitRequest{})
+	assert.Equal(t, 500, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorUpdateLimit}, resp.Body)
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleAdminAddPromoCode создаёт промокод со скидкой в процентах или фиксированной суммой.
+// Администратор уже проверен middleware, его имя лежит в контексте
+func (e *Engine) HandleAdminAddPromoCode(ctx context.Context, request models.PromoCodeRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminAddPromoCode")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("promo_code", request.Code))
+
+	switch {
+	case (request.Percent > 0) == (request.Amount > 0), request.Percent < 0, request.Percent > 100, request.Amount < 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeDiscount}), nil
+	case request.StartsAt != nil && request.EndsAt != nil && !request.StartsAt.Before(*request.EndsAt):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeWindow}), nil
+	case request.MaxUses != nil && *request.MaxUses <= 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeMaxUses}), nil
+	}
+	promo := database.PromoCode{
+		Code:     request.Code,
+		Percent:  int64(request.Percent),
+		Amount:   float64(request.Amount),
+		Item:     request.Item,
+		StartsAt: request.StartsAt,
+		EndsAt:   request.EndsAt,
+		MaxUses:  request.MaxUses,
+	}
+	err := e.db.AddPromoCode(ctx, promo)
+	switch {
+	case errors.Is(err, database.ErrPromoCodeExists):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorPromoCodeExists + request.Code}), nil
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + request.Item}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "add promo code", "code", request.Code, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorPromoCode}), nil
+	}
+	slog.InfoContext(ctx, "promo code added", "code", request.Code, "percent", request.Percent, "amount", request.Amount, "item", request.Item)
+	return models.Response(200, promoCodeResponse(&promo)), nil
+}
+
+// HandleAdminPromoCodes возвращает все промокоды вместе с числом применений
+func (e *Engine) HandleAdminPromoCodes(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminPromoCodes")
+	defer func() { endSpan(span, result) }()
+
+	promos, err := e.db.GetPromoCodes(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "get promo codes", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorPromoCode}), nil
+	}
+	body := models.PromoCodesResponse{Items: make([]models.PromoCodeResponse, 0, len(promos))}
+	for i := range promos {
+		body.Items = append(body.Items, promoCodeResponse(&promos[i]))
+	}
+	return models.Response(200, body), nil
+}
+
+// HandleAdminDisablePromoCode отключает промокод. Код не удаляется, на него ссылаются покупки
+func (e *Engine) HandleAdminDisablePromoCode(ctx context.Context, code string) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminDisablePromoCode")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.String("promo_code", code))
+
+	promo, err := e.db.DisablePromoCode(ctx, code)
+	if errors.Is(err, database.ErrPromoCodeNotFound) {
+		return models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotFound}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "disable promo code", "code", code, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorPromoCode}), nil
+	}
+	slog.InfoContext(ctx, "promo code disabled", "code", code)
+	return models.Response(200, promoCodeResponse(promo)), nil
+}
+
+func promoCodeResponse(promo *database.PromoCode) models.PromoCodeResponse {
+	return models.PromoCodeResponse{
+		Code:       promo.Code,
+		Percent:    int32(promo.Percent),
+		Amount:     int32(promo.Amount),
+		Item:       promo.Item,
+		StartsAt:   promo.StartsAt,
+		EndsAt:     promo.EndsAt,
+		MaxUses:    promo.MaxUses,
+		Uses:       promo.Uses,
+		DisabledAt: promo.DisabledAt,
+	}
+}
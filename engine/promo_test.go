@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoCodes(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PromoCodesKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.PurchaseKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	one := int64(1)
+	resp, _ := e.HandleAdminAddPromoCode(admin, models.PromoCodeRequest{Code: "HALF", Percent: 50, Item: "t-shirt", MaxUses: &one})
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, models.PromoCodeResponse{Code: "HALF", Percent: 50, Item: "t-shirt", MaxUses: &one}, resp.Body)
+	resp, _ = e.HandleAdminAddPromoCode(admin, models.PromoCodeRequest{Code: "TEN", Amount: 10})
+	require.Equal(t, 200, resp.Code)
+
+	start := time.Now()
+	for _, tc := range []struct {
+		request models.PromoCodeRequest
+		code    int
+		errors  string
+	}{
+		{models.PromoCodeRequest{Code: "BAD", Percent: 10, Amount: 10}, 400, ErrorPromoCodeDiscount},
+		{models.PromoCodeRequest{Code: "BAD"}, 400, ErrorPromoCodeDiscount},
+		{models.PromoCodeRequest{Code: "BAD", Percent: 150}, 400, ErrorPromoCodeDiscount},
+		{models.PromoCodeRequest{Code: "BAD", Percent: 10, StartsAt: &start, EndsAt: &start}, 400, ErrorPromoCodeWindow},
+		{models.PromoCodeRequest{Code: "BAD", Percent: 10, MaxUses: new(int64)}, 400, ErrorPromoCodeMaxUses},
+		{models.PromoCodeRequest{Code: "BAD", Percent: 10, Item: "unknown"}, 400, ErrorProductNotFound + "unknown"},
+		{models.PromoCodeRequest{Code: "HALF", Percent: 10}, 409, ErrorPromoCodeExists + "HALF"},
+	} {
+		resp, _ = e.HandleAdminAddPromoCode(admin, tc.request)
+		assert.Equal(t, models.Response(tc.code, models.ErrorResponse{Errors: tc.errors}), resp)
+	}
+
+	// скидка списывается в покупке и видна в ответе и в записи покупки
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "t-shirt", "HALF")
+	require.Equal(t, 200, resp.Code)
+	purchase := resp.Body.(models.PurchaseResponse)
+	assert.Equal(t, int32(50), purchase.Price)
+	assert.Equal(t, int32(50), purchase.Discount)
+	coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+	require.NoError(t, err)
+	assert.Equal(t, float64(950), coins)
+	record, err := mockDb.GetPurchase(ctx, purchase.PurchaseId)
+	require.NoError(t, err)
+	assert.Equal(t, "HALF", record.PromoCode)
+
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "t-shirt", "HALF")
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: ErrorPromoCodeExhausted}), resp)
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "cup", "UNKNOWN")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotFound}), resp)
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "cup", "TEN")
+	assert.Equal(t, 200, resp.Code)
+
+	resp, _ = e.HandleAdminPromoCodes(admin)
+	require.Equal(t, 200, resp.Code)
+	promos := resp.Body.(models.PromoCodesResponse).Items
+	require.Len(t, promos, 2)
+	assert.Equal(t, int64(1), promos[0].Uses)
+	assert.Equal(t, "TEN", promos[1].Code)
+
+	resp, _ = e.HandleAdminDisablePromoCode(admin, "TEN")
+	require.Equal(t, 200, resp.Code)
+	assert.NotNil(t, resp.Body.(models.PromoCodeResponse).DisabledAt)
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "cup", "TEN")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeInactive}), resp)
+	resp, _ = e.HandleAdminDisablePromoCode(admin, "UNKNOWN")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotFound}), resp)
+}
+
+func TestPromoCodeNotApplicable(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PromoCodesKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+
+	require.NoError(t, mockDb.AddPromoCode(ctx, database.PromoCode{Code: "CUP", Percent: 100, Item: "cup"}))
+	resp, _ := e.HandleApiBuyItemWithPromoCode(ctx, "t-shirt", "CUP")
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorPromoCodeNotApplicable + "t-shirt"}), resp)
+	resp, _ = e.HandleApiBuyItemWithPromoCode(ctx, "cup", "CUP")
+	assert.Equal(t, models.Response(200, models.PurchaseResponse{PurchaseId: 1, Discount: 20}), resp)
+}
+
+func TestPromoCodesErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PromoCodesKey).Return(errors.New("error"))
+	e := NewEngine(mockDb)
+	admin := logging.WithAdmin(context.Background(), "alice")
+
+	resp, _ := e.HandleAdminAddPromoCode(admin, models.PromoCodeRequest{Code: "TEN", Amount: 10})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorPromoCode}), resp)
+	resp, _ = e.HandleAdminPromoCodes(admin)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorPromoCode}), resp)
+}
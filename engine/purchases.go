@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleApiRefundPurchase возвращает покупку пользователя, если с неё прошло не больше shop.refund_window.
+// Подарки возвращает только администратор: предмет забирается у получателя, и покупатель не должен делать это сам.
+// Повторный возврат уже возвращённой покупки ничего не меняет и отвечает так же, как первый
+func (e *Engine) HandleApiRefundPurchase(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiRefundPurchase")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id), attribute.Int64("purchase.id", id))
+
+	purchase, err := e.db.GetPurchase(ctx, id)
+	// чужие покупки не отличаются от несуществующих
+	if errors.Is(err, database.ErrPurchaseNotFound) || (err == nil && purchase.UserId != data.Id) {
+		return models.Response(400, models.ErrorResponse{Errors: fmt.Sprintf("%s%d", ErrorPurchaseNotFound, id)}), nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "get purchase", "purchase_id", id, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), nil
+	}
+	if purchase.RefundedAt == nil {
+		if purchase.Recipient != "" {
+			return models.Response(400, models.ErrorResponse{Errors: ErrorRefundGift}), nil
+		}
+		if e.cfg.Shop.RefundWindow == 0 {
+			return models.Response(400, models.ErrorResponse{Errors: ErrorRefundDisabled}), nil
+		}
+		if e.now().Sub(purchase.CreatedAt) > e.cfg.Shop.RefundWindow {
+			return models.Response(400, models.ErrorResponse{Errors: ErrorRefundWindow}), nil
+		}
+	}
+	return e.refundPurchase(ctx, id, "")
+}
+
+// HandleAdminRefundPurchase возвращает любую покупку без ограничения по сроку.
+// Администратор уже проверен middleware, его имя лежит в контексте и сохраняется в покупке
+func (e *Engine) HandleAdminRefundPurchase(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleAdminRefundPurchase")
+	defer func() { endSpan(span, result) }()
+	span.SetAttributes(attribute.Int64("purchase.id", id))
+
+	return e.refundPurchase(ctx, id, logging.Admin(ctx))
+}
+
+// refundPurchase оформляет возврат от имени администратора by, пустая строка -- от имени покупателя
+func (e *Engine) refundPurchase(ctx context.Context, id int64, by string) (models.ImplResponse, error) {
+	purchase, refunded, err := e.db.RefundPurchase(ctx, id, by)
+	switch {
+	case errors.Is(err, database.ErrPurchaseNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: fmt.Sprintf("%s%d", ErrorPurchaseNotFound, id)}), nil
+	case errors.Is(err, database.ErrItemNotOwned):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorItemNotOwned}), nil
+	case err != nil:
+		slog.ErrorContext(ctx, "refund purchase", "purchase_id", id, "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorRefund}), nil
+	}
+	if refunded {
+		e.info.invalidate(purchase.Username)
+		if purchase.Recipient != "" {
+			e.info.invalidate(purchase.Recipient)
+		}
+		slog.InfoContext(ctx, "purchase refunded", "purchase_id", id, "item", purchase.Item, "price", purchase.Price)
+	}
+	return models.Response(200, models.RefundResponse{
+		Id:         purchase.Id,
+		Item:       purchase.Item,
+		Price:      int32(purchase.Price),
+		RefundedAt: *purchase.RefundedAt,
+		RefundedBy: purchase.RefundedBy,
+	}), nil
+}
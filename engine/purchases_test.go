@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buyItem покупает товар и возвращает id покупки
+func buyItem(t *testing.T, e *Engine, ctx context.Context, item string) int64 {
+	resp, _ := e.HandleApiByuItem(ctx, item)
+	require.Equal(t, 200, resp.Code)
+	return resp.Body.(models.PurchaseResponse).PurchaseId
+}
+
+func TestRefundPurchase(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PurchaseKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.RefundPurchaseKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	cfg := config.Default()
+	cfg.Shop.RefundWindow = time.Hour
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg))
+
+	cup := buyItem(t, e, ctx, "cup")
+	shirt := buyItem(t, e, ctx, "t-shirt")
+	resp, _ := e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, int32(1000-20-100), resp.Body.(models.InfoResponse).Coins)
+
+	// пользователь возвращает покупку сам, повторный возврат отвечает так же
+	resp, _ = e.HandleApiRefundPurchase(ctx, cup)
+	assert.Equal(t, 200, resp.Code)
+	refund := resp.Body.(models.RefundResponse)
+	assert.Equal(t, cup, refund.Id)
+	assert.Equal(t, "cup", refund.Item)
+	assert.Equal(t, int32(20), refund.Price)
+	assert.Empty(t, refund.RefundedBy)
+	resp, _ = e.HandleApiRefundPurchase(ctx, cup)
+	assert.Equal(t, models.Response(200, refund), resp)
+
+	resp, _ = e.HandleApiInfo(ctx)
+	require.Equal(t, 200, resp.Code)
+	info := resp.Body.(models.InfoResponse)
+	assert.Equal(t, int32(1000-100), info.Coins)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "t-shirt", Quantity: 1}}, info.Inventory)
+
+	// чужую покупку нельзя вернуть и даже узнать о ней
+	other := context.Background()
+	resp, _ = e.HandleApiAuth(other, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+	addTokenToCtx(&other, resp.Body.(models.AuthResponse).Token)
+	resp, _ = e.HandleApiRefundPurchase(other, shirt)
+	assert.Equal(t, 400, resp.Code)
+	resp, _ = e.HandleApiRefundPurchase(ctx, 1000)
+	assert.Equal(t, 400, resp.Code)
+
+	// после окончания окна вернуть покупку может только администратор
+	e.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	resp, _ = e.HandleApiRefundPurchase(ctx, shirt)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorRefundWindow}), resp)
+
+	admin := logging.WithAdmin(context.Background(), "alice")
+	resp, _ = e.HandleAdminRefundPurchase(admin, shirt)
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "alice", resp.Body.(models.RefundResponse).RefundedBy)
+	resp, _ = e.HandleAdminRefundPurchase(admin, 1000)
+	assert.Equal(t, 400, resp.Code)
+
+	coins, err := mockDb.GetUserCoins(ctx, "test_user1")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1000), coins)
+}
+
+func TestRefundPurchaseDisabled(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PurchaseKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.RefundPurchaseKey).Return(nil)
+	cfg := config.Default()
+	cfg.Shop.RefundWindow = 0
+	e, ctx := newProductsEngine(t, mockDb, WithConfig(cfg))
+
+	cup := buyItem(t, e, ctx, "cup")
+	resp, _ := e.HandleApiRefundPurchase(ctx, cup)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorRefundDisabled}), resp)
+
+	resp, _ = e.HandleAdminRefundPurchase(logging.WithAdmin(context.Background(), "alice"), cup)
+	assert.Equal(t, 200, resp.Code)
+}
+
+func TestRefundPurchaseErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.PurchaseKey).Return(errors.New("error"))
+	mockDb.On("ErrorWithDb", database.RefundPurchaseKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+
+	cup := buyItem(t, e, ctx, "cup")
+	resp, _ := e.HandleApiRefundPurchase(ctx, cup)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorDatabase}), resp)
+	resp, _ = e.HandleAdminRefundPurchase(context.Background(), cup)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorRefund}), resp)
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HandleApiCreateTrade предлагает другому пользователю обмен предметов из инвентаря на его предметы и/или монеты.
+// Предметы не резервируются: обмен выполняется целиком, когда получатель принимает предложение
+func (e *Engine) HandleApiCreateTrade(ctx context.Context, request models.TradeRequest) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiCreateTrade")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	switch {
+	case data.Username == request.ToUser:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorSameUser}), nil
+	case request.Quantity <= 0, request.WantQuantity < 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorItemQuantity}), nil
+	case request.Coins < 0:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorTradeCoins}), nil
+	case (request.WantItem == "") != (request.WantQuantity == 0), request.WantQuantity == 0 && request.Coins == 0, request.WantItem == request.Item:
+		return models.Response(400, models.ErrorResponse{Errors: ErrorTradeWant}), nil
+	}
+
+	trade := database.Trade{
+		From:         data.Username,
+		To:           request.ToUser,
+		Item:         request.Item,
+		Quantity:     int64(request.Quantity),
+		WantItem:     request.WantItem,
+		WantQuantity: int64(request.WantQuantity),
+		Coins:        float64(request.Coins),
+	}
+	id, err := e.db.AddTrade(ctx, trade)
+	if err != nil {
+		return e.tradeError(ctx, err, request.ToUser, 0), nil
+	}
+	trade.Id = id
+	trade.Status = database.TradeOpen
+	trade.CreatedAt = e.now()
+	slog.InfoContext(ctx, "trade offered", "trade_id", id, "to_user", request.ToUser, "item", request.Item, "quantity", request.Quantity)
+	return models.Response(200, tradeResponse(&trade)), nil
+}
+
+// HandleApiTrades возвращает предложения обмена, отправленные и полученные пользователем, включая закрытые
+func (e *Engine) HandleApiTrades(ctx context.Context) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiTrades")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id))
+
+	trades, err := e.db.GetTrades(ctx, data.Username)
+	if err != nil {
+		slog.ErrorContext(ctx, "get trades", "error", err)
+		return models.Response(500, models.ErrorResponse{Errors: ErrorTrade}), nil
+	}
+	body := models.TradesResponse{Items: make([]models.TradeResponse, 0, len(trades))}
+	for i := range trades {
+		body.Items = append(body.Items, tradeResponse(&trades[i]))
+	}
+	return models.Response(200, body), nil
+}
+
+// HandleApiAcceptTrade принимает полученное предложение: предметы и монеты обеих сторон переходят одной транзакцией,
+// обмен виден в истории /api/info обоих пользователей как передачи предметов и перевод монет
+func (e *Engine) HandleApiAcceptTrade(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiAcceptTrade")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id), attribute.Int64("trade.id", id))
+
+	trade, err := e.db.AcceptTrade(ctx, id, data.Username)
+	if err != nil {
+		return e.tradeError(ctx, err, "", id), nil
+	}
+	e.info.invalidate(trade.From, trade.To)
+	slog.InfoContext(ctx, "trade accepted", "trade_id", id, "from_user", trade.From)
+	return models.Response(200, tradeResponse(trade)), nil
+}
+
+// HandleApiCloseTrade закрывает предложение без обмена: автор его отменяет, получатель отклоняет
+func (e *Engine) HandleApiCloseTrade(ctx context.Context, id int64) (result models.ImplResponse, _ error) {
+	ctx, span := tracing.Tracer().Start(ctx, "engine.HandleApiCloseTrade")
+	defer func() { endSpan(span, result) }()
+
+	data, response := e.getAccountData(ctx)
+	if data == nil {
+		return response, nil
+	}
+	ctx = logging.WithUserID(ctx, data.Id)
+	span.SetAttributes(attribute.Int64("user.id", data.Id), attribute.Int64("trade.id", id))
+
+	trade, err := e.db.CloseTrade(ctx, id, data.Username)
+	if err != nil {
+		return e.tradeError(ctx, err, "", id), nil
+	}
+	slog.InfoContext(ctx, "trade closed", "trade_id", id, "status", trade.Status)
+	return models.Response(200, tradeResponse(trade)), nil
+}
+
+// tradeError переводит ошибку хранилища при работе с обменом в ответ: toUser -- получатель нового предложения,
+// id -- принимаемое или закрываемое предложение. Вызывающий уже прошёл проверку блокировки,
+// поэтому ErrUserFrozen относится ко второму участнику
+func (e *Engine) tradeError(ctx context.Context, err error, toUser string, id int64) models.ImplResponse {
+	var item string
+	var itemErr *database.ItemError
+	if errors.As(err, &itemErr) {
+		item = itemErr.Item
+	}
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserNotFound + toUser})
+	case errors.Is(err, database.ErrProductNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorProductNotFound + item})
+	case errors.Is(err, database.ErrTradeNotFound):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorTradeNotFound + strconv.FormatInt(id, 10)})
+	case errors.Is(err, database.ErrTradeClosed):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorTradeClosed + strconv.FormatInt(id, 10)})
+	case errors.Is(err, database.ErrItemNotOwned):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorTradeItems + item})
+	case errors.Is(err, database.ErrPurchaseLimit):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorTradeLimit + item})
+	case errors.Is(err, database.ErrInsufficientFunds):
+		return models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance})
+	case errors.Is(err, database.ErrUserFrozen):
+		return models.Response(409, models.ErrorResponse{Errors: ErrorTradeUserFrozen})
+	}
+	slog.ErrorContext(ctx, "trade", "trade_id", id, "to_user", toUser, "error", err)
+	return models.Response(500, models.ErrorResponse{Errors: ErrorTrade})
+}
+
+func tradeResponse(trade *database.Trade) models.TradeResponse {
+	return models.TradeResponse{
+		Id:           trade.Id,
+		FromUser:     trade.From,
+		ToUser:       trade.To,
+		Item:         trade.Item,
+		Quantity:     int32(trade.Quantity),
+		WantItem:     trade.WantItem,
+		WantQuantity: int32(trade.WantQuantity),
+		Coins:        int32(trade.Coins),
+		Status:       trade.Status,
+		CreatedAt:    trade.CreatedAt,
+		ClosedAt:     trade.ClosedAt,
+	}
+}
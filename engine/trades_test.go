@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"api-avito-shop/database"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTrade(t *testing.T, e *Engine, ctx context.Context, request models.TradeRequest) int64 {
+	resp, _ := e.HandleApiCreateTrade(ctx, request)
+	require.Equal(t, 200, resp.Code)
+	return resp.Body.(models.TradeResponse).Id
+}
+
+func TestTrades(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.TradesKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendItemKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserInventoryKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.UserTransactionsKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	e.info = newInfoCache(time.Minute)
+
+	other := context.Background()
+	resp, _ := e.HandleApiAuth(other, models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+	addTokenToCtx(&other, resp.Body.(models.AuthResponse).Token)
+	info := func(ctx context.Context) models.InfoResponse {
+		resp, _ := e.HandleApiInfo(ctx)
+		require.Equal(t, 200, resp.Code)
+		return resp.Body.(models.InfoResponse)
+	}
+	buyItem(t, e, ctx, "cup")
+	buyItem(t, e, ctx, "cup")
+	buyItem(t, e, other, "t-shirt")
+	// сводки обоих участников попадают в кеш до обмена
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 2}}, info(ctx).Inventory)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "t-shirt", Quantity: 1}}, info(other).Inventory)
+
+	// предмет на предмет: предложение видят оба участника, принимает получатель
+	itemTrade := createTrade(t, e, ctx, models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 2, WantItem: "t-shirt", WantQuantity: 1})
+	resp, _ = e.HandleApiTrades(other)
+	require.Equal(t, 200, resp.Code)
+	trades := resp.Body.(models.TradesResponse).Items
+	require.Len(t, trades, 1)
+	assert.Equal(t, database.TradeOpen, trades[0].Status)
+	assert.Equal(t, "test_user1", trades[0].FromUser)
+
+	resp, _ = e.HandleApiAcceptTrade(ctx, itemTrade)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: fmt.Sprintf("%s%d", ErrorTradeNotFound, itemTrade)}), resp)
+	resp, _ = e.HandleApiAcceptTrade(other, itemTrade)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, database.TradeAccepted, resp.Body.(models.TradeResponse).Status)
+	sender := info(ctx)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "t-shirt", Quantity: 1}}, sender.Inventory)
+	assert.Equal(t, []models.InfoResponseItemHistoryReceivedInner{{FromUser: "test_user2", Type: "t-shirt", Quantity: 1}}, sender.ItemHistory.Received)
+	assert.Equal(t, []models.InfoResponseInventoryInner{{Type: "cup", Quantity: 2}}, info(other).Inventory)
+	resp, _ = e.HandleApiAcceptTrade(other, itemTrade)
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: fmt.Sprintf("%s%d", ErrorTradeClosed, itemTrade)}), resp)
+
+	// предмет за монеты: если у получателя не хватает монет или у автора уже нет предмета, обмен не выполняется
+	coinTrade := createTrade(t, e, ctx, models.TradeRequest{ToUser: "test_user2", Item: "t-shirt", Quantity: 1, Coins: 50})
+	require.NoError(t, mockDb.SendCoins(ctx, "test_user2", "test_user1", 860))
+	resp, _ = e.HandleApiAcceptTrade(other, coinTrade)
+	assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: ErrorUserBalance}), resp)
+	require.NoError(t, mockDb.SendCoins(ctx, "test_user1", "test_user2", 860))
+	require.NoError(t, mockDb.SendItem(ctx, "test_user1", "test_user2", "t-shirt", 1))
+	resp, _ = e.HandleApiAcceptTrade(other, coinTrade)
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: ErrorTradeItems + "t-shirt"}), resp)
+	require.NoError(t, mockDb.SendItem(ctx, "test_user2", "test_user1", "t-shirt", 1))
+	resp, _ = e.HandleApiAcceptTrade(other, coinTrade)
+	require.Equal(t, 200, resp.Code)
+	sender = info(ctx)
+	assert.Equal(t, int32(1000-40+50), sender.Coins)
+	assert.Empty(t, sender.Inventory)
+	assert.Equal(t, int32(1000-100-50), info(other).Coins)
+
+	// получатель отклоняет предложение, автор отменяет своё
+	declined := createTrade(t, e, ctx, models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, Coins: 10})
+	resp, _ = e.HandleApiCloseTrade(other, declined)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, database.TradeDeclined, resp.Body.(models.TradeResponse).Status)
+	cancelled := createTrade(t, e, ctx, models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, Coins: 10})
+	resp, _ = e.HandleApiCloseTrade(ctx, cancelled)
+	require.Equal(t, 200, resp.Code)
+	assert.Equal(t, database.TradeCancelled, resp.Body.(models.TradeResponse).Status)
+	resp, _ = e.HandleApiCloseTrade(other, cancelled)
+	assert.Equal(t, models.Response(409, models.ErrorResponse{Errors: fmt.Sprintf("%s%d", ErrorTradeClosed, cancelled)}), resp)
+}
+
+func TestCreateTradeInvalid(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.TradesKey).Return(nil)
+	e, ctx := newProductsEngine(t, mockDb)
+	resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: "test_user2", Password: "test_pass2"})
+	require.Equal(t, 200, resp.Code)
+
+	for _, tc := range []struct {
+		request models.TradeRequest
+		errors  string
+	}{
+		{models.TradeRequest{ToUser: "test_user1", Item: "cup", Quantity: 1, Coins: 10}, ErrorSameUser},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: -1, Coins: 10}, ErrorItemQuantity},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, Coins: -10}, ErrorTradeCoins},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1}, ErrorTradeWant},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, WantItem: "t-shirt"}, ErrorTradeWant},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, WantItem: "cup", WantQuantity: 1}, ErrorTradeWant},
+		{models.TradeRequest{ToUser: "unknown", Item: "cup", Quantity: 1, Coins: 10}, ErrorUserNotFound + "unknown"},
+		{models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, WantItem: "unknown", WantQuantity: 1}, ErrorProductNotFound + "unknown"},
+	} {
+		resp, _ := e.HandleApiCreateTrade(ctx, tc.request)
+		assert.Equal(t, models.Response(400, models.ErrorResponse{Errors: tc.errors}), resp, "%+v", tc.request)
+	}
+	resp, _ = e.HandleApiTrades(ctx)
+	require.Equal(t, 200, resp.Code)
+	assert.Empty(t, resp.Body.(models.TradesResponse).Items)
+}
+
+func TestTradesErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.TradesKey).Return(errors.New("error"))
+	e, ctx := newProductsEngine(t, mockDb)
+
+	resp, _ := e.HandleApiCreateTrade(ctx, models.TradeRequest{ToUser: "test_user2", Item: "cup", Quantity: 1, Coins: 10})
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorTrade}), resp)
+	resp, _ = e.HandleApiTrades(ctx)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorTrade}), resp)
+	resp, _ = e.HandleApiAcceptTrade(ctx, 1)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorTrade}), resp)
+	resp, _ = e.HandleApiCloseTrade(ctx, 1)
+	assert.Equal(t, models.Response(500, models.ErrorResponse{Errors: ErrorTrade}), resp)
+}
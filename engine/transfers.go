@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"context"
+	"fmt"
+	"time"
+)
+
+// коды правил переводов: код ошибки в ответе, правило в записи для разбора и значение метки rule в метриках
+const (
+	RuleBlocklist      = "transfer_blocked"
+	RuleMaxAmount      = "transfer_max_amount"
+	RuleDailyLimit     = "transfer_daily_limit"
+	RuleRecipientLimit = "transfer_recipient_limit"
+	RuleVelocity       = "transfer_velocity"
+)
+
+// суточные лимиты считаются за скользящие 24 часа, а не за календарный день
+const transferDay = 24 * time.Hour
+
+// pendingTransfer -- проверяемый перевод
+type pendingTransfer struct {
+	fromId   int64
+	from, to string
+	amount   float64
+	now      time.Time
+	// исходящие переводы отправителя за самое длинное окно правил
+	history []database.Transfer
+}
+
+// sentSince возвращает число и сумму переводов из истории, отправленных не раньше since и прошедших filter
+func (t *pendingTransfer) sentSince(since time.Time, filter func(database.Transfer) bool) (count int, sum float64) {
+	for _, h := range t.history {
+		if h.CreatedAt.Before(since) || (filter != nil && !filter(h)) {
+			continue
+		}
+		count++
+		sum += h.Amount
+	}
+	return count, sum
+}
+
+// transferRule -- правило проверки перевода
+type transferRule struct {
+	code    string
+	message string
+	// за какой период правилу нужна история переводов отправителя, 0 -- не нужна
+	window time.Duration
+	// violated возвращает true, если перевод нарушает правило
+	violated func(t *pendingTransfer) bool
+}
+
+// transferRules проверяет переводы правилами из config.Transfers в порядке их объявления.
+// Правила проверяются до перевода и без блокировок, поэтому параллельные переводы одного пользователя
+// могут немного превысить суточные лимиты.
+// Методы безопасно вызывать на nil -- правила выключены
+type transferRules struct {
+	rules []transferRule
+	// самое длинное окно среди правил, за него загружается история
+	window time.Duration
+	now    func() time.Time
+}
+
+// newTransferRules создаёт включённые в cfg правила, если ни одно не включено, возвращается nil
+func newTransferRules(cfg config.Transfers) *transferRules {
+	var rules []transferRule
+	if len(cfg.Blocklist) > 0 {
+		blocked := make(map[string]bool, len(cfg.Blocklist))
+		for _, username := range cfg.Blocklist {
+			blocked[username] = true
+		}
+		rules = append(rules, transferRule{
+			code:    RuleBlocklist,
+			message: ErrorTransferBlocked,
+			violated: func(t *pendingTransfer) bool {
+				return blocked[t.from] || blocked[t.to]
+			},
+		})
+	}
+	if cfg.MaxAmount > 0 {
+		rules = append(rules, transferRule{
+			code:    RuleMaxAmount,
+			message: fmt.Sprintf("%s%v", ErrorTransferMaxAmount, cfg.MaxAmount),
+			violated: func(t *pendingTransfer) bool {
+				return t.amount > cfg.MaxAmount
+			},
+		})
+	}
+	if cfg.DailyLimit > 0 {
+		rules = append(rules, transferRule{
+			code:    RuleDailyLimit,
+			message: fmt.Sprintf("%s%v", ErrorTransferDailyLimit, cfg.DailyLimit),
+			window:  transferDay,
+			violated: func(t *pendingTransfer) bool {
+				_, sent := t.sentSince(t.now.Add(-transferDay), nil)
+				return sent+t.amount > cfg.DailyLimit
+			},
+		})
+	}
+	if cfg.RecipientDailyLimit > 0 {
+		rules = append(rules, transferRule{
+			code:    RuleRecipientLimit,
+			message: fmt.Sprintf("%s%v", ErrorTransferRecipientLimit, cfg.RecipientDailyLimit),
+			window:  transferDay,
+			violated: func(t *pendingTransfer) bool {
+				_, sent := t.sentSince(t.now.Add(-transferDay), func(h database.Transfer) bool { return h.ToUser == t.to })
+				return sent+t.amount > cfg.RecipientDailyLimit
+			},
+		})
+	}
+	if cfg.VelocityCount > 0 {
+		rules = append(rules, transferRule{
+			code:    RuleVelocity,
+			message: ErrorTransferVelocity,
+			window:  cfg.VelocityWindow,
+			violated: func(t *pendingTransfer) bool {
+				count, _ := t.sentSince(t.now.Add(-cfg.VelocityWindow), nil)
+				return count >= cfg.VelocityCount
+			},
+		})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	r := &transferRules{rules: rules, now: time.Now}
+	for _, rule := range rules {
+		r.window = max(r.window, rule.window)
+	}
+	return r
+}
+
+// check возвращает первое правило, которое нарушает перевод, или nil.
+// История переводов отправителя загружается один раз и только если она нужна правилам
+func (r *transferRules) check(ctx context.Context, db database.Database, t *pendingTransfer) (*transferRule, error) {
+	if r == nil {
+		return nil, nil
+	}
+	t.now = r.now()
+	if r.window > 0 {
+		history, err := db.GetSentTransfers(ctx, t.fromId, t.now.Add(-r.window))
+		if err != nil {
+			return nil, err
+		}
+		t.history = history
+	}
+	for i := range r.rules {
+		if r.rules[i].violated(t) {
+			return &r.rules[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/metrics"
+	"api-avito-shop/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTransfersEngine создаёт движок с правилами переводов и пользователей user1 -- user5,
+// возвращает контекст с токеном user1
+func newTransfersEngine(t *testing.T, mockDb *database.MockDatabase, transfers config.Transfers, opts ...Option) (*Engine, context.Context) {
+	cfg := config.Default()
+	cfg.Transfers = transfers
+	e := NewEngine(mockDb, append(opts, WithConfig(cfg))...)
+
+	mockDb.On("ErrorWithDb", database.AddUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AuthorizeUserKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.GetUserCoinsKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.SendCoinsKey).Return(nil)
+
+	var ctx context.Context
+	for _, username := range []string{"user1", "user2", "user3", "user4", "user5"} {
+		resp, _ := e.HandleApiAuth(context.Background(), models.AuthRequest{Username: username, Password: "pass"})
+		require.Equal(t, 200, resp.Code)
+		if ctx == nil {
+			ctx = context.Background()
+			addTokenToCtx(&ctx, resp.Body.(models.AuthResponse).Token)
+		}
+	}
+	return e, ctx
+}
+
+func sendCoins(e *Engine, ctx context.Context, toUser string, amount int32) models.ImplResponse {
+	resp, _ := e.HandleApiSendCoin(ctx, models.SendCoinRequest{ToUser: toUser, Amount: amount})
+	return resp
+}
+
+func TestTransferRules(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.SentTransfersKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.AddTransferReviewKey).Return(nil)
+	mockDb.On("ErrorWithDb", database.TransferReviewsKey).Return(nil)
+	registry := prometheus.NewRegistry()
+	e, ctx := newTransfersEngine(t, mockDb, config.Transfers{
+		MaxAmount:           300,
+		DailyLimit:          600,
+		RecipientDailyLimit: 250,
+		VelocityCount:       3,
+		VelocityWindow:      time.Minute,
+		Blocklist:           []string{"user3"},
+	}, WithMetrics(metrics.New(registry)))
+
+	resp := sendCoins(e, ctx, "user3", 10)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorTransferBlocked, Code: RuleBlocklist}, resp.Body)
+
+	resp = sendCoins(e, ctx, "user2", 301)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, RuleMaxAmount, resp.Body.(models.ErrorResponse).Code)
+
+	assert.Equal(t, 200, sendCoins(e, ctx, "user2", 200).Code)
+	resp = sendCoins(e, ctx, "user2", 100)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, RuleRecipientLimit, resp.Body.(models.ErrorResponse).Code)
+
+	assert.Equal(t, 200, sendCoins(e, ctx, "user4", 250).Code)
+	resp = sendCoins(e, ctx, "user4", 200)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, RuleDailyLimit, resp.Body.(models.ErrorResponse).Code)
+
+	assert.Equal(t, 200, sendCoins(e, ctx, "user2", 50).Code)
+	resp = sendCoins(e, ctx, "user5", 1)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorTransferVelocity, Code: RuleVelocity}, resp.Body)
+
+	// отклонённые переводы не меняют баланс и сохраняются для разбора
+	coins, _ := mockDb.GetUserCoins(ctx, "user1")
+	assert.Equal(t, float64(500), coins)
+	data, _ := e.getAccountData(ctx)
+	reviews, err := mockDb.GetTransferReviews(ctx, data.Id)
+	assert.NoError(t, err)
+	rules := make([]string, 0, len(reviews))
+	for _, review := range reviews {
+		assert.Equal(t, data.Id, review.UserId)
+		rules = append(rules, review.Rule)
+	}
+	assert.Equal(t, []string{RuleBlocklist, RuleMaxAmount, RuleRecipientLimit, RuleDailyLimit, RuleVelocity}, rules)
+
+	// через минуту окно частоты освобождается, а суточные лимиты ещё действуют
+	now := time.Now()
+	e.transfers.now = func() time.Time { return now.Add(2 * time.Minute) }
+	assert.Equal(t, 200, sendCoins(e, ctx, "user5", 50).Code)
+	resp = sendCoins(e, ctx, "user5", 100)
+	assert.Equal(t, RuleDailyLimit, resp.Body.(models.ErrorResponse).Code)
+
+	// через сутки снимаются и они
+	e.transfers.now = func() time.Time { return now.Add(transferDay + time.Minute) }
+	assert.Equal(t, 200, sendCoins(e, ctx, "user5", 100).Code)
+
+	expected := `
+# HELP avito_shop_transfers_denied_total Количество переводов, отклонённых правилами, по правилам.
+# TYPE avito_shop_transfers_denied_total counter
+avito_shop_transfers_denied_total{rule="transfer_blocked"} 1
+avito_shop_transfers_denied_total{rule="transfer_daily_limit"} 2
+avito_shop_transfers_denied_total{rule="transfer_max_amount"} 1
+avito_shop_transfers_denied_total{rule="transfer_recipient_limit"} 1
+avito_shop_transfers_denied_total{rule="transfer_velocity"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "avito_shop_transfers_denied_total"))
+}
+
+func TestTransferRulesErrorDb(t *testing.T) {
+	mockDb := database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.SentTransfersKey).Return(errors.New("error"))
+	mockDb.On("ErrorWithDb", database.AddTransferReviewKey).Return(errors.New("error"))
+	e, ctx := newTransfersEngine(t, mockDb, config.Transfers{MaxAmount: 100, DailyLimit: 500})
+
+	// без истории переводов суточный лимит проверить нельзя
+	resp := sendCoins(e, ctx, "user2", 10)
+	assert.Equal(t, 500, resp.Code)
+	assert.Equal(t, models.ErrorResponse{Errors: ErrorTransferRules}, resp.Body)
+
+	// перевод отклоняется, даже если его не удалось сохранить для разбора
+	mockDb = database.NewMockDb()
+	mockDb.On("ErrorWithDb", database.AddTransferReviewKey).Return(errors.New("error"))
+	e, ctx = newTransfersEngine(t, mockDb, config.Transfers{MaxAmount: 100})
+	resp = sendCoins(e, ctx, "user2", 101)
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, RuleMaxAmount, resp.Body.(models.ErrorResponse).Code)
+}
+
+func TestTransferRulesDisabled(t *testing.T) {
+	assert.Nil(t, newTransferRules(config.Default().Transfers))
+
+	// без правил история переводов не запрашивается
+	e, ctx := newTransfersEngine(t, database.NewMockDb(), config.Transfers{})
+	assert.Equal(t, 200, sendCoins(e, ctx, "user2", 1000).Code)
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// Check -- проверка состояния отдельного компонента, nil означает, что компонент исправен
+type Check func(ctx context.Context) error
+
+// Component -- результат проверки отдельного компонента
+type Component struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report -- сводный результат проверок
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Ok сообщает, что все компоненты исправны
+func (r Report) Ok() bool {
+	return r.Status == StatusOk
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker выполняет набор именованных проверок параллельно с общим таймаутом
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку компонента
+func (c *Checker) Add(name string, check Check) *Checker {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+	return c
+}
+
+// Run выполняет все проверки и собирает отчёт
+func (c *Checker) Run(ctx context.Context) Report {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	report := Report{
+		Status:     StatusOk,
+		Components: make(map[string]Component, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := nc.check(ctx)
+			component := Component{Status: StatusOk, Duration: time.Since(start).String()}
+			if err != nil {
+				component.Status = StatusFail
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[nc.name] = component
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}(nc)
+	}
+	wg.Wait()
+
+	return report
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerOk(t *testing.T) {
+	checker := NewChecker(time.Second).
+		Add("first", func(ctx context.Context) error { return nil }).
+		Add("second", func(ctx context.Context) error { return nil })
+
+	report := checker.Run(context.Background())
+	assert.True(t, report.Ok())
+	assert.Len(t, report.Components, 2)
+	assert.Equal(t, StatusOk, report.Components["first"].Status)
+}
+
+func TestCheckerFail(t *testing.T) {
+	checker := NewChecker(time.Second).
+		Add("ok", func(ctx context.Context) error { return nil }).
+		Add("broken", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := checker.Run(context.Background())
+	assert.False(t, report.Ok())
+	assert.Equal(t, StatusOk, report.Components["ok"].Status)
+	assert.Equal(t, StatusFail, report.Components["broken"].Status)
+	assert.Equal(t, "connection refused", report.Components["broken"].Error)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond).
+		Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	report := checker.Run(context.Background())
+	assert.False(t, report.Ok())
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+}
+
+func TestCheckerEmpty(t *testing.T) {
+	report := NewChecker(0).Run(context.Background())
+	assert.True(t, report.Ok())
+	assert.Empty(t, report.Components)
+}
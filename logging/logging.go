@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userIDKey
+	routeKey
+	adminKey
+)
+
+// New создаёт логгер с заданным уровнем (debug, info, warn, error) и форматом (json, text).
+// Записи дополняются идентификатором запроса, пользователя или администратора и именем маршрута из контекста
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("неизвестный уровень логирования %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("неизвестный формат логирования %q", format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// contextHandler добавляет к записи атрибуты, сохранённые в контексте запроса
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id, ok := UserID(ctx); ok {
+		r.AddAttrs(slog.Int64("user_id", id))
+	}
+	if admin := Admin(ctx); admin != "" {
+		r.AddAttrs(slog.String("admin", admin))
+	}
+	if route := Route(ctx); route != "" {
+		r.AddAttrs(slog.String("route", route))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// WithRequestID сохраняет идентификатор запроса в контексте
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithUserID сохраняет идентификатор авторизованного пользователя в контексте
+func WithUserID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserID возвращает идентификатор пользователя из контекста
+func UserID(ctx context.Context) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	id, ok := ctx.Value(userIDKey).(int64)
+	return id, ok
+}
+
+// WithAdmin сохраняет имя администратора, выполняющего запрос, в контексте
+func WithAdmin(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, adminKey, name)
+}
+
+// Admin возвращает имя администратора из контекста
+func Admin(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(adminKey).(string)
+	return name
+}
+
+// WithRoute сохраняет имя маршрута в контексте
+func WithRoute(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, routeKey, name)
+}
+
+// Route возвращает имя маршрута из контекста
+func Route(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	route, _ := ctx.Value(routeKey).(string)
+	return route
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "verbose", "json")
+	assert.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	assert.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithRoute(ctx, "ApiInfoGet")
+	ctx = WithUserID(ctx, 42)
+	ctx = WithAdmin(ctx, "support")
+	logger.With(slog.String("component", "engine")).InfoContext(ctx, "info requested")
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "info requested", record["msg"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "ApiInfoGet", record["route"])
+	assert.Equal(t, float64(42), record["user_id"])
+	assert.Equal(t, "support", record["admin"])
+	assert.Equal(t, "engine", record["component"])
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "text")
+	assert.NoError(t, err)
+
+	logger.Info("skipped")
+	assert.Empty(t, buf.String())
+
+	logger.Warn("written")
+	assert.Contains(t, buf.String(), "written")
+}
+
+func TestEmptyContext(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Empty(t, Route(context.Background()))
+	assert.Empty(t, Admin(context.Background()))
+	_, ok := UserID(context.Background())
+	assert.False(t, ok)
+}
@@ -1,23 +1,130 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"api-avito-shop/database"
+	"api-avito-shop/cache"
+	"api-avito-shop/config"
 	"api-avito-shop/engine"
+	"api-avito-shop/health"
+	"api-avito-shop/logging"
+	"api-avito-shop/metrics"
 	openapi "api-avito-shop/openapi"
+	"api-avito-shop/ratelimit"
+	"api-avito-shop/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
-	log.Printf("Server started")
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("ошибка загрузки конфигурации: %v", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("configuration loaded", "config", cfg.String())
+
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			fatal("parse arguments", fmt.Errorf("неизвестная команда: %q", args[0]))
+		}
+		if err := runMigrate(context.Background(), cfg, args[1:]); err != nil {
+			fatal("migrate", err)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, os.Stdout)
+	if err != nil {
+		fatal("setup tracing", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("shutdown tracing", "error", err)
+		}
+	}()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(registry)
+
+	live := health.NewChecker(cfg.Server.HealthTimeout)
+	ready := health.NewChecker(cfg.Server.HealthTimeout)
+
+	db, closeDb, err := openDatabase(context.Background(), cfg, m, ready)
+	if err != nil {
+		fatal("open database", err)
+	}
+	defer closeDb()
+
+	c, err := cache.New(context.Background(), cfg.Cache)
+	if err != nil {
+		fatal("open cache", err)
+	}
+	if c != nil {
+		defer c.Close()
+	}
+
+	limiter, err := ratelimit.New(context.Background(), cfg.RateLimit, cfg.Cache)
+	if err != nil {
+		fatal("open rate limit store", err)
+	}
+	defer limiter.Close()
 
-	db := database.Postgres{}
-	e := engine.NewEngine(&db)
+	e := engine.NewEngine(db, engine.WithConfig(cfg), engine.WithMetrics(m), engine.WithCache(c))
 	DefaultAPIService := openapi.NewDefaultAPIService(e)
 	DefaultAPIController := openapi.NewDefaultAPIController(DefaultAPIService)
 
-	router := openapi.NewRouter(DefaultAPIController)
+	AdminAPIService := openapi.NewAdminAPIService(e)
+	AdminAPIController := openapi.NewAdminAPIController(AdminAPIService, cfg.Admin.Tokens)
+
+	HealthAPIController := openapi.NewHealthAPIController(live, ready)
+
+	router := openapi.NewRouter([]openapi.Router{DefaultAPIController, AdminAPIController, HealthAPIController}, openapi.WithRouterConfig(cfg), openapi.WithRouterMetrics(m), openapi.WithRouterRateLimiter(limiter))
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go e.RunScheduler(ctx)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("shutdown server", "error", err)
+		}
+	}()
+
+	slog.Info("server started", "addr", server.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("listen and serve", err)
+	}
+	slog.Info("server stopped")
+}
 
-	log.Fatal(http.ListenAndServe(":8080", router))
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
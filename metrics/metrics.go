@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "avito_shop"
+
+// Metrics хранит метрики сервиса. Все методы безопасно вызывать у nil,
+// чтобы движок и роутер могли работать без метрик
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	errors           *prometheus.CounterVec
+	coinsTransferred prometheus.Counter
+	itemsBought      *prometheus.CounterVec
+	usersRegistered  prometheus.Counter
+	cacheLookups     *prometheus.CounterVec
+	transfersDenied  *prometheus.CounterVec
+	coinsGranted     prometheus.Counter
+	coinsExpired     prometheus.Counter
+}
+
+// New создаёт метрики и регистрирует их в переданном реестре.
+// В тестах удобно передавать отдельный prometheus.NewRegistry()
+func New(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Количество HTTP-запросов по маршрутам.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP-запросов по маршрутам.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_errors_total",
+			Help:      "Количество ответов с ошибкой по маршрутам и кодам.",
+		}, []string{"route", "code"}),
+		coinsTransferred: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "coins_transferred_total",
+			Help:      "Суммарное количество переведённых монет.",
+		}),
+		itemsBought: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "items_bought_total",
+			Help:      "Количество купленных товаров по продуктам.",
+		}, []string{"product"}),
+		usersRegistered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_registered_total",
+			Help:      "Количество зарегистрированных пользователей.",
+		}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Количество обращений к кешу по видам данных и результатам: hit, miss, error.",
+		}, []string{"kind", "result"}),
+		transfersDenied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_denied_total",
+			Help:      "Количество переводов, отклонённых правилами, по правилам.",
+		}, []string{"rule"}),
+		coinsGranted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "coins_granted_total",
+			Help:      "Суммарное количество монет, начисленных системой.",
+		}),
+		coinsExpired: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "coins_expired_total",
+			Help:      "Суммарное количество сгоревших монет.",
+		}),
+	}
+
+	registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.errors,
+		m.coinsTransferred,
+		m.itemsBought,
+		m.usersRegistered,
+		m.cacheLookups,
+		m.transfersDenied,
+		m.coinsGranted,
+		m.coinsExpired,
+	)
+	return m
+}
+
+// Handler возвращает HTTP-обработчик для /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDBStats добавляет метрики пула соединений sql.DB
+func (m *Metrics) RegisterDBStats(db *sql.DB, name string) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest учитывает обработанный HTTP-запрос
+func (m *Metrics) ObserveRequest(route, method string, code int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	status := strconv.Itoa(code)
+	m.requests.WithLabelValues(route, method, status).Inc()
+	m.duration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+	if code >= http.StatusBadRequest {
+		m.errors.WithLabelValues(route, status).Inc()
+	}
+}
+
+// CoinsTransferred учитывает успешный перевод монет
+func (m *Metrics) CoinsTransferred(amount float64) {
+	if m == nil {
+		return
+	}
+	m.coinsTransferred.Add(amount)
+}
+
+// ItemBought учитывает покупку товара
+func (m *Metrics) ItemBought(product string) {
+	if m == nil {
+		return
+	}
+	m.itemsBought.WithLabelValues(product).Inc()
+}
+
+// UserRegistered учитывает регистрацию нового пользователя
+func (m *Metrics) UserRegistered() {
+	if m == nil {
+		return
+	}
+	m.usersRegistered.Inc()
+}
+
+// CacheLookup учитывает обращение к кешу за данными вида kind с результатом hit, miss или error
+func (m *Metrics) CacheLookup(kind, result string) {
+	if m == nil {
+		return
+	}
+	m.cacheLookups.WithLabelValues(kind, result).Inc()
+}
+
+// TransferDenied учитывает перевод, отклонённый правилом rule
+func (m *Metrics) TransferDenied(rule string) {
+	if m == nil {
+		return
+	}
+	m.transfersDenied.WithLabelValues(rule).Inc()
+}
+
+// CoinsGranted учитывает монеты, начисленные системой
+func (m *Metrics) CoinsGranted(amount float64) {
+	if m == nil {
+		return
+	}
+	m.coinsGranted.Add(amount)
+}
+
+// CoinsExpired учитывает сгоревшие монеты
+func (m *Metrics) CoinsExpired(amount float64) {
+	if m == nil {
+		return
+	}
+	m.coinsExpired.Add(amount)
+}
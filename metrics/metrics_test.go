@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveRequest(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.ObserveRequest("ApiInfoGet", http.MethodGet, 200, 10*time.Millisecond)
+	m.ObserveRequest("ApiInfoGet", http.MethodGet, 401, time.Millisecond)
+	m.ObserveRequest("ApiInfoGet", http.MethodGet, 401, time.Millisecond)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("ApiInfoGet", "GET", "200")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.requests.WithLabelValues("ApiInfoGet", "GET", "401")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.errors.WithLabelValues("ApiInfoGet", "401")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.duration))
+}
+
+func TestBusinessCounters(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.CoinsTransferred(100)
+	m.CoinsTransferred(50)
+	m.ItemBought("cup")
+	m.ItemBought("cup")
+	m.ItemBought("t-shirt")
+	m.UserRegistered()
+	m.CacheLookup("product", "hit")
+	m.CacheLookup("product", "miss")
+	m.CacheLookup("product", "hit")
+
+	assert.Equal(t, float64(150), testutil.ToFloat64(m.coinsTransferred))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.itemsBought.WithLabelValues("cup")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.itemsBought.WithLabelValues("t-shirt")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.usersRegistered))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.cacheLookups.WithLabelValues("product", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.cacheLookups.WithLabelValues("product", "miss")))
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.ObserveRequest("ApiInfoGet", http.MethodGet, 200, time.Millisecond)
+		m.CoinsTransferred(1)
+		m.ItemBought("cup")
+		m.UserRegistered()
+		m.CacheLookup("product", "hit")
+		m.RegisterDBStats(&sql.DB{}, "shop")
+	})
+}
+
+func TestHandler(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.UserRegistered()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "avito_shop_users_registered_total 1"))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"api-avito-shop/config"
+	"api-avito-shop/migrations"
+)
+
+const migrateUsage = `использование: api-avito-shop [флаги конфигурации] migrate [-dry-run] <команда>
+
+команды:
+  up        применить все новые миграции
+  down [N]  откатить N последних миграций (по умолчанию 1)
+  version   вывести текущую и последнюю версию схемы
+`
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), migrateUsage) }
+	dryRun := fs.Bool("dry-run", false, "только вывести SQL, не применяя его")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("не указана команда migrate")
+	}
+
+	var opts []migrations.Option
+	if *dryRun {
+		opts = append(opts, migrations.WithDryRun(os.Stdout))
+	}
+	db, migrator, err := openSQL(cfg.Database, opts...)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations("applied", applied, *dryRun)
+		return err
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("некорректное количество миграций для отката: %q", fs.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		printMigrations("reverted", reverted, *dryRun)
+		return err
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current: %d\nlatest: %d\n", version, migrator.Latest())
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("неизвестная команда migrate: %q", fs.Arg(0))
+	}
+}
+
+func printMigrations(action string, list []migrations.Migration, dryRun bool) {
+	if dryRun {
+		action = "would be " + action
+	}
+	for _, m := range list {
+		fmt.Printf("%s: %04d_%s\n", action, m.Version, m.Name)
+	}
+	if len(list) == 0 {
+		fmt.Println("nothing to do")
+	}
+}
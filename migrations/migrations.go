@@ -0,0 +1,324 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var embedded embed.FS
+
+// ключ advisory lock, под которым выполняются миграции, чтобы несколько реплик
+// сервиса не применяли их одновременно
+const lockKey int64 = 4242_0001
+
+// dialect -- запросы мигратора, которые отличаются между СУБД
+type dialect struct {
+	// каталог со скриптами миграций внутри embedded
+	dir string
+	// захват и освобождение блокировки миграций, пустые -- если блокировка не нужна
+	lock, unlock string
+	// запрос, возвращающий true, если таблица schema_migrations существует
+	tableExists string
+	createTable string
+}
+
+var postgres = dialect{
+	dir:         "postgres",
+	lock:        fmt.Sprintf("SELECT pg_advisory_lock(%d)", lockKey),
+	unlock:      fmt.Sprintf("SELECT pg_advisory_unlock(%d)", lockKey),
+	tableExists: "SELECT to_regclass('schema_migrations') IS NOT NULL",
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+}
+
+// SQLite -- база одного процесса, запись в ней и так сериализуется, поэтому отдельная блокировка не нужна
+var sqlite = dialect{
+	dir:         "sqlite",
+	tableExists: "SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'",
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+}
+
+// имя файла миграции: 0001_init.up.sql или 0001_init.down.sql
+var fileNameRe = regexp.MustCompile(`^(\d+)_([\w-]+)\.(up|down)\.sql$`)
+
+// Migration -- пара скриптов, переводящих схему на версию Version и обратно
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load читает миграции из каталога и сортирует их по версии
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога миграций: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная версия миграции %s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения миграции %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("разные имена у миграции версии %d: %s и %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет up-скрипта", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator применяет и откатывает миграции, храня текущую версию в таблице schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+	dryRun     bool
+	out        io.Writer
+}
+
+// Option настраивает Migrator
+type Option func(*Migrator)
+
+// WithDryRun включает режим, в котором скрипты только выводятся в out, но не выполняются
+func WithDryRun(out io.Writer) Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+		m.out = out
+	}
+}
+
+// NewPostgres создаёт мигратор для Postgres со встроенными в бинарник миграциями
+func NewPostgres(db *sql.DB, opts ...Option) (*Migrator, error) {
+	return newMigrator(db, postgres, opts)
+}
+
+// NewSQLite создаёт мигратор для SQLite со встроенными в бинарник миграциями
+func NewSQLite(db *sql.DB, opts ...Option) (*Migrator, error) {
+	return newMigrator(db, sqlite, opts)
+}
+
+func newMigrator(db *sql.DB, d dialect, opts []Option) (*Migrator, error) {
+	migrations, err := Load(embedded, d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:         db,
+		dialect:    d,
+		migrations: migrations,
+		out:        io.Discard,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Latest возвращает версию последней известной миграции
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы, 0 -- если миграции ещё не применялись
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.currentVersion(ctx, m.db)
+}
+
+// Check проверяет, что схема базы данных находится на последней версии
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version != m.Latest() {
+		return fmt.Errorf("версия схемы %d, ожидается %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up применяет все ещё не применённые миграции и возвращает их список
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			err := m.apply(ctx, conn, migration, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних применённых миграций и возвращает их список
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("у миграции %04d_%s нет down-скрипта", migration.Version, migration.Name)
+			}
+			err := m.apply(ctx, conn, migration, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// apply выполняет скрипт миграции и обновляет schema_migrations в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, script, record string, args ...any) error {
+	if m.dryRun {
+		_, err := fmt.Fprintf(m.out, "-- %04d_%s\n%s\n", migration.Version, migration.Name, script)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("старт транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("ошибка миграции %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("ошибка записи версии %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите миграции %d: %w", migration.Version, err)
+	}
+
+	slog.InfoContext(ctx, "migration applied", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения соединения: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock); err != nil {
+			return fmt.Errorf("ошибка захвата блокировки миграций: %w", err)
+		}
+		defer func() {
+			// контекст мог быть отменён, блокировку снимаем в любом случае
+			_, unlockErr := conn.ExecContext(context.Background(), m.dialect.unlock)
+			err = errors.Join(err, unlockErr)
+		}()
+	}
+
+	// в режиме dry-run база данных не должна меняться
+	if !m.dryRun {
+		_, err = conn.ExecContext(ctx, m.dialect.createTable)
+		if err != nil {
+			return fmt.Errorf("ошибка создания таблицы миграций: %w", err)
+		}
+	}
+
+	return fn(conn)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// currentVersion возвращает текущую версию схемы, 0 -- если таблицы миграций ещё нет
+func (m *Migrator) currentVersion(ctx context.Context, q queryRower) (int64, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, m.dialect.tableExists).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при проверке таблицы миграций: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int64
+	err = q.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения версии схемы: %w", err)
+	}
+	return version, nil
+}
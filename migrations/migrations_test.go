@@ -0,0 +1,126 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"sql/README.md":            {Data: []byte("не миграция")},
+	}
+
+	migrations, err := Load(fsys, "sql")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "DROP TABLE b;", migrations[1].Down)
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"sql/0001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	}, "sql")
+	assert.ErrorContains(t, err, "up")
+
+	_, err = Load(fstest.MapFS{
+		"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+		"sql/0001_other.up.sql": {Data: []byte("CREATE TABLE b ();")},
+	}, "sql")
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	postgres, err := NewPostgres(nil)
+	assert.NoError(t, err)
+	assert.NotZero(t, postgres.Latest())
+	sqlite, err := NewSQLite(nil)
+	assert.NoError(t, err)
+
+	// у диалектов одинаковый набор версий, версии идут подряд, у каждой миграции есть down-скрипт
+	assert.Equal(t, len(postgres.migrations), len(sqlite.migrations))
+	for _, m := range []*Migrator{postgres, sqlite} {
+		for i, migration := range m.migrations {
+			assert.Equal(t, int64(i+1), migration.Version)
+			assert.Equal(t, postgres.migrations[i].Name, migration.Name)
+			assert.NotEmpty(t, migration.Down, migration.Name)
+		}
+	}
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "shop.db")+"?_pragma=foreign_keys(1)")
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m, err := NewSQLite(db)
+	assert.NoError(t, err)
+
+	assert.Error(t, m.Check(ctx))
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, int(m.Latest()))
+	assert.NoError(t, m.Check(ctx))
+
+	// повторный запуск ничего не применяет
+	applied, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	var products int
+	assert.NoError(t, db.QueryRowContext(ctx, "SELECT count(*) FROM products").Scan(&products))
+	assert.Equal(t, 10, products)
+
+	reverted, err := m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	version, err := m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Latest()-1, version)
+
+	// все миграции откатываются и применяются заново
+	_, err = m.Down(ctx, int(m.Latest()))
+	assert.NoError(t, err)
+	version, err = m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Check(ctx))
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	var out bytes.Buffer
+	m, err := NewSQLite(db, WithDryRun(&out))
+	assert.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, int(m.Latest()))
+	assert.Contains(t, out.String(), "-- 0001_init")
+
+	// в режиме dry-run схема не меняется
+	version, err := m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS inventory;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,17 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_src_dst_check;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_amount_check;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_dst_fkey;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_src_fkey;
+
+CREATE INDEX IF NOT EXISTS idx_user_id_product_id ON inventory (user_id, product_id);
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_quantity_check;
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_product_id_fkey;
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_user_id_fkey;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_price_check;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_name_key;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_check;
+CREATE INDEX IF NOT EXISTS idx_name ON users (name);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_name_key;
+ALTER TABLE users ADD CONSTRAINT users_name_md5_key UNIQUE (name, md5);
@@ -0,0 +1,26 @@
+-- имя пользователя уникально само по себе, а не в паре с хешем пароля,
+-- иначе параллельная регистрация могла создать двух пользователей с одним именем.
+-- если такие дубликаты уже есть, миграция упадёт и их нужно разобрать вручную
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_name_md5_key;
+ALTER TABLE users ADD CONSTRAINT users_name_key UNIQUE (name);
+-- индекс по имени теперь создаётся ограничением уникальности
+DROP INDEX IF EXISTS idx_name;
+ALTER TABLE users ADD CONSTRAINT users_balance_check CHECK (balance >= 0);
+
+ALTER TABLE products ADD CONSTRAINT products_name_key UNIQUE (name);
+ALTER TABLE products ADD CONSTRAINT products_price_check CHECK (price >= 0);
+
+ALTER TABLE inventory ADD CONSTRAINT inventory_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE inventory ADD CONSTRAINT inventory_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products (id);
+ALTER TABLE inventory ADD CONSTRAINT inventory_quantity_check CHECK (quantity > 0);
+-- индекс по (user_id, product_id) дублирует ограничение уникальности
+DROP INDEX IF EXISTS idx_user_id_product_id;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_src_fkey
+    FOREIGN KEY (src) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD CONSTRAINT transactions_dst_fkey
+    FOREIGN KEY (dst) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD CONSTRAINT transactions_amount_check CHECK (amount > 0);
+ALTER TABLE transactions ADD CONSTRAINT transactions_src_dst_check CHECK (src <> dst);
@@ -0,0 +1,18 @@
+-- правилам переводов нужно время перевода, чтобы считать суммы и частоту за период.
+-- существующим переводам достаётся время применения миграции
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- переводы отправителя выбираются за период, индекс по (src, created_at) заменяет индекс по src
+CREATE INDEX IF NOT EXISTS idx_src_created_at ON transactions (src, created_at);
+DROP INDEX IF EXISTS idx_src;
+
+-- переводы, отклонённые правилами, сохраняются для разбора
+CREATE TABLE IF NOT EXISTS transfer_reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    to_user TEXT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    rule TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT transfer_reviews_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_transfer_reviews_user_id ON transfer_reviews (user_id);
@@ -0,0 +1 @@
+ALTER TABLE products DROP COLUMN IF EXISTS max_per_user;
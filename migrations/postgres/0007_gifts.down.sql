@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_purchases_recipient_id;
+ALTER TABLE purchases DROP COLUMN IF EXISTS message;
+ALTER TABLE purchases DROP COLUMN IF EXISTS recipient_id;
@@ -0,0 +1,5 @@
+-- подарок -- покупка, предмет которой попадает в инвентарь получателя, NULL -- покупатель купил товар себе.
+-- Сообщение к подарку видят оба пользователя в /api/info
+ALTER TABLE purchases ADD COLUMN recipient_id INTEGER CONSTRAINT purchases_recipient_id_fkey REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE purchases ADD COLUMN message TEXT;
+CREATE INDEX IF NOT EXISTS idx_purchases_recipient_id ON purchases (recipient_id) WHERE recipient_id IS NOT NULL;
@@ -0,0 +1,16 @@
+-- передачи предметов между пользователями, показываются в истории рядом с переводами монет
+CREATE TABLE IF NOT EXISTS item_transfers (
+    id SERIAL PRIMARY KEY,
+    src INTEGER NOT NULL,
+    dst INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT item_transfers_src_fkey FOREIGN KEY (src) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT item_transfers_dst_fkey FOREIGN KEY (dst) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT item_transfers_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT item_transfers_quantity_check CHECK (quantity > 0),
+    CONSTRAINT item_transfers_src_dst_check CHECK (src <> dst)
+);
+CREATE INDEX IF NOT EXISTS idx_item_transfers_src ON item_transfers (src);
+CREATE INDEX IF NOT EXISTS idx_item_transfers_dst ON item_transfers (dst);
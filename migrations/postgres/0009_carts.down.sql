@@ -0,0 +1,3 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS receipt_id;
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS cart_items;
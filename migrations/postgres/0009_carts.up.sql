@@ -0,0 +1,21 @@
+-- корзина пользователя: товары из неё покупаются одной транзакцией при оформлении
+CREATE TABLE IF NOT EXISTS cart_items (
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    PRIMARY KEY (user_id, product_id),
+    CONSTRAINT cart_items_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT cart_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT cart_items_quantity_check CHECK (quantity > 0)
+);
+
+-- чек оформленной корзины, покупки из корзины ссылаются на него
+CREATE TABLE IF NOT EXISTS receipts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    total NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT receipts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_receipts_user_id ON receipts (user_id);
+ALTER TABLE purchases ADD COLUMN receipt_id INTEGER CONSTRAINT purchases_receipt_id_fkey REFERENCES receipts (id) ON DELETE CASCADE;
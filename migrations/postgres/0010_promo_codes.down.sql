@@ -0,0 +1,3 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS discount;
+ALTER TABLE purchases DROP COLUMN IF EXISTS promo_code_id;
+DROP TABLE IF EXISTS promo_codes;
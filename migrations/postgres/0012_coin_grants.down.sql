@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS coin_grants;
+DROP TABLE IF EXISTS grant_schedule_users;
+DROP TABLE IF EXISTS grant_schedules;
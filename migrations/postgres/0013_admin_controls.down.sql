@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS admin_actions;
+DROP TABLE IF EXISTS balance_adjustments;
+ALTER TABLE users DROP COLUMN IF EXISTS frozen_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS frozen_by;
+ALTER TABLE users DROP COLUMN IF EXISTS frozen_at;
@@ -0,0 +1,11 @@
+-- монеты со сроком, которыми оплачена покупка: при возврате они возвращаются в remaining своего начисления,
+-- чтобы покупка с возвратом не делала сгорающие монеты бессрочными
+CREATE TABLE IF NOT EXISTS purchase_grants (
+    purchase_id INTEGER NOT NULL,
+    grant_id INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    PRIMARY KEY (purchase_id, grant_id),
+    CONSTRAINT purchase_grants_purchase_id_fkey FOREIGN KEY (purchase_id) REFERENCES purchases (id) ON DELETE CASCADE,
+    CONSTRAINT purchase_grants_grant_id_fkey FOREIGN KEY (grant_id) REFERENCES coin_grants (id) ON DELETE CASCADE,
+    CONSTRAINT purchase_grants_amount_check CHECK (amount > 0)
+);
@@ -0,0 +1,26 @@
+-- предложения обмена: from_id отдаёт quantity предметов product_id пользователю to_id в обмен на want_quantity
+-- предметов want_product_id и/или coins монет. Предметы не резервируются, обмен выполняется при принятии
+CREATE TABLE IF NOT EXISTS trades (
+    id SERIAL PRIMARY KEY,
+    from_id INTEGER NOT NULL,
+    to_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    want_product_id INTEGER,
+    want_quantity INTEGER NOT NULL DEFAULT 0,
+    coins NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ,
+    CONSTRAINT trades_from_id_fkey FOREIGN KEY (from_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT trades_to_id_fkey FOREIGN KEY (to_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT trades_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT trades_want_product_id_fkey FOREIGN KEY (want_product_id) REFERENCES products (id),
+    CONSTRAINT trades_from_to_check CHECK (from_id <> to_id),
+    CONSTRAINT trades_quantity_check CHECK (quantity > 0 AND want_quantity >= 0 AND coins >= 0),
+    CONSTRAINT trades_want_check CHECK ((want_product_id IS NULL) = (want_quantity = 0) AND (want_quantity > 0 OR coins > 0)
+        AND (want_product_id IS NULL OR want_product_id <> product_id)),
+    CONSTRAINT trades_status_check CHECK (status IN ('open', 'accepted', 'declined', 'cancelled'))
+);
+CREATE INDEX IF NOT EXISTS idx_trades_from_id ON trades (from_id);
+CREATE INDEX IF NOT EXISTS idx_trades_to_id ON trades (to_id);
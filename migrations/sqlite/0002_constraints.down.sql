@@ -0,0 +1,47 @@
+CREATE TABLE transactions_old (
+    id INTEGER PRIMARY KEY,
+    src INTEGER NOT NULL,
+    dst INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL DEFAULT 0.00
+);
+INSERT INTO transactions_old (id, src, dst, amount) SELECT id, src, dst, amount FROM transactions;
+DROP TABLE transactions;
+ALTER TABLE transactions_old RENAME TO transactions;
+CREATE INDEX IF NOT EXISTS idx_src ON transactions (src);
+CREATE INDEX IF NOT EXISTS idx_dst ON transactions (dst);
+
+CREATE TABLE inventory_old (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    purchase_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, product_id)
+);
+INSERT INTO inventory_old (id, user_id, product_id, quantity, purchase_date)
+SELECT id, user_id, product_id, quantity, purchase_date FROM inventory;
+DROP TABLE inventory;
+ALTER TABLE inventory_old RENAME TO inventory;
+CREATE INDEX IF NOT EXISTS idx_user_id_product_id ON inventory (user_id, product_id);
+
+CREATE TABLE products_old (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    price NUMERIC(10, 2) NOT NULL
+);
+INSERT INTO products_old (id, name, description, price) SELECT id, name, description, price FROM products;
+DROP TABLE products;
+ALTER TABLE products_old RENAME TO products;
+
+CREATE TABLE users_old (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    md5 TEXT NOT NULL,
+    balance NUMERIC(10, 2) NOT NULL DEFAULT 0.00,
+    UNIQUE(name, md5)
+);
+INSERT INTO users_old (id, name, md5, balance) SELECT id, name, md5, balance FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+CREATE INDEX IF NOT EXISTS idx_name ON users (name);
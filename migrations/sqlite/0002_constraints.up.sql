@@ -0,0 +1,58 @@
+-- SQLite не умеет добавлять ограничения в существующую таблицу, поэтому таблицы пересоздаются
+-- с копированием данных. Имена ограничений совпадают с Postgres, по ним разбираются ошибки
+
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    md5 TEXT NOT NULL,
+    balance NUMERIC(10, 2) NOT NULL DEFAULT 0.00,
+    CONSTRAINT users_name_key UNIQUE (name),
+    CONSTRAINT users_balance_check CHECK (balance >= 0)
+);
+INSERT INTO users_new (id, name, md5, balance) SELECT id, name, md5, balance FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE TABLE products_new (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    price NUMERIC(10, 2) NOT NULL,
+    CONSTRAINT products_name_key UNIQUE (name),
+    CONSTRAINT products_price_check CHECK (price >= 0)
+);
+INSERT INTO products_new (id, name, description, price) SELECT id, name, description, price FROM products;
+DROP TABLE products;
+ALTER TABLE products_new RENAME TO products;
+
+CREATE TABLE inventory_new (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    purchase_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, product_id),
+    CONSTRAINT inventory_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT inventory_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT inventory_quantity_check CHECK (quantity > 0)
+);
+INSERT INTO inventory_new (id, user_id, product_id, quantity, purchase_date)
+SELECT id, user_id, product_id, quantity, purchase_date FROM inventory;
+DROP TABLE inventory;
+ALTER TABLE inventory_new RENAME TO inventory;
+
+CREATE TABLE transactions_new (
+    id INTEGER PRIMARY KEY,
+    src INTEGER NOT NULL,
+    dst INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL DEFAULT 0.00,
+    CONSTRAINT transactions_src_fkey FOREIGN KEY (src) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT transactions_dst_fkey FOREIGN KEY (dst) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT transactions_amount_check CHECK (amount > 0),
+    CONSTRAINT transactions_src_dst_check CHECK (src <> dst)
+);
+INSERT INTO transactions_new (id, src, dst, amount) SELECT id, src, dst, amount FROM transactions;
+DROP TABLE transactions;
+ALTER TABLE transactions_new RENAME TO transactions;
+CREATE INDEX IF NOT EXISTS idx_src ON transactions (src);
+CREATE INDEX IF NOT EXISTS idx_dst ON transactions (dst);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS transfer_reviews;
+
+DROP INDEX IF EXISTS idx_src_created_at;
+ALTER TABLE transactions DROP COLUMN created_at;
+CREATE INDEX IF NOT EXISTS idx_src ON transactions (src);
@@ -0,0 +1,29 @@
+-- SQLite не добавляет столбец с непостоянным значением по умолчанию, поэтому таблица пересоздаётся.
+-- существующим переводам достаётся время применения миграции
+CREATE TABLE transactions_new (
+    id INTEGER PRIMARY KEY,
+    src INTEGER NOT NULL,
+    dst INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL DEFAULT 0.00,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transactions_src_fkey FOREIGN KEY (src) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT transactions_dst_fkey FOREIGN KEY (dst) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT transactions_amount_check CHECK (amount > 0),
+    CONSTRAINT transactions_src_dst_check CHECK (src <> dst)
+);
+INSERT INTO transactions_new (id, src, dst, amount) SELECT id, src, dst, amount FROM transactions;
+DROP TABLE transactions;
+ALTER TABLE transactions_new RENAME TO transactions;
+CREATE INDEX IF NOT EXISTS idx_src_created_at ON transactions (src, created_at);
+CREATE INDEX IF NOT EXISTS idx_dst ON transactions (dst);
+
+CREATE TABLE IF NOT EXISTS transfer_reviews (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    to_user TEXT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    rule TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transfer_reviews_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_transfer_reviews_user_id ON transfer_reviews (user_id);
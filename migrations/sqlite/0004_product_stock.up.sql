@@ -0,0 +1,3 @@
+-- остаток товара на складе, NULL -- товар не ограничен. Покупка уменьшает остаток,
+-- а уход в минус отсекает ограничение products_stock_check
+ALTER TABLE products ADD COLUMN stock INTEGER CONSTRAINT products_stock_check CHECK (stock >= 0);
@@ -0,0 +1,3 @@
+-- сколько единиц товара может купить один пользователь, NULL -- без ограничения.
+-- Лимит проверяется по инвентарю в транзакции покупки
+ALTER TABLE products ADD COLUMN max_per_user INTEGER CONSTRAINT products_max_per_user_check CHECK (max_per_user > 0);
@@ -0,0 +1,15 @@
+-- каждая покупка сохраняется, чтобы её можно было вернуть. Возврат отмечается в самой записи,
+-- поэтому повторный запрос возврата не начисляет монеты второй раз
+CREATE TABLE IF NOT EXISTS purchases (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    refunded_at TIMESTAMP,
+    -- кто оформил возврат: пустая строка -- сам пользователь, иначе имя администратора
+    refunded_by TEXT,
+    CONSTRAINT purchases_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT purchases_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE INDEX IF NOT EXISTS idx_purchases_user_id ON purchases (user_id);
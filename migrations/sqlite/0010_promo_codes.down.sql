@@ -0,0 +1,3 @@
+ALTER TABLE purchases DROP COLUMN discount;
+ALTER TABLE purchases DROP COLUMN promo_code_id;
+DROP TABLE IF EXISTS promo_codes;
@@ -0,0 +1,26 @@
+-- промокоды: скидка в процентах или фиксированная сумма, окно действия, ограничение числа применений
+-- и товар, на который действует код (NULL -- любой). Отключённый код остаётся, на него ссылаются покупки
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id INTEGER PRIMARY KEY,
+    code TEXT NOT NULL,
+    percent INTEGER,
+    amount NUMERIC(10, 2),
+    product_id INTEGER,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT promo_codes_code_key UNIQUE (code),
+    CONSTRAINT promo_codes_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT promo_codes_discount_check CHECK ((percent IS NULL) <> (amount IS NULL) AND (percent IS NULL OR percent BETWEEN 1 AND 100) AND (amount IS NULL OR amount > 0)),
+    CONSTRAINT promo_codes_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at),
+    CONSTRAINT promo_codes_max_uses_check CHECK (max_uses IS NULL OR max_uses > 0),
+    -- применения считаются в транзакции покупки, лишнее применение отсекает это ограничение
+    CONSTRAINT promo_codes_uses_check CHECK (max_uses IS NULL OR uses <= max_uses)
+);
+
+-- в покупке сохраняются применённый код и скидка, price -- фактически уплаченная цена
+ALTER TABLE purchases ADD COLUMN promo_code_id INTEGER CONSTRAINT purchases_promo_code_id_fkey REFERENCES promo_codes (id);
+ALTER TABLE purchases ADD COLUMN discount NUMERIC(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+-- акции: цена товара на время с starts_at до ends_at. Действующую цену выбирает движок при покупке,
+-- из нескольких одновременных акций на товар действует самая низкая цена
+CREATE TABLE IF NOT EXISTS price_campaigns (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    product_id INTEGER NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT price_campaigns_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id),
+    CONSTRAINT price_campaigns_price_check CHECK (price > 0),
+    CONSTRAINT price_campaigns_window_check CHECK (starts_at < ends_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_campaigns_product_id_ends_at ON price_campaigns (product_id, ends_at);
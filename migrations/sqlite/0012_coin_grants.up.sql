@@ -0,0 +1,47 @@
+-- регулярные начисления монет: amount каждому получателю каждые interval_seconds начиная с next_run_at.
+-- runs -- число выполненных периодов, по нему экземпляры сервиса договариваются, кто выполняет период.
+-- Остановленное расписание остаётся, на него ссылаются начисления
+CREATE TABLE IF NOT EXISTS grant_schedules (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    interval_seconds BIGINT NOT NULL,
+    expires_after_seconds BIGINT,
+    runs BIGINT NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMP NOT NULL,
+    stopped_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT grant_schedules_amount_check CHECK (amount > 0),
+    CONSTRAINT grant_schedules_interval_check CHECK (interval_seconds > 0),
+    CONSTRAINT grant_schedules_expires_after_check CHECK (expires_after_seconds IS NULL OR expires_after_seconds > 0)
+);
+
+-- получатели расписания, расписание без получателей начисляет монеты всем пользователям
+CREATE TABLE IF NOT EXISTS grant_schedule_users (
+    schedule_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (schedule_id, user_id),
+    CONSTRAINT grant_schedule_users_schedule_id_fkey FOREIGN KEY (schedule_id) REFERENCES grant_schedules (id) ON DELETE CASCADE,
+    CONSTRAINT grant_schedule_users_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- начисления монет от имени системы. У монет со сроком remaining -- сколько ещё не потрачено, expired -- сколько
+-- сгорело; списания тратят их раньше бессрочных монет в порядке начисления. У бессрочных монет remaining = 0
+CREATE TABLE IF NOT EXISTS coin_grants (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    remaining NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    expired NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    schedule_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT coin_grants_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT coin_grants_schedule_id_fkey FOREIGN KEY (schedule_id) REFERENCES grant_schedules (id),
+    CONSTRAINT coin_grants_amount_check CHECK (amount > 0),
+    CONSTRAINT coin_grants_remaining_check CHECK (remaining >= 0 AND expired >= 0 AND remaining + expired <= amount)
+);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_user_id ON coin_grants (user_id);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_expires_at ON coin_grants (expires_at) WHERE remaining > 0;
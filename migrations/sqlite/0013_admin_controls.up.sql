@@ -0,0 +1,32 @@
+-- заблокированный администратором пользователь не проходит аутентификацию, NULL -- пользователь не заблокирован
+ALTER TABLE users ADD COLUMN frozen_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN frozen_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN frozen_reason TEXT NOT NULL DEFAULT '';
+
+-- исправления баланса администратором: положительная сумма начисляет монеты, отрицательная списывает
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    reason TEXT NOT NULL,
+    admin TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT balance_adjustments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT balance_adjustments_amount_check CHECK (amount <> 0),
+    CONSTRAINT balance_adjustments_reason_check CHECK (reason <> '')
+);
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_user_id ON balance_adjustments (user_id);
+
+-- журнал запросов администраторов к изменяющим эндпоинтам, записи не меняются и не удаляются
+CREATE TABLE IF NOT EXISTS admin_actions (
+    id INTEGER PRIMARY KEY,
+    admin TEXT NOT NULL,
+    action TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_admin_actions_admin ON admin_actions (admin, id);
@@ -0,0 +1 @@
+ALTER TABLE coin_grants DROP COLUMN transaction_id;
@@ -0,0 +1,4 @@
+-- монеты со сроком, переданные переводом или оплатой обмена, переходят получателю начислением с тем же сроком:
+-- transaction_id -- перевод, которым они получены. Такие начисления в истории показывает сам перевод,
+-- поэтому они не попадают в полученные от системы, но сгорают как обычные
+ALTER TABLE coin_grants ADD COLUMN transaction_id INTEGER CONSTRAINT coin_grants_transaction_id_fkey REFERENCES transactions (id) ON DELETE CASCADE;
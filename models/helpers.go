@@ -5,7 +5,6 @@ import (
 )
 
 const JwtUserKey = "jwtToken"
-const JwtUniqueKey = "dnajdsoewASDLAKMSjqwekmq#$%ASD"
 
 // Response return a ImplResponse struct filled
 func Response(code int, body interface{}) ImplResponse {
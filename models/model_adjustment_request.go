@@ -0,0 +1,30 @@
+package models
+
+type AdjustmentRequest struct {
+
+	// Сколько монет начислить (положительная сумма) или списать (отрицательная).
+	Amount int32 `json:"amount"`
+
+	// Причина исправления, обязательна.
+	Reason string `json:"reason"`
+}
+
+// AssertAdjustmentRequestRequired checks if the required fields are not zero-ed
+func AssertAdjustmentRequestRequired(obj AdjustmentRequest) error {
+	elements := map[string]interface{}{
+		"amount": obj.Amount,
+		"reason": obj.Reason,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertAdjustmentRequestConstraints checks if the values respects the defined constraints
+func AssertAdjustmentRequestConstraints(obj AdjustmentRequest) error {
+	return nil
+}
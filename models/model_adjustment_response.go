@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type AdjustmentResponse struct {
+
+	// Идентификатор исправления.
+	Id int64 `json:"id"`
+
+	// Пользователь, баланс которого исправлен.
+	Username string `json:"username"`
+
+	// Начисленная (положительная) или списанная (отрицательная) сумма.
+	Amount int32 `json:"amount"`
+
+	// Причина исправления.
+	Reason string `json:"reason"`
+
+	// Администратор, исправивший баланс.
+	Admin string `json:"admin"`
+
+	// Баланс после исправления.
+	Balance int32 `json:"balance"`
+
+	// Время исправления.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AssertAdjustmentResponseRequired checks if the required fields are not zero-ed
+func AssertAdjustmentResponseRequired(obj AdjustmentResponse) error {
+	elements := map[string]interface{}{
+		"id":        obj.Id,
+		"username":  obj.Username,
+		"amount":    obj.Amount,
+		"reason":    obj.Reason,
+		"admin":     obj.Admin,
+		"createdAt": obj.CreatedAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertAdjustmentResponseConstraints checks if the values respects the defined constraints
+func AssertAdjustmentResponseConstraints(obj AdjustmentResponse) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+type AdminActionResponse struct {
+
+	// Идентификатор записи журнала.
+	Id int64 `json:"id"`
+
+	// Администратор, выполнивший запрос.
+	Admin string `json:"admin"`
+
+	// Эндпоинт административного API.
+	Action string `json:"action"`
+
+	// Метод запроса.
+	Method string `json:"method"`
+
+	// Путь запроса.
+	Path string `json:"path"`
+
+	// Код ответа.
+	Status int32 `json:"status"`
+
+	// Идентификатор запроса, по нему запись связывается с логами и трассой.
+	RequestId string `json:"requestId,omitempty"`
+
+	// Начало тела запроса.
+	Body string `json:"body,omitempty"`
+
+	// Время запроса.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AssertAdminActionResponseRequired checks if the required fields are not zero-ed
+func AssertAdminActionResponseRequired(obj AdminActionResponse) error {
+	elements := map[string]interface{}{
+		"id":        obj.Id,
+		"admin":     obj.Admin,
+		"action":    obj.Action,
+		"method":    obj.Method,
+		"path":      obj.Path,
+		"status":    obj.Status,
+		"createdAt": obj.CreatedAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertAdminActionResponseConstraints checks if the values respects the defined constraints
+func AssertAdminActionResponseConstraints(obj AdminActionResponse) error {
+	return nil
+}
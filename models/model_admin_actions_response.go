@@ -0,0 +1,25 @@
+package models
+
+type AdminActionsResponse struct {
+	Items []AdminActionResponse `json:"items"`
+}
+
+// AssertAdminActionsResponseRequired checks if the required fields are not zero-ed
+func AssertAdminActionsResponseRequired(obj AdminActionsResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertAdminActionResponseRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertAdminActionsResponseConstraints checks if the values respects the defined constraints
+func AssertAdminActionsResponseConstraints(obj AdminActionsResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertAdminActionResponseConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
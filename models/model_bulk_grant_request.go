@@ -0,0 +1,36 @@
+package models
+
+type BulkGrantRequest struct {
+
+	// Начисления, которые выполняются все вместе или не выполняются вовсе.
+	Grants []GrantRequest `json:"grants"`
+}
+
+// AssertBulkGrantRequestRequired checks if the required fields are not zero-ed
+func AssertBulkGrantRequestRequired(obj BulkGrantRequest) error {
+	elements := map[string]interface{}{
+		"grants": obj.Grants,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	for _, el := range obj.Grants {
+		if err := AssertGrantRequestRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertBulkGrantRequestConstraints checks if the values respects the defined constraints
+func AssertBulkGrantRequestConstraints(obj BulkGrantRequest) error {
+	for _, el := range obj.Grants {
+		if err := AssertGrantRequestConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+type CartItemRequest struct {
+
+	// Название товара.
+	Item string `json:"item"`
+
+	// Сколько единиц товара добавить в корзину.
+	Quantity int32 `json:"quantity"`
+}
+
+// AssertCartItemRequestRequired checks if the required fields are not zero-ed
+func AssertCartItemRequestRequired(obj CartItemRequest) error {
+	elements := map[string]interface{}{
+		"item":     obj.Item,
+		"quantity": obj.Quantity,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertCartItemRequestConstraints checks if the values respects the defined constraints
+func AssertCartItemRequestConstraints(obj CartItemRequest) error {
+	return nil
+}
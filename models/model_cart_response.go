@@ -0,0 +1,28 @@
+package models
+
+type CartResponse struct {
+	Items []CartResponseItemsInner `json:"items"`
+
+	// Стоимость корзины по текущим ценам.
+	Total int32 `json:"total"`
+}
+
+// AssertCartResponseRequired checks if the required fields are not zero-ed
+func AssertCartResponseRequired(obj CartResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertCartResponseItemsInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertCartResponseConstraints checks if the values respects the defined constraints
+func AssertCartResponseConstraints(obj CartResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertCartResponseItemsInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
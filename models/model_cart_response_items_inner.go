@@ -0,0 +1,23 @@
+package models
+
+type CartResponseItemsInner struct {
+
+	// Название товара.
+	Item string `json:"item"`
+
+	// Количество единиц товара в корзине.
+	Quantity int32 `json:"quantity"`
+
+	// Текущая цена одной единицы.
+	Price int32 `json:"price"`
+}
+
+// AssertCartResponseItemsInnerRequired checks if the required fields are not zero-ed
+func AssertCartResponseItemsInnerRequired(obj CartResponseItemsInner) error {
+	return nil
+}
+
+// AssertCartResponseItemsInnerConstraints checks if the values respects the defined constraints
+func AssertCartResponseItemsInnerConstraints(obj CartResponseItemsInner) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+type CatalogItem struct {
+
+	// Название товара.
+	Name string `json:"name"`
+
+	// Цена товара в монетах по каталогу, без учёта акции.
+	Price int32 `json:"price"`
+
+	// Действующая акция на товар. Отсутствует, если акции нет.
+	Sale *CatalogItemSale `json:"sale,omitempty"`
+
+	// Оставшееся количество товара. Отсутствует, если количество не ограничено.
+	Stock *int64 `json:"stock,omitempty"`
+
+	// Сколько единиц товара может купить один пользователь. Отсутствует, если лимита нет.
+	MaxPerUser *int64 `json:"maxPerUser,omitempty"`
+}
+
+// AssertCatalogItemRequired checks if the required fields are not zero-ed
+func AssertCatalogItemRequired(obj CatalogItem) error {
+	elements := map[string]interface{}{
+		"name": obj.Name,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	if obj.Sale != nil {
+		if err := AssertCatalogItemSaleRequired(*obj.Sale); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AssertCatalogItemConstraints checks if the values respects the defined constraints
+func AssertCatalogItemConstraints(obj CatalogItem) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type CatalogItemSale struct {
+
+	// Название акции.
+	Name string `json:"name"`
+
+	// Цена товара по акции в монетах.
+	Price int32 `json:"price"`
+
+	// Окончание акции.
+	EndsAt time.Time `json:"endsAt"`
+}
+
+// AssertCatalogItemSaleRequired checks if the required fields are not zero-ed
+func AssertCatalogItemSaleRequired(obj CatalogItemSale) error {
+	elements := map[string]interface{}{
+		"name":   obj.Name,
+		"price":  obj.Price,
+		"endsAt": obj.EndsAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertCatalogItemSaleConstraints checks if the values respects the defined constraints
+func AssertCatalogItemSaleConstraints(obj CatalogItemSale) error {
+	return nil
+}
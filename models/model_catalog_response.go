@@ -0,0 +1,25 @@
+package models
+
+type CatalogResponse struct {
+	Items []CatalogItem `json:"items"`
+}
+
+// AssertCatalogResponseRequired checks if the required fields are not zero-ed
+func AssertCatalogResponseRequired(obj CatalogResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertCatalogItemRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertCatalogResponseConstraints checks if the values respects the defined constraints
+func AssertCatalogResponseConstraints(obj CatalogResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertCatalogItemConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,9 @@ type ErrorResponse struct {
 
 	// Сообщение об ошибке, описывающее проблему.
 	Errors string `json:"errors,omitempty"`
+
+	// Машиночитаемый код ошибки, если он есть.
+	Code string `json:"code,omitempty"`
 }
 
 // AssertErrorResponseRequired checks if the required fields are not zero-ed
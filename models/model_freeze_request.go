@@ -0,0 +1,26 @@
+package models
+
+type FreezeRequest struct {
+
+	// Причина блокировки, обязательна.
+	Reason string `json:"reason"`
+}
+
+// AssertFreezeRequestRequired checks if the required fields are not zero-ed
+func AssertFreezeRequestRequired(obj FreezeRequest) error {
+	elements := map[string]interface{}{
+		"reason": obj.Reason,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertFreezeRequestConstraints checks if the values respects the defined constraints
+func AssertFreezeRequestConstraints(obj FreezeRequest) error {
+	return nil
+}
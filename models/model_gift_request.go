@@ -0,0 +1,33 @@
+package models
+
+type GiftRequest struct {
+
+	// Имя пользователя, которому дарится предмет.
+	ToUser string `json:"toUser"`
+
+	// Название товара.
+	Item string `json:"item"`
+
+	// Сообщение к подарку, его увидят оба пользователя.
+	Message string `json:"message,omitempty"`
+}
+
+// AssertGiftRequestRequired checks if the required fields are not zero-ed
+func AssertGiftRequestRequired(obj GiftRequest) error {
+	elements := map[string]interface{}{
+		"toUser": obj.ToUser,
+		"item":   obj.Item,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertGiftRequestConstraints checks if the values respects the defined constraints
+func AssertGiftRequestConstraints(obj GiftRequest) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type GrantRequest struct {
+
+	// Получатель монет.
+	Username string `json:"username"`
+
+	// Сколько монет начислить.
+	Amount int32 `json:"amount"`
+
+	// Причина начисления, например «зарплата за март».
+	Reason string `json:"reason,omitempty"`
+
+	// Когда непотраченные монеты начисления сгорают. Не указано -- монеты бессрочные.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// AssertGrantRequestRequired checks if the required fields are not zero-ed
+func AssertGrantRequestRequired(obj GrantRequest) error {
+	elements := map[string]interface{}{
+		"username": obj.Username,
+		"amount":   obj.Amount,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertGrantRequestConstraints checks if the values respects the defined constraints
+func AssertGrantRequestConstraints(obj GrantRequest) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type GrantScheduleRequest struct {
+
+	// Название регулярного начисления.
+	Name string `json:"name"`
+
+	// Сколько монет начислять каждому получателю за период.
+	Amount int32 `json:"amount"`
+
+	// Причина начисления.
+	Reason string `json:"reason,omitempty"`
+
+	// Период начисления, например «720h». Не меньше секунды.
+	Every string `json:"every"`
+
+	// Через сколько после периода начисленные монеты сгорают. Не указано -- монеты бессрочные.
+	ExpiresAfter string `json:"expiresAfter,omitempty"`
+
+	// Получатели. Не указаны -- все пользователи.
+	Usernames []string `json:"usernames,omitempty"`
+
+	// Время первого периода. Не указано -- сразу.
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+}
+
+// AssertGrantScheduleRequestRequired checks if the required fields are not zero-ed
+func AssertGrantScheduleRequestRequired(obj GrantScheduleRequest) error {
+	elements := map[string]interface{}{
+		"name":   obj.Name,
+		"amount": obj.Amount,
+		"every":  obj.Every,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertGrantScheduleRequestConstraints checks if the values respects the defined constraints
+func AssertGrantScheduleRequestConstraints(obj GrantScheduleRequest) error {
+	return nil
+}
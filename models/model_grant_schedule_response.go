@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type GrantScheduleResponse struct {
+
+	// Идентификатор регулярного начисления.
+	Id int64 `json:"id"`
+
+	// Название регулярного начисления.
+	Name string `json:"name"`
+
+	// Сколько монет начисляется каждому получателю за период.
+	Amount int32 `json:"amount"`
+
+	// Причина начисления.
+	Reason string `json:"reason,omitempty"`
+
+	// Период начисления.
+	Every string `json:"every"`
+
+	// Через сколько после периода начисленные монеты сгорают. Не указано -- монеты бессрочные.
+	ExpiresAfter string `json:"expiresAfter,omitempty"`
+
+	// Получатели. Не указаны -- все пользователи.
+	Usernames []string `json:"usernames,omitempty"`
+
+	// Время ближайшего периода.
+	NextRunAt time.Time `json:"nextRunAt"`
+
+	// Время остановки. Не указано -- начисление действует.
+	StoppedAt *time.Time `json:"stoppedAt,omitempty"`
+}
+
+// AssertGrantScheduleResponseRequired checks if the required fields are not zero-ed
+func AssertGrantScheduleResponseRequired(obj GrantScheduleResponse) error {
+	elements := map[string]interface{}{
+		"id":        obj.Id,
+		"name":      obj.Name,
+		"amount":    obj.Amount,
+		"every":     obj.Every,
+		"nextRunAt": obj.NextRunAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertGrantScheduleResponseConstraints checks if the values respects the defined constraints
+func AssertGrantScheduleResponseConstraints(obj GrantScheduleResponse) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+type GrantSchedulesResponse struct {
+	Items []GrantScheduleResponse `json:"items"`
+}
+
+// AssertGrantSchedulesResponseRequired checks if the required fields are not zero-ed
+func AssertGrantSchedulesResponseRequired(obj GrantSchedulesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertGrantScheduleResponseRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertGrantSchedulesResponseConstraints checks if the values respects the defined constraints
+func AssertGrantSchedulesResponseConstraints(obj GrantSchedulesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertGrantScheduleResponseConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
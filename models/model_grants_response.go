@@ -0,0 +1,30 @@
+package models
+
+type GrantsResponse struct {
+
+	// Сколько начислений выполнено.
+	Count int32 `json:"count"`
+
+	// Сколько монет начислено всего.
+	Total int32 `json:"total"`
+}
+
+// AssertGrantsResponseRequired checks if the required fields are not zero-ed
+func AssertGrantsResponseRequired(obj GrantsResponse) error {
+	elements := map[string]interface{}{
+		"count": obj.Count,
+		"total": obj.Total,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertGrantsResponseConstraints checks if the values respects the defined constraints
+func AssertGrantsResponseConstraints(obj GrantsResponse) error {
+	return nil
+}
@@ -8,6 +8,14 @@ type InfoResponse struct {
 	Inventory []InfoResponseInventoryInner `json:"inventory,omitempty"`
 
 	CoinHistory InfoResponseCoinHistory `json:"coinHistory,omitempty"`
+
+	ItemHistory InfoResponseItemHistory `json:"itemHistory,omitempty"`
+
+	// Товары с лимитом покупок на пользователя и сколько их ещё можно купить.
+	Limits []InfoResponseLimitsInner `json:"limits,omitempty"`
+
+	// Отправленные и полученные подарки, возвращённые покупки сюда не попадают.
+	Gifts InfoResponseGifts `json:"gifts,omitempty"`
 }
 
 // AssertInfoResponseRequired checks if the required fields are not zero-ed
@@ -20,6 +28,17 @@ func AssertInfoResponseRequired(obj InfoResponse) error {
 	if err := AssertInfoResponseCoinHistoryRequired(obj.CoinHistory); err != nil {
 		return err
 	}
+	if err := AssertInfoResponseItemHistoryRequired(obj.ItemHistory); err != nil {
+		return err
+	}
+	for _, el := range obj.Limits {
+		if err := AssertInfoResponseLimitsInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	if err := AssertInfoResponseGiftsRequired(obj.Gifts); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -33,5 +52,16 @@ func AssertInfoResponseConstraints(obj InfoResponse) error {
 	if err := AssertInfoResponseCoinHistoryConstraints(obj.CoinHistory); err != nil {
 		return err
 	}
+	if err := AssertInfoResponseItemHistoryConstraints(obj.ItemHistory); err != nil {
+		return err
+	}
+	for _, el := range obj.Limits {
+		if err := AssertInfoResponseLimitsInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	if err := AssertInfoResponseGiftsConstraints(obj.Gifts); err != nil {
+		return err
+	}
 	return nil
 }
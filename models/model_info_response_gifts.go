@@ -0,0 +1,37 @@
+package models
+
+type InfoResponseGifts struct {
+	Received []InfoResponseGiftsReceivedInner `json:"received,omitempty"`
+
+	Sent []InfoResponseGiftsSentInner `json:"sent,omitempty"`
+}
+
+// AssertInfoResponseGiftsRequired checks if the required fields are not zero-ed
+func AssertInfoResponseGiftsRequired(obj InfoResponseGifts) error {
+	for _, el := range obj.Received {
+		if err := AssertInfoResponseGiftsReceivedInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Sent {
+		if err := AssertInfoResponseGiftsSentInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertInfoResponseGiftsConstraints checks if the values respects the defined constraints
+func AssertInfoResponseGiftsConstraints(obj InfoResponseGifts) error {
+	for _, el := range obj.Received {
+		if err := AssertInfoResponseGiftsReceivedInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Sent {
+		if err := AssertInfoResponseGiftsSentInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+type InfoResponseGiftsReceivedInner struct {
+
+	// Имя пользователя, который подарил предмет.
+	FromUser string `json:"fromUser,omitempty"`
+
+	// Подаренный предмет.
+	Item string `json:"item,omitempty"`
+
+	// Сообщение к подарку.
+	Message string `json:"message,omitempty"`
+}
+
+// AssertInfoResponseGiftsReceivedInnerRequired checks if the required fields are not zero-ed
+func AssertInfoResponseGiftsReceivedInnerRequired(obj InfoResponseGiftsReceivedInner) error {
+	return nil
+}
+
+// AssertInfoResponseGiftsReceivedInnerConstraints checks if the values respects the defined constraints
+func AssertInfoResponseGiftsReceivedInnerConstraints(obj InfoResponseGiftsReceivedInner) error {
+	return nil
+}
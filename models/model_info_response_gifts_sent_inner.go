@@ -0,0 +1,26 @@
+package models
+
+type InfoResponseGiftsSentInner struct {
+
+	// Имя пользователя, которому подарен предмет.
+	ToUser string `json:"toUser,omitempty"`
+
+	// Подаренный предмет.
+	Item string `json:"item,omitempty"`
+
+	// Сколько монет стоил подарок.
+	Amount int32 `json:"amount,omitempty"`
+
+	// Сообщение к подарку.
+	Message string `json:"message,omitempty"`
+}
+
+// AssertInfoResponseGiftsSentInnerRequired checks if the required fields are not zero-ed
+func AssertInfoResponseGiftsSentInnerRequired(obj InfoResponseGiftsSentInner) error {
+	return nil
+}
+
+// AssertInfoResponseGiftsSentInnerConstraints checks if the values respects the defined constraints
+func AssertInfoResponseGiftsSentInnerConstraints(obj InfoResponseGiftsSentInner) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+type InfoResponseItemHistory struct {
+	Received []InfoResponseItemHistoryReceivedInner `json:"received,omitempty"`
+
+	Sent []InfoResponseItemHistorySentInner `json:"sent,omitempty"`
+}
+
+// AssertInfoResponseItemHistoryRequired checks if the required fields are not zero-ed
+func AssertInfoResponseItemHistoryRequired(obj InfoResponseItemHistory) error {
+	for _, el := range obj.Received {
+		if err := AssertInfoResponseItemHistoryReceivedInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Sent {
+		if err := AssertInfoResponseItemHistorySentInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertInfoResponseItemHistoryConstraints checks if the values respects the defined constraints
+func AssertInfoResponseItemHistoryConstraints(obj InfoResponseItemHistory) error {
+	for _, el := range obj.Received {
+		if err := AssertInfoResponseItemHistoryReceivedInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Sent {
+		if err := AssertInfoResponseItemHistorySentInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+type InfoResponseItemHistoryReceivedInner struct {
+
+	// Имя пользователя, который передал предметы.
+	FromUser string `json:"fromUser,omitempty"`
+
+	// Тип предмета.
+	Type string `json:"type,omitempty"`
+
+	// Количество полученных предметов.
+	Quantity int32 `json:"quantity,omitempty"`
+}
+
+// AssertInfoResponseItemHistoryReceivedInnerRequired checks if the required fields are not zero-ed
+func AssertInfoResponseItemHistoryReceivedInnerRequired(obj InfoResponseItemHistoryReceivedInner) error {
+	return nil
+}
+
+// AssertInfoResponseItemHistoryReceivedInnerConstraints checks if the values respects the defined constraints
+func AssertInfoResponseItemHistoryReceivedInnerConstraints(obj InfoResponseItemHistoryReceivedInner) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+type InfoResponseItemHistorySentInner struct {
+
+	// Имя пользователя, которому переданы предметы.
+	ToUser string `json:"toUser,omitempty"`
+
+	// Тип предмета.
+	Type string `json:"type,omitempty"`
+
+	// Количество переданных предметов.
+	Quantity int32 `json:"quantity,omitempty"`
+}
+
+// AssertInfoResponseItemHistorySentInnerRequired checks if the required fields are not zero-ed
+func AssertInfoResponseItemHistorySentInnerRequired(obj InfoResponseItemHistorySentInner) error {
+	return nil
+}
+
+// AssertInfoResponseItemHistorySentInnerConstraints checks if the values respects the defined constraints
+func AssertInfoResponseItemHistorySentInnerConstraints(obj InfoResponseItemHistorySentInner) error {
+	return nil
+}
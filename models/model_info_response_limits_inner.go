@@ -0,0 +1,23 @@
+package models
+
+type InfoResponseLimitsInner struct {
+
+	// Тип предмета.
+	Type string `json:"type,omitempty"`
+
+	// Сколько предметов может купить один пользователь.
+	MaxPerUser int32 `json:"maxPerUser,omitempty"`
+
+	// Сколько предметов пользователь ещё может купить.
+	Remaining int32 `json:"remaining"`
+}
+
+// AssertInfoResponseLimitsInnerRequired checks if the required fields are not zero-ed
+func AssertInfoResponseLimitsInnerRequired(obj InfoResponseLimitsInner) error {
+	return nil
+}
+
+// AssertInfoResponseLimitsInnerConstraints checks if the values respects the defined constraints
+func AssertInfoResponseLimitsInnerConstraints(obj InfoResponseLimitsInner) error {
+	return nil
+}
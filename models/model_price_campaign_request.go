@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type PriceCampaignRequest struct {
+
+	// Название акции, показывается в каталоге.
+	Name string `json:"name"`
+
+	// Товар, цена которого меняется на время акции.
+	Item string `json:"item"`
+
+	// Цена товара по акции в монетах.
+	Price int32 `json:"price"`
+
+	// Начало акции.
+	StartsAt time.Time `json:"startsAt"`
+
+	// Окончание акции.
+	EndsAt time.Time `json:"endsAt"`
+}
+
+// AssertPriceCampaignRequestRequired checks if the required fields are not zero-ed
+func AssertPriceCampaignRequestRequired(obj PriceCampaignRequest) error {
+	elements := map[string]interface{}{
+		"name":     obj.Name,
+		"item":     obj.Item,
+		"price":    obj.Price,
+		"startsAt": obj.StartsAt,
+		"endsAt":   obj.EndsAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertPriceCampaignRequestConstraints checks if the values respects the defined constraints
+func AssertPriceCampaignRequestConstraints(obj PriceCampaignRequest) error {
+	return nil
+}
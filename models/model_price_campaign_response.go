@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type PriceCampaignResponse struct {
+
+	// Идентификатор акции.
+	Id int64 `json:"id"`
+
+	// Название акции.
+	Name string `json:"name"`
+
+	// Товар, цена которого меняется на время акции.
+	Item string `json:"item"`
+
+	// Цена товара по акции в монетах.
+	Price int32 `json:"price"`
+
+	// Начало акции.
+	StartsAt time.Time `json:"startsAt"`
+
+	// Окончание акции.
+	EndsAt time.Time `json:"endsAt"`
+
+	// Действует ли акция сейчас.
+	Active bool `json:"active"`
+}
+
+// AssertPriceCampaignResponseRequired checks if the required fields are not zero-ed
+func AssertPriceCampaignResponseRequired(obj PriceCampaignResponse) error {
+	elements := map[string]interface{}{
+		"id":       obj.Id,
+		"name":     obj.Name,
+		"item":     obj.Item,
+		"price":    obj.Price,
+		"startsAt": obj.StartsAt,
+		"endsAt":   obj.EndsAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertPriceCampaignResponseConstraints checks if the values respects the defined constraints
+func AssertPriceCampaignResponseConstraints(obj PriceCampaignResponse) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+type PriceCampaignsResponse struct {
+	Items []PriceCampaignResponse `json:"items"`
+}
+
+// AssertPriceCampaignsResponseRequired checks if the required fields are not zero-ed
+func AssertPriceCampaignsResponseRequired(obj PriceCampaignsResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertPriceCampaignResponseRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertPriceCampaignsResponseConstraints checks if the values respects the defined constraints
+func AssertPriceCampaignsResponseConstraints(obj PriceCampaignsResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertPriceCampaignResponseConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
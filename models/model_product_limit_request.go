@@ -0,0 +1,17 @@
+package models
+
+type ProductLimitRequest struct {
+
+	// Сколько единиц товара может купить один пользователь. null снимает лимит.
+	MaxPerUser *int64 `json:"maxPerUser"`
+}
+
+// AssertProductLimitRequestRequired checks if the required fields are not zero-ed
+func AssertProductLimitRequestRequired(obj ProductLimitRequest) error {
+	return nil
+}
+
+// AssertProductLimitRequestConstraints checks if the values respects the defined constraints
+func AssertProductLimitRequestConstraints(obj ProductLimitRequest) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+type ProductLimitResponse struct {
+
+	// Название товара.
+	Name string `json:"name"`
+
+	// Лимит покупок на пользователя после изменения, null -- без лимита.
+	MaxPerUser *int64 `json:"maxPerUser"`
+}
+
+// AssertProductLimitResponseRequired checks if the required fields are not zero-ed
+func AssertProductLimitResponseRequired(obj ProductLimitResponse) error {
+	return nil
+}
+
+// AssertProductLimitResponseConstraints checks if the values respects the defined constraints
+func AssertProductLimitResponseConstraints(obj ProductLimitResponse) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+type ProductStockRequest struct {
+
+	// Новый остаток товара. null снимает ограничение количества.
+	Stock *int64 `json:"stock"`
+}
+
+// AssertProductStockRequestRequired checks if the required fields are not zero-ed
+func AssertProductStockRequestRequired(obj ProductStockRequest) error {
+	return nil
+}
+
+// AssertProductStockRequestConstraints checks if the values respects the defined constraints
+func AssertProductStockRequestConstraints(obj ProductStockRequest) error {
+	return nil
+}
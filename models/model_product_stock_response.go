@@ -0,0 +1,20 @@
+package models
+
+type ProductStockResponse struct {
+
+	// Название товара.
+	Name string `json:"name"`
+
+	// Остаток товара после изменения, null -- количество не ограничено.
+	Stock *int64 `json:"stock"`
+}
+
+// AssertProductStockResponseRequired checks if the required fields are not zero-ed
+func AssertProductStockResponseRequired(obj ProductStockResponse) error {
+	return nil
+}
+
+// AssertProductStockResponseConstraints checks if the values respects the defined constraints
+func AssertProductStockResponseConstraints(obj ProductStockResponse) error {
+	return nil
+}
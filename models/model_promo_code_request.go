@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type PromoCodeRequest struct {
+
+	// Промокод, который вводит пользователь.
+	Code string `json:"code"`
+
+	// Скидка в процентах от цены, от 1 до 100. Указывается либо процент, либо сумма.
+	Percent int32 `json:"percent,omitempty"`
+
+	// Фиксированная скидка в монетах.
+	Amount int32 `json:"amount,omitempty"`
+
+	// Товар, на который действует промокод. Не указан -- любой товар.
+	Item string `json:"item,omitempty"`
+
+	// Начало действия промокода. Не указано -- действует сразу.
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+
+	// Окончание действия промокода. Не указано -- действует бессрочно.
+	EndsAt *time.Time `json:"endsAt,omitempty"`
+
+	// Сколько раз промокод можно применить. Не указано -- без ограничения.
+	MaxUses *int64 `json:"maxUses,omitempty"`
+}
+
+// AssertPromoCodeRequestRequired checks if the required fields are not zero-ed
+func AssertPromoCodeRequestRequired(obj PromoCodeRequest) error {
+	elements := map[string]interface{}{
+		"code": obj.Code,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertPromoCodeRequestConstraints checks if the values respects the defined constraints
+func AssertPromoCodeRequestConstraints(obj PromoCodeRequest) error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type PromoCodeResponse struct {
+
+	// Промокод.
+	Code string `json:"code"`
+
+	// Скидка в процентах от цены.
+	Percent int32 `json:"percent,omitempty"`
+
+	// Фиксированная скидка в монетах.
+	Amount int32 `json:"amount,omitempty"`
+
+	// Товар, на который действует промокод. Отсутствует, если промокод действует на любой товар.
+	Item string `json:"item,omitempty"`
+
+	// Начало действия промокода.
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+
+	// Окончание действия промокода.
+	EndsAt *time.Time `json:"endsAt,omitempty"`
+
+	// Сколько раз промокод можно применить. Отсутствует, если ограничения нет.
+	MaxUses *int64 `json:"maxUses,omitempty"`
+
+	// Сколько раз промокод уже применён.
+	Uses int64 `json:"uses"`
+
+	// Время отключения промокода администратором.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+}
+
+// AssertPromoCodeResponseRequired checks if the required fields are not zero-ed
+func AssertPromoCodeResponseRequired(obj PromoCodeResponse) error {
+	return nil
+}
+
+// AssertPromoCodeResponseConstraints checks if the values respects the defined constraints
+func AssertPromoCodeResponseConstraints(obj PromoCodeResponse) error {
+	return nil
+}
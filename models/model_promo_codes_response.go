@@ -0,0 +1,25 @@
+package models
+
+type PromoCodesResponse struct {
+	Items []PromoCodeResponse `json:"items"`
+}
+
+// AssertPromoCodesResponseRequired checks if the required fields are not zero-ed
+func AssertPromoCodesResponseRequired(obj PromoCodesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertPromoCodeResponseRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertPromoCodesResponseConstraints checks if the values respects the defined constraints
+func AssertPromoCodesResponseConstraints(obj PromoCodesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertPromoCodeResponseConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+type PurchaseResponse struct {
+
+	// Идентификатор покупки, по нему покупку можно вернуть.
+	PurchaseId int64 `json:"purchaseId"`
+
+	// Сколько монет списано.
+	Price int32 `json:"price"`
+
+	// Скидка по промокоду. Отсутствует, если промокод не применялся.
+	Discount int32 `json:"discount,omitempty"`
+}
+
+// AssertPurchaseResponseRequired checks if the required fields are not zero-ed
+func AssertPurchaseResponseRequired(obj PurchaseResponse) error {
+	return nil
+}
+
+// AssertPurchaseResponseConstraints checks if the values respects the defined constraints
+func AssertPurchaseResponseConstraints(obj PurchaseResponse) error {
+	return nil
+}
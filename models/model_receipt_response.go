@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type ReceiptResponse struct {
+
+	// Идентификатор чека.
+	Id int64 `json:"id"`
+
+	Items []ReceiptResponseItemsInner `json:"items"`
+
+	// Сколько монет списано за всю корзину.
+	Total int32 `json:"total"`
+
+	// Баланс после оплаты.
+	Balance int32 `json:"balance"`
+
+	// Время оформления.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AssertReceiptResponseRequired checks if the required fields are not zero-ed
+func AssertReceiptResponseRequired(obj ReceiptResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertReceiptResponseItemsInnerRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertReceiptResponseConstraints checks if the values respects the defined constraints
+func AssertReceiptResponseConstraints(obj ReceiptResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertReceiptResponseItemsInnerConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
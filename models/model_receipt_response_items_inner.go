@@ -0,0 +1,26 @@
+package models
+
+type ReceiptResponseItemsInner struct {
+
+	// Название товара.
+	Item string `json:"item"`
+
+	// Сколько единиц куплено.
+	Quantity int32 `json:"quantity"`
+
+	// Цена одной единицы.
+	Price int32 `json:"price"`
+
+	// Идентификаторы покупок, по одной на единицу товара, по ним единицы можно вернуть.
+	PurchaseIds []int64 `json:"purchaseIds"`
+}
+
+// AssertReceiptResponseItemsInnerRequired checks if the required fields are not zero-ed
+func AssertReceiptResponseItemsInnerRequired(obj ReceiptResponseItemsInner) error {
+	return nil
+}
+
+// AssertReceiptResponseItemsInnerConstraints checks if the values respects the defined constraints
+func AssertReceiptResponseItemsInnerConstraints(obj ReceiptResponseItemsInner) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type RefundResponse struct {
+
+	// Идентификатор покупки.
+	Id int64 `json:"id"`
+
+	// Название товара.
+	Item string `json:"item"`
+
+	// Сколько монет вернулось на баланс.
+	Price int32 `json:"price"`
+
+	// Время возврата.
+	RefundedAt time.Time `json:"refundedAt"`
+
+	// Имя администратора, оформившего возврат, пустое -- вернул сам пользователь.
+	RefundedBy string `json:"refundedBy,omitempty"`
+}
+
+// AssertRefundResponseRequired checks if the required fields are not zero-ed
+func AssertRefundResponseRequired(obj RefundResponse) error {
+	return nil
+}
+
+// AssertRefundResponseConstraints checks if the values respects the defined constraints
+func AssertRefundResponseConstraints(obj RefundResponse) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+type RestockRequest struct {
+
+	// Сколько единиц товара добавить к остатку.
+	Quantity int64 `json:"quantity"`
+}
+
+// AssertRestockRequestRequired checks if the required fields are not zero-ed
+func AssertRestockRequestRequired(obj RestockRequest) error {
+	elements := map[string]interface{}{
+		"quantity": obj.Quantity,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertRestockRequestConstraints checks if the values respects the defined constraints
+func AssertRestockRequestConstraints(obj RestockRequest) error {
+	return nil
+}
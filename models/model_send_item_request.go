@@ -0,0 +1,34 @@
+package models
+
+type SendItemRequest struct {
+
+	// Имя пользователя, которому нужно передать предметы.
+	ToUser string `json:"toUser"`
+
+	// Тип предмета из инвентаря.
+	Item string `json:"item"`
+
+	// Сколько предметов передать.
+	Quantity int32 `json:"quantity"`
+}
+
+// AssertSendItemRequestRequired checks if the required fields are not zero-ed
+func AssertSendItemRequestRequired(obj SendItemRequest) error {
+	elements := map[string]interface{}{
+		"toUser":   obj.ToUser,
+		"item":     obj.Item,
+		"quantity": obj.Quantity,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertSendItemRequestConstraints checks if the values respects the defined constraints
+func AssertSendItemRequestConstraints(obj SendItemRequest) error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+type TradeRequest struct {
+
+	// Имя пользователя, которому предлагается обмен.
+	ToUser string `json:"toUser"`
+
+	// Тип предмета из инвентаря, который предлагается отдать.
+	Item string `json:"item"`
+
+	// Сколько предметов отдать.
+	Quantity int32 `json:"quantity"`
+
+	// Тип предмета, который нужен в обмен. Не указан -- в обмен нужны только монеты.
+	WantItem string `json:"wantItem,omitempty"`
+
+	// Сколько предметов нужно в обмен.
+	WantQuantity int32 `json:"wantQuantity,omitempty"`
+
+	// Сколько монет нужно в обмен.
+	Coins int32 `json:"coins,omitempty"`
+}
+
+// AssertTradeRequestRequired checks if the required fields are not zero-ed
+func AssertTradeRequestRequired(obj TradeRequest) error {
+	elements := map[string]interface{}{
+		"toUser":   obj.ToUser,
+		"item":     obj.Item,
+		"quantity": obj.Quantity,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertTradeRequestConstraints checks if the values respects the defined constraints
+func AssertTradeRequestConstraints(obj TradeRequest) error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+type TradeResponse struct {
+
+	// Идентификатор предложения обмена.
+	Id int64 `json:"id"`
+
+	// Автор предложения.
+	FromUser string `json:"fromUser"`
+
+	// Получатель предложения.
+	ToUser string `json:"toUser"`
+
+	// Тип предмета, который отдаёт автор.
+	Item string `json:"item"`
+
+	// Сколько предметов отдаёт автор.
+	Quantity int32 `json:"quantity"`
+
+	// Тип предмета, который отдаёт получатель. Не указан -- получатель платит только монетами.
+	WantItem string `json:"wantItem,omitempty"`
+
+	// Сколько предметов отдаёт получатель.
+	WantQuantity int32 `json:"wantQuantity,omitempty"`
+
+	// Сколько монет платит получатель.
+	Coins int32 `json:"coins,omitempty"`
+
+	// Состояние: open, accepted, declined или cancelled.
+	Status string `json:"status"`
+
+	// Время создания.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Время принятия, отклонения или отмены. Не указано -- предложение открыто.
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+}
+
+// AssertTradeResponseRequired checks if the required fields are not zero-ed
+func AssertTradeResponseRequired(obj TradeResponse) error {
+	elements := map[string]interface{}{
+		"id":        obj.Id,
+		"fromUser":  obj.FromUser,
+		"toUser":    obj.ToUser,
+		"item":      obj.Item,
+		"quantity":  obj.Quantity,
+		"status":    obj.Status,
+		"createdAt": obj.CreatedAt,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertTradeResponseConstraints checks if the values respects the defined constraints
+func AssertTradeResponseConstraints(obj TradeResponse) error {
+	return nil
+}
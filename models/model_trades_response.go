@@ -0,0 +1,25 @@
+package models
+
+type TradesResponse struct {
+	Items []TradeResponse `json:"items"`
+}
+
+// AssertTradesResponseRequired checks if the required fields are not zero-ed
+func AssertTradesResponseRequired(obj TradesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertTradeResponseRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertTradesResponseConstraints checks if the values respects the defined constraints
+func AssertTradesResponseConstraints(obj TradesResponse) error {
+	for _, el := range obj.Items {
+		if err := AssertTradeResponseConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
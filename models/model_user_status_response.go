@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type UserStatusResponse struct {
+
+	// Имя пользователя.
+	Username string `json:"username"`
+
+	// Баланс пользователя.
+	Balance int32 `json:"balance"`
+
+	// Время блокировки. Не указано -- пользователь не заблокирован.
+	FrozenAt *time.Time `json:"frozenAt,omitempty"`
+
+	// Администратор, заблокировавший пользователя.
+	FrozenBy string `json:"frozenBy,omitempty"`
+
+	// Причина блокировки.
+	FrozenReason string `json:"frozenReason,omitempty"`
+}
+
+// AssertUserStatusResponseRequired checks if the required fields are not zero-ed
+func AssertUserStatusResponseRequired(obj UserStatusResponse) error {
+	elements := map[string]interface{}{
+		"username": obj.Username,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertUserStatusResponseConstraints checks if the values respects the defined constraints
+func AssertUserStatusResponseConstraints(obj UserStatusResponse) error {
+	return nil
+}
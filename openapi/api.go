@@ -14,6 +14,18 @@ type DefaultAPIRouter interface {
 	ApiSendCoinPost(http.ResponseWriter, *http.Request)
 	ApiBuyItemGet(http.ResponseWriter, *http.Request)
 	ApiAuthPost(http.ResponseWriter, *http.Request)
+	ApiProductsGet(http.ResponseWriter, *http.Request)
+	ApiPurchaseRefundPost(http.ResponseWriter, *http.Request)
+	ApiGiftPost(http.ResponseWriter, *http.Request)
+	ApiSendItemPost(http.ResponseWriter, *http.Request)
+	ApiTradesGet(http.ResponseWriter, *http.Request)
+	ApiTradesPost(http.ResponseWriter, *http.Request)
+	ApiTradeAcceptPost(http.ResponseWriter, *http.Request)
+	ApiTradeDelete(http.ResponseWriter, *http.Request)
+	ApiCartGet(http.ResponseWriter, *http.Request)
+	ApiCartPost(http.ResponseWriter, *http.Request)
+	ApiCartItemDelete(http.ResponseWriter, *http.Request)
+	ApiCartCheckoutPost(http.ResponseWriter, *http.Request)
 }
 
 // DefaultAPIServicer defines the api actions for the DefaultAPI service
@@ -23,6 +35,67 @@ type DefaultAPIRouter interface {
 type DefaultAPIServicer interface {
 	ApiInfoGet(context.Context) (models.ImplResponse, error)
 	ApiSendCoinPost(context.Context, models.SendCoinRequest) (models.ImplResponse, error)
-	ApiBuyItemGet(context.Context, string) (models.ImplResponse, error)
+	ApiBuyItemGet(context.Context, string, string) (models.ImplResponse, error)
 	ApiAuthPost(context.Context, models.AuthRequest) (models.ImplResponse, error)
+	ApiProductsGet(context.Context) (models.ImplResponse, error)
+	ApiPurchaseRefundPost(context.Context, int64) (models.ImplResponse, error)
+	ApiGiftPost(context.Context, models.GiftRequest) (models.ImplResponse, error)
+	ApiSendItemPost(context.Context, models.SendItemRequest) (models.ImplResponse, error)
+	ApiTradesGet(context.Context) (models.ImplResponse, error)
+	ApiTradesPost(context.Context, models.TradeRequest) (models.ImplResponse, error)
+	ApiTradeAcceptPost(context.Context, int64) (models.ImplResponse, error)
+	ApiTradeDelete(context.Context, int64) (models.ImplResponse, error)
+	ApiCartGet(context.Context) (models.ImplResponse, error)
+	ApiCartPost(context.Context, models.CartItemRequest) (models.ImplResponse, error)
+	ApiCartItemDelete(context.Context, string) (models.ImplResponse, error)
+	ApiCartCheckoutPost(context.Context) (models.ImplResponse, error)
+}
+
+// AdminAPIRouter defines the required methods for binding the api requests to a responses for the AdminAPI
+type AdminAPIRouter interface {
+	ApiAdminProductStockPut(http.ResponseWriter, *http.Request)
+	ApiAdminProductRestockPost(http.ResponseWriter, *http.Request)
+	ApiAdminProductLimitPut(http.ResponseWriter, *http.Request)
+	ApiAdminPurchaseRefundPost(http.ResponseWriter, *http.Request)
+	ApiAdminPromoCodesPost(http.ResponseWriter, *http.Request)
+	ApiAdminPromoCodesGet(http.ResponseWriter, *http.Request)
+	ApiAdminPromoCodeDelete(http.ResponseWriter, *http.Request)
+	ApiAdminCampaignsPost(http.ResponseWriter, *http.Request)
+	ApiAdminCampaignsGet(http.ResponseWriter, *http.Request)
+	ApiAdminCampaignDelete(http.ResponseWriter, *http.Request)
+	ApiAdminGrantsPost(http.ResponseWriter, *http.Request)
+	ApiAdminGrantsBulkPost(http.ResponseWriter, *http.Request)
+	ApiAdminGrantSchedulesPost(http.ResponseWriter, *http.Request)
+	ApiAdminGrantSchedulesGet(http.ResponseWriter, *http.Request)
+	ApiAdminGrantScheduleDelete(http.ResponseWriter, *http.Request)
+	ApiAdminUserAdjustmentsPost(http.ResponseWriter, *http.Request)
+	ApiAdminUserGet(http.ResponseWriter, *http.Request)
+	ApiAdminUserFreezePost(http.ResponseWriter, *http.Request)
+	ApiAdminUserFreezeDelete(http.ResponseWriter, *http.Request)
+	ApiAdminActionsGet(http.ResponseWriter, *http.Request)
+}
+
+// AdminAPIServicer defines the api actions for the AdminAPI service
+type AdminAPIServicer interface {
+	ApiAdminProductStockPut(context.Context, string, models.ProductStockRequest) (models.ImplResponse, error)
+	ApiAdminProductRestockPost(context.Context, string, models.RestockRequest) (models.ImplResponse, error)
+	ApiAdminProductLimitPut(context.Context, string, models.ProductLimitRequest) (models.ImplResponse, error)
+	ApiAdminPurchaseRefundPost(context.Context, int64) (models.ImplResponse, error)
+	ApiAdminPromoCodesPost(context.Context, models.PromoCodeRequest) (models.ImplResponse, error)
+	ApiAdminPromoCodesGet(context.Context) (models.ImplResponse, error)
+	ApiAdminPromoCodeDelete(context.Context, string) (models.ImplResponse, error)
+	ApiAdminCampaignsPost(context.Context, models.PriceCampaignRequest) (models.ImplResponse, error)
+	ApiAdminCampaignsGet(context.Context) (models.ImplResponse, error)
+	ApiAdminCampaignDelete(context.Context, int64) (models.ImplResponse, error)
+	ApiAdminGrantsPost(context.Context, models.GrantRequest) (models.ImplResponse, error)
+	ApiAdminGrantsBulkPost(context.Context, models.BulkGrantRequest) (models.ImplResponse, error)
+	ApiAdminGrantSchedulesPost(context.Context, models.GrantScheduleRequest) (models.ImplResponse, error)
+	ApiAdminGrantSchedulesGet(context.Context) (models.ImplResponse, error)
+	ApiAdminGrantScheduleDelete(context.Context, int64) (models.ImplResponse, error)
+	ApiAdminUserAdjustmentsPost(context.Context, string, models.AdjustmentRequest) (models.ImplResponse, error)
+	ApiAdminUserGet(context.Context, string) (models.ImplResponse, error)
+	ApiAdminUserFreezePost(context.Context, string, models.FreezeRequest) (models.ImplResponse, error)
+	ApiAdminUserFreezeDelete(context.Context, string) (models.ImplResponse, error)
+	ApiAdminActionsGet(context.Context, string, int64, int32) (models.ImplResponse, error)
+	AdminAuditor
 }
@@ -0,0 +1,718 @@
+package openapi
+
+import (
+	"api-avito-shop/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminAPIController binds administrative endpoints. The routes do not require JWT,
+// instead every handler is wrapped into AdminAuth with the configured admin tokens
+type AdminAPIController struct {
+	service      AdminAPIServicer
+	tokens       map[string]string
+	errorHandler ErrorHandler
+}
+
+// AdminAPIOption for how the controller is set up.
+type AdminAPIOption func(*AdminAPIController)
+
+// WithAdminAPIErrorHandler inject ErrorHandler into controller
+func WithAdminAPIErrorHandler(h ErrorHandler) AdminAPIOption {
+	return func(c *AdminAPIController) {
+		c.errorHandler = h
+	}
+}
+
+// NewAdminAPIController creates an admin api controller accepting the given admin tokens by admin names
+func NewAdminAPIController(s AdminAPIServicer, tokens map[string]string, opts ...AdminAPIOption) *AdminAPIController {
+	controller := &AdminAPIController{
+		service:      s,
+		tokens:       tokens,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// Routes returns all the api routes for the AdminAPIController
+func (c *AdminAPIController) Routes() Routes {
+	return Routes{
+		"ApiAdminProductStockPut": Route{
+			strings.ToUpper("Put"),
+			"/api/admin/products/{item}/stock",
+			c.admin(c.ApiAdminProductStockPut),
+			false,
+		},
+		"ApiAdminProductRestockPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/products/{item}/restock",
+			c.admin(c.ApiAdminProductRestockPost),
+			false,
+		},
+		"ApiAdminProductLimitPut": Route{
+			strings.ToUpper("Put"),
+			"/api/admin/products/{item}/limit",
+			c.admin(c.ApiAdminProductLimitPut),
+			false,
+		},
+		"ApiAdminPurchaseRefundPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/purchases/{id}/refund",
+			c.admin(c.ApiAdminPurchaseRefundPost),
+			false,
+		},
+		"ApiAdminPromoCodesPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/promoCodes",
+			c.admin(c.ApiAdminPromoCodesPost),
+			false,
+		},
+		"ApiAdminPromoCodesGet": Route{
+			strings.ToUpper("Get"),
+			"/api/admin/promoCodes",
+			c.admin(c.ApiAdminPromoCodesGet),
+			false,
+		},
+		"ApiAdminPromoCodeDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/admin/promoCodes/{code}",
+			c.admin(c.ApiAdminPromoCodeDelete),
+			false,
+		},
+		"ApiAdminCampaignsPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/campaigns",
+			c.admin(c.ApiAdminCampaignsPost),
+			false,
+		},
+		"ApiAdminCampaignsGet": Route{
+			strings.ToUpper("Get"),
+			"/api/admin/campaigns",
+			c.admin(c.ApiAdminCampaignsGet),
+			false,
+		},
+		"ApiAdminCampaignDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/admin/campaigns/{id}",
+			c.admin(c.ApiAdminCampaignDelete),
+			false,
+		},
+		"ApiAdminGrantsPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/grants",
+			c.admin(c.ApiAdminGrantsPost),
+			false,
+		},
+		"ApiAdminGrantsBulkPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/grants/bulk",
+			c.admin(c.ApiAdminGrantsBulkPost),
+			false,
+		},
+		"ApiAdminGrantSchedulesPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/grantSchedules",
+			c.admin(c.ApiAdminGrantSchedulesPost),
+			false,
+		},
+		"ApiAdminGrantSchedulesGet": Route{
+			strings.ToUpper("Get"),
+			"/api/admin/grantSchedules",
+			c.admin(c.ApiAdminGrantSchedulesGet),
+			false,
+		},
+		"ApiAdminGrantScheduleDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/admin/grantSchedules/{id}",
+			c.admin(c.ApiAdminGrantScheduleDelete),
+			false,
+		},
+		"ApiAdminUserAdjustmentsPost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/users/{username}/adjustments",
+			c.admin(c.ApiAdminUserAdjustmentsPost),
+			false,
+		},
+		"ApiAdminUserGet": Route{
+			strings.ToUpper("Get"),
+			"/api/admin/users/{username}",
+			c.admin(c.ApiAdminUserGet),
+			false,
+		},
+		"ApiAdminUserFreezePost": Route{
+			strings.ToUpper("Post"),
+			"/api/admin/users/{username}/freeze",
+			c.admin(c.ApiAdminUserFreezePost),
+			false,
+		},
+		"ApiAdminUserFreezeDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/admin/users/{username}/freeze",
+			c.admin(c.ApiAdminUserFreezeDelete),
+			false,
+		},
+		"ApiAdminActionsGet": Route{
+			strings.ToUpper("Get"),
+			"/api/admin/actions",
+			c.admin(c.ApiAdminActionsGet),
+			false,
+		},
+	}
+}
+
+// admin checks the admin token and records the request, accepted or rejected, in the admin audit log
+func (c *AdminAPIController) admin(h http.HandlerFunc) http.HandlerFunc {
+	return AdminAuth(AdminAudit(h, c.service), c.tokens, c.service).ServeHTTP
+}
+
+// ApiAdminProductStockPut - Задать остаток товара или снять ограничение количества.
+func (c *AdminAPIController) ApiAdminProductStockPut(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	itemParam := params["item"]
+	if itemParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "item"}, nil)
+		return
+	}
+	var productStockRequestParam models.ProductStockRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&productStockRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertProductStockRequestRequired(productStockRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertProductStockRequestConstraints(productStockRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminProductStockPut(r.Context(), itemParam, productStockRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminProductRestockPost - Пополнить остаток товара.
+func (c *AdminAPIController) ApiAdminProductRestockPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	itemParam := params["item"]
+	if itemParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "item"}, nil)
+		return
+	}
+	var restockRequestParam models.RestockRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&restockRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertRestockRequestRequired(restockRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertRestockRequestConstraints(restockRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminProductRestockPost(r.Context(), itemParam, restockRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminProductLimitPut - Задать лимит покупок товара на пользователя или снять его.
+func (c *AdminAPIController) ApiAdminProductLimitPut(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	itemParam := params["item"]
+	if itemParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "item"}, nil)
+		return
+	}
+	var productLimitRequestParam models.ProductLimitRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&productLimitRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertProductLimitRequestRequired(productLimitRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertProductLimitRequestConstraints(productLimitRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminProductLimitPut(r.Context(), itemParam, productLimitRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminPurchaseRefundPost - Вернуть покупку любого пользователя без ограничения по сроку.
+func (c *AdminAPIController) ApiAdminPurchaseRefundPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminPurchaseRefundPost(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminPromoCodesPost - Создать промокод.
+func (c *AdminAPIController) ApiAdminPromoCodesPost(w http.ResponseWriter, r *http.Request) {
+	var promoCodeRequestParam models.PromoCodeRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&promoCodeRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertPromoCodeRequestRequired(promoCodeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertPromoCodeRequestConstraints(promoCodeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminPromoCodesPost(r.Context(), promoCodeRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminPromoCodesGet - Получить все промокоды.
+func (c *AdminAPIController) ApiAdminPromoCodesGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiAdminPromoCodesGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminPromoCodeDelete - Отключить промокод.
+func (c *AdminAPIController) ApiAdminPromoCodeDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	codeParam := params["code"]
+	if codeParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "code"}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminPromoCodeDelete(r.Context(), codeParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminCampaignsPost - Создать акцию на товар.
+func (c *AdminAPIController) ApiAdminCampaignsPost(w http.ResponseWriter, r *http.Request) {
+	var priceCampaignRequestParam models.PriceCampaignRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&priceCampaignRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertPriceCampaignRequestRequired(priceCampaignRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertPriceCampaignRequestConstraints(priceCampaignRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminCampaignsPost(r.Context(), priceCampaignRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminCampaignsGet - Получить незакончившиеся акции.
+func (c *AdminAPIController) ApiAdminCampaignsGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiAdminCampaignsGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminCampaignDelete - Удалить акцию.
+func (c *AdminAPIController) ApiAdminCampaignDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminCampaignDelete(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminGrantsPost - Начислить монеты пользователю.
+func (c *AdminAPIController) ApiAdminGrantsPost(w http.ResponseWriter, r *http.Request) {
+	var grantRequestParam models.GrantRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&grantRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertGrantRequestRequired(grantRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertGrantRequestConstraints(grantRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminGrantsPost(r.Context(), grantRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminGrantsBulkPost - Начислить монеты пакетом. Пакет принимается в JSON или в CSV с Content-Type text/csv
+func (c *AdminAPIController) ApiAdminGrantsBulkPost(w http.ResponseWriter, r *http.Request) {
+	var bulkGrantRequestParam models.BulkGrantRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		grants, err := decodeGrantsCSV(r.Body)
+		if err != nil {
+			c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+			return
+		}
+		bulkGrantRequestParam.Grants = grants
+	} else {
+		d := json.NewDecoder(r.Body)
+		d.DisallowUnknownFields()
+		if err := d.Decode(&bulkGrantRequestParam); err != nil {
+			c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+			return
+		}
+	}
+	if err := models.AssertBulkGrantRequestRequired(bulkGrantRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertBulkGrantRequestConstraints(bulkGrantRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminGrantsBulkPost(r.Context(), bulkGrantRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// decodeGrantsCSV читает начисления из CSV со строками username,amount[,reason[,expiresAt]],
+// expiresAt -- в RFC 3339. Первая строка пропускается, если это заголовок username,amount,...
+func decodeGrantsCSV(body io.Reader) ([]models.GrantRequest, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var grants []models.GrantRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return grants, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if line == 1 && record[0] == "username" {
+			continue
+		}
+		if len(record) < 2 || len(record) > 4 {
+			return nil, fmt.Errorf("строка %d: ожидается username,amount[,reason[,expiresAt]]", line)
+		}
+		amount, err := strconv.ParseInt(record[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: amount: %w", line, err)
+		}
+		grant := models.GrantRequest{Username: record[0], Amount: int32(amount)}
+		if len(record) > 2 {
+			grant.Reason = record[2]
+		}
+		if len(record) > 3 && record[3] != "" {
+			expiresAt, err := time.Parse(time.RFC3339, record[3])
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: expiresAt: %w", line, err)
+			}
+			grant.ExpiresAt = &expiresAt
+		}
+		grants = append(grants, grant)
+	}
+}
+
+// ApiAdminGrantSchedulesPost - Создать регулярное начисление.
+func (c *AdminAPIController) ApiAdminGrantSchedulesPost(w http.ResponseWriter, r *http.Request) {
+	var grantScheduleRequestParam models.GrantScheduleRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&grantScheduleRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertGrantScheduleRequestRequired(grantScheduleRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertGrantScheduleRequestConstraints(grantScheduleRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminGrantSchedulesPost(r.Context(), grantScheduleRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminGrantSchedulesGet - Получить регулярные начисления.
+func (c *AdminAPIController) ApiAdminGrantSchedulesGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiAdminGrantSchedulesGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminGrantScheduleDelete - Остановить регулярное начисление.
+func (c *AdminAPIController) ApiAdminGrantScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminGrantScheduleDelete(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminUserAdjustmentsPost - Исправить баланс пользователя.
+func (c *AdminAPIController) ApiAdminUserAdjustmentsPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	usernameParam := params["username"]
+	if usernameParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "username"}, nil)
+		return
+	}
+	var adjustmentRequestParam models.AdjustmentRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&adjustmentRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertAdjustmentRequestRequired(adjustmentRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertAdjustmentRequestConstraints(adjustmentRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminUserAdjustmentsPost(r.Context(), usernameParam, adjustmentRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminUserGet - Получить баланс пользователя и сведения о блокировке.
+func (c *AdminAPIController) ApiAdminUserGet(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	usernameParam := params["username"]
+	if usernameParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "username"}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminUserGet(r.Context(), usernameParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminUserFreezePost - Заблокировать пользователя.
+func (c *AdminAPIController) ApiAdminUserFreezePost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	usernameParam := params["username"]
+	if usernameParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "username"}, nil)
+		return
+	}
+	var freezeRequestParam models.FreezeRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&freezeRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertFreezeRequestRequired(freezeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertFreezeRequestConstraints(freezeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiAdminUserFreezePost(r.Context(), usernameParam, freezeRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminUserFreezeDelete - Снять блокировку пользователя.
+func (c *AdminAPIController) ApiAdminUserFreezeDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	usernameParam := params["username"]
+	if usernameParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "username"}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminUserFreezeDelete(r.Context(), usernameParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiAdminActionsGet - Получить журнал действий администраторов.
+func (c *AdminAPIController) ApiAdminActionsGet(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	adminParam := query.Get("admin")
+	beforeParam, err := parseNumericParameter[int64](
+		query.Get("before"),
+		WithParse[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "before", Err: err}, nil)
+		return
+	}
+	limitParam, err := parseNumericParameter[int32](
+		query.Get("limit"),
+		WithParse[int32](parseInt32),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "limit", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiAdminActionsGet(r.Context(), adminParam, beforeParam, limitParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -0,0 +1,124 @@
+package openapi
+
+import (
+	"api-avito-shop/engine"
+	"api-avito-shop/models"
+	"context"
+)
+
+// AdminAPIService is a service that implements the logic for the AdminAPIServicer
+type AdminAPIService struct {
+	engine *engine.Engine
+}
+
+// NewAdminAPIService creates an admin api service
+func NewAdminAPIService(engine *engine.Engine) *AdminAPIService {
+	return &AdminAPIService{
+		engine: engine,
+	}
+}
+
+// ApiAdminProductStockPut - Задать остаток товара или снять ограничение количества.
+func (s *AdminAPIService) ApiAdminProductStockPut(ctx context.Context, item string, productStockRequest models.ProductStockRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminSetProductStock(ctx, item, productStockRequest)
+}
+
+// ApiAdminProductRestockPost - Пополнить остаток товара.
+func (s *AdminAPIService) ApiAdminProductRestockPost(ctx context.Context, item string, restockRequest models.RestockRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminRestockProduct(ctx, item, restockRequest)
+}
+
+// ApiAdminProductLimitPut - Задать лимит покупок товара на пользователя или снять его.
+func (s *AdminAPIService) ApiAdminProductLimitPut(ctx context.Context, item string, productLimitRequest models.ProductLimitRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminSetProductLimit(ctx, item, productLimitRequest)
+}
+
+// ApiAdminPurchaseRefundPost - Вернуть покупку любого пользователя без ограничения по сроку.
+func (s *AdminAPIService) ApiAdminPurchaseRefundPost(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleAdminRefundPurchase(ctx, id)
+}
+
+// ApiAdminPromoCodesPost - Создать промокод.
+func (s *AdminAPIService) ApiAdminPromoCodesPost(ctx context.Context, promoCodeRequest models.PromoCodeRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminAddPromoCode(ctx, promoCodeRequest)
+}
+
+// ApiAdminPromoCodesGet - Получить все промокоды.
+func (s *AdminAPIService) ApiAdminPromoCodesGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleAdminPromoCodes(ctx)
+}
+
+// ApiAdminPromoCodeDelete - Отключить промокод.
+func (s *AdminAPIService) ApiAdminPromoCodeDelete(ctx context.Context, code string) (models.ImplResponse, error) {
+	return s.engine.HandleAdminDisablePromoCode(ctx, code)
+}
+
+// ApiAdminCampaignsPost - Создать акцию на товар.
+func (s *AdminAPIService) ApiAdminCampaignsPost(ctx context.Context, priceCampaignRequest models.PriceCampaignRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminAddPriceCampaign(ctx, priceCampaignRequest)
+}
+
+// ApiAdminCampaignsGet - Получить незакончившиеся акции.
+func (s *AdminAPIService) ApiAdminCampaignsGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleAdminPriceCampaigns(ctx)
+}
+
+// ApiAdminCampaignDelete - Удалить акцию.
+func (s *AdminAPIService) ApiAdminCampaignDelete(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleAdminDeletePriceCampaign(ctx, id)
+}
+
+// ApiAdminGrantsPost - Начислить монеты пользователю.
+func (s *AdminAPIService) ApiAdminGrantsPost(ctx context.Context, grantRequest models.GrantRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminGrantCoins(ctx, grantRequest)
+}
+
+// ApiAdminGrantsBulkPost - Начислить монеты пакетом.
+func (s *AdminAPIService) ApiAdminGrantsBulkPost(ctx context.Context, bulkGrantRequest models.BulkGrantRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminBulkGrantCoins(ctx, bulkGrantRequest)
+}
+
+// ApiAdminGrantSchedulesPost - Создать регулярное начисление.
+func (s *AdminAPIService) ApiAdminGrantSchedulesPost(ctx context.Context, grantScheduleRequest models.GrantScheduleRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminAddGrantSchedule(ctx, grantScheduleRequest)
+}
+
+// ApiAdminGrantSchedulesGet - Получить регулярные начисления.
+func (s *AdminAPIService) ApiAdminGrantSchedulesGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleAdminGrantSchedules(ctx)
+}
+
+// ApiAdminGrantScheduleDelete - Остановить регулярное начисление.
+func (s *AdminAPIService) ApiAdminGrantScheduleDelete(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleAdminStopGrantSchedule(ctx, id)
+}
+
+// ApiAdminUserAdjustmentsPost - Исправить баланс пользователя.
+func (s *AdminAPIService) ApiAdminUserAdjustmentsPost(ctx context.Context, username string, adjustmentRequest models.AdjustmentRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminAdjustBalance(ctx, username, adjustmentRequest)
+}
+
+// ApiAdminUserGet - Получить баланс пользователя и сведения о блокировке.
+func (s *AdminAPIService) ApiAdminUserGet(ctx context.Context, username string) (models.ImplResponse, error) {
+	return s.engine.HandleAdminUserStatus(ctx, username)
+}
+
+// ApiAdminUserFreezePost - Заблокировать пользователя.
+func (s *AdminAPIService) ApiAdminUserFreezePost(ctx context.Context, username string, freezeRequest models.FreezeRequest) (models.ImplResponse, error) {
+	return s.engine.HandleAdminFreezeUser(ctx, username, freezeRequest)
+}
+
+// ApiAdminUserFreezeDelete - Снять блокировку пользователя.
+func (s *AdminAPIService) ApiAdminUserFreezeDelete(ctx context.Context, username string) (models.ImplResponse, error) {
+	return s.engine.HandleAdminUnfreezeUser(ctx, username)
+}
+
+// ApiAdminActionsGet - Получить журнал действий администраторов.
+func (s *AdminAPIService) ApiAdminActionsGet(ctx context.Context, admin string, before int64, limit int32) (models.ImplResponse, error) {
+	return s.engine.HandleAdminActions(ctx, admin, before, limit)
+}
+
+// RecordAdminAction saves an admin request to the audit log
+func (s *AdminAPIService) RecordAdminAction(ctx context.Context, method, path string, status int, body string) {
+	s.engine.RecordAdminAction(ctx, method, path, status, body)
+}
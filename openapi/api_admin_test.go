@@ -0,0 +1,30 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGrantsCSV(t *testing.T) {
+	grants, err := decodeGrantsCSV(strings.NewReader("username,amount,reason,expiresAt\n" +
+		"bob,100\n" +
+		"eve, 200, Хакатон, 2025-06-01T00:00:00Z\n"))
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+	assert.Equal(t, "bob", grants[0].Username)
+	assert.Equal(t, int32(100), grants[0].Amount)
+	assert.Nil(t, grants[0].ExpiresAt)
+	assert.Equal(t, "Хакатон", grants[1].Reason)
+	require.NotNil(t, grants[1].ExpiresAt)
+	assert.True(t, grants[1].ExpiresAt.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	_, err = decodeGrantsCSV(strings.NewReader("bob,100\neve,много\n"))
+	assert.ErrorContains(t, err, "строка 2: amount")
+
+	_, err = decodeGrantsCSV(strings.NewReader("bob\n"))
+	assert.ErrorContains(t, err, "строка 1")
+}
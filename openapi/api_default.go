@@ -66,6 +66,78 @@ func (c *DefaultAPIController) Routes() Routes {
 			c.ApiAuthPost,
 			false,
 		},
+		"ApiProductsGet": Route{
+			strings.ToUpper("Get"),
+			"/api/products",
+			c.ApiProductsGet,
+			true,
+		},
+		"ApiPurchaseRefundPost": Route{
+			strings.ToUpper("Post"),
+			"/api/purchases/{id}/refund",
+			c.ApiPurchaseRefundPost,
+			true,
+		},
+		"ApiGiftPost": Route{
+			strings.ToUpper("Post"),
+			"/api/gift",
+			c.ApiGiftPost,
+			true,
+		},
+		"ApiSendItemPost": Route{
+			strings.ToUpper("Post"),
+			"/api/sendItem",
+			c.ApiSendItemPost,
+			true,
+		},
+		"ApiTradesGet": Route{
+			strings.ToUpper("Get"),
+			"/api/trades",
+			c.ApiTradesGet,
+			true,
+		},
+		"ApiTradesPost": Route{
+			strings.ToUpper("Post"),
+			"/api/trades",
+			c.ApiTradesPost,
+			true,
+		},
+		"ApiTradeAcceptPost": Route{
+			strings.ToUpper("Post"),
+			"/api/trades/{id}/accept",
+			c.ApiTradeAcceptPost,
+			true,
+		},
+		"ApiTradeDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/trades/{id}",
+			c.ApiTradeDelete,
+			true,
+		},
+		"ApiCartGet": Route{
+			strings.ToUpper("Get"),
+			"/api/cart",
+			c.ApiCartGet,
+			true,
+		},
+		"ApiCartPost": Route{
+			strings.ToUpper("Post"),
+			"/api/cart",
+			c.ApiCartPost,
+			true,
+		},
+		"ApiCartItemDelete": Route{
+			strings.ToUpper("Delete"),
+			"/api/cart/{item}",
+			c.ApiCartItemDelete,
+			true,
+		},
+		"ApiCartCheckoutPost": Route{
+			strings.ToUpper("Post"),
+			"/api/cart/checkout",
+			c.ApiCartCheckoutPost,
+			true,
+		},
 	}
 }
 
@@ -111,12 +183,21 @@ func (c *DefaultAPIController) ApiSendCoinPost(w http.ResponseWriter, r *http.Re
 // ApiBuyItemGet - Купить предмет за монеты.
 func (c *DefaultAPIController) ApiBuyItemGet(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
 	itemParam := params["item"]
 	if itemParam == "" {
 		c.errorHandler(w, r, &models.RequiredError{Field: "item"}, nil)
 		return
 	}
-	result, err := c.service.ApiBuyItemGet(r.Context(), itemParam)
+	var promoCodeParam string
+	if query.Has("promoCode") {
+		promoCodeParam = query.Get("promoCode")
+	}
+	result, err := c.service.ApiBuyItemGet(r.Context(), itemParam, promoCodeParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
@@ -152,3 +233,240 @@ func (c *DefaultAPIController) ApiAuthPost(w http.ResponseWriter, r *http.Reques
 	// If no error, encode the body and the result code
 	_ = EncodeJSONResponse(result.Body, &result.Code, w)
 }
+
+// ApiProductsGet - Получить каталог товаров с ценами и остатками.
+func (c *DefaultAPIController) ApiProductsGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiProductsGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiPurchaseRefundPost - Вернуть свою покупку.
+func (c *DefaultAPIController) ApiPurchaseRefundPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiPurchaseRefundPost(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiGiftPost - Купить предмет в подарок другому пользователю.
+func (c *DefaultAPIController) ApiGiftPost(w http.ResponseWriter, r *http.Request) {
+	var giftRequestParam models.GiftRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&giftRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertGiftRequestRequired(giftRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertGiftRequestConstraints(giftRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiGiftPost(r.Context(), giftRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiSendItemPost - Передать предметы из инвентаря другому пользователю.
+func (c *DefaultAPIController) ApiSendItemPost(w http.ResponseWriter, r *http.Request) {
+	var sendItemRequestParam models.SendItemRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&sendItemRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertSendItemRequestRequired(sendItemRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertSendItemRequestConstraints(sendItemRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiSendItemPost(r.Context(), sendItemRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiTradesGet - Получить отправленные и полученные предложения обмена.
+func (c *DefaultAPIController) ApiTradesGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiTradesGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiTradesPost - Предложить обмен другому пользователю.
+func (c *DefaultAPIController) ApiTradesPost(w http.ResponseWriter, r *http.Request) {
+	var tradeRequestParam models.TradeRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&tradeRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertTradeRequestRequired(tradeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertTradeRequestConstraints(tradeRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiTradesPost(r.Context(), tradeRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiTradeAcceptPost - Принять полученное предложение обмена.
+func (c *DefaultAPIController) ApiTradeAcceptPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiTradeAcceptPost(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiTradeDelete - Отменить своё или отклонить полученное предложение обмена.
+func (c *DefaultAPIController) ApiTradeDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	idParam, err := parseNumericParameter[int64](
+		params["id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Param: "id", Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApiTradeDelete(r.Context(), idParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiCartGet - Получить корзину.
+func (c *DefaultAPIController) ApiCartGet(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiCartGet(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiCartPost - Добавить товар в корзину.
+func (c *DefaultAPIController) ApiCartPost(w http.ResponseWriter, r *http.Request) {
+	var cartItemRequestParam models.CartItemRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&cartItemRequestParam); err != nil {
+		c.errorHandler(w, r, &models.ParsingError{Err: err}, nil)
+		return
+	}
+	if err := models.AssertCartItemRequestRequired(cartItemRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := models.AssertCartItemRequestConstraints(cartItemRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.ApiCartPost(r.Context(), cartItemRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiCartItemDelete - Убрать товар из корзины.
+func (c *DefaultAPIController) ApiCartItemDelete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	itemParam := params["item"]
+	if itemParam == "" {
+		c.errorHandler(w, r, &models.RequiredError{Field: "item"}, nil)
+		return
+	}
+	result, err := c.service.ApiCartItemDelete(r.Context(), itemParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApiCartCheckoutPost - Купить всё содержимое корзины.
+func (c *DefaultAPIController) ApiCartCheckoutPost(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ApiCartCheckoutPost(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
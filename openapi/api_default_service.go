@@ -30,8 +30,11 @@ func (s *DefaultAPIService) ApiSendCoinPost(ctx context.Context, sendCoinRequest
 	return s.engine.HandleApiSendCoin(ctx, sendCoinRequest)
 }
 
-// ApiBuyItemGet - Купить предмет за монеты.
-func (s *DefaultAPIService) ApiBuyItemGet(ctx context.Context, item string) (models.ImplResponse, error) {
+// ApiBuyItemGet - Купить предмет за монеты, при необходимости со скидкой по промокоду.
+func (s *DefaultAPIService) ApiBuyItemGet(ctx context.Context, item string, promoCode string) (models.ImplResponse, error) {
+	if promoCode != "" {
+		return s.engine.HandleApiBuyItemWithPromoCode(ctx, item, promoCode)
+	}
 	return s.engine.HandleApiByuItem(ctx, item)
 }
 
@@ -39,3 +42,63 @@ func (s *DefaultAPIService) ApiBuyItemGet(ctx context.Context, item string) (mod
 func (s *DefaultAPIService) ApiAuthPost(ctx context.Context, authRequest models.AuthRequest) (models.ImplResponse, error) {
 	return s.engine.HandleApiAuth(ctx, authRequest)
 }
+
+// ApiProductsGet - Получить каталог товаров с ценами и остатками.
+func (s *DefaultAPIService) ApiProductsGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleApiProducts(ctx)
+}
+
+// ApiPurchaseRefundPost - Вернуть свою покупку.
+func (s *DefaultAPIService) ApiPurchaseRefundPost(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleApiRefundPurchase(ctx, id)
+}
+
+// ApiGiftPost - Купить предмет в подарок другому пользователю.
+func (s *DefaultAPIService) ApiGiftPost(ctx context.Context, giftRequest models.GiftRequest) (models.ImplResponse, error) {
+	return s.engine.HandleApiGiftItem(ctx, giftRequest)
+}
+
+// ApiSendItemPost - Передать предметы из инвентаря другому пользователю.
+func (s *DefaultAPIService) ApiSendItemPost(ctx context.Context, sendItemRequest models.SendItemRequest) (models.ImplResponse, error) {
+	return s.engine.HandleApiSendItem(ctx, sendItemRequest)
+}
+
+// ApiTradesGet - Получить отправленные и полученные предложения обмена.
+func (s *DefaultAPIService) ApiTradesGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleApiTrades(ctx)
+}
+
+// ApiTradesPost - Предложить обмен другому пользователю.
+func (s *DefaultAPIService) ApiTradesPost(ctx context.Context, tradeRequest models.TradeRequest) (models.ImplResponse, error) {
+	return s.engine.HandleApiCreateTrade(ctx, tradeRequest)
+}
+
+// ApiTradeAcceptPost - Принять полученное предложение обмена.
+func (s *DefaultAPIService) ApiTradeAcceptPost(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleApiAcceptTrade(ctx, id)
+}
+
+// ApiTradeDelete - Отменить своё или отклонить полученное предложение обмена.
+func (s *DefaultAPIService) ApiTradeDelete(ctx context.Context, id int64) (models.ImplResponse, error) {
+	return s.engine.HandleApiCloseTrade(ctx, id)
+}
+
+// ApiCartGet - Получить корзину.
+func (s *DefaultAPIService) ApiCartGet(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleApiCart(ctx)
+}
+
+// ApiCartPost - Добавить товар в корзину.
+func (s *DefaultAPIService) ApiCartPost(ctx context.Context, cartItemRequest models.CartItemRequest) (models.ImplResponse, error) {
+	return s.engine.HandleApiAddToCart(ctx, cartItemRequest)
+}
+
+// ApiCartItemDelete - Убрать товар из корзины.
+func (s *DefaultAPIService) ApiCartItemDelete(ctx context.Context, item string) (models.ImplResponse, error) {
+	return s.engine.HandleApiRemoveFromCart(ctx, item)
+}
+
+// ApiCartCheckoutPost - Купить всё содержимое корзины.
+func (s *DefaultAPIService) ApiCartCheckoutPost(ctx context.Context) (models.ImplResponse, error) {
+	return s.engine.HandleApiCheckout(ctx)
+}
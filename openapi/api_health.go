@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"api-avito-shop/health"
+	"net/http"
+	"strings"
+)
+
+// HealthAPIController binds health check endpoints. The routes do not require JWT
+type HealthAPIController struct {
+	live  *health.Checker
+	ready *health.Checker
+}
+
+// NewHealthAPIController creates a health api controller with liveness and readiness checks
+func NewHealthAPIController(live, ready *health.Checker) *HealthAPIController {
+	return &HealthAPIController{
+		live:  live,
+		ready: ready,
+	}
+}
+
+// Routes returns all the api routes for the HealthAPIController
+func (c *HealthAPIController) Routes() Routes {
+	return Routes{
+		"Healthz": Route{
+			strings.ToUpper("Get"),
+			"/healthz",
+			c.Healthz,
+			false,
+		},
+		"Livez": Route{
+			strings.ToUpper("Get"),
+			"/livez",
+			c.Livez,
+			false,
+		},
+		"Readyz": Route{
+			strings.ToUpper("Get"),
+			"/readyz",
+			c.Readyz,
+			false,
+		},
+	}
+}
+
+// Healthz - Процесс запущен и обрабатывает запросы.
+func (c *HealthAPIController) Healthz(w http.ResponseWriter, r *http.Request) {
+	_ = EncodeJSONResponse(health.Report{Status: health.StatusOk}, nil, w)
+}
+
+// Livez - Результат проверок живости процесса.
+func (c *HealthAPIController) Livez(w http.ResponseWriter, r *http.Request) {
+	encodeHealthReport(c.live.Run(r.Context()), w)
+}
+
+// Readyz - Результат проверок готовности принимать трафик (БД, миграции).
+func (c *HealthAPIController) Readyz(w http.ResponseWriter, r *http.Request) {
+	encodeHealthReport(c.ready.Run(r.Context()), w)
+}
+
+func encodeHealthReport(report health.Report, w http.ResponseWriter) {
+	code := http.StatusOK
+	if !report.Ok() {
+		code = http.StatusServiceUnavailable
+	}
+	_ = EncodeJSONResponse(report, &code, w)
+}
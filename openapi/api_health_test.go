@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"api-avito-shop/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	var dbErr error
+	live := health.NewChecker(time.Second)
+	ready := health.NewChecker(time.Second).
+		Add("database", func(ctx context.Context) error { return dbErr })
+	router := NewRouter([]Router{NewHealthAPIController(live, ready)})
+
+	serve := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	// проверки не требуют токена
+	for _, path := range []string{"/healthz", "/livez", "/readyz"} {
+		code, report := serve(path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, health.StatusOk, report.Status, path)
+	}
+	_, report := serve("/readyz")
+	assert.Equal(t, health.StatusOk, report.Components["database"].Status)
+
+	// отказ компонента готовности отдаёт 503 с ошибкой компонента, живость и healthz от него не зависят
+	dbErr = errors.New("connection refused")
+	code, report := serve("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, health.StatusFail, report.Components["database"].Status)
+	assert.Equal(t, "connection refused", report.Components["database"].Error)
+	for _, path := range []string{"/healthz", "/livez"} {
+		code, report := serve(path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, health.StatusOk, report.Status, path)
+	}
+}
@@ -1,33 +1,27 @@
-// Code generated by OpenAPI Generator (https://openapi-generator.tech); DO NOT EDIT.
-
-/*
- * API Avito shop
- *
- * No description provided (generated by Openapi Generator https://github.com/openapitools/openapi-generator)
- *
- * API version: 1.0.0
- */
-
 package openapi
 
 import (
-	"log"
+	"api-avito-shop/logging"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
+// Logger stores the route name in the request context and writes an access log record
 func Logger(inner http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		ctx := logging.WithRoute(r.Context(), name)
+		recorder := newStatusRecorder(w)
 
-		inner.ServeHTTP(w, r)
+		inner.ServeHTTP(recorder, r.WithContext(ctx))
 
-		log.Printf(
-			"%s %s %s %s",
-			r.Method,
-			r.RequestURI,
-			name,
-			time.Since(start),
+		slog.InfoContext(ctx, "request handled",
+			"method", r.Method,
+			"uri", r.RequestURI,
+			"status", recorder.status,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
 		)
 	})
 }
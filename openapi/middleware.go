@@ -0,0 +1,290 @@
+package openapi
+
+import (
+	"api-avito-shop/logging"
+	"api-avito-shop/metrics"
+	"api-avito-shop/models"
+	"api-avito-shop/ratelimit"
+	"api-avito-shop/tracing"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/form3tech-oss/jwt-go"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header used to propagate the request id
+const RequestIDHeader = "X-Request-ID"
+
+// максимальная длина принимаемого от клиента идентификатора запроса
+const maxRequestIDLength = 128
+
+// statusRecorder remembers the status code written by the inner handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Metrics records request count, latency and errors for the named route
+func Metrics(inner http.Handler, name string, m *metrics.Metrics) http.Handler {
+	if m == nil {
+		return inner
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newStatusRecorder(w)
+
+		inner.ServeHTTP(recorder, r)
+
+		m.ObserveRequest(name, r.Method, recorder.status, time.Since(start))
+	})
+}
+
+// RequestID takes the request id from the X-Request-ID header or generates a new one,
+// stores it in the request context and echoes it in the response
+func RequestID(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		inner.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Tracing continues the trace from the W3C traceparent header (or starts a new one)
+// and wraps the request into a server span named after the route
+func Tracing(inner http.Handler, name, pattern string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(pattern),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request.id", logging.RequestID(ctx)),
+			),
+		)
+		defer span.End()
+
+		recorder := newStatusRecorder(w)
+		inner.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// rate limit response headers, see draft-ietf-httpapi-ratelimit-headers
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// ErrorTooManyRequests is returned in the body of 429 responses
+const ErrorTooManyRequests = "слишком много запросов, повторите позже"
+
+// RateLimit takes a token from the bucket of the named route for the key returned by key
+// and rejects the request with 429 when the bucket is empty. Requests with an empty key
+// are not limited. When the store is unavailable requests are let through.
+// Several RateLimit middlewares on one route report the most restrictive state in the headers
+func RateLimit(inner http.Handler, name, kind string, limiter *ratelimit.Limiter, key func(*http.Request) string) http.Handler {
+	if limiter == nil {
+		return inner
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if k == "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		res, limited, err := limiter.Take(r.Context(), name, kind, k)
+		if err != nil {
+			slog.WarnContext(r.Context(), "rate limit", "kind", kind, "error", err)
+			inner.ServeHTTP(w, r)
+			return
+		}
+		if !limited {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		setRateLimitHeaders(w.Header(), res)
+		if !res.Allowed {
+			slog.InfoContext(r.Context(), "rate limited", "kind", kind, "key", k)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			status := http.StatusTooManyRequests
+			_ = EncodeJSONResponse(models.ErrorResponse{Errors: ErrorTooManyRequests}, &status, w)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+func setRateLimitHeaders(h http.Header, res ratelimit.Result) {
+	if current, err := strconv.Atoi(h.Get(RateLimitRemainingHeader)); err == nil && current < res.Remaining {
+		return
+	}
+	h.Set(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+	h.Set(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+	h.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(res.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP returns a key function for the client address. With trustForwardedFor
+// the address is taken from the last X-Forwarded-For entry, which is added by the nearest proxy
+func ClientIP(trustForwardedFor bool) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if trustForwardedFor {
+			if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+				last := forwarded[len(forwarded)-1]
+				if i := strings.LastIndexByte(last, ','); i >= 0 {
+					last = last[i+1:]
+				}
+				if ip := strings.TrimSpace(last); ip != "" {
+					return ip
+				}
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// JwtUser is a key function for the user name from a token already validated by the JWT middleware
+func JwtUser(r *http.Request) string {
+	token, ok := r.Context().Value(models.JwtUserKey).(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	username, _ := claims["username"].(string)
+	return username
+}
+
+// ErrorAdminUnauthorized is returned in the body of 401 responses of the admin API
+const ErrorAdminUnauthorized = "требуется токен администратора"
+
+// AdminAuth lets through requests with one of the admin tokens in the Authorization: Bearer header
+// and stores the admin name in the request context for logs. Without tokens every request is rejected.
+// Rejected requests are recorded by auditor, if set, with an empty admin name and the start of the body
+func AdminAuth(inner http.Handler, tokens map[string]string, auditor AdminAuditor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := adminName(r, tokens)
+		if !ok {
+			status := http.StatusUnauthorized
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			_ = EncodeJSONResponse(models.ErrorResponse{Errors: ErrorAdminUnauthorized}, &status, w)
+			if auditor != nil {
+				body, _ := io.ReadAll(io.LimitReader(r.Body, maxAuditBody))
+				ctx := context.WithoutCancel(r.Context())
+				auditor.RecordAdminAction(ctx, r.Method, r.URL.Path, status, strings.ToValidUTF8(string(body), ""))
+			}
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(logging.WithAdmin(r.Context(), name)))
+	})
+}
+
+// adminName возвращает имя администратора по токену из запроса. Все токены сравниваются
+// за постоянное время, чтобы по времени ответа нельзя было подобрать токен
+func adminName(r *http.Request, tokens map[string]string) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	found := ""
+	for name, expected := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			found = name
+		}
+	}
+	return found, found != ""
+}
+
+// maxAuditBody limits the part of the request body kept in the admin audit log
+const maxAuditBody = 4096
+
+// AdminAuditor saves admin requests to the audit log
+type AdminAuditor interface {
+	RecordAdminAction(ctx context.Context, method, path string, status int, body string)
+}
+
+// AdminAudit records every admin request, reads included, in the audit log together with the response status.
+// Only the start of the body the handler has read is kept. The record is saved after the response, so it must
+// go inside AdminAuth which puts the admin name into the context; AdminAuth records rejected requests itself
+func AdminAudit(inner http.Handler, auditor AdminAuditor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := &auditBody{ReadCloser: r.Body}
+		audited := r.Clone(r.Context())
+		audited.Body = body
+		recorder := newStatusRecorder(w)
+
+		inner.ServeHTTP(recorder, audited)
+
+		// клиент мог уже отключиться, а запись в журнал всё равно нужна
+		ctx := context.WithoutCancel(r.Context())
+		auditor.RecordAdminAction(ctx, r.Method, r.URL.Path, recorder.status, strings.ToValidUTF8(body.buf.String(), ""))
+	})
+}
+
+// auditBody keeps the first maxAuditBody bytes read from the request body
+type auditBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxAuditBody - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"api-avito-shop/config"
+	"api-avito-shop/logging"
+	"api-avito-shop/models"
+	"api-avito-shop/ratelimit"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/form3tech-oss/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestIDPropagated(t *testing.T) {
+	var got string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.Header.Set(RequestIDHeader, "client-request-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-request-id", got)
+	assert.Equal(t, "client-request-id", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	var got string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.RequestID(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+
+	assert.Len(t, got, 32)
+	assert.Equal(t, got, w.Header().Get(RequestIDHeader))
+}
+
+func TestLoggerStoresRoute(t *testing.T) {
+	var got string
+	handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.Route(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}), "ApiInfoGet")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+
+	assert.Equal(t, "ApiInfoGet", got)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestTracingContinuesTraceparent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	handler := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}), "ApiInfoGet", "/api/info")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "ApiInfoGet", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func withUser(r *http.Request, username string) *http.Request {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": username})
+	return r.WithContext(context.WithValue(r.Context(), models.JwtUserKey, token))
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemory(), config.RateLimit{
+		Default: config.RouteLimits{User: config.Limit{Rate: 0.5, Burst: 2}},
+	})
+	handler := RateLimit(okHandler(), "ApiInfoGet", ratelimit.KindUser, limiter, JwtUser)
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/info", nil), "user1"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get(RateLimitLimitHeader))
+		assert.Equal(t, strconv.Itoa(remaining), w.Header().Get(RateLimitRemainingHeader))
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/info", nil), "user1"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "4", w.Header().Get(RateLimitResetHeader))
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), ErrorTooManyRequests)
+
+	// у другого пользователя своя корзина
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/info", nil), "user2"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// запрос без пользователя не ограничивается
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(RateLimitLimitHeader))
+}
+
+func TestRateLimitHeadersMostRestrictive(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemory(), config.RateLimit{
+		Default: config.RouteLimits{User: config.Limit{Rate: 1, Burst: 2}, IP: config.Limit{Rate: 1, Burst: 10}},
+	})
+	handler := RateLimit(okHandler(), "ApiInfoGet", ratelimit.KindUser, limiter, JwtUser)
+	handler = RateLimit(handler, "ApiInfoGet", ratelimit.KindIP, limiter, ClientIP(false))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/info", nil), "user1"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "1", w.Header().Get(RateLimitRemainingHeader))
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	inner := okHandler()
+	handler := RateLimit(inner, "ApiInfoGet", ratelimit.KindIP, nil, ClientIP(false))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(RateLimitLimitHeader))
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.RemoteAddr = "10.0.0.1:54321"
+	req.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	req.Header.Add("X-Forwarded-For", "3.3.3.3")
+
+	// клиент может подставить любой X-Forwarded-For, поэтому без доверия к прокси берётся адрес соединения
+	assert.Equal(t, "10.0.0.1", ClientIP(false)(req))
+	assert.Equal(t, "3.3.3.3", ClientIP(true)(req))
+
+	req.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.0.0.1", ClientIP(true)(req))
+}
+
+func TestAdminAuth(t *testing.T) {
+	var admin string
+	handler := AdminAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin = logging.Admin(r.Context())
+	}), map[string]string{"alice": "token1", "bob": "token2"}, nil)
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		admin = ""
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/products/cup/stock", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("Bearer token2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", admin)
+
+	for _, authorization := range []string{"", "Bearer", "Bearer token3", "Basic token1", "token1"} {
+		w = serve(authorization)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, authorization)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, w.Body.String(), ErrorAdminUnauthorized)
+		assert.Empty(t, admin)
+	}
+
+	// без настроенных токенов административный API закрыт
+	handler = AdminAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer ").Code)
+}
+
+type auditRecord struct {
+	admin, method, path, body string
+	status                    int
+}
+
+type auditRecorder []auditRecord
+
+func (a *auditRecorder) RecordAdminAction(ctx context.Context, method, path string, status int, body string) {
+	*a = append(*a, auditRecord{logging.Admin(ctx), method, path, body, status})
+}
+
+func TestAdminAudit(t *testing.T) {
+	var records auditRecorder
+	handler := AdminAuth(AdminAudit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+	}), &records), map[string]string{"alice": "token1"}, &records)
+
+	serve := func(method, token, body string) {
+		req := httptest.NewRequest(method, "/api/admin/users/bob/freeze", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(http.MethodPost, "token1", `{"reason":"взлом"}`)
+	serve(http.MethodGet, "token1", "")
+	// отклонённые попытки записываются без имени администратора
+	serve(http.MethodPost, "token2", `{"reason":"подбор"}`)
+	// в журнал попадает только начало тела, без разрезанных символов
+	serve(http.MethodPost, "token1", "a"+strings.Repeat("я", maxAuditBody))
+	serve(http.MethodPost, "token2", "a"+strings.Repeat("я", maxAuditBody))
+
+	require.Len(t, records, 5)
+	assert.Equal(t, auditRecord{"alice", http.MethodPost, "/api/admin/users/bob/freeze", `{"reason":"взлом"}`, http.StatusBadRequest}, records[0])
+	assert.Equal(t, auditRecord{"alice", http.MethodGet, "/api/admin/users/bob/freeze", "", http.StatusBadRequest}, records[1])
+	assert.Equal(t, auditRecord{"", http.MethodPost, "/api/admin/users/bob/freeze", `{"reason":"подбор"}`, http.StatusUnauthorized}, records[2])
+	assert.Equal(t, "a"+strings.Repeat("я", maxAuditBody/2-1), records[3].body)
+	assert.Equal(t, records[3].body, records[4].body)
+}
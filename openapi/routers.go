@@ -1,7 +1,10 @@
 package openapi
 
 import (
+	"api-avito-shop/config"
+	"api-avito-shop/metrics"
 	"api-avito-shop/models"
+	"api-avito-shop/ratelimit"
 	"encoding/json"
 	"errors"
 	"io"
@@ -38,30 +41,74 @@ const errMsgRequiredMissing = "required parameter is missing"
 const errMsgMinValueConstraint = "provided parameter is not respecting minimum value constraint"
 const errMsgMaxValueConstraint = "provided parameter is not respecting maximum value constraint"
 
+// RouterOption for how the router is set up.
+type RouterOption func(*routerOptions)
+
+type routerOptions struct {
+	cfg     *config.Config
+	metrics *metrics.Metrics
+	limiter *ratelimit.Limiter
+}
+
+// WithRouterConfig inject service configuration into router
+func WithRouterConfig(cfg *config.Config) RouterOption {
+	return func(o *routerOptions) {
+		o.cfg = cfg
+	}
+}
+
+// WithRouterMetrics enables per-route metrics and exposes them on /metrics
+func WithRouterMetrics(m *metrics.Metrics) RouterOption {
+	return func(o *routerOptions) {
+		o.metrics = m
+	}
+}
+
+// WithRouterRateLimiter limits request rate per route by client address and by the user from the JWT
+func WithRouterRateLimiter(l *ratelimit.Limiter) RouterOption {
+	return func(o *routerOptions) {
+		o.limiter = l
+	}
+}
+
 // Функция для создания нового JWT Middleware
-func NewJWTMiddleware() *jwtmiddleware.JWTMiddleware {
+func NewJWTMiddleware(key string, debug bool) *jwtmiddleware.JWTMiddleware {
 	var keyFunc jwt.Keyfunc = func(token *jwt.Token) (interface{}, error) {
-		return []byte(models.JwtUniqueKey), nil
+		return []byte(key), nil
 	}
 	jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
 		ValidationKeyGetter: keyFunc,
 		SigningMethod:       jwt.SigningMethodHS256,
-		Debug:               true,
+		Debug:               debug,
 		UserProperty:        models.JwtUserKey,
 	})
 	return jwtMiddleware
 }
 
 // NewRouter creates a new router for any number of api routers
-func NewRouter(routers ...Router) *mux.Router {
+func NewRouter(routers []Router, opts ...RouterOption) *mux.Router {
+	options := &routerOptions{
+		cfg: config.Default(),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	router := mux.NewRouter().StrictSlash(true)
-	jwtMiddleware := NewJWTMiddleware()
+	jwtMiddleware := NewJWTMiddleware(options.cfg.Auth.JwtKey, options.cfg.Log.Level == "debug")
 	for _, api := range routers {
 		for name, route := range api.Routes() {
 			var handler http.Handler = route.HandlerFunc
 			if route.NeedJwt {
-				handler = jwtMiddleware.Handler(Logger(handler, name))
+				// пользователь известен только после проверки токена, иначе поддельный токен расходовал бы чужую корзину
+				handler = RateLimit(handler, name, ratelimit.KindUser, options.limiter, JwtUser)
+				handler = jwtMiddleware.Handler(handler)
 			}
+			handler = RateLimit(handler, name, ratelimit.KindIP, options.limiter, ClientIP(options.limiter.TrustForwardedFor()))
+			handler = Logger(handler, name)
+			handler = Metrics(handler, name, options.metrics)
+			handler = Tracing(handler, name, route.Pattern)
+			handler = RequestID(handler)
 
 			router.
 				Methods(route.Method).
@@ -71,6 +118,14 @@ func NewRouter(routers ...Router) *mux.Router {
 		}
 	}
 
+	if options.metrics != nil {
+		router.
+			Methods(http.MethodGet).
+			Path("/metrics").
+			Name("Metrics").
+			Handler(options.metrics.Handler())
+	}
+
 	return router
 }
 
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval -- как часто Memory удаляет заполнившиеся корзины
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// когда корзина наполнится, после этого её можно удалить без потери состояния
+	full time.Time
+}
+
+// Memory хранит корзины в памяти процесса, у каждого экземпляра сервиса свои счётчики
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit config.Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+	return result(limit, allowed, b.tokens), nil
+}
+
+// sweep удаляет заполнившиеся корзины: новая корзина для того же ключа будет такой же полной
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// Len возвращает число хранимых корзин
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом корзины токенов.
+// Корзина ёмкостью Burst пополняется со скоростью Rate токенов в секунду, каждый запрос забирает один токен.
+// Состояние корзин хранится в памяти процесса или в Redis, если экземпляров сервиса несколько
+package ratelimit
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// виды ограничений маршрута
+const (
+	KindUser = "user"
+	KindIP   = "ip"
+)
+
+// keyPrefix отделяет ключи сервиса от чужих ключей в общем Redis
+const keyPrefix = "avito-shop:ratelimit:"
+
+// Result -- состояние корзины после попытки взять токен
+type Result struct {
+	Allowed bool
+	// ёмкость корзины
+	Limit int
+	// сколько запросов ещё можно сделать подряд
+	Remaining int
+	// через сколько корзина наполнится целиком
+	Reset time.Duration
+	// через сколько появится следующий токен, если запрос отклонён
+	RetryAfter time.Duration
+}
+
+// Store хранит корзины по ключам
+type Store interface {
+	// Take забирает токен из корзины key, создавая полную корзину при первом обращении
+	Take(ctx context.Context, key string, limit config.Limit) (Result, error)
+	Close() error
+}
+
+// Limiter выбирает ограничения маршрута и берёт токены из корзин Store.
+// Методы безопасно вызывать на nil -- ограничения выключены
+type Limiter struct {
+	store Store
+	cfg   config.RateLimit
+}
+
+// New создаёт ограничитель по cfg. Если ограничения выключены, возвращается nil.
+// Хранилище redis подключается к серверу из настроек кеша redisCfg
+func New(ctx context.Context, cfg config.RateLimit, redisCfg config.Cache) (*Limiter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	var store Store
+	switch cfg.Store {
+	case config.RateLimitMemory:
+		store = NewMemory()
+	case config.RateLimitRedis:
+		r, err := NewRedis(ctx, redisCfg)
+		if err != nil {
+			return nil, err
+		}
+		store = r
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище ограничителя: %q", cfg.Store)
+	}
+	return NewLimiter(store, cfg), nil
+}
+
+// NewLimiter создаёт ограничитель поверх готового хранилища
+func NewLimiter(store Store, cfg config.RateLimit) *Limiter {
+	return &Limiter{store: store, cfg: cfg}
+}
+
+// limit возвращает ограничение вида kind для маршрута route
+func (l *Limiter) limit(route, kind string) config.Limit {
+	limits, ok := l.cfg.Routes[route]
+	if !ok {
+		limits = l.cfg.Default
+	}
+	if kind == KindUser {
+		return limits.User
+	}
+	return limits.IP
+}
+
+// Take забирает токен из корзины маршрута route для ключа key (пользователя или адреса).
+// Второе значение false означает, что маршрут этим видом ограничений не ограничен
+func (l *Limiter) Take(ctx context.Context, route, kind, key string) (Result, bool, error) {
+	if l == nil {
+		return Result{}, false, nil
+	}
+	limit := l.limit(route, kind)
+	if limit.Rate <= 0 {
+		return Result{}, false, nil
+	}
+	res, err := l.store.Take(ctx, keyPrefix+route+":"+kind+":"+key, limit)
+	return res, true, err
+}
+
+// TrustForwardedFor сообщает, можно ли брать адрес клиента из X-Forwarded-For
+func (l *Limiter) TrustForwardedFor() bool {
+	return l != nil && l.cfg.TrustForwardedFor
+}
+
+func (l *Limiter) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.store.Close()
+}
+
+// result описывает корзину, в которой после попытки осталось tokens токенов
+func result(limit config.Limit, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
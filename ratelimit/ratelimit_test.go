@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore проверяет общее для всех хранилищ поведение корзины, advance сдвигает часы хранилища
+func testStore(t *testing.T, s Store, advance func(time.Duration)) {
+	ctx := context.Background()
+	limit := config.Limit{Rate: 2, Burst: 3}
+
+	// полная корзина пропускает burst запросов подряд
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take(ctx, "key", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, err := s.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// другие ключи не затронуты
+	res, err = s.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// за полсекунды появляется один токен, за минуту корзина не переполняется
+	advance(500 * time.Millisecond)
+	res, _ = s.Take(ctx, "key", limit)
+	assert.True(t, res.Allowed)
+	res, _ = s.Take(ctx, "key", limit)
+	assert.False(t, res.Allowed)
+
+	advance(time.Minute)
+	res, _ = s.Take(ctx, "key", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestMemory(t *testing.T) {
+	now := time.Now()
+	s := NewMemory()
+	s.now = func() time.Time { return now }
+	testStore(t, s, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestMemorySweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemory()
+	s.now = func() time.Time { return now }
+	limit := config.Limit{Rate: 1, Burst: 100}
+
+	_, _ = s.Take(ctx, "idle", config.Limit{Rate: 1, Burst: 1})
+	for i := 0; i < 100; i++ {
+		_, _ = s.Take(ctx, "busy", limit)
+	}
+	assert.Equal(t, 2, s.Len())
+
+	// через минуту первая корзина давно полна и удаляется, а второй до заполнения ещё 40 секунд
+	now = now.Add(sweepInterval)
+	res, _ := s.Take(ctx, "busy", limit)
+	assert.Equal(t, 1, s.Len())
+	assert.Equal(t, 59, res.Remaining)
+}
+
+func TestMemoryConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	limit := config.Limit{Rate: 0.001, Burst: 10}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.Take(ctx, "key", limit)
+			if err == nil && res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, allowed)
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	s, err := NewRedis(context.Background(), config.Cache{RedisAddr: server.Addr()})
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	testStore(t, s, func(d time.Duration) { now = now.Add(d) })
+
+	// ключ удаляется, когда корзина наполнится
+	assert.Greater(t, server.TTL("key"), time.Duration(0))
+	server.FastForward(time.Minute)
+	assert.False(t, server.Exists("key"))
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(NewMemory(), config.RateLimit{
+		Default: config.RouteLimits{User: config.Limit{Rate: 1, Burst: 1}},
+		Routes: map[string]config.RouteLimits{
+			"ApiSendCoinPost": {User: config.Limit{Rate: 1, Burst: 2}, IP: config.Limit{Rate: 1, Burst: 1}},
+		},
+	})
+
+	// маршрут без своей записи получает ограничения по умолчанию, ip в них не ограничен
+	res, limited, err := l.Take(ctx, "ApiInfoGet", KindUser, "user1")
+	assert.NoError(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 1, res.Limit)
+	_, limited, _ = l.Take(ctx, "ApiInfoGet", KindIP, "10.0.0.1")
+	assert.False(t, limited)
+
+	// у маршрутов и видов ограничений отдельные корзины
+	res, _, _ = l.Take(ctx, "ApiSendCoinPost", KindUser, "user1")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	res, _, _ = l.Take(ctx, "ApiSendCoinPost", KindIP, "user1")
+	assert.True(t, res.Allowed)
+
+	var disabled *Limiter
+	_, limited, err = disabled.Take(ctx, "ApiInfoGet", KindUser, "user1")
+	assert.NoError(t, err)
+	assert.False(t, limited)
+	assert.NoError(t, disabled.Close())
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	l, err := New(ctx, config.RateLimit{Store: config.RateLimitMemory}, config.Cache{})
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+
+	l, err = New(ctx, config.RateLimit{Enabled: true, Store: config.RateLimitMemory}, config.Cache{})
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+
+	_, err = New(ctx, config.RateLimit{Enabled: true, Store: "etcd"}, config.Cache{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"api-avito-shop/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript атомарно пополняет корзину и забирает из неё токен.
+// Время передаёт клиент, чтобы не зависеть от версии сервера; при расхождении часов экземпляров
+// корзина не пополняется задним числом. Ключ живёт, пока корзина не наполнится
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis хранит корзины в Redis, счётчики общие для всех экземпляров сервиса
+type Redis struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedis подключается к серверу из cfg и проверяет его доступность
+func NewRedis(ctx context.Context, cfg config.Cache) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ошибка подключения к Redis %s: %w", cfg.RedisAddr, err)
+	}
+	slog.Info("rate limit store connected", "addr", cfg.RedisAddr, "db", cfg.RedisDB)
+	return &Redis{client: client, now: time.Now}, nil
+}
+
+func (r *Redis) Take(ctx context.Context, key string, limit config.Limit) (Result, error) {
+	reply, err := takeScript.Run(ctx, r.client, []string{key}, limit.Rate, limit.Burst, r.now().UnixMilli()).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ошибка обращения к хранилищу ограничителя: %w", err)
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("неожиданный ответ хранилища ограничителя: %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	s, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("неожиданный ответ хранилища ограничителя: %w", err)
+	}
+	return result(limit, allowed == 1, tokens), nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
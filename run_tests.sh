@@ -1,10 +1,14 @@
 #!/bin/bash
 
 # прогоним юнит-тесты
-cd engine && go test --cover && cd -
+go test --cover ./...
 
-# прогоним интеграционные тесты
+# прогоним общие сценарии хранилища на временном Postgres
+TEST_POSTGRES=embedded go test ./database/ -run Postgres
+
+# прогоним интеграционные тесты; без заданного ключа подписи токенов генерируем случайный на один прогон
 export MODE=test
+export JWT_KEY=${JWT_KEY:-$(head -c 32 /dev/urandom | od -An -tx1 | tr -d ' \n')}
 docker-compose up --build -d
 docker wait $(docker-compose ps -q tests)
 docker-compose logs tests
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"api-avito-shop/config"
+	"api-avito-shop/database"
+	"api-avito-shop/health"
+	"api-avito-shop/metrics"
+	"api-avito-shop/migrations"
+)
+
+// sqlStorage -- хранилище поверх database/sql, схемой которого управляют миграции
+type sqlStorage interface {
+	database.Database
+	DB() *sql.DB
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// openSQL открывает хранилище Postgres или SQLite вместе с мигратором для его диалекта
+func openSQL(cfg config.Database, opts ...migrations.Option) (sqlStorage, *migrations.Migrator, error) {
+	var db sqlStorage
+	var newMigrator func(*sql.DB, ...migrations.Option) (*migrations.Migrator, error)
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		postgres, err := database.NewPostgres(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, newMigrator = postgres, migrations.NewPostgres
+	case config.DriverSQLite:
+		sqlite, err := database.NewSQLite(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, newMigrator = sqlite, migrations.NewSQLite
+	default:
+		return nil, nil, fmt.Errorf("драйвер %q не поддерживает миграции", cfg.Driver)
+	}
+
+	migrator, err := newMigrator(db.DB(), opts...)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, migrator, nil
+}
+
+// openDatabase создаёт хранилище по cfg.Database.Driver и добавляет его проверки в ready.
+// Возвращаемая функция освобождает ресурсы хранилища
+func openDatabase(ctx context.Context, cfg *config.Config, m *metrics.Metrics, ready *health.Checker) (database.Database, func() error, error) {
+	if cfg.Database.Driver == config.DriverMemory {
+		slog.Warn("using in-memory database, data will be lost on restart")
+		db := database.NewMemory()
+		ready.Add("database", db.Ping)
+		return db, func() error { return nil }, nil
+	}
+
+	db, migrator, err := openSQL(cfg.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Database.MigrateOnStart {
+		if _, err := migrator.Up(ctx); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("ошибка применения миграций: %w", err)
+		}
+	}
+
+	m.RegisterDBStats(db.DB(), cfg.Database.Name)
+	if postgres, ok := db.(*database.Postgres); ok {
+		for name, replica := range postgres.Replicas() {
+			m.RegisterDBStats(replica, cfg.Database.Name+"-"+name)
+		}
+	}
+	ready.Add("database", db.Ping).Add("migrations", migrator.Check)
+	return db, db.Close, nil
+}
@@ -1,9 +1,10 @@
+import os
 import requests
 
 from urllib.parse import urljoin
 
 class Server:
-    URL = "http://avito-shop-service:8080"
+    URL = os.environ.get("SERVICE_URL", "http://avito-shop-service:8080")
 
     def get(self, endpoint, headers):
         return requests.get(urljoin(self.URL, endpoint), headers=headers)
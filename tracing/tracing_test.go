@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"api-avito-shop/config"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupStdout(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.Default().Tracing
+	cfg.Exporter = "stdout"
+
+	shutdown, err := Setup(context.Background(), cfg, &buf)
+	assert.NoError(t, err)
+
+	_, span := Tracer().Start(context.Background(), "test-span")
+	span.End()
+
+	// спаны выгружаются батчами, при остановке провайдера они должны записаться
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, buf.String(), "test-span")
+	assert.Contains(t, buf.String(), cfg.ServiceName)
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	cfg := config.Default().Tracing
+	cfg.Exporter = "zipkin"
+
+	_, err := Setup(context.Background(), cfg, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	_, span := Tracer().Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = Tracer().Start(context.Background(), "failed")
+	End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+}